@@ -0,0 +1,61 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Request manager approval
+// @Description Approve a single action the authenticated employee lacks the permission for, such as a refund, a large discount or voiding items. A manager authenticates with their PIN for the business or their username and password, and must hold the permission themselves, within their limits for the amount and percentage the action may come to, as reported in the approval required response. The approval covers at most that amount and percentage. The returned token is passed once in the X-Approval-Token header of the action and expires after a few minutes. The approval is recorded on the order with both accounts.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   approval  body  models.RequestApprovalRequest  true  "Action to approve and manager credentials"
+// @Success 201 {object} models.ApprovalDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 423 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/approval [post]
+// @Id requestApproval
+func (ctrl *Controller) RequestApproval(c *gin.Context) {
+	var req accountModels.RequestApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	approval, err := ctrl.service.RequestApproval(req, userID, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "invalid action", "manager credentials required", "cannot approve own action":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		case "invalid credentials", "two-factor code required", "invalid two-factor code":
+			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		case "unauthorized", "manager lacks this permission":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "order not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "too many failed attempts", "account is temporarily locked":
+			c.IndentedJSON(http.StatusLocked, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, approval)
+}
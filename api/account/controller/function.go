@@ -43,7 +43,7 @@ func (ctrl *Controller) GetAllFunctions(c *gin.Context) {
 }
 
 // @Summary Assign a function to an account role
-// @Description Assign a function to an account role. Only business owners can perform this action.
+// @Description Assign a function to an account role, optionally with a maximum amount or percentage. Assigning a function the role already has replaces its access levels and limits. Only business owners can perform this action.
 // @Tags account
 // @Accept  json
 // @Param   id   path      int  true  "Role ID"
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLoginRateLimitPerIP       = 30
+	defaultLoginRateLimitPerUsername = 10
+)
+
+// loginIPRateLimiter limits login attempts per client IP per minute
+func loginIPRateLimiter() *middleware.RateLimiter {
+	return middleware.NewScopedRateLimiter("login-ip", env.PositiveInt("LOGIN_RATE_LIMIT_PER_IP", defaultLoginRateLimitPerIP), time.Minute, false)
+}
+
+// loginUsernameRateLimiter limits login attempts per username per minute, wherever they come from
+func loginUsernameRateLimiter() *middleware.RateLimiter {
+	return middleware.NewScopedRateLimiter("login-username", env.PositiveInt("LOGIN_RATE_LIMIT_PER_USERNAME", defaultLoginRateLimitPerUsername), time.Minute, false)
+}
+
+// @Summary Unlock account
+// @Description Lift the lockout of an employee's account after repeated failed logins. Requires write access to accounts in one of the employee's businesses.
+// @Tags account
+// @Param   id  path  int  true  "Account ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/{id}/unlock [post]
+// @Id unlockAccount
+func (ctrl *Controller) UnlockAccount(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid account ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.UnlockAccount(uint(accountID), userID); err != nil {
+		switch err.Error() {
+		case "account not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -22,7 +22,7 @@ func NewController() *Controller {
 }
 
 // @Summary Create account
-// @Description Create a new account. If creating an account for a business, authentication is required and the user must be the business owner.
+// @Description Create a new account. If creating an account for a business, authentication is required and the user must be the business owner. The password has to satisfy the password policy.
 // @Tags account
 // @Accept  json
 // @Produce  json
@@ -41,6 +41,10 @@ func (ctrl *Controller) CreateAccount(c *gin.Context) {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		return
 	}
+	if err := req.Validate(); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
 
 	var claims map[string]interface{}
 	var err error
@@ -54,6 +58,10 @@ func (ctrl *Controller) CreateAccount(c *gin.Context) {
 
 	account, err := ctrl.service.CreateAccount(req, claims)
 	if err != nil {
+		if service.IsPasswordPolicyError(err) {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		switch err.Error() {
 		case "unauthorized":
 			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
@@ -71,14 +79,16 @@ func (ctrl *Controller) CreateAccount(c *gin.Context) {
 }
 
 // @Summary Log in to account
-// @Description Log in to account
+// @Description Log in to account. Starts a session and returns a short-lived access token together with a refresh token. Accounts with two-factor authentication enabled also have to send a TOTP or recovery code in twoFactorCode. Requests are rate limited per client and username, and repeated failures lock the account for increasingly long periods.
 // @Tags account
 // @Accept  json
 // @Produce  json
 // @Param   credentials  body  models.LoginRequest  true  "Login credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} models.TokenResponse
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
+// @Failure 423 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
 // @Router /account/login [post]
 // @Id loginAccount
 func (ctrl *Controller) Login(c *gin.Context) {
@@ -88,17 +98,20 @@ func (ctrl *Controller) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := ctrl.service.Login(req)
+	tokens, err := ctrl.service.Login(req, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		if err.Error() == "invalid credentials" {
+		switch err.Error() {
+		case "invalid credentials", "two-factor code required", "invalid two-factor code":
 			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
-		} else {
+		case "account is temporarily locked":
+			c.IndentedJSON(http.StatusLocked, models.HTTPError{Error: err.Error()})
+		default:
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, gin.H{"token": token})
+	c.IndentedJSON(http.StatusOK, tokens)
 }
 
 // @Summary Get account information
@@ -315,15 +328,43 @@ func (ctrl *Controller) UpdateAccountRoleStatus(c *gin.Context) {
 
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	// Public routes
-	r.POST("/account/login", ctrl.Login)
+	r.POST("/account/login",
+		middleware.RateLimitMiddleware(loginIPRateLimiter(), middleware.ClientIPKey),
+		middleware.RateLimitMiddleware(loginUsernameRateLimiter(), middleware.JSONBodyKey("username")),
+		ctrl.Login)
+	r.POST("/account/refresh", ctrl.RefreshToken)
+	r.POST("/account/pin-login", ctrl.PinLogin)
+	r.GET("/account/password-policy", ctrl.GetPasswordPolicy)
+	r.POST("/account/password-reset/request", middleware.RateLimitMiddleware(passwordResetRateLimiter(), func(c *gin.Context) string {
+		return c.ClientIP()
+	}), ctrl.RequestPasswordReset)
+	r.POST("/account/password-reset/confirm", ctrl.ConfirmPasswordReset)
+	r.GET("/account/pin-login/accounts", ctrl.GetPinLoginAccounts)
 	r.POST("/account", ctrl.CreateAccount)
 
-	// Authenticated routes
+	// Authenticated routes. Responses here carry secrets such as 2FA setup, recovery codes and tokens, so they are
+	// not stored for idempotent retries.
 	accountGroup := r.Group("/account")
 	accountGroup.Use(middleware.AuthMiddleware())
 	{
 		accountGroup.GET("/:businessId", ctrl.GetAccounts)
 		accountGroup.GET("/me", ctrl.GetMyAccount)
+		accountGroup.POST("/logout", ctrl.Logout)
+		accountGroup.POST("/logout-all", ctrl.LogoutAllSessions)
+		accountGroup.GET("/sessions", ctrl.GetSessions)
+		accountGroup.DELETE("/sessions/:sessionId", ctrl.RevokeSession)
+		accountGroup.PUT("/password", ctrl.ChangePassword)
+		accountGroup.POST("/:id/password-reset", ctrl.InitiatePasswordReset)
+		accountGroup.POST("/:id/unlock", ctrl.UnlockAccount)
+		accountGroup.POST("/2fa/setup", ctrl.StartTwoFactorSetup)
+		accountGroup.POST("/2fa/enable", ctrl.EnableTwoFactor)
+		accountGroup.POST("/2fa/disable", ctrl.DisableTwoFactor)
+		accountGroup.POST("/2fa/recovery-codes", ctrl.RegenerateRecoveryCodes)
+		accountGroup.POST("/terminal", ctrl.RegisterTerminal)
+		accountGroup.GET("/terminal", ctrl.GetTerminals)
+		accountGroup.DELETE("/terminal/:id", ctrl.RevokeTerminal)
+		accountGroup.PUT("/:id/pin", ctrl.SetPin)
+		accountGroup.DELETE("/:id/pin", ctrl.RemovePin)
 		accountGroup.DELETE("/:id", ctrl.DeleteAccount)
 		accountGroup.GET("/role/:id", ctrl.GetRole)
 		accountGroup.PUT("/role/:id", ctrl.UpdateRole)
@@ -333,5 +374,8 @@ func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 		accountGroup.POST("/role", ctrl.CreateAccountRole)
 		accountGroup.GET("/functions", ctrl.GetAllFunctions)
 		accountGroup.POST("/role/:id/function", ctrl.AssignFunctionToRole)
+		accountGroup.PUT("/role/:id/locations", ctrl.SetRoleLocations)
+		accountGroup.GET("/permissions", ctrl.GetMyPermissions)
+		accountGroup.POST("/approval", ctrl.RequestApproval)
 	}
 }
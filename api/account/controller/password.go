@@ -0,0 +1,167 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/account/service"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultPasswordResetRateLimit = 5
+
+// passwordResetRateLimiter limits self-service reset requests per client IP per hour
+func passwordResetRateLimiter() *middleware.RateLimiter {
+	return middleware.NewRateLimiter(env.PositiveInt("PASSWORD_RESET_RATE_LIMIT", defaultPasswordResetRateLimit), time.Hour)
+}
+
+// @Summary Get password policy
+// @Description Get the rules new passwords have to satisfy
+// @Tags account
+// @Produce  json
+// @Success 200 {object} models.PasswordPolicyDto
+// @Router /account/password-policy [get]
+// @Id getPasswordPolicy
+func (ctrl *Controller) GetPasswordPolicy(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, ctrl.service.GetPasswordPolicy())
+}
+
+// @Summary Change password
+// @Description Change the current account's password. Other sessions of the account are logged out.
+// @Tags account
+// @Accept  json
+// @Param   request  body  models.ChangePasswordRequest  true  "Current and new password"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/password [put]
+// @Id changePassword
+func (ctrl *Controller) ChangePassword(c *gin.Context) {
+	var req accountModels.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	sessionID, _ := middleware.GetSessionIDFromContext(c)
+
+	if err := ctrl.service.ChangePassword(userID, sessionID, req); err != nil {
+		if err.Error() == "current password is incorrect" || service.IsPasswordPolicyError(err) {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Request password reset
+// @Description Send a single-use password reset link to the account. The response is the same whether or not the account exists. Requests are rate limited per client.
+// @Tags account
+// @Accept  json
+// @Param   request  body  models.PasswordResetRequest  true  "Account to reset"
+// @Success 202
+// @Failure 400 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /account/password-reset/request [post]
+// @Id requestPasswordReset
+func (ctrl *Controller) RequestPasswordReset(c *gin.Context) {
+	var req accountModels.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.RequestPasswordReset(req.Username); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Summary Confirm password reset
+// @Description Set a new password with a reset token. The token works once and all sessions of the account are logged out.
+// @Tags account
+// @Accept  json
+// @Param   request  body  models.ConfirmPasswordResetRequest  true  "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /account/password-reset/confirm [post]
+// @Id confirmPasswordReset
+func (ctrl *Controller) ConfirmPasswordReset(c *gin.Context) {
+	var req accountModels.ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.ConfirmPasswordReset(req); err != nil {
+		if err.Error() == "invalid or expired reset token" || service.IsPasswordPolicyError(err) {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Reset an employee's password
+// @Description Send a single-use password reset link to an employee. Requires write access to accounts in one of the employee's businesses.
+// @Tags account
+// @Param   id  path  int  true  "Account ID"
+// @Success 202
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/{id}/password-reset [post]
+// @Id initiatePasswordReset
+func (ctrl *Controller) InitiatePasswordReset(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid account ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.InitiatePasswordReset(uint(accountID), userID); err != nil {
+		switch err.Error() {
+		case "account not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
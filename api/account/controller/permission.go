@@ -0,0 +1,60 @@
+package controller
+
+import (
+	genericModels "VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get my permissions
+// @Description Get what the authenticated user may do in a business, combined across their roles, including monetary limits. When a location is given, roles limited to other locations are left out.
+// @Tags account
+// @Produce  json
+// @Param   businessId  query  int  true   "Business ID"
+// @Param   locationId  query  int  false  "Location ID"
+// @Success 200 {array} models.PermissionDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/permissions [get]
+// @Id getMyPermissions
+func (ctrl *Controller) GetMyPermissions(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, genericModels.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(c.Query("businessId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	var locationID *uint
+	if locationIDStr := c.Query("locationId"); locationIDStr != "" {
+		parsed, err := strconv.ParseUint(locationIDStr, 10, 32)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: "invalid locationId"})
+			return
+		}
+		id := uint(parsed)
+		locationID = &id
+	}
+
+	permissions, err := ctrl.service.GetMyPermissions(userID, uint(businessID), locationID)
+	if err != nil {
+		if err.Error() == "location not found" {
+			c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: err.Error()})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, genericModels.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, permissions)
+}
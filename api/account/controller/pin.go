@@ -0,0 +1,168 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Set PIN
+// @Description Set an account's numeric PIN for logging in on the terminals of a business. Employees can set their own PIN, setting someone else's requires write access to accounts.
+// @Tags account
+// @Accept  json
+// @Param   id   path  int                   true  "Account ID"
+// @Param   pin  body  models.SetPinRequest  true  "PIN to set"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/{id}/pin [put]
+// @Id setPin
+func (ctrl *Controller) SetPin(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid account ID"})
+		return
+	}
+
+	var req accountModels.SetPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.SetPin(uint(accountID), req, userID); err != nil {
+		writePinError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Remove PIN
+// @Description Remove an account's PIN for a business
+// @Tags account
+// @Param   id          path   int  true  "Account ID"
+// @Param   businessId  query  int  true  "Business ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/{id}/pin [delete]
+// @Id removePin
+func (ctrl *Controller) RemovePin(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid account ID"})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(c.Query("businessId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.RemovePin(uint(accountID), uint(businessID), userID); err != nil {
+		writePinError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writePinError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "account not found", "PIN not found":
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+	case "account does not belong to this business":
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+	case "unauthorized":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+	default:
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+	}
+}
+
+// @Summary Get PIN login accounts
+// @Description Get the employees that can log in with a PIN on the terminal identified by the X-Terminal-Token header
+// @Tags account
+// @Produce  json
+// @Param   X-Terminal-Token  header  string  true  "Device token of the terminal"
+// @Success 200 {array} models.PinLoginAccountDto
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /account/pin-login/accounts [get]
+// @Id getPinLoginAccounts
+func (ctrl *Controller) GetPinLoginAccounts(c *gin.Context) {
+	accounts, err := ctrl.service.GetPinLoginAccounts(c.GetHeader(terminalTokenHeader))
+	if err != nil {
+		if err.Error() == "invalid terminal" {
+			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, accounts)
+}
+
+// @Summary Log in with PIN
+// @Description Log in on a registered terminal with an employee PIN. The returned access token is short-lived, cannot be refreshed and is only valid for the terminal's business: requests made with it have to name that business with a businessId path or query parameter or body field, or act on an order or payment of that business identified in the path; other entity routes are refused, except /account/me and /account/logout. Logging in ends the session of whoever was logged in on the terminal before. The PIN is locked after repeated failures.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   X-Terminal-Token  header  string                  true  "Device token of the terminal"
+// @Param   credentials       body    models.PinLoginRequest  true  "PIN login credentials"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 423 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /account/pin-login [post]
+// @Id pinLogin
+func (ctrl *Controller) PinLogin(c *gin.Context) {
+	var req accountModels.PinLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	tokens, err := ctrl.service.PinLogin(c.GetHeader(terminalTokenHeader), req, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "invalid terminal", "invalid credentials":
+			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		case "too many failed attempts":
+			c.IndentedJSON(http.StatusLocked, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, tokens)
+}
@@ -151,6 +151,60 @@ func (ctrl *Controller) UpdateRole(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, role)
 }
 
+// @Summary Set the locations of an account role
+// @Description Limit an account role to locations of its business. An empty list lets the role apply at every location. Only business owners can change roles.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   id   path      int  true  "Role ID"
+// @Param   locations  body  models.SetRoleLocationsRequest  true  "Locations of the role"
+// @Success 200 {object} models.AccountRoleDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/role/{id}/locations [put]
+// @Id setAccountRoleLocations
+func (ctrl *Controller) SetRoleLocations(c *gin.Context) {
+	roleIDStr := c.Param("id")
+	roleID, err := strconv.ParseUint(roleIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: "Invalid role ID"})
+		return
+	}
+
+	var req models.SetRoleLocationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: err.Error()})
+		return
+	}
+
+	claims, err := middleware.AuthorizeAndGetClaims(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, genericModels.HTTPError{Error: err.Error()})
+		return
+	}
+
+	role, err := ctrl.service.SetRoleLocations(uint(roleID), req, claims)
+	if err != nil {
+		switch err.Error() {
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, genericModels.HTTPError{Error: err.Error()})
+		case "role not found":
+			c.IndentedJSON(http.StatusNotFound, genericModels.HTTPError{Error: err.Error()})
+		case "location not found":
+			c.IndentedJSON(http.StatusBadRequest, genericModels.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, genericModels.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, role)
+}
+
 // @Summary Delete an account role by ID
 // @Description Delete an account role by ID. Only business owners can delete roles.
 // @Tags account
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated, the returned one replaces it. Reusing a rotated refresh token revokes the session.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   request  body  models.RefreshTokenRequest  true  "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /account/refresh [post]
+// @Id refreshToken
+func (ctrl *Controller) RefreshToken(c *gin.Context) {
+	var req accountModels.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	tokens, err := ctrl.service.RefreshSession(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, tokens)
+}
+
+// @Summary Log out
+// @Description Revoke the current session, its access and refresh tokens stop working
+// @Tags account
+// @Success 204
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/logout [post]
+// @Id logout
+func (ctrl *Controller) Logout(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	sessionID, err := middleware.GetSessionIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.Logout(sessionID, userID); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Log out all sessions
+// @Description Revoke every session of the current account, including the current one
+// @Tags account
+// @Success 204
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/logout-all [post]
+// @Id logoutAllSessions
+func (ctrl *Controller) LogoutAllSessions(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.LogoutAllSessions(userID); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get sessions
+// @Description Get the active sessions of the current account
+// @Tags account
+// @Produce  json
+// @Success 200 {array} models.SessionDto
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/sessions [get]
+// @Id getSessions
+func (ctrl *Controller) GetSessions(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	sessionID, _ := middleware.GetSessionIDFromContext(c)
+
+	sessions, err := ctrl.service.GetSessions(userID, sessionID)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, sessions)
+}
+
+// @Summary Revoke a session
+// @Description Revoke one of the current account's sessions
+// @Tags account
+// @Param   sessionId  path  int  true  "Session ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/sessions/{sessionId} [delete]
+// @Id revokeSession
+func (ctrl *Controller) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid session ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.RevokeSession(uint(sessionID), userID); err != nil {
+		if err.Error() == "session not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// terminalTokenHeader carries a registered terminal's device token on PIN login requests
+const terminalTokenHeader = "X-Terminal-Token"
+
+// @Summary Register terminal
+// @Description Register a device of the business on which employees log in with their PIN. The returned device token is shown only once.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   terminal  body  models.RegisterTerminalRequest  true  "Terminal to register"
+// @Success 201 {object} models.TerminalDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/terminal [post]
+// @Id registerTerminal
+func (ctrl *Controller) RegisterTerminal(c *gin.Context) {
+	var req accountModels.RegisterTerminalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	terminal, err := ctrl.service.RegisterTerminal(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, terminal)
+}
+
+// @Summary Get terminals
+// @Description Get the registered terminals of a business
+// @Tags account
+// @Produce  json
+// @Param   businessId query int true "Business ID"
+// @Success 200 {array} models.TerminalDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/terminal [get]
+// @Id getTerminals
+func (ctrl *Controller) GetTerminals(c *gin.Context) {
+	businessID, err := strconv.ParseUint(c.Query("businessId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	terminals, err := ctrl.service.GetTerminals(uint(businessID), userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, terminals)
+}
+
+// @Summary Revoke terminal
+// @Description Unregister a terminal. Its device token stops working and whoever is logged in on it is logged out.
+// @Tags account
+// @Param   id  path  int  true  "Terminal ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/terminal/{id} [delete]
+// @Id revokeTerminal
+func (ctrl *Controller) RevokeTerminal(c *gin.Context) {
+	terminalID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid terminal ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.RevokeTerminal(uint(terminalID), userID); err != nil {
+		switch err.Error() {
+		case "terminal not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
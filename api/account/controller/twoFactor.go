@@ -0,0 +1,156 @@
+package controller
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeTwoFactorError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "account not found":
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+	case "two-factor authentication is already enabled":
+		c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+	case "two-factor authentication is not enabled", "two-factor setup not started", "invalid two-factor code":
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+	case "invalid credentials":
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+	default:
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+	}
+}
+
+// @Summary Start two-factor setup
+// @Description Generate a TOTP secret for the current account. Two-factor authentication is enabled once a code from the authenticator app is confirmed.
+// @Tags account
+// @Produce  json
+// @Success 200 {object} models.TwoFactorSetupDto
+// @Failure 401 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/2fa/setup [post]
+// @Id startTwoFactorSetup
+func (ctrl *Controller) StartTwoFactorSetup(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	setup, err := ctrl.service.StartTwoFactorSetup(userID)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, setup)
+}
+
+// @Summary Enable two-factor authentication
+// @Description Confirm the two-factor setup with a code from the authenticator app. Returns recovery codes, which are shown only once.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   request  body  models.TwoFactorCodeRequest  true  "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/2fa/enable [post]
+// @Id enableTwoFactor
+func (ctrl *Controller) EnableTwoFactor(c *gin.Context) {
+	var req accountModels.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	codes, err := ctrl.service.EnableTwoFactor(userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, codes)
+}
+
+// @Summary Disable two-factor authentication
+// @Description Disable two-factor authentication for the current account. Requires the password and a TOTP or recovery code.
+// @Tags account
+// @Accept  json
+// @Param   request  body  models.DisableTwoFactorRequest  true  "Password and second factor"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/2fa/disable [post]
+// @Id disableTwoFactor
+func (ctrl *Controller) DisableTwoFactor(c *gin.Context) {
+	var req accountModels.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.DisableTwoFactor(userID, req); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replace the current account's recovery codes. The old codes stop working.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param   request  body  models.TwoFactorCodeRequest  true  "TOTP or recovery code"
+// @Success 200 {object} models.RecoveryCodesDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /account/2fa/recovery-codes [post]
+// @Id regenerateRecoveryCodes
+func (ctrl *Controller) RegenerateRecoveryCodes(c *gin.Context) {
+	var req accountModels.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	codes, err := ctrl.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, codes)
+}
@@ -8,7 +8,9 @@ type AccountDto struct {
 	ID               uint                 `json:"id"`
 	Name             string               `json:"name"`
 	Username         string               `json:"username" gorm:"unique"`
+	Email            string               `json:"email,omitempty"`
 	BusinessId       *uint                `json:"businessId,omitempty"`
+	TwoFactorEnabled bool                 `json:"twoFactorEnabled"`
 	AccountRoleLinks []AccountRoleLinkDto `json:"roles"`
 }
 
@@ -20,6 +22,8 @@ func NewAccountDtoFromEntity(acc entities.Account, roleLinks []AccountRoleLinkDt
 		ID:               acc.ID,
 		Name:             acc.Name,
 		Username:         acc.Username,
+		Email:            acc.Email,
+		TwoFactorEnabled: acc.TwoFactorEnabled,
 		AccountRoleLinks: roleLinks,
 	}
 
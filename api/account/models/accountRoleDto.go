@@ -6,6 +6,7 @@ type AccountRoleDto struct {
 	ID            uint                         `json:"id"`
 	Name          string                       `json:"name"`
 	BusinessId    *uint                        `json:"businessId"`
+	LocationIDs   []uint                       `json:"locationIds"`
 	FunctionLinks []AccountRoleFunctionLinkDto `json:"functionLinks,omitempty"`
 }
 
@@ -17,10 +18,16 @@ func NewAccountRoleDtoFromEntity(role entities.AccountRole, funcLinks []entities
 		fr = append(fr, NewAccountRoleFunctionLinkDtoFromEntity(fl))
 	}
 
+	locationIDs := make([]uint, 0, len(role.Locations))
+	for _, location := range role.Locations {
+		locationIDs = append(locationIDs, location.ID)
+	}
+
 	return AccountRoleDto{
 		ID:            role.ID,
 		Name:          role.Name,
 		BusinessId:    &role.BusinessID,
+		LocationIDs:   locationIDs,
 		FunctionLinks: fr,
 	}
 }
@@ -6,9 +6,11 @@ import (
 )
 
 type AccountRoleFunctionLinkDto struct {
-	ID           uint                    `json:"id"`
-	AccessLevels []constants.AccessLevel `json:"accessLevels"`
-	Function     FunctionDto             `json:"function"`
+	ID            uint                    `json:"id"`
+	AccessLevels  []constants.AccessLevel `json:"accessLevels"`
+	MaxAmount     *float64                `json:"maxAmount,omitempty"`
+	MaxPercentage *float64                `json:"maxPercentage,omitempty"`
+	Function      FunctionDto             `json:"function"`
 }
 
 // NewAccountRoleFunctionLinkDtoFromEntity constructs the DTO from the DB entity.
@@ -20,8 +22,10 @@ func NewAccountRoleFunctionLinkDtoFromEntity(fl entities.AccountRoleFunctionLink
 	}
 
 	return AccountRoleFunctionLinkDto{
-		ID:           fl.ID,
-		AccessLevels: conv,
-		Function:     NewFunctionDtoFromEntity(fl.Function),
+		ID:            fl.ID,
+		AccessLevels:  conv,
+		MaxAmount:     fl.MaxAmount,
+		MaxPercentage: fl.MaxPercentage,
+		Function:      NewFunctionDtoFromEntity(fl.Function),
 	}
 }
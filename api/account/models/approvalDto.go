@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ApprovalDto is a manager approval. The token is passed once in the X-Approval-Token header of the approved action.
+type ApprovalDto struct {
+	ID           uint      `json:"id"`
+	Token        string    `json:"token"`
+	Action       string    `json:"action"`
+	OrderID      *uint     `json:"orderId,omitempty"`
+	Amount       *float64  `json:"amount,omitempty"`
+	Percentage   *float64  `json:"percentage,omitempty"`
+	ApprovedByID uint      `json:"approvedById"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
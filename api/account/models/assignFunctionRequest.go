@@ -3,6 +3,8 @@ package models
 import "VersatilePOS/generic/constants"
 
 type AssignFunctionRequest struct {
-	FunctionID   uint                    `json:"functionId" binding:"required"`
-	AccessLevels []constants.AccessLevel `json:"accessLevels" binding:"required"`
+	FunctionID    uint                    `json:"functionId" binding:"required"`
+	AccessLevels  []constants.AccessLevel `json:"accessLevels" binding:"required"`
+	MaxAmount     *float64                `json:"maxAmount,omitempty" binding:"omitempty,gte=0"`
+	MaxPercentage *float64                `json:"maxPercentage,omitempty" binding:"omitempty,gte=0,lte=100"`
 }
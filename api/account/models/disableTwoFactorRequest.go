@@ -0,0 +1,7 @@
+package models
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	// Code is a TOTP or recovery code
+	Code string `json:"code" binding:"required"`
+}
@@ -3,4 +3,6 @@ package models
 type LoginRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// TwoFactorCode is a TOTP or recovery code, required once two-factor authentication is enabled
+	TwoFactorCode string `json:"twoFactorCode,omitempty"`
 }
@@ -0,0 +1,12 @@
+package models
+
+// PasswordPolicyDto describes the rules new passwords have to satisfy
+type PasswordPolicyDto struct {
+	MinLength        int  `json:"minLength"`
+	MaxLength        int  `json:"maxLength"`
+	RequireLetter    bool `json:"requireLetter"`
+	RequireUppercase bool `json:"requireUppercase"`
+	RequireLowercase bool `json:"requireLowercase"`
+	RequireDigit     bool `json:"requireDigit"`
+	RequireSymbol    bool `json:"requireSymbol"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type PasswordResetRequest struct {
+	Username string `json:"username" binding:"required"`
+}
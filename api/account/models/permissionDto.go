@@ -0,0 +1,23 @@
+package models
+
+import (
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/rbac"
+)
+
+type PermissionDto struct {
+	Action        constants.Action        `json:"action"`
+	AccessLevels  []constants.AccessLevel `json:"accessLevels"`
+	MaxAmount     *float64                `json:"maxAmount,omitempty"`
+	MaxPercentage *float64                `json:"maxPercentage,omitempty"`
+}
+
+// NewPermissionDto constructs a PermissionDto from a combined rbac grant.
+func NewPermissionDto(p rbac.Permission) PermissionDto {
+	return PermissionDto{
+		Action:        p.Action,
+		AccessLevels:  p.AccessLevels,
+		MaxAmount:     p.MaxAmount,
+		MaxPercentage: p.MaxPercentage,
+	}
+}
@@ -0,0 +1,8 @@
+package models
+
+// PinLoginAccountDto is an employee a terminal can offer for PIN login
+type PinLoginAccountDto struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Locked bool   `json:"locked"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type PinLoginRequest struct {
+	AccountID uint   `json:"accountId" binding:"required"`
+	Pin       string `json:"pin" binding:"required,numeric,min=4,max=8"`
+}
@@ -0,0 +1,6 @@
+package models
+
+// RecoveryCodesDto holds newly generated recovery codes, they are only shown once
+type RecoveryCodesDto struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type RegisterTerminalRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+}
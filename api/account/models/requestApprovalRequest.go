@@ -0,0 +1,22 @@
+package models
+
+// RequestApprovalRequest is a manager authenticating a single action the requesting employee lacks the permission for.
+// The manager identifies with their PIN for the business, or with their username and password.
+type RequestApprovalRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Action     string `json:"action" binding:"required"`
+	// OrderID is the order the action is performed on, left out when the order is being created with it
+	OrderID *uint `json:"orderId,omitempty"`
+	// Amount and Percentage are the most the action may come to, as reported when the approval was required. The
+	// approval cannot be used beyond them.
+	Amount     *float64 `json:"amount,omitempty" binding:"omitempty,gte=0"`
+	Percentage *float64 `json:"percentage,omitempty" binding:"omitempty,gte=0"`
+
+	ManagerAccountID *uint  `json:"managerAccountId,omitempty"`
+	ManagerPin       string `json:"managerPin,omitempty"`
+
+	ManagerUsername string `json:"managerUsername,omitempty"`
+	ManagerPassword string `json:"managerPassword,omitempty"`
+	// ManagerTwoFactorCode is required with a password once the manager has two-factor authentication enabled
+	ManagerTwoFactorCode string `json:"managerTwoFactorCode,omitempty"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type SetPinRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Pin        string `json:"pin" binding:"required,numeric,min=4,max=8"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type SetRoleLocationsRequest struct {
+	LocationIDs []uint `json:"locationIds"`
+}
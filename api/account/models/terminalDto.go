@@ -0,0 +1,26 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+type TerminalDto struct {
+	ID         uint   `json:"id"`
+	BusinessID uint   `json:"businessId"`
+	Name       string `json:"name"`
+	// DeviceToken identifies the terminal on PIN login and is only returned when the terminal is registered
+	DeviceToken string     `json:"deviceToken,omitempty"`
+	LastSeenAt  *time.Time `json:"lastSeenAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func NewTerminalDtoFromEntity(terminal entities.Terminal) TerminalDto {
+	return TerminalDto{
+		ID:         terminal.ID,
+		BusinessID: terminal.BusinessID,
+		Name:       terminal.Name,
+		LastSeenAt: terminal.LastSeenAt,
+		CreatedAt:  terminal.CreatedAt,
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type TokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	// TwoFactorSetupRequired is set when a business withholds the account's write access to accounts or roles until 2FA is enabled
+	TwoFactorSetupRequired bool `json:"twoFactorSetupRequired,omitempty"`
+	// PasswordChangeRequired is set when the password has to be changed before the account's roles apply
+	PasswordChangeRequired bool `json:"passwordChangeRequired,omitempty"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type TwoFactorSetupDto struct {
+	Secret string `json:"secret"`
+	// OtpauthURL is the otpauth:// URI to show as a QR code for authenticator apps
+	OtpauthURL string `json:"otpauthUrl"`
+}
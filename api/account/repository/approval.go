@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+)
+
+type ApprovalRepository struct{}
+
+func (r *ApprovalRepository) CreateApproval(approval *entities.ManagerApproval) error {
+	return database.DB.Omit("Order", "RequestedBy", "ApprovedBy").Create(approval).Error
+}
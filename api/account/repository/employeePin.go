@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm"
+)
+
+type EmployeePinRepository struct{}
+
+func (r *EmployeePinRepository) SavePin(pin *entities.EmployeePin) error {
+	return database.DB.Omit("Account", "Business").Save(pin).Error
+}
+
+func (r *EmployeePinRepository) GetPin(accountID uint, businessID uint) (*entities.EmployeePin, error) {
+	var pin entities.EmployeePin
+	if err := database.DB.Where("account_id = ? AND business_id = ?", accountID, businessID).First(&pin).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (r *EmployeePinRepository) GetPinsByBusinessID(businessID uint) ([]entities.EmployeePin, error) {
+	var pins []entities.EmployeePin
+	if err := database.DB.Preload("Account").Where("business_id = ?", businessID).Find(&pins).Error; err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+func (r *EmployeePinRepository) DeletePin(pin *entities.EmployeePin) error {
+	return database.DB.Unscoped().Delete(pin).Error
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+	"errors"
+
+	"gorm.io/gorm"
 )
 
 type FunctionRepository struct{}
@@ -27,6 +30,22 @@ func (r *FunctionRepository) AssignFunctionToRole(link *entities.AccountRoleFunc
 	return database.DB.Create(link).Error
 }
 
+// GetFunctionLink returns the role's link to a function, or nil if the function is not assigned
+func (r *FunctionRepository) GetFunctionLink(roleID uint, functionID uint) (*entities.AccountRoleFunctionLink, error) {
+	var link entities.AccountRoleFunctionLink
+	if err := database.DB.Where("account_role_id = ? AND function_id = ?", roleID, functionID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *FunctionRepository) UpdateFunctionLink(link *entities.AccountRoleFunctionLink) error {
+	return database.DB.Omit("AccountRole", "Function").Save(link).Error
+}
+
 func (r *FunctionRepository) GetFunctionsByRoleID(roleID uint) ([]entities.AccountRoleFunctionLink, error) {
 	var links []entities.AccountRoleFunctionLink
 	if err := database.DB.Preload("Function").Where("account_role_id = ?", roleID).Find(&links).Error; err != nil {
@@ -3,6 +3,8 @@ package repository
 import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct{}
@@ -50,3 +52,16 @@ func (r *Repository) DissociateEmployeeFromBusiness(account *entities.Account, b
 func (r *Repository) DeleteAccount(account *entities.Account) error {
 	return database.DB.Delete(account).Error
 }
+
+func (r *Repository) UpdateAccount(account *entities.Account) error {
+	return database.DB.Omit(clause.Associations).Save(account).Error
+}
+
+// UpdateLoginState stores only the failed login counters, so it cannot overwrite concurrent account changes
+func (r *Repository) UpdateLoginState(account *entities.Account) error {
+	return database.DB.Model(account).Select("failed_login_attempts", "lockout_count", "locked_until").Updates(map[string]interface{}{
+		"failed_login_attempts": account.FailedLoginAttempts,
+		"lockout_count":         account.LockoutCount,
+		"locked_until":          account.LockedUntil,
+	}).Error
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository struct{}
+
+func (r *PasswordResetRepository) CreateToken(token *entities.PasswordResetToken) error {
+	return database.DB.Create(token).Error
+}
+
+func (r *PasswordResetRepository) GetTokenByHash(hash string) (*entities.PasswordResetToken, error) {
+	var token entities.PasswordResetToken
+	if err := database.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseToken marks the token used. It reports false if it was used concurrently, so a token only works once.
+func (r *PasswordResetRepository) UseToken(token *entities.PasswordResetToken) (bool, error) {
+	now := time.Now()
+	result := database.DB.Model(token).Where("used_at IS NULL").Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// InvalidateTokens marks all unused tokens of the account used, e.g. after the password was changed
+func (r *PasswordResetRepository) InvalidateTokens(accountID uint) error {
+	return database.DB.Model(&entities.PasswordResetToken{}).
+		Where("account_id = ? AND used_at IS NULL", accountID).
+		Update("used_at", time.Now()).Error
+}
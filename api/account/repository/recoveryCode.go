@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct{}
+
+// ReplaceRecoveryCodes deletes the account's recovery codes and stores the new ones
+func (r *RecoveryCodeRepository) ReplaceRecoveryCodes(accountID uint, codes []entities.RecoveryCode) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("account_id = ?", accountID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code of the account as used. It reports false if there was none.
+func (r *RecoveryCodeRepository) UseRecoveryCode(accountID uint, codeHash string) (bool, error) {
+	result := database.DB.Model(&entities.RecoveryCode{}).
+		Where("account_id = ? AND code_hash = ? AND used_at IS NULL", accountID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *RecoveryCodeRepository) CountUnusedRecoveryCodes(accountID uint) (int64, error) {
+	var count int64
+	if err := database.DB.Model(&entities.RecoveryCode{}).Where("account_id = ? AND used_at IS NULL", accountID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
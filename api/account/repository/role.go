@@ -14,14 +14,19 @@ func (r *RoleRepository) CreateRole(role *entities.AccountRole) error {
 
 func (r *RoleRepository) GetRoleByID(id uint) (*entities.AccountRole, error) {
 	var role entities.AccountRole
-	if err := database.DB.First(&role, id).Error; err != nil {
+	if err := database.DB.Preload("Locations").First(&role, id).Error; err != nil {
 		return nil, err
 	}
 	return &role, nil
 }
 
 func (r *RoleRepository) UpdateRole(role *entities.AccountRole) error {
-	return database.DB.Save(role).Error
+	return database.DB.Omit("Locations").Save(role).Error
+}
+
+// SetRoleLocations replaces the locations a role is limited to
+func (r *RoleRepository) SetRoleLocations(role *entities.AccountRole, locations []entities.Location) error {
+	return database.DB.Model(role).Association("Locations").Replace(locations)
 }
 
 func (r *RoleRepository) DeleteRole(role *entities.AccountRole) error {
@@ -37,6 +42,11 @@ func (r *RoleRepository) DeleteRole(role *entities.AccountRole) error {
 		return err
 	}
 
+	if err := tx.Model(role).Association("Locations").Clear(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Delete(role).Error; err != nil {
 		tx.Rollback()
 		return err
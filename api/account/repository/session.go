@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct{}
+
+func (r *SessionRepository) CreateSession(session *entities.Session) error {
+	return database.DB.Create(session).Error
+}
+
+func (r *SessionRepository) UpdateSession(session *entities.Session) error {
+	return database.DB.Omit("Account").Save(session).Error
+}
+
+func (r *SessionRepository) GetSessionByID(id uint) (*entities.Session, error) {
+	var session entities.Session
+	if err := database.DB.First(&session, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) GetSessionByRefreshTokenHash(hash string) (*entities.Session, error) {
+	var session entities.Session
+	if err := database.DB.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) GetSessionByPreviousRefreshTokenHash(hash string) (*entities.Session, error) {
+	var session entities.Session
+	if err := database.DB.Where("previous_refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveSessionsByAccountID returns the account's sessions that are neither revoked nor expired
+func (r *SessionRepository) GetActiveSessionsByAccountID(accountID uint, now time.Time) ([]entities.Session, error) {
+	var sessions []entities.Session
+	if err := database.DB.Where("account_id = ? AND revoked_at IS NULL AND expires_at > ?", accountID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) GetActiveSessionsByTerminalID(terminalID uint, now time.Time) ([]entities.Session, error) {
+	var sessions []entities.Session
+	if err := database.DB.Where("terminal_id = ? AND revoked_at IS NULL AND expires_at > ?", terminalID, now).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm"
+)
+
+type TerminalRepository struct{}
+
+func (r *TerminalRepository) CreateTerminal(terminal *entities.Terminal) error {
+	return database.DB.Create(terminal).Error
+}
+
+func (r *TerminalRepository) UpdateTerminal(terminal *entities.Terminal) error {
+	return database.DB.Omit("Business").Save(terminal).Error
+}
+
+func (r *TerminalRepository) GetTerminalByID(id uint) (*entities.Terminal, error) {
+	var terminal entities.Terminal
+	if err := database.DB.First(&terminal, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &terminal, nil
+}
+
+func (r *TerminalRepository) GetTerminalByDeviceTokenHash(hash string) (*entities.Terminal, error) {
+	var terminal entities.Terminal
+	if err := database.DB.Where("device_token_hash = ?", hash).First(&terminal).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &terminal, nil
+}
+
+func (r *TerminalRepository) GetTerminalsByBusinessID(businessID uint) ([]entities.Terminal, error) {
+	var terminals []entities.Terminal
+	if err := database.DB.Where("business_id = ? AND revoked_at IS NULL", businessID).Order("name").Find(&terminals).Error; err != nil {
+		return nil, err
+	}
+	return terminals, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/rbac"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const defaultManagerApprovalTTLMinutes = 5
+
+// managerApprovalTTL is how long an approval can be used, configured by MANAGER_APPROVAL_TTL_MINUTES
+func managerApprovalTTL() time.Duration {
+	return time.Duration(env.PositiveInt("MANAGER_APPROVAL_TTL_MINUTES", defaultManagerApprovalTTLMinutes)) * time.Minute
+}
+
+// RequestApproval lets a manager approve a single action the employee lacks the permission for. The manager
+// authenticates with their PIN or password and must hold the permission themselves, within their limits for the
+// requested amount and percentage. The approval covers at most that amount and percentage, and the manager's limits
+// are checked again when it is used.
+func (s *Service) RequestApproval(req accountModels.RequestApprovalRequest, userID uint, ipAddress string) (accountModels.ApprovalDto, error) {
+	action := constants.Action(req.Action)
+	if !slices.Contains(constants.PermissionActions, action) {
+		return accountModels.ApprovalDto{}, errors.New("invalid action")
+	}
+
+	businessIDs, err := GetBusinessIDsFromAccount(userID)
+	if err != nil {
+		return accountModels.ApprovalDto{}, errors.New("failed to get account")
+	}
+	if !slices.Contains(businessIDs, req.BusinessID) {
+		return accountModels.ApprovalDto{}, errors.New("unauthorized")
+	}
+
+	var locationID *uint
+	if req.OrderID != nil {
+		order, err := s.orderRepo.GetOrderByID(*req.OrderID)
+		if err != nil {
+			return accountModels.ApprovalDto{}, errors.New("failed to get order")
+		}
+		if order == nil || order.BusinessID != req.BusinessID {
+			return accountModels.ApprovalDto{}, errors.New("order not found")
+		}
+
+		ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, rbac.Scope{LocationID: order.LocationID})
+		if err != nil {
+			return accountModels.ApprovalDto{}, errors.New("failed to verify permissions")
+		}
+		if !ok {
+			return accountModels.ApprovalDto{}, errors.New("unauthorized")
+		}
+		locationID = order.LocationID
+	}
+
+	manager, err := s.authenticateManager(req, ipAddress)
+	if err != nil {
+		return accountModels.ApprovalDto{}, err
+	}
+	if manager.ID == userID {
+		return accountModels.ApprovalDto{}, errors.New("cannot approve own action")
+	}
+
+	scope := rbac.Scope{LocationID: locationID, Amount: req.Amount, Percentage: req.Percentage}
+	ok, err := rbac.HasScopedAccess(action, constants.Write, req.BusinessID, manager.ID, scope)
+	if err != nil {
+		return accountModels.ApprovalDto{}, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return accountModels.ApprovalDto{}, errors.New("manager lacks this permission")
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return accountModels.ApprovalDto{}, errors.New("failed to generate token")
+	}
+
+	approval := &entities.ManagerApproval{
+		BusinessID:         req.BusinessID,
+		Action:             action,
+		TokenHash:          rbac.HashApprovalToken(token),
+		OrderID:            req.OrderID,
+		RequestedByID:      userID,
+		ApprovedByID:       manager.ID,
+		ApprovedAmount:     req.Amount,
+		ApprovedPercentage: req.Percentage,
+		ExpiresAt:          time.Now().Add(managerApprovalTTL()),
+	}
+	if err := s.approvalRepo.CreateApproval(approval); err != nil {
+		return accountModels.ApprovalDto{}, errors.New("failed to create approval")
+	}
+
+	log.Printf("Account %d approved %s for account %d in business %d", manager.ID, action, userID, req.BusinessID)
+
+	return accountModels.ApprovalDto{
+		ID:           approval.ID,
+		Token:        token,
+		Action:       string(approval.Action),
+		OrderID:      approval.OrderID,
+		Amount:       approval.ApprovedAmount,
+		Percentage:   approval.ApprovedPercentage,
+		ApprovedByID: approval.ApprovedByID,
+		ExpiresAt:    approval.ExpiresAt,
+	}, nil
+}
+
+// authenticateManager checks the manager's PIN for the business or their password, with the same lockouts as logging in.
+// Whether they may approve is left to their permissions.
+func (s *Service) authenticateManager(req accountModels.RequestApprovalRequest, ipAddress string) (entities.Account, error) {
+	var account entities.Account
+	switch {
+	case req.ManagerAccountID != nil && req.ManagerPin != "":
+		pin, err := s.pinRepo.GetPin(*req.ManagerAccountID, req.BusinessID)
+		if err != nil {
+			return entities.Account{}, errors.New("internal server error")
+		}
+		if pin == nil {
+			return entities.Account{}, errors.New("invalid credentials")
+		}
+		if err := s.checkPin(pin, req.ManagerPin); err != nil {
+			return entities.Account{}, err
+		}
+
+		pin.FailedAttempts = 0
+		pin.LockedUntil = nil
+		if err := s.pinRepo.SavePin(pin); err != nil {
+			return entities.Account{}, errors.New("internal server error")
+		}
+
+		account, err = s.accountRepo.GetAccountByID(pin.AccountID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return entities.Account{}, errors.New("invalid credentials")
+			}
+			return entities.Account{}, errors.New("internal server error")
+		}
+
+	case req.ManagerUsername != "" && req.ManagerPassword != "":
+		var err error
+		account, err = s.accountRepo.GetAccountByUsername(req.ManagerUsername)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return entities.Account{}, errors.New("invalid credentials")
+			}
+			return entities.Account{}, errors.New("internal server error")
+		}
+
+		if isLockedOut(account) {
+			return entities.Account{}, errors.New("account is temporarily locked")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.ManagerPassword)); err != nil {
+			s.recordFailedLogin(&account, ipAddress)
+			return entities.Account{}, errors.New("invalid credentials")
+		}
+		if account.TwoFactorEnabled {
+			if req.ManagerTwoFactorCode == "" {
+				return entities.Account{}, errors.New("two-factor code required")
+			}
+			if err := s.verifySecondFactor(&account, req.ManagerTwoFactorCode); err != nil {
+				if err.Error() == "invalid two-factor code" {
+					s.recordFailedLogin(&account, ipAddress)
+				}
+				return entities.Account{}, err
+			}
+		}
+		s.recordSuccessfulLogin(&account)
+
+	default:
+		return entities.Account{}, errors.New("manager credentials required")
+	}
+
+	return account, nil
+}
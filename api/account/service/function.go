@@ -96,10 +96,24 @@ func (s *Service) AssignFunctionToRole(roleID uint, req models.AssignFunctionReq
 		als = append(als, string(a))
 	}
 
+	// Assigning a function the role already has replaces its access levels and limits
+	existing, err := s.functionRepo.GetFunctionLink(role.ID, function.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		existing.AccessLevels = als
+		existing.MaxAmount = req.MaxAmount
+		existing.MaxPercentage = req.MaxPercentage
+		return s.functionRepo.UpdateFunctionLink(existing)
+	}
+
 	link := &entities.AccountRoleFunctionLink{
 		AccountRoleID: role.ID,
 		FunctionID:    function.ID,
 		AccessLevels:  als,
+		MaxAmount:     req.MaxAmount,
+		MaxPercentage: req.MaxPercentage,
 	}
 
 	return s.functionRepo.AssignFunctionToRole(link)
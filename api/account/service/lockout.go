@@ -0,0 +1,102 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/rbac"
+	"VersatilePOS/middleware"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	defaultLoginMaxFailedAttempts = 5
+	defaultLoginLockoutMinutes    = 5
+	defaultLoginLockoutMaxMinutes = 24 * 60
+)
+
+// loginLockoutDuration is how long the nth consecutive lockout lasts. It starts at LOGIN_LOCKOUT_MINUTES and doubles
+// with every lockout up to LOGIN_LOCKOUT_MAX_MINUTES.
+func loginLockoutDuration(lockoutCount int) time.Duration {
+	base := time.Duration(env.PositiveInt("LOGIN_LOCKOUT_MINUTES", defaultLoginLockoutMinutes)) * time.Minute
+	max := time.Duration(env.PositiveInt("LOGIN_LOCKOUT_MAX_MINUTES", defaultLoginLockoutMaxMinutes)) * time.Minute
+
+	duration := base
+	for i := 1; i < lockoutCount && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		duration = max
+	}
+	return duration
+}
+
+func isLockedOut(account entities.Account) bool {
+	return account.LockedUntil != nil && account.LockedUntil.After(time.Now())
+}
+
+// recordFailedLogin counts a failed login and locks the account once LOGIN_MAX_FAILED_ATTEMPTS is reached
+func (s *Service) recordFailedLogin(account *entities.Account, ipAddress string) {
+	account.FailedLoginAttempts++
+	if account.FailedLoginAttempts >= env.PositiveInt("LOGIN_MAX_FAILED_ATTEMPTS", defaultLoginMaxFailedAttempts) {
+		account.LockoutCount++
+		lockedUntil := time.Now().Add(loginLockoutDuration(account.LockoutCount))
+		account.LockedUntil = &lockedUntil
+		account.FailedLoginAttempts = 0
+		middleware.RecordLockout("account", account.Username, ipAddress, &account.ID, lockedUntil)
+	}
+
+	if err := s.accountRepo.UpdateLoginState(account); err != nil {
+		log.Printf("Warning: Failed to record failed login of account %d: %v", account.ID, err)
+	}
+}
+
+// recordSuccessfulLogin clears the failed login counters
+func (s *Service) recordSuccessfulLogin(account *entities.Account) {
+	if account.FailedLoginAttempts == 0 && account.LockoutCount == 0 && account.LockedUntil == nil {
+		return
+	}
+
+	account.FailedLoginAttempts = 0
+	account.LockoutCount = 0
+	account.LockedUntil = nil
+	if err := s.accountRepo.UpdateLoginState(account); err != nil {
+		log.Printf("Warning: Failed to reset failed logins of account %d: %v", account.ID, err)
+	}
+}
+
+// UnlockAccount lifts a login lockout of an employee, which requires write access to accounts in one of their businesses
+func (s *Service) UnlockAccount(accountID uint, userID uint) error {
+	account, err := s.getAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkAccountManagement(account, userID); err != nil {
+		return err
+	}
+
+	account.FailedLoginAttempts = 0
+	account.LockoutCount = 0
+	account.LockedUntil = nil
+	if err := s.accountRepo.UpdateLoginState(&account); err != nil {
+		return errors.New("failed to unlock account")
+	}
+	return nil
+}
+
+// checkAccountManagement allows managing an account with write access to accounts in any business it belongs to
+func (s *Service) checkAccountManagement(account entities.Account, userID uint) error {
+	for _, business := range account.MemberOf {
+		ok, err := rbac.HasAccess(constants.Accounts, constants.Write, business.ID, userID)
+		if err != nil {
+			return errors.New("failed to verify permissions")
+		}
+		if ok {
+			return nil
+		}
+	}
+	return errors.New("unauthorized")
+}
@@ -7,8 +7,10 @@ import (
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
 	"VersatilePOS/generic/rbac"
-	"VersatilePOS/middleware"
+	locationRepository "VersatilePOS/location/repository"
+	orderRepository "VersatilePOS/order/repository"
 	"errors"
+	"log"
 	"strconv"
 
 	"golang.org/x/crypto/bcrypt"
@@ -20,6 +22,15 @@ type Service struct {
 	businessRepo *businessRepository.Repository
 	roleRepo     *accountRepository.RoleRepository
 	functionRepo *accountRepository.FunctionRepository
+	sessionRepo  *accountRepository.SessionRepository
+	terminalRepo *accountRepository.TerminalRepository
+	pinRepo      *accountRepository.EmployeePinRepository
+	locationRepo *locationRepository.Repository
+	orderRepo    *orderRepository.Repository
+	approvalRepo *accountRepository.ApprovalRepository
+
+	recoveryCodeRepo  *accountRepository.RecoveryCodeRepository
+	passwordResetRepo *accountRepository.PasswordResetRepository
 }
 
 func NewService() *Service {
@@ -28,6 +39,15 @@ func NewService() *Service {
 		businessRepo: &businessRepository.Repository{},
 		roleRepo:     &accountRepository.RoleRepository{},
 		functionRepo: &accountRepository.FunctionRepository{},
+		sessionRepo:  &accountRepository.SessionRepository{},
+		terminalRepo: &accountRepository.TerminalRepository{},
+		pinRepo:      &accountRepository.EmployeePinRepository{},
+		locationRepo: &locationRepository.Repository{},
+		orderRepo:    &orderRepository.Repository{},
+		approvalRepo: &accountRepository.ApprovalRepository{},
+
+		recoveryCodeRepo:  &accountRepository.RecoveryCodeRepository{},
+		passwordResetRepo: &accountRepository.PasswordResetRepository{},
 	}
 }
 
@@ -48,15 +68,20 @@ func (s *Service) CreateAccount(req accountModels.CreateAccountRequest, claims m
 		}
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := validatePassword(req.Password, req.Username); err != nil {
+		return accountModels.AccountDto{}, err
+	}
+
+	passwordHash, err := hashPassword(req.Password)
 	if err != nil {
-		return accountModels.AccountDto{}, errors.New("failed to hash password")
+		return accountModels.AccountDto{}, err
 	}
 
 	account := entities.Account{
 		Name:         req.Name,
 		Username:     req.Username,
-		PasswordHash: string(passwordHash),
+		Email:        req.Email,
+		PasswordHash: passwordHash,
 	}
 
 	if err := s.accountRepo.CreateAccount(&account); err != nil {
@@ -101,25 +126,51 @@ func (s *Service) CreateAccount(req accountModels.CreateAccountRequest, claims m
 	return response, nil
 }
 
-func (s *Service) Login(req accountModels.LoginRequest) (string, error) {
+func (s *Service) Login(req accountModels.LoginRequest, userAgent string, ipAddress string) (accountModels.TokenResponse, error) {
 	account, err := s.accountRepo.GetAccountByUsername(req.Username)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", errors.New("invalid credentials")
+			return accountModels.TokenResponse{}, errors.New("invalid credentials")
 		}
-		return "", errors.New("internal server error")
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+
+	// A locked account is rejected before the password is checked, so guessing during the lockout reveals nothing
+	if isLockedOut(account) {
+		return accountModels.TokenResponse{}, errors.New("account is temporarily locked")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
-		return "", errors.New("invalid credentials")
+		s.recordFailedLogin(&account, ipAddress)
+		return accountModels.TokenResponse{}, errors.New("invalid credentials")
+	}
+
+	if account.TwoFactorEnabled {
+		if req.TwoFactorCode == "" {
+			return accountModels.TokenResponse{}, errors.New("two-factor code required")
+		}
+		if err := s.verifySecondFactor(&account, req.TwoFactorCode); err != nil {
+			if err.Error() == "invalid two-factor code" {
+				s.recordFailedLogin(&account, ipAddress)
+			}
+			return accountModels.TokenResponse{}, err
+		}
 	}
 
-	tokenString, err := middleware.GenerateToken(account.Username, account.ID)
+	s.recordSuccessfulLogin(&account)
+
+	tokens, err := s.startSession(account, userAgent, ipAddress)
 	if err != nil {
-		return "", errors.New("failed to generate token")
+		return accountModels.TokenResponse{}, err
 	}
 
-	return tokenString, nil
+	setupRequired, err := rbac.TwoFactorSetupRequired(account.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to check two-factor requirement of account %d: %v", account.ID, err)
+	}
+	tokens.TwoFactorSetupRequired = setupRequired
+	tokens.PasswordChangeRequired = account.MustChangePassword
+	return tokens, nil
 }
 
 func (s *Service) GetMyAccount(userID uint) (accountModels.AccountDto, error) {
@@ -224,6 +275,10 @@ func (s *Service) DeleteAccount(id string, requestingUserID uint) error {
 		return errors.New("failed to delete account")
 	}
 
+	if err := s.revokeAccountSessions(targetAccount.ID); err != nil {
+		log.Printf("Warning: Failed to revoke sessions of deleted account %d: %v", targetAccount.ID, err)
+	}
+
 	return nil
 }
 
@@ -297,6 +352,13 @@ func (s *Service) UpdateAccountRoleLinkStatus(accountID, roleID uint, req accoun
 		return accountModels.AccountRoleLinkDto{}, errors.New("failed to update role assignment")
 	}
 
+	// Suspended or deactivated accounts have to log in again
+	if link.Status != constants.Active {
+		if err := s.revokeAccountSessions(link.AccountID); err != nil {
+			log.Printf("Warning: Failed to revoke sessions of account %d: %v", link.AccountID, err)
+		}
+	}
+
 	// delegate role DTO construction to repository
 	roleDto, _ := s.roleRepo.GetRoleDtoByID(link.AccountRole.ID)
 
@@ -0,0 +1,206 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/notification"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const defaultPasswordResetTTLMinutes = 60
+
+// passwordResetTTL is how long a reset token stays valid, configured by PASSWORD_RESET_TOKEN_TTL_MINUTES
+func passwordResetTTL() time.Duration {
+	return time.Duration(env.PositiveInt("PASSWORD_RESET_TOKEN_TTL_MINUTES", defaultPasswordResetTTLMinutes)) * time.Minute
+}
+
+// passwordResetLink is the page where the token is redeemed, configured by PASSWORD_RESET_URL
+func passwordResetLink(token string) string {
+	baseURL := os.Getenv("PASSWORD_RESET_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:5173/reset-password"
+	}
+	return fmt.Sprintf("%s?token=%s", strings.TrimRight(baseURL, "/"), url.QueryEscape(token))
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+	return string(hash), nil
+}
+
+// ChangePassword replaces the password after checking the current one. Other sessions of the account are logged out.
+func (s *Service) ChangePassword(userID uint, currentSessionID uint, req accountModels.ChangePasswordRequest) error {
+	account, err := s.getAccount(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return errors.New("current password is incorrect")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("password must differ from the current password")
+	}
+
+	if err := s.setPassword(&account, req.NewPassword); err != nil {
+		return err
+	}
+
+	sessions, err := s.sessionRepo.GetActiveSessionsByAccountID(account.ID, time.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to get sessions of account %d: %v", account.ID, err)
+		return nil
+	}
+	for i := range sessions {
+		if sessions[i].ID == currentSessionID {
+			continue
+		}
+		if err := s.revokeSession(&sessions[i]); err != nil {
+			log.Printf("Warning: Failed to revoke session %d after password change: %v", sessions[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// RequestPasswordReset sends a reset link to the account with the given username. It does not reveal whether
+// the account exists, so unknown usernames are not an error.
+func (s *Service) RequestPasswordReset(username string) error {
+	account, err := s.accountRepo.GetAccountByUsername(username)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return errors.New("internal server error")
+	}
+
+	return s.sendPasswordReset(account, nil)
+}
+
+// InitiatePasswordReset sends a reset link to an employee, which requires write access to accounts in one of their businesses
+func (s *Service) InitiatePasswordReset(accountID uint, userID uint) error {
+	account, err := s.getAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkAccountManagement(account, userID); err != nil {
+		return err
+	}
+
+	return s.sendPasswordReset(account, &userID)
+}
+
+// ConfirmPasswordReset sets a new password with a reset token and logs out all sessions of the account
+func (s *Service) ConfirmPasswordReset(req accountModels.ConfirmPasswordResetRequest) error {
+	token, err := s.passwordResetRepo.GetTokenByHash(hashToken(req.Token))
+	if err != nil {
+		return errors.New("internal server error")
+	}
+	if token == nil || token.UsedAt != nil || !token.ExpiresAt.After(time.Now()) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	account, err := s.getAccount(token.AccountID)
+	if err != nil {
+		if err.Error() == "account not found" {
+			return errors.New("invalid or expired reset token")
+		}
+		return err
+	}
+
+	// Validate before using up the token, so a rejected password can be retried
+	if err := validatePassword(req.NewPassword, account.Username); err != nil {
+		return err
+	}
+
+	used, err := s.passwordResetRepo.UseToken(token)
+	if err != nil {
+		return errors.New("internal server error")
+	}
+	if !used {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.setPassword(&account, req.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.revokeAccountSessions(account.ID); err != nil {
+		log.Printf("Warning: Failed to revoke sessions of account %d after password reset: %v", account.ID, err)
+	}
+	return nil
+}
+
+// setPassword validates and stores a new password. It lifts a required password change and voids outstanding reset tokens.
+func (s *Service) setPassword(account *entities.Account, password string) error {
+	if err := validatePassword(password, account.Username); err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	account.PasswordHash = hash
+	account.PasswordChangedAt = &now
+	account.MustChangePassword = false
+	if err := s.accountRepo.UpdateAccount(account); err != nil {
+		return errors.New("failed to update account")
+	}
+
+	if err := s.passwordResetRepo.InvalidateTokens(account.ID); err != nil {
+		log.Printf("Warning: Failed to invalidate password reset tokens of account %d: %v", account.ID, err)
+	}
+	return nil
+}
+
+func (s *Service) sendPasswordReset(account entities.Account, requestedByID *uint) error {
+	rawToken, err := newRefreshToken()
+	if err != nil {
+		return errors.New("failed to generate reset token")
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL())
+	token := &entities.PasswordResetToken{
+		AccountID:     account.ID,
+		TokenHash:     hashToken(rawToken),
+		ExpiresAt:     expiresAt,
+		RequestedByID: requestedByID,
+	}
+	if err := s.passwordResetRepo.CreateToken(token); err != nil {
+		return errors.New("failed to create reset token")
+	}
+
+	link := passwordResetLink(rawToken)
+	if err := notification.Notify(notification.Event{
+		Type:    "account.password_reset",
+		Email:   account.Email,
+		Subject: "Reset your password",
+		Message: fmt.Sprintf("Hi %s, use this link to set a new password before %s: %s", account.Name, expiresAt.Format(time.RFC1123), link),
+		Data: map[string]string{
+			"accountId": strconv.FormatUint(uint64(account.ID), 10),
+			"token":     rawToken,
+			"resetUrl":  link,
+		},
+	}); err != nil {
+		log.Printf("Warning: Failed to send password reset for account %d: %v", account.ID, err)
+		return errors.New("failed to send reset link")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/generic/env"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// bcrypt ignores everything past 72 bytes
+	passwordMaxLength = 72
+)
+
+// knownPasswords are rejected regardless of the configured rules
+var knownPasswords = []string{"supersecretadmin123"}
+
+func envBool(name string, fallback bool) bool {
+	if value := os.Getenv(name); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// passwordPolicy returns the rules new passwords have to satisfy, configured by the PASSWORD_* environment variables
+func passwordPolicy() accountModels.PasswordPolicyDto {
+	minLength := env.PositiveInt("PASSWORD_MIN_LENGTH", defaultPasswordMinLength)
+	if minLength > passwordMaxLength {
+		minLength = passwordMaxLength
+	}
+
+	return accountModels.PasswordPolicyDto{
+		MinLength:        minLength,
+		MaxLength:        passwordMaxLength,
+		RequireLetter:    envBool("PASSWORD_REQUIRE_LETTER", true),
+		RequireUppercase: envBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		RequireLowercase: envBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		RequireDigit:     envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    envBool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+}
+
+func (s *Service) GetPasswordPolicy() accountModels.PasswordPolicyDto {
+	return passwordPolicy()
+}
+
+// validatePassword checks a new password against the policy. Its errors all start with "password ", which
+// controllers report as bad requests.
+func validatePassword(password string, username string) error {
+	policy := passwordPolicy()
+
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", policy.MinLength)
+	}
+	if len(password) > policy.MaxLength {
+		return fmt.Errorf("password must be at most %d bytes long", policy.MaxLength)
+	}
+
+	var hasLetter, hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasLetter, hasUpper = true, true
+		case unicode.IsLower(c):
+			hasLetter, hasLower = true, true
+		case unicode.IsLetter(c):
+			hasLetter = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+
+	switch {
+	case policy.RequireLetter && !hasLetter:
+		return errors.New("password must contain a letter")
+	case policy.RequireUppercase && !hasUpper:
+		return errors.New("password must contain an uppercase letter")
+	case policy.RequireLowercase && !hasLower:
+		return errors.New("password must contain a lowercase letter")
+	case policy.RequireDigit && !hasDigit:
+		return errors.New("password must contain a digit")
+	case policy.RequireSymbol && !hasSymbol:
+		return errors.New("password must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		return errors.New("password must not contain the username")
+	}
+	for _, known := range knownPasswords {
+		if lowered == known {
+			return errors.New("password must not be a default password")
+		}
+	}
+	return nil
+}
+
+// IsPasswordPolicyError reports whether err came from validatePassword
+func IsPasswordPolicyError(err error) bool {
+	return strings.HasPrefix(err.Error(), "password must")
+}
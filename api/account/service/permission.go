@@ -0,0 +1,32 @@
+package service
+
+import (
+	"VersatilePOS/account/models"
+	"VersatilePOS/generic/rbac"
+	"errors"
+)
+
+// GetMyPermissions lists what the user may do in a business, optionally at one of its locations, so clients can
+// hide actions such as opening the drawer or viewing reports
+func (s *Service) GetMyPermissions(userID uint, businessID uint, locationID *uint) ([]models.PermissionDto, error) {
+	if locationID != nil {
+		location, err := s.locationRepo.GetLocationByID(*locationID)
+		if err != nil {
+			return nil, errors.New("failed to load location")
+		}
+		if location == nil || location.BusinessID != businessID {
+			return nil, errors.New("location not found")
+		}
+	}
+
+	permissions, err := rbac.EffectivePermissions(businessID, userID, locationID)
+	if err != nil {
+		return nil, errors.New("failed to load permissions")
+	}
+
+	dtos := make([]models.PermissionDto, 0, len(permissions))
+	for _, p := range permissions {
+		dtos = append(dtos, models.NewPermissionDto(p))
+	}
+	return dtos, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/rbac"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPinMaxAttempts     = 5
+	defaultPinLockoutMinutes  = 15
+	defaultPinTokenTTLMinutes = 10
+)
+
+// pinMaxAttempts is how many wrong PINs in a row lock the PIN, configured by PIN_MAX_ATTEMPTS
+func pinMaxAttempts() int {
+	return env.PositiveInt("PIN_MAX_ATTEMPTS", defaultPinMaxAttempts)
+}
+
+// pinLockoutDuration is how long a locked PIN stays locked, configured by PIN_LOCKOUT_MINUTES
+func pinLockoutDuration() time.Duration {
+	return time.Duration(env.PositiveInt("PIN_LOCKOUT_MINUTES", defaultPinLockoutMinutes)) * time.Minute
+}
+
+// pinTokenTTL is how long a PIN login lasts, configured by PIN_TOKEN_TTL_MINUTES
+func pinTokenTTL() time.Duration {
+	return time.Duration(env.PositiveInt("PIN_TOKEN_TTL_MINUTES", defaultPinTokenTTLMinutes)) * time.Minute
+}
+
+// SetPin sets the account's PIN for the business. Employees can set their own PIN, others need write access to accounts.
+func (s *Service) SetPin(accountID uint, req accountModels.SetPinRequest, userID uint) error {
+	if err := s.checkPinAccess(accountID, req.BusinessID, userID); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Pin), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash PIN")
+	}
+
+	pin, err := s.pinRepo.GetPin(accountID, req.BusinessID)
+	if err != nil {
+		return errors.New("failed to get PIN")
+	}
+	if pin == nil {
+		pin = &entities.EmployeePin{AccountID: accountID, BusinessID: req.BusinessID}
+	}
+
+	// Setting a new PIN also lifts a lockout
+	pin.PinHash = string(hash)
+	pin.FailedAttempts = 0
+	pin.LockedUntil = nil
+	if err := s.pinRepo.SavePin(pin); err != nil {
+		return errors.New("failed to save PIN")
+	}
+	return nil
+}
+
+func (s *Service) RemovePin(accountID uint, businessID uint, userID uint) error {
+	if err := s.checkPinAccess(accountID, businessID, userID); err != nil {
+		return err
+	}
+
+	pin, err := s.pinRepo.GetPin(accountID, businessID)
+	if err != nil {
+		return errors.New("failed to get PIN")
+	}
+	if pin == nil {
+		return errors.New("PIN not found")
+	}
+
+	if err := s.pinRepo.DeletePin(pin); err != nil {
+		return errors.New("failed to remove PIN")
+	}
+	return nil
+}
+
+// GetPinLoginAccounts lists the employees that can log in with a PIN on the terminal
+func (s *Service) GetPinLoginAccounts(deviceToken string) ([]accountModels.PinLoginAccountDto, error) {
+	terminal, err := s.authenticateTerminal(deviceToken)
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := s.pinRepo.GetPinsByBusinessID(terminal.BusinessID)
+	if err != nil {
+		return nil, errors.New("failed to get accounts")
+	}
+
+	now := time.Now()
+	dtos := make([]accountModels.PinLoginAccountDto, 0, len(pins))
+	for _, pin := range pins {
+		dtos = append(dtos, accountModels.PinLoginAccountDto{
+			ID:     pin.AccountID,
+			Name:   pin.Account.Name,
+			Locked: pin.LockedUntil != nil && pin.LockedUntil.After(now),
+		})
+	}
+	return dtos, nil
+}
+
+// PinLogin logs an employee in on a terminal. The session is scoped to the terminal's business and replaces
+// whichever session was active on the terminal, so employees can switch by entering their PIN.
+func (s *Service) PinLogin(deviceToken string, req accountModels.PinLoginRequest, userAgent string, ipAddress string) (accountModels.TokenResponse, error) {
+	terminal, err := s.authenticateTerminal(deviceToken)
+	if err != nil {
+		return accountModels.TokenResponse{}, err
+	}
+
+	pin, err := s.pinRepo.GetPin(req.AccountID, terminal.BusinessID)
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+	if pin == nil {
+		return accountModels.TokenResponse{}, errors.New("invalid credentials")
+	}
+
+	if err := s.checkPin(pin, req.Pin); err != nil {
+		return accountModels.TokenResponse{}, err
+	}
+
+	account, err := s.accountRepo.GetAccountByID(pin.AccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return accountModels.TokenResponse{}, errors.New("invalid credentials")
+		}
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+	if !hasActiveRoleInBusiness(account, terminal.BusinessID) {
+		return accountModels.TokenResponse{}, errors.New("invalid credentials")
+	}
+
+	pin.FailedAttempts = 0
+	pin.LockedUntil = nil
+	if err := s.pinRepo.SavePin(pin); err != nil {
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+
+	now := time.Now()
+	if err := s.revokeTerminalSessions(terminal.ID); err != nil {
+		log.Printf("Warning: Failed to end previous sessions of terminal %d: %v", terminal.ID, err)
+	}
+
+	terminal.LastSeenAt = &now
+	if err := s.terminalRepo.UpdateTerminal(terminal); err != nil {
+		log.Printf("Warning: Failed to update terminal %d: %v", terminal.ID, err)
+	}
+
+	// PIN logins are not refreshed, the unused refresh token only keeps the session row unique
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to generate token")
+	}
+
+	session := &entities.Session{
+		AccountID:        account.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		BusinessID:       &terminal.BusinessID,
+		TerminalID:       &terminal.ID,
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(pinTokenTTL()),
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to create session")
+	}
+
+	tokens, err := s.issueTokens(account, session, refreshToken)
+	if err != nil {
+		return accountModels.TokenResponse{}, err
+	}
+	tokens.RefreshToken = ""
+	return tokens, nil
+}
+
+// checkPin compares a PIN attempt, counting failures and locking the PIN after PIN_MAX_ATTEMPTS of them
+func (s *Service) checkPin(pin *entities.EmployeePin, attempt string) error {
+	now := time.Now()
+	if pin.LockedUntil != nil && pin.LockedUntil.After(now) {
+		return errors.New("too many failed attempts")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(pin.PinHash), []byte(attempt)); err != nil {
+		pin.FailedAttempts++
+		if pin.FailedAttempts >= pinMaxAttempts() {
+			lockedUntil := now.Add(pinLockoutDuration())
+			pin.LockedUntil = &lockedUntil
+			pin.FailedAttempts = 0
+			log.Printf("PIN of account %d for business %d locked after repeated failures", pin.AccountID, pin.BusinessID)
+		}
+		if err := s.pinRepo.SavePin(pin); err != nil {
+			log.Printf("Warning: Failed to record failed PIN attempt for account %d: %v", pin.AccountID, err)
+		}
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
+
+// checkPinAccess allows managing one's own PIN in a business one works for, or anyone's with write access to its accounts
+func (s *Service) checkPinAccess(accountID uint, businessID uint, userID uint) error {
+	businessIDs, err := GetBusinessIDsFromAccount(accountID)
+	if err != nil {
+		if err.Error() == "account not found" {
+			return err
+		}
+		return errors.New("failed to get account")
+	}
+	if !slices.Contains(businessIDs, businessID) {
+		return errors.New("account does not belong to this business")
+	}
+
+	if accountID == userID {
+		return nil
+	}
+
+	ok, err := rbac.HasAccess(constants.Accounts, constants.Write, businessID, userID)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
+	}
+	return nil
+}
+
+func hasActiveRoleInBusiness(account entities.Account, businessID uint) bool {
+	for _, link := range account.AccountRoleLinks {
+		if link.AccountRole.BusinessID == businessID && link.Status == constants.Active {
+			return true
+		}
+	}
+	return false
+}
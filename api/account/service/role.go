@@ -91,6 +91,45 @@ func (s *Service) UpdateRole(roleID uint, req models.UpdateAccountRoleRequest, c
 	return s.GetRole(role.ID, claims)
 }
 
+// SetRoleLocations limits a role to locations of its business. An empty list lets the role apply everywhere again.
+func (s *Service) SetRoleLocations(roleID uint, req models.SetRoleLocationsRequest, claims map[string]interface{}) (models.AccountRoleDto, error) {
+	if claims == nil {
+		return models.AccountRoleDto{}, errors.New("unauthorized")
+	}
+
+	role, err := s.roleRepo.GetRoleByID(roleID)
+	if err != nil {
+		return models.AccountRoleDto{}, errors.New("role not found")
+	}
+
+	userID := uint(claims["id"].(float64))
+	ok, err := rbac.HasAccess(constants.Roles, constants.Write, role.BusinessID, userID)
+	if err != nil {
+		return models.AccountRoleDto{}, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return models.AccountRoleDto{}, errors.New("unauthorized")
+	}
+
+	locations := make([]entities.Location, 0, len(req.LocationIDs))
+	for _, locationID := range req.LocationIDs {
+		location, err := s.locationRepo.GetLocationByID(locationID)
+		if err != nil {
+			return models.AccountRoleDto{}, errors.New("failed to load location")
+		}
+		if location == nil || location.BusinessID != role.BusinessID {
+			return models.AccountRoleDto{}, errors.New("location not found")
+		}
+		locations = append(locations, *location)
+	}
+
+	if err := s.roleRepo.SetRoleLocations(role, locations); err != nil {
+		return models.AccountRoleDto{}, errors.New("failed to update role locations")
+	}
+
+	return s.GetRole(role.ID, claims)
+}
+
 func (s *Service) DeleteRole(roleID uint, claims map[string]interface{}) error {
 	if claims == nil {
 		return errors.New("unauthorized")
@@ -0,0 +1,216 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/middleware"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultRefreshTokenTTLDays = 30
+
+// refreshTokenTTL is how long a session lasts without being refreshed, configured by REFRESH_TOKEN_TTL_DAYS
+func refreshTokenTTL() time.Duration {
+	return time.Duration(env.PositiveInt("REFRESH_TOKEN_TTL_DAYS", defaultRefreshTokenTTLDays)) * 24 * time.Hour
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// startSession creates a session for the account and issues its first pair of tokens
+func (s *Service) startSession(account entities.Account, userAgent string, ipAddress string) (accountModels.TokenResponse, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to generate token")
+	}
+
+	now := time.Now()
+	session := &entities.Session{
+		AccountID:        account.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to create session")
+	}
+
+	return s.issueTokens(account, session, refreshToken)
+}
+
+// issueTokens signs an access token for the session and stores the session's new refresh token
+func (s *Service) issueTokens(account entities.Account, session *entities.Session, refreshToken string) (accountModels.TokenResponse, error) {
+	var token, jti string
+	var expiresAt time.Time
+	var err error
+	if session.TerminalID != nil && session.BusinessID != nil {
+		token, jti, expiresAt, err = middleware.GenerateTerminalToken(account.Username, account.ID, session.ID, *session.BusinessID, *session.TerminalID, session.ExpiresAt.Sub(time.Now()))
+	} else {
+		token, jti, expiresAt, err = middleware.GenerateToken(account.Username, account.ID, session.ID)
+	}
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to generate token")
+	}
+
+	session.AccessTokenJTI = jti
+	session.AccessTokenExpiresAt = expiresAt
+	if err := s.sessionRepo.UpdateSession(session); err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to update session")
+	}
+
+	return accountModels.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// RefreshSession rotates the refresh token and issues a new access token.
+// Presenting an already rotated refresh token revokes the session, as the token has likely leaked.
+func (s *Service) RefreshSession(refreshToken string, userAgent string, ipAddress string) (accountModels.TokenResponse, error) {
+	hash := hashToken(refreshToken)
+
+	session, err := s.sessionRepo.GetSessionByRefreshTokenHash(hash)
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+	if session == nil {
+		reused, err := s.sessionRepo.GetSessionByPreviousRefreshTokenHash(hash)
+		if err != nil {
+			return accountModels.TokenResponse{}, errors.New("internal server error")
+		}
+		if reused != nil && reused.RevokedAt == nil {
+			log.Printf("Refresh token reuse detected for session %d, revoking it", reused.ID)
+			s.revokeSession(reused)
+		}
+		return accountModels.TokenResponse{}, errors.New("invalid refresh token")
+	}
+
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return accountModels.TokenResponse{}, errors.New("invalid refresh token")
+	}
+
+	account, err := s.accountRepo.GetAccountByID(session.AccountID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			s.revokeSession(session)
+			return accountModels.TokenResponse{}, errors.New("invalid refresh token")
+		}
+		return accountModels.TokenResponse{}, errors.New("internal server error")
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return accountModels.TokenResponse{}, errors.New("failed to generate token")
+	}
+
+	// The access token issued before the refresh is replaced, so it no longer needs to stay valid
+	if err := middleware.RevokeToken(session.AccessTokenJTI, session.AccessTokenExpiresAt); err != nil {
+		log.Printf("Warning: Failed to revoke previous access token of session %d: %v", session.ID, err)
+	}
+
+	session.PreviousRefreshTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = hashToken(newToken)
+	session.LastUsedAt = time.Now()
+	session.ExpiresAt = session.LastUsedAt.Add(refreshTokenTTL())
+	if userAgent != "" {
+		session.UserAgent = userAgent
+	}
+	if ipAddress != "" {
+		session.IPAddress = ipAddress
+	}
+
+	return s.issueTokens(account, session, newToken)
+}
+
+// Logout revokes the session the request was made with
+func (s *Service) Logout(sessionID uint, userID uint) error {
+	return s.RevokeSession(sessionID, userID)
+}
+
+// LogoutAllSessions revokes every session of the account
+func (s *Service) LogoutAllSessions(userID uint) error {
+	return s.revokeAccountSessions(userID)
+}
+
+func (s *Service) GetSessions(userID uint, currentSessionID uint) ([]accountModels.SessionDto, error) {
+	sessions, err := s.sessionRepo.GetActiveSessionsByAccountID(userID, time.Now())
+	if err != nil {
+		return nil, errors.New("failed to get sessions")
+	}
+
+	dtos := make([]accountModels.SessionDto, 0, len(sessions))
+	for _, session := range sessions {
+		dtos = append(dtos, accountModels.NewSessionDtoFromEntity(session, currentSessionID))
+	}
+	return dtos, nil
+}
+
+// RevokeSession revokes one of the account's own sessions
+func (s *Service) RevokeSession(sessionID uint, userID uint) error {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return errors.New("failed to get session")
+	}
+	if session == nil || session.AccountID != userID {
+		return errors.New("session not found")
+	}
+
+	if session.RevokedAt == nil {
+		if err := s.revokeSession(session); err != nil {
+			return errors.New("failed to revoke session")
+		}
+	}
+	return nil
+}
+
+// revokeAccountSessions revokes all active sessions of the account, e.g. when it is deleted or suspended
+func (s *Service) revokeAccountSessions(accountID uint) error {
+	sessions, err := s.sessionRepo.GetActiveSessionsByAccountID(accountID, time.Now())
+	if err != nil {
+		return errors.New("failed to get sessions")
+	}
+
+	for i := range sessions {
+		if err := s.revokeSession(&sessions[i]); err != nil {
+			return errors.New("failed to revoke session")
+		}
+	}
+	return nil
+}
+
+// revokeSession marks the session revoked and denylists its current access token
+func (s *Service) revokeSession(session *entities.Session) error {
+	now := time.Now()
+	session.RevokedAt = &now
+	if err := s.sessionRepo.UpdateSession(session); err != nil {
+		log.Printf("Warning: Failed to revoke session %d: %v", session.ID, err)
+		return err
+	}
+
+	if err := middleware.RevokeToken(session.AccessTokenJTI, session.AccessTokenExpiresAt); err != nil {
+		log.Printf("Warning: Failed to denylist access token of session %d: %v", session.ID, err)
+		return err
+	}
+	return nil
+}
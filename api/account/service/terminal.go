@@ -0,0 +1,122 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/rbac"
+	"errors"
+	"log"
+	"time"
+)
+
+// RegisterTerminal registers a device of the business for PIN login and returns it together with its secret device token
+func (s *Service) RegisterTerminal(req accountModels.RegisterTerminalRequest, userID uint) (accountModels.TerminalDto, error) {
+	ok, err := rbac.HasAccess(constants.Accounts, constants.Write, req.BusinessID, userID)
+	if err != nil {
+		return accountModels.TerminalDto{}, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return accountModels.TerminalDto{}, errors.New("unauthorized")
+	}
+
+	deviceToken, err := newRefreshToken()
+	if err != nil {
+		return accountModels.TerminalDto{}, errors.New("failed to generate device token")
+	}
+
+	terminal := &entities.Terminal{
+		BusinessID:      req.BusinessID,
+		Name:            req.Name,
+		DeviceTokenHash: hashToken(deviceToken),
+		CreatedByID:     userID,
+	}
+	if err := s.terminalRepo.CreateTerminal(terminal); err != nil {
+		return accountModels.TerminalDto{}, errors.New("failed to register terminal")
+	}
+
+	dto := accountModels.NewTerminalDtoFromEntity(*terminal)
+	dto.DeviceToken = deviceToken
+	return dto, nil
+}
+
+func (s *Service) GetTerminals(businessID uint, userID uint) ([]accountModels.TerminalDto, error) {
+	ok, err := rbac.HasAccess(constants.Accounts, constants.Read, businessID, userID)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+
+	terminals, err := s.terminalRepo.GetTerminalsByBusinessID(businessID)
+	if err != nil {
+		return nil, errors.New("failed to get terminals")
+	}
+
+	dtos := make([]accountModels.TerminalDto, 0, len(terminals))
+	for _, terminal := range terminals {
+		dtos = append(dtos, accountModels.NewTerminalDtoFromEntity(terminal))
+	}
+	return dtos, nil
+}
+
+// RevokeTerminal unregisters the terminal and logs out whoever is logged in on it
+func (s *Service) RevokeTerminal(terminalID uint, userID uint) error {
+	terminal, err := s.terminalRepo.GetTerminalByID(terminalID)
+	if err != nil {
+		return errors.New("failed to get terminal")
+	}
+	if terminal == nil || terminal.RevokedAt != nil {
+		return errors.New("terminal not found")
+	}
+
+	ok, err := rbac.HasAccess(constants.Accounts, constants.Write, terminal.BusinessID, userID)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
+	}
+
+	now := time.Now()
+	terminal.RevokedAt = &now
+	if err := s.terminalRepo.UpdateTerminal(terminal); err != nil {
+		return errors.New("failed to revoke terminal")
+	}
+
+	if err := s.revokeTerminalSessions(terminal.ID); err != nil {
+		log.Printf("Warning: Failed to revoke sessions of terminal %d: %v", terminal.ID, err)
+	}
+	return nil
+}
+
+// authenticateTerminal resolves the registered terminal a device token belongs to
+func (s *Service) authenticateTerminal(deviceToken string) (*entities.Terminal, error) {
+	if deviceToken == "" {
+		return nil, errors.New("invalid terminal")
+	}
+
+	terminal, err := s.terminalRepo.GetTerminalByDeviceTokenHash(hashToken(deviceToken))
+	if err != nil {
+		return nil, errors.New("internal server error")
+	}
+	if terminal == nil || terminal.RevokedAt != nil {
+		return nil, errors.New("invalid terminal")
+	}
+	return terminal, nil
+}
+
+func (s *Service) revokeTerminalSessions(terminalID uint) error {
+	sessions, err := s.sessionRepo.GetActiveSessionsByTerminalID(terminalID, time.Now())
+	if err != nil {
+		return errors.New("failed to get sessions")
+	}
+
+	for i := range sessions {
+		if err := s.revokeSession(&sessions[i]); err != nil {
+			return errors.New("failed to revoke session")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	accountModels "VersatilePOS/account/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTotpIssuer = "VersatilePOS"
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpIssuer is the name authenticator apps show next to the code, configured by TOTP_ISSUER
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTotpIssuer
+}
+
+// StartTwoFactorSetup generates a new TOTP secret for the account. It takes effect once confirmed with EnableTwoFactor.
+func (s *Service) StartTwoFactorSetup(userID uint) (accountModels.TwoFactorSetupDto, error) {
+	account, err := s.getAccount(userID)
+	if err != nil {
+		return accountModels.TwoFactorSetupDto{}, err
+	}
+	if account.TwoFactorEnabled {
+		return accountModels.TwoFactorSetupDto{}, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return accountModels.TwoFactorSetupDto{}, errors.New("failed to generate secret")
+	}
+
+	account.TotpSecret = secret
+	account.TotpLastUsedStep = 0
+	if err := s.accountRepo.UpdateAccount(&account); err != nil {
+		return accountModels.TwoFactorSetupDto{}, errors.New("failed to update account")
+	}
+
+	return accountModels.TwoFactorSetupDto{
+		Secret:     secret,
+		OtpauthURL: totp.URI(totpIssuer(), account.Username, secret),
+	}, nil
+}
+
+// EnableTwoFactor confirms the setup with a code from the authenticator and returns the account's recovery codes
+func (s *Service) EnableTwoFactor(userID uint, code string) (accountModels.RecoveryCodesDto, error) {
+	account, err := s.getAccount(userID)
+	if err != nil {
+		return accountModels.RecoveryCodesDto{}, err
+	}
+	if account.TwoFactorEnabled {
+		return accountModels.RecoveryCodesDto{}, errors.New("two-factor authentication is already enabled")
+	}
+	if account.TotpSecret == "" {
+		return accountModels.RecoveryCodesDto{}, errors.New("two-factor setup not started")
+	}
+
+	step, ok := totp.Validate(account.TotpSecret, code, time.Now())
+	if !ok {
+		return accountModels.RecoveryCodesDto{}, errors.New("invalid two-factor code")
+	}
+
+	codes, err := s.replaceRecoveryCodes(account.ID)
+	if err != nil {
+		return accountModels.RecoveryCodesDto{}, err
+	}
+
+	now := time.Now()
+	account.TwoFactorEnabled = true
+	account.TwoFactorEnabledAt = &now
+	account.TotpLastUsedStep = step
+	if err := s.accountRepo.UpdateAccount(&account); err != nil {
+		return accountModels.RecoveryCodesDto{}, errors.New("failed to update account")
+	}
+
+	return accountModels.RecoveryCodesDto{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off, which requires the password and a second factor
+func (s *Service) DisableTwoFactor(userID uint, req accountModels.DisableTwoFactorRequest) error {
+	account, err := s.getAccount(userID)
+	if err != nil {
+		return err
+	}
+	if !account.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+		return errors.New("invalid credentials")
+	}
+	if err := s.verifySecondFactor(&account, req.Code); err != nil {
+		return err
+	}
+
+	account.TwoFactorEnabled = false
+	account.TwoFactorEnabledAt = nil
+	account.TotpSecret = ""
+	account.TotpLastUsedStep = 0
+	if err := s.accountRepo.UpdateAccount(&account); err != nil {
+		return errors.New("failed to update account")
+	}
+
+	if _, err := s.replaceRecoveryCodes(account.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the account's recovery codes, invalidating the old ones
+func (s *Service) RegenerateRecoveryCodes(userID uint, code string) (accountModels.RecoveryCodesDto, error) {
+	account, err := s.getAccount(userID)
+	if err != nil {
+		return accountModels.RecoveryCodesDto{}, err
+	}
+	if !account.TwoFactorEnabled {
+		return accountModels.RecoveryCodesDto{}, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifySecondFactor(&account, code); err != nil {
+		return accountModels.RecoveryCodesDto{}, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(account.ID)
+	if err != nil {
+		return accountModels.RecoveryCodesDto{}, err
+	}
+	return accountModels.RecoveryCodesDto{RecoveryCodes: codes}, nil
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before, or an unused recovery code
+func (s *Service) verifySecondFactor(account *entities.Account, code string) error {
+	if step, ok := totp.Validate(account.TotpSecret, code, time.Now()); ok {
+		if step <= account.TotpLastUsedStep {
+			return errors.New("invalid two-factor code")
+		}
+		account.TotpLastUsedStep = step
+		if err := s.accountRepo.UpdateAccount(account); err != nil {
+			return errors.New("failed to update account")
+		}
+		return nil
+	}
+
+	used, err := s.recoveryCodeRepo.UseRecoveryCode(account.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return errors.New("internal server error")
+	}
+	if !used {
+		return errors.New("invalid two-factor code")
+	}
+	return nil
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes. With 2FA disabled the account keeps none.
+func (s *Service) replaceRecoveryCodes(accountID uint) ([]string, error) {
+	account, err := s.getAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	var entries []entities.RecoveryCode
+	if account.TotpSecret != "" {
+		for i := 0; i < recoveryCodeCount; i++ {
+			b := make([]byte, 10)
+			if _, err := rand.Read(b); err != nil {
+				return nil, errors.New("failed to generate recovery codes")
+			}
+			code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)[:10])
+			codes = append(codes, code[:5]+"-"+code[5:])
+			entries = append(entries, entities.RecoveryCode{AccountID: accountID, CodeHash: hashToken(code)})
+		}
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceRecoveryCodes(accountID, entries); err != nil {
+		return nil, errors.New("failed to save recovery codes")
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *Service) getAccount(userID uint) (entities.Account, error) {
+	account, err := s.accountRepo.GetAccountByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return entities.Account{}, errors.New("account not found")
+		}
+		return entities.Account{}, errors.New("internal server error")
+	}
+	return account, nil
+}
@@ -23,7 +23,7 @@ func NewController() *Controller {
 }
 
 // @Summary Create a business
-// @Description Create a business with the provided details
+// @Description Create a business with the provided details. The currency is an ISO 4217 code and defaults to USD.
 // @Tags business
 // @Accept  json
 // @Produce  json
@@ -50,6 +50,10 @@ func (ctrl *Controller) CreateBusiness(c *gin.Context) {
 
 	business, err := ctrl.service.CreateBusiness(req, ownerID)
 	if err != nil {
+		if err.Error() == "invalid currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to create business:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
@@ -131,11 +135,122 @@ func (ctrl *Controller) GetBusinessById(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, business)
 }
 
+// @Summary Set two-factor requirement
+// @Description Require members with write access to accounts or roles to enable two-factor authentication. Until they do, that access is withheld while the rest of their roles still apply. Requiring it needs 2FA on the requesting account.
+// @Tags business
+// @Accept  json
+// @Produce  json
+// @Param   id       path  int                                  true  "Business ID"
+// @Param   request  body  models.UpdateTwoFactorPolicyRequest  true  "Two-factor requirement"
+// @Success 200 {object} models.BusinessDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /business/{id}/two-factor [put]
+// @Id setBusinessTwoFactorRequirement
+func (ctrl *Controller) SetTwoFactorRequirement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	var req businessModels.UpdateTwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	business, err := ctrl.service.SetTwoFactorRequirement(uint(id), *req.Required, userID)
+	if err != nil {
+		switch err.Error() {
+		case "business not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "enable two-factor authentication before requiring it":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to update business:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, business)
+}
+
+// @Summary Set business currency
+// @Description Change the ISO 4217 currency of a business. New orders, payments, gift cards and absolute price modifiers are in it; existing ones keep their currency and can only be combined with records in the same currency.
+// @Tags business
+// @Accept  json
+// @Produce  json
+// @Param   id       path  int                           true  "Business ID"
+// @Param   request  body  models.UpdateCurrencyRequest  true  "Currency"
+// @Success 200 {object} models.BusinessDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /business/{id}/currency [put]
+// @Id setBusinessCurrency
+func (ctrl *Controller) SetCurrency(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	var req businessModels.UpdateCurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	business, err := ctrl.service.SetCurrency(uint(id), req.Currency, userID)
+	if err != nil {
+		switch err.Error() {
+		case "business not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "invalid currency":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to update business:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, business)
+}
+
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	businessGroup := r.Group("/business")
-	businessGroup.Use(middleware.AuthMiddleware())
+	businessGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	businessGroup.POST("", ctrl.CreateBusiness)
 	businessGroup.GET("", ctrl.GetBusinesses)
 	businessGroup.GET("/:id", ctrl.GetBusinessById)
+	businessGroup.PUT("/:id/two-factor", ctrl.SetTwoFactorRequirement)
+	businessGroup.PUT("/:id/currency", ctrl.SetCurrency)
 	ctrl.RegisterRoleRoutes(businessGroup)
+	ctrl.RegisterTenderRuleRoutes(businessGroup)
 }
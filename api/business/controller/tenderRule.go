@@ -0,0 +1,156 @@
+package controller
+
+import (
+	businessModels "VersatilePOS/business/models"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeTenderRuleError responds with the status of a tender rule error
+func writeTenderRuleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "business not found", "tender rule not found":
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+	case "unauthorized":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+	case "only card and digital wallet payments can carry a surcharge",
+		"rounding increment must be greater than 0",
+		"rounding increment must be a multiple of the currency's smallest unit",
+		"only cash payments can be rounded",
+		"invalid surcharge",
+		"surcharge must be greater than 0",
+		"tender rules only apply to Cash, CreditCard and DigitalWallet payments":
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+	default:
+		log.Println("Failed to manage tender rules:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+	}
+}
+
+// @Summary Get business tender rules
+// @Description Get how a business adjusts payments taken against orders by payment type: cash rounding and card surcharges
+// @Tags business
+// @Produce  json
+// @Param   id   path      int  true  "Business ID"
+// @Success 200 {array} models.TenderRuleDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /business/{id}/tender-rules [get]
+// @Id getBusinessTenderRules
+func (ctrl *Controller) GetTenderRules(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	rules, err := ctrl.service.GetTenderRules(uint(id), userID)
+	if err != nil {
+		writeTenderRuleError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, rules)
+}
+
+// @Summary Set a business tender rule
+// @Description Set how payments of a type are adjusted when they are taken against an order, in the business's currency. Cash payments are rounded to the nearest multiple of roundingIncrement and the difference is recorded as its own line on the order. CreditCard and DigitalWallet payments carry a surcharge of surchargePercent of the payment plus surchargeFixed, recorded on the order.
+// @Tags business
+// @Accept  json
+// @Produce  json
+// @Param   id           path  int                          true  "Business ID"
+// @Param   paymentType  path  string                       true  "Payment type: Cash, CreditCard or DigitalWallet"
+// @Param   request      body  models.SetTenderRuleRequest  true  "Tender rule"
+// @Success 200 {object} models.TenderRuleDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /business/{id}/tender-rules/{paymentType} [put]
+// @Id setBusinessTenderRule
+func (ctrl *Controller) SetTenderRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	var req businessModels.SetTenderRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	rule, err := ctrl.service.SetTenderRule(uint(id), constants.PaymentType(c.Param("paymentType")), req, userID)
+	if err != nil {
+		writeTenderRuleError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, rule)
+}
+
+// @Summary Delete a business tender rule
+// @Description Stop adjusting payments of a type taken against orders
+// @Tags business
+// @Param   id           path  int     true  "Business ID"
+// @Param   paymentType  path  string  true  "Payment type"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /business/{id}/tender-rules/{paymentType} [delete]
+// @Id deleteBusinessTenderRule
+func (ctrl *Controller) DeleteTenderRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.DeleteTenderRule(uint(id), constants.PaymentType(c.Param("paymentType")), userID); err != nil {
+		writeTenderRuleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *Controller) RegisterTenderRuleRoutes(rg *gin.RouterGroup) {
+	rg.GET("/:id/tender-rules", ctrl.GetTenderRules)
+	rg.PUT("/:id/tender-rules/:paymentType", ctrl.SetTenderRule)
+	rg.DELETE("/:id/tender-rules/:paymentType", ctrl.DeleteTenderRule)
+}
@@ -1,6 +1,9 @@
 package models
 
-import "VersatilePOS/database/entities"
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/currency"
+)
 
 type BusinessDto struct {
 	ID      uint   `json:"id"`
@@ -8,6 +11,12 @@ type BusinessDto struct {
 	Address string `json:"address"`
 	Phone   string `json:"phone"`
 	Email   string `json:"email"`
+
+	Currency string `json:"currency"`
+	// CurrencyMinorUnits is how many decimal places amounts in the currency have
+	CurrencyMinorUnits int `json:"currencyMinorUnits"`
+
+	RequireTwoFactor bool `json:"requireTwoFactor"`
 }
 
 // NewBusinessDtoFromEntity constructs a BusinessDto from the DB entity.
@@ -18,5 +27,10 @@ func NewBusinessDtoFromEntity(b entities.Business) BusinessDto {
 		Address: b.Address,
 		Phone:   b.Phone,
 		Email:   b.Email,
+
+		Currency:           b.Currency,
+		CurrencyMinorUnits: currency.MinorUnits(b.Currency),
+
+		RequireTwoFactor: b.RequireTwoFactor,
 	}
 }
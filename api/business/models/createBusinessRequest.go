@@ -5,4 +5,6 @@ type CreateBusinessRequest struct {
 	Address string `json:"address" validate:"required"`
 	Phone   string `json:"phone" validate:"required"`
 	Email   string `json:"email" validate:"required,email"`
+	// Currency is an ISO 4217 code, USD when omitted
+	Currency string `json:"currency,omitempty"`
 }
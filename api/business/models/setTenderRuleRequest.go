@@ -0,0 +1,9 @@
+package models
+
+type SetTenderRuleRequest struct {
+	// RoundingIncrement rounds Cash payments to the nearest multiple of it, e.g. 0.05
+	RoundingIncrement float64 `json:"roundingIncrement,omitempty"`
+	// SurchargePercent and SurchargeFixed add a fee to CreditCard and DigitalWallet payments, e.g. 2.5% plus 0.30
+	SurchargePercent float64 `json:"surchargePercent,omitempty"`
+	SurchargeFixed   float64 `json:"surchargeFixed,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+)
+
+type TenderRuleDto struct {
+	PaymentType       string  `json:"paymentType"`
+	Currency          string  `json:"currency"`
+	RoundingIncrement float64 `json:"roundingIncrement,omitempty"`
+	SurchargePercent  float64 `json:"surchargePercent,omitempty"`
+	SurchargeFixed    float64 `json:"surchargeFixed,omitempty"`
+}
+
+// NewTenderRuleDtoFromEntity constructs a TenderRuleDto from the DB entity.
+func NewTenderRuleDtoFromEntity(r entities.TenderRule) TenderRuleDto {
+	return TenderRuleDto{
+		PaymentType:       string(r.PaymentType),
+		Currency:          r.Currency,
+		RoundingIncrement: r.RoundingIncrement,
+		SurchargePercent:  r.SurchargePercent,
+		SurchargeFixed:    r.SurchargeFixed,
+	}
+}
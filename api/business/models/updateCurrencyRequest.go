@@ -0,0 +1,6 @@
+package models
+
+type UpdateCurrencyRequest struct {
+	// Currency is an ISO 4217 code, e.g. EUR
+	Currency string `json:"currency" binding:"required"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type UpdateTwoFactorPolicyRequest struct {
+	// Required makes members with write access to accounts or roles enable 2FA before that access applies
+	Required *bool `json:"required" binding:"required"`
+}
@@ -40,6 +40,18 @@ func (r *Repository) GetBusinessByID(id uint) (*entities.Business, error) {
 	return &business, nil
 }
 
+// GetBusinessCurrency returns the currency of a business, or an empty string if it does not exist
+func (r *Repository) GetBusinessCurrency(id uint) (string, error) {
+	var business entities.Business
+	if result := database.DB.Select("id", "currency").First(&business, id); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		return "", result.Error
+	}
+	return business.Currency, nil
+}
+
 func (r *Repository) GetAccountWithMemberships(userID uint) (*entities.Account, error) {
 	var userAccount entities.Account
 	if err := database.DB.Preload("MemberOf").First(&userAccount, userID).Error; err != nil {
@@ -50,3 +62,7 @@ func (r *Repository) GetAccountWithMemberships(userID uint) (*entities.Account,
 	}
 	return &userAccount, nil
 }
+
+func (r *Repository) UpdateBusiness(business *entities.Business) error {
+	return database.DB.Omit("Owner", "Employees", "AccountRoles").Save(business).Error
+}
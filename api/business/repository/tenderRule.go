@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+
+	"gorm.io/gorm"
+)
+
+func (repo *Repository) GetTenderRules(businessID uint) ([]entities.TenderRule, error) {
+	var rules []entities.TenderRule
+	if err := database.DB.Where("business_id = ?", businessID).Order("payment_type").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetTenderRule returns the tender rule of a business for a payment type, or nil if it has none
+func (repo *Repository) GetTenderRule(businessID uint, paymentType constants.PaymentType) (*entities.TenderRule, error) {
+	var rule entities.TenderRule
+	if err := database.DB.Where("business_id = ? AND payment_type = ?", businessID, paymentType).First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveTenderRule creates the tender rule of a business for its payment type, or replaces the existing one
+func (repo *Repository) SaveTenderRule(rule *entities.TenderRule) error {
+	existing, err := repo.GetTenderRule(rule.BusinessID, rule.PaymentType)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	}
+	return database.DB.Omit("Business").Save(rule).Error
+}
+
+// DeleteTenderRule removes the tender rule of a business for a payment type, reporting whether it had one
+func (repo *Repository) DeleteTenderRule(businessID uint, paymentType constants.PaymentType) (bool, error) {
+	// Deleted for good, so the rule can be set again under the unique index
+	result := database.DB.Unscoped().Where("business_id = ? AND payment_type = ?", businessID, paymentType).Delete(&entities.TenderRule{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
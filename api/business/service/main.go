@@ -7,6 +7,7 @@ import (
 	"VersatilePOS/business/repository"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
 	"VersatilePOS/generic/rbac"
 	"errors"
 
@@ -26,12 +27,21 @@ func NewService() *Service {
 }
 
 func (s *Service) CreateBusiness(req businessModels.CreateBusinessRequest, ownerID uint) (*businessModels.BusinessDto, error) {
+	code := currency.Default
+	if req.Currency != "" {
+		if !currency.IsValid(req.Currency) {
+			return nil, errors.New("invalid currency")
+		}
+		code = currency.Normalize(req.Currency)
+	}
+
 	business := &entities.Business{
-		Name:    req.Name,
-		OwnerID: ownerID,
-		Address: req.Address,
-		Phone:   req.Phone,
-		Email:   req.Email,
+		Name:     req.Name,
+		OwnerID:  ownerID,
+		Address:  req.Address,
+		Phone:    req.Phone,
+		Email:    req.Email,
+		Currency: code,
 	}
 
 	createdBusiness, err := s.repo.CreateBusiness(business)
@@ -41,7 +51,7 @@ func (s *Service) CreateBusiness(req businessModels.CreateBusinessRequest, owner
 
 	// Create default roles and assign functions/links using account service helper
 	accSvc := accountService.NewService()
-	ownerFuncs := []constants.Action{constants.Accounts, constants.Businesses, constants.Roles, constants.PriceModifiers, constants.Items, constants.ItemOptions, constants.Orders, constants.Services, constants.Reservations, constants.Tags}
+	ownerFuncs := []constants.Action{constants.Accounts, constants.Businesses, constants.Roles, constants.PriceModifiers, constants.Items, constants.ItemOptions, constants.Orders, constants.Services, constants.Reservations, constants.Tags, constants.Payments, constants.GiftCards, constants.ApplyDiscount, constants.VoidItem, constants.Refund, constants.OverridePrice, constants.ViewReports, constants.ManageInventory}
 	ownerAls := []constants.AccessLevel{constants.Write, constants.Read}
 	ownerRole, err := accSvc.CreateRoleWithFunctions("Business Owner", createdBusiness.ID, ownerFuncs, ownerAls, &ownerID)
 	if err != nil {
@@ -126,3 +136,73 @@ func (s *Service) GetBusiness(id uint, userID uint) (*businessModels.BusinessDto
 	dto := businessModels.NewBusinessDtoFromEntity(*business)
 	return &dto, nil
 }
+
+// SetTwoFactorRequirement sets whether members with write access to accounts or roles need 2FA for that access.
+// The requesting user has to have 2FA enabled to require it, so they keep access to the setting.
+func (s *Service) SetTwoFactorRequirement(id uint, required bool, userID uint) (*businessModels.BusinessDto, error) {
+	business, err := s.repo.GetBusinessByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil {
+		return nil, errors.New("business not found")
+	}
+
+	ok, err := rbac.HasAccess(constants.Businesses, constants.Write, id, userID)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+
+	if required {
+		account, err := s.accountRepo.GetAccountByID(userID)
+		if err != nil {
+			return nil, errors.New("internal server error")
+		}
+		if !account.TwoFactorEnabled {
+			return nil, errors.New("enable two-factor authentication before requiring it")
+		}
+	}
+
+	business.RequireTwoFactor = required
+	if err := s.repo.UpdateBusiness(business); err != nil {
+		return nil, errors.New("failed to update business")
+	}
+
+	dto := businessModels.NewBusinessDtoFromEntity(*business)
+	return &dto, nil
+}
+
+// SetCurrency changes the currency of a business. Orders, payments, gift cards and price modifiers created before
+// keep the currency they were created in, and can only be combined with records in the same currency.
+func (s *Service) SetCurrency(id uint, code string, userID uint) (*businessModels.BusinessDto, error) {
+	business, err := s.repo.GetBusinessByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil {
+		return nil, errors.New("business not found")
+	}
+
+	ok, err := rbac.HasAccess(constants.Businesses, constants.Write, id, userID)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+
+	if !currency.IsValid(code) {
+		return nil, errors.New("invalid currency")
+	}
+
+	business.Currency = currency.Normalize(code)
+	if err := s.repo.UpdateBusiness(business); err != nil {
+		return nil, errors.New("failed to update business")
+	}
+
+	dto := businessModels.NewBusinessDtoFromEntity(*business)
+	return &dto, nil
+}
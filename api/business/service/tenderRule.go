@@ -0,0 +1,114 @@
+package service
+
+import (
+	businessModels "VersatilePOS/business/models"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/rbac"
+	"errors"
+)
+
+// getBusinessWithAccess loads a business the user has the given access to
+func (s *Service) getBusinessWithAccess(id uint, userID uint, level constants.AccessLevel) (*entities.Business, error) {
+	business, err := s.repo.GetBusinessByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil {
+		return nil, errors.New("business not found")
+	}
+
+	ok, err := rbac.HasAccess(constants.Businesses, level, id, userID)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return business, nil
+}
+
+func (s *Service) GetTenderRules(businessID uint, userID uint) ([]businessModels.TenderRuleDto, error) {
+	if _, err := s.getBusinessWithAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.repo.GetTenderRules(businessID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]businessModels.TenderRuleDto, 0, len(rules))
+	for _, rule := range rules {
+		dtos = append(dtos, businessModels.NewTenderRuleDtoFromEntity(rule))
+	}
+	return dtos, nil
+}
+
+// SetTenderRule sets how payments of a type are adjusted when they are taken against an order: Cash payments are
+// rounded to an increment, CreditCard and DigitalWallet payments carry a surcharge. Amounts are in the business's
+// currency.
+func (s *Service) SetTenderRule(businessID uint, paymentType constants.PaymentType, req businessModels.SetTenderRuleRequest, userID uint) (*businessModels.TenderRuleDto, error) {
+	business, err := s.getBusinessWithAccess(businessID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &entities.TenderRule{
+		BusinessID:  businessID,
+		PaymentType: paymentType,
+		Currency:    business.Currency,
+	}
+
+	switch paymentType {
+	case constants.Cash:
+		if req.SurchargePercent != 0 || req.SurchargeFixed != 0 {
+			return nil, errors.New("only card and digital wallet payments can carry a surcharge")
+		}
+		if req.RoundingIncrement <= 0 {
+			return nil, errors.New("rounding increment must be greater than 0")
+		}
+		// An increment finer than the currency's smallest unit would not round anything
+		if currency.Round(req.RoundingIncrement, business.Currency) != req.RoundingIncrement {
+			return nil, errors.New("rounding increment must be a multiple of the currency's smallest unit")
+		}
+		rule.RoundingIncrement = req.RoundingIncrement
+	case constants.CreditCard, constants.DigitalWallet:
+		if req.RoundingIncrement != 0 {
+			return nil, errors.New("only cash payments can be rounded")
+		}
+		if req.SurchargePercent < 0 || req.SurchargePercent > 100 || req.SurchargeFixed < 0 {
+			return nil, errors.New("invalid surcharge")
+		}
+		if req.SurchargePercent == 0 && req.SurchargeFixed == 0 {
+			return nil, errors.New("surcharge must be greater than 0")
+		}
+		rule.SurchargePercent = req.SurchargePercent
+		rule.SurchargeFixed = currency.Round(req.SurchargeFixed, business.Currency)
+	default:
+		return nil, errors.New("tender rules only apply to Cash, CreditCard and DigitalWallet payments")
+	}
+
+	if err := s.repo.SaveTenderRule(rule); err != nil {
+		return nil, err
+	}
+
+	dto := businessModels.NewTenderRuleDtoFromEntity(*rule)
+	return &dto, nil
+}
+
+func (s *Service) DeleteTenderRule(businessID uint, paymentType constants.PaymentType, userID uint) error {
+	if _, err := s.getBusinessWithAccess(businessID, userID, constants.Write); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteTenderRule(businessID, paymentType)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("tender rule not found")
+	}
+	return nil
+}
@@ -1,13 +1,36 @@
 package entities
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Account struct {
 	gorm.Model
 	Name         string `json:"name"`
 	Username     string `json:"username" gorm:"unique"`
+	Email        string `json:"email"`
 	PasswordHash string `json:"-"`
 
+	// MustChangePassword withholds the account's roles until the password is changed, e.g. the seeded admin's default password
+	MustChangePassword bool       `json:"mustChangePassword"`
+	PasswordChangedAt  *time.Time `json:"passwordChangedAt"`
+
+	// Failed logins since the last successful one. Reaching the limit locks the account, each further lockout for longer.
+	FailedLoginAttempts int        `json:"-"`
+	LockoutCount        int        `json:"-"`
+	LockedUntil         *time.Time `json:"lockedUntil"`
+
+	// TOTP secret, set when enrollment starts. It is only required on login once TwoFactorEnabled is set.
+	TotpSecret         string     `json:"-"`
+	TwoFactorEnabled   bool       `json:"twoFactorEnabled"`
+	TwoFactorEnabledAt *time.Time `json:"twoFactorEnabledAt"`
+	// Last time step a code was accepted for, so a code cannot be used twice
+	TotpLastUsedStep int64 `json:"-"`
+
+	RecoveryCodes []RecoveryCode `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountID"`
+
 	AccountRoleLinks []AccountRoleLink `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountID"`
 
 	OwnedBusinesses []Business `gorm:"foreignKey:OwnerID"`
@@ -12,6 +12,9 @@ type AccountRole struct {
 
 	BusinessID uint     `json:"businessId"`
 	Business   Business `gorm:"foreignKey:BusinessID"`
+
+	// Roles without locations apply at every location of the business
+	Locations []Location `gorm:"many2many:account_role_locations;"`
 }
 
 type AccountRoleLink struct {
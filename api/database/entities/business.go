@@ -9,6 +9,12 @@ type Business struct {
 	Phone   string `json:"phone"`
 	Email   string `json:"email"`
 
+	// Currency is the ISO 4217 code new orders, payments and gift cards of the business are in
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
+
+	// RequireTwoFactor withholds roles with write access to accounts or roles from members without 2FA
+	RequireTwoFactor bool `json:"requireTwoFactor"`
+
 	OwnerID uint    `json:"ownerId"`
 	Owner   Account `gorm:"foreignKey:OwnerID"`
 
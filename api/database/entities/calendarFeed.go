@@ -0,0 +1,20 @@
+package entities
+
+import "gorm.io/gorm"
+
+// CalendarFeed is a secret tokenized iCalendar feed of a Business' reservations,
+// or of a single employee's reservations when AccountID is set
+type CalendarFeed struct {
+	gorm.Model
+
+	BusinessID uint     `json:"businessId"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
+
+	AccountID *uint    `json:"accountId"`
+	Account   *Account `gorm:"foreignKey:AccountID"`
+
+	// Only the SHA-256 hash of the token is stored, the token itself is part of the feed URL
+	TokenHash string `json:"-" gorm:"uniqueIndex;not null"`
+
+	CreatedByID uint `json:"createdById"`
+}
@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmployeePin is an Account's numeric PIN for logging in on the terminals of one business
+type EmployeePin struct {
+	gorm.Model
+
+	AccountID uint    `json:"accountId" gorm:"uniqueIndex:idx_employee_pin_account_business"`
+	Account   Account `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountID"`
+
+	BusinessID uint     `json:"businessId" gorm:"uniqueIndex:idx_employee_pin_account_business"`
+	Business   Business `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:BusinessID"`
+
+	PinHash string `json:"-"`
+
+	// Failed attempts since the last successful login, the PIN is locked once they reach the limit
+	FailedAttempts int        `json:"failedAttempts"`
+	LockedUntil    *time.Time `json:"lockedUntil"`
+}
@@ -20,6 +20,10 @@ type AccountRoleFunctionLink struct {
 
 	AccessLevels pq.StringArray `gorm:"type:text[]" json:"accessLevels"`
 
+	// Optional monetary limits, e.g. the largest discount or refund the role may grant
+	MaxAmount     *float64 `json:"maxAmount" gorm:"type:decimal(19,4)"`
+	MaxPercentage *float64 `json:"maxPercentage" gorm:"type:decimal(5,2)"`
+
 	AccountRoleID uint        `json:"accountRoleId"`
 	AccountRole   AccountRole `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountRoleID"`
 
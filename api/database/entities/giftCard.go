@@ -1,15 +1,30 @@
 package entities
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type GiftCard struct {
 	gorm.Model
 	Code         string   `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
-	InitialValue float64  `json:"initialValue" gorm:"type:decimal(10,2);not null"`
-	Balance      float64  `json:"balance" gorm:"type:decimal(10,2);not null"`
+	InitialValue float64  `json:"initialValue" gorm:"type:decimal(19,4);not null"`
+	Balance      float64  `json:"balance" gorm:"type:decimal(19,4);not null"`
+	Currency     string   `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
 	IsActive     bool     `json:"isActive" gorm:"default:true"`
 	BusinessID   uint     `json:"businessId"`
 	Business     Business `gorm:"foreignKey:BusinessID"`
+
+	// ExpiresAt is when the remaining balance expires, cards without it never expire
+	ExpiresAt *time.Time `json:"expiresAt"`
+
+	// PinHash is the bcrypt hash of the optional security code required for redemption and balance checks
+	PinHash           string     `json:"-"`
+	PinFailedAttempts int        `json:"-" gorm:"default:0"`
+	PinLockedUntil    *time.Time `json:"-"`
+
+	// OrderItemID is the order line the card was sold with
+	OrderItemID *uint      `json:"orderItemId" gorm:"index"`
+	OrderItem   *OrderItem `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:OrderItemID"`
 }
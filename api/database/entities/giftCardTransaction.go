@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+
+	"gorm.io/gorm"
+)
+
+// GiftCardTransaction is an entry of a gift card's append-only ledger. Amount is the signed change of the balance,
+// BalanceAfter the balance once it was applied.
+type GiftCardTransaction struct {
+	gorm.Model
+
+	GiftCardID uint     `json:"giftCardId" gorm:"index;not null"`
+	GiftCard   GiftCard `gorm:"foreignKey:GiftCardID"`
+
+	Type         constants.GiftCardTransactionType `json:"type" gorm:"type:varchar(50);not null"`
+	Amount       float64                           `json:"amount" gorm:"type:decimal(19,4);not null"`
+	BalanceAfter float64                           `json:"balanceAfter" gorm:"type:decimal(19,4);not null"`
+
+	PaymentID *uint    `json:"paymentId" gorm:"index"`
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+	AccountID *uint    `json:"accountId"`
+	Account   *Account `gorm:"foreignKey:AccountID"`
+}
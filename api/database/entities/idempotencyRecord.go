@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+// IdempotencyRecord is the stored response to a request made with an Idempotency-Key, replayed when the request
+// is retried. StatusCode is 0 while the first request is still being handled.
+type IdempotencyRecord struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	AccountID uint   `json:"accountId" gorm:"uniqueIndex:idx_idempotency_account_key;not null"`
+	Key       string `json:"key" gorm:"type:varchar(255);uniqueIndex:idx_idempotency_account_key;not null"`
+	// Fingerprint is a SHA-256 hash of the method, URL and body of the request
+	Fingerprint string `json:"-" gorm:"type:varchar(64);not null"`
+
+	StatusCode   int    `json:"statusCode"`
+	ContentType  string `json:"contentType"`
+	ResponseBody []byte `json:"-"`
+
+	ExpiresAt time.Time `json:"expiresAt" gorm:"index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -7,7 +7,10 @@ type Item struct {
 	BusinessID uint     `json:"businessId"`
 	Business   Business `gorm:"foreignKey:BusinessID"`
 	Name       string   `json:"name"`
-	Price      float64  `json:"price" gorm:"type:decimal(10,2)"`
+	Price      float64  `json:"price" gorm:"type:decimal(19,4)"`
+
+	// IsGiftCard items sell a gift card worth their price, issued once the order is paid
+	IsGiftCard bool `json:"isGiftCard"`
 
 	ItemOptions        []ItemOption               `gorm:"foreignKey:ItemID" json:"-"`
 	PriceModifierLinks []PriceModifierItemLink    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:ItemID" json:"-"`
@@ -0,0 +1,12 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Location is a physical site of a business, such as a store or a counter, that roles can be limited to
+type Location struct {
+	gorm.Model
+	BusinessID uint     `json:"businessId"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
+	Name       string   `json:"name" gorm:"not null"`
+	Address    string   `json:"address"`
+}
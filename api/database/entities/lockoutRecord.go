@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// LockoutRecord audits a lockout, either of an account after failed logins or of a rate limited key
+type LockoutRecord struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Scope is what was locked out, e.g. "account" or the name of a rate limiter
+	Scope     string `json:"scope" gorm:"index"`
+	Key       string `json:"key"`
+	AccountID *uint  `json:"accountId" gorm:"index"`
+	IPAddress string `json:"ipAddress"`
+
+	LockedUntil time.Time `json:"lockedUntil"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ManagerApproval lets an employee perform a single action they lack the permission for, after a manager
+// authenticated for it. Only a SHA-256 hash of the approval token is stored.
+type ManagerApproval struct {
+	gorm.Model
+
+	BusinessID uint             `json:"businessId" gorm:"index"`
+	Action     constants.Action `json:"action" gorm:"type:varchar(50);not null"`
+	TokenHash  string           `json:"-" gorm:"uniqueIndex;not null"`
+
+	OrderID *uint  `json:"orderId" gorm:"index"`
+	Order   *Order `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
+
+	RequestedByID uint    `json:"requestedById"`
+	RequestedBy   Account `gorm:"foreignKey:RequestedByID"`
+	ApprovedByID  uint    `json:"approvedById"`
+	ApprovedBy    Account `gorm:"foreignKey:ApprovedByID"`
+
+	// The amount and percentage the manager approved, uses beyond them are refused
+	ApprovedAmount     *float64 `json:"approvedAmount" gorm:"type:decimal(19,4)"`
+	ApprovedPercentage *float64 `json:"approvedPercentage" gorm:"type:decimal(5,2)"`
+
+	// The amount and percentage the approval was used for
+	Amount     *float64 `json:"amount" gorm:"type:decimal(19,4)"`
+	Percentage *float64 `json:"percentage" gorm:"type:decimal(5,2)"`
+
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+}
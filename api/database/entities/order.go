@@ -16,12 +16,16 @@ type Order struct {
 	Business           Business  `gorm:"foreignKey:BusinessID"`
 	ServicingAccountID *uint     `json:"servicingAccountId"`
 	ServicingAccount   *Account  `gorm:"foreignKey:ServicingAccountID"`
+	LocationID         *uint     `json:"locationId"`
+	Location           *Location `gorm:"foreignKey:LocationID"`
 
 	// Order details
 	DatePlaced    time.Time            `json:"datePlaced" gorm:"not null"`
 	Status        constants.OrderStatus `json:"status" gorm:"type:varchar(50);not null;default:'Pending'"`
-	TipAmount     float64              `json:"tipAmount" gorm:"type:decimal(10,2);default:0"`
-	ServiceCharge float64              `json:"serviceCharge" gorm:"type:decimal(10,2);default:0"`
+	TipAmount     float64              `json:"tipAmount" gorm:"type:decimal(19,4);default:0"`
+	ServiceCharge float64              `json:"serviceCharge" gorm:"type:decimal(19,4);default:0"`
+	// Currency is the business's currency when the order was placed, its payments and price modifiers have to match
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
 
 	// Customer information
 	Customer      string `json:"customer"`
@@ -36,6 +40,8 @@ type Order struct {
 	OrderItems              []OrderItem              `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
 	OrderPaymentLinks       []OrderPaymentLink       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
 	PriceModifierOrderLinks []PriceModifierOrderLink `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
+	ManagerApprovals        []ManagerApproval        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
+	OrderAdjustments        []OrderAdjustment        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
 }
 
 // OrderItem represents a specific item added to an order
@@ -50,6 +56,9 @@ type OrderItem struct {
 
 	Count uint32 `json:"count" gorm:"not null;default:1"`
 
+	// UnitPrice overrides the item's price for this order when set
+	UnitPrice *float64 `json:"unitPrice" gorm:"type:decimal(19,4)"`
+
 	// Relationships
 	ItemOptionLinks []ItemOptionLink `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderItemID"`
 }
@@ -64,3 +73,19 @@ type OrderPaymentLink struct {
 	PaymentID uint    `json:"paymentId"`
 	Payment   Payment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PaymentID"`
 }
+
+// OrderAdjustment is an amount a business's tender rule added to an order when a payment was taken against it, e.g.
+// the difference a cash payment was rounded by or a card surcharge
+type OrderAdjustment struct {
+	gorm.Model
+
+	OrderID uint  `json:"orderId" gorm:"index;not null"`
+	Order   Order `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OrderID"`
+
+	PaymentID uint    `json:"paymentId" gorm:"index;not null"`
+	Payment   Payment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PaymentID"`
+
+	Type constants.OrderAdjustmentType `json:"type" gorm:"type:varchar(50);not null"`
+	// Amount is signed, cash rounded down is a negative adjustment
+	Amount float64 `json:"amount" gorm:"type:decimal(19,4);not null"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken is a single-use token for setting a new password without the current one.
+// Only a SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	gorm.Model
+
+	AccountID uint    `json:"accountId" gorm:"index"`
+	Account   Account `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountID"`
+
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+
+	// RequestedByID is the account that initiated the reset, nil for self-service resets
+	RequestedByID *uint `json:"requestedById"`
+}
@@ -2,16 +2,29 @@ package entities
 
 import (
 	"VersatilePOS/generic/constants"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type Payment struct {
 	gorm.Model
-	Amount            float64        `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Amount            float64        `json:"amount" gorm:"type:decimal(19,4);not null"`
+	Currency          string         `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
 	Type              constants.PaymentType   `json:"type" gorm:"type:varchar(50);not null"`
 	Status            constants.PaymentStatus `json:"status" gorm:"type:varchar(50);not null;default:'Pending'"`
 	StripePaymentIntentID *string    `json:"stripePaymentIntentId,omitempty" gorm:"type:varchar(255)"`
 	StripeCustomerID      *string    `json:"stripeCustomerId,omitempty" gorm:"type:varchar(255)"`
 	GiftCardCode          *string    `json:"giftCardCode,omitempty" gorm:"type:varchar(50)"`
+	// ManualCapture card payments are authorized first and captured later, e.g. for tabs
+	ManualCapture         bool       `json:"manualCapture" gorm:"not null;default:false"`
+	AuthorizedAmount      float64    `json:"authorizedAmount" gorm:"type:decimal(19,4);not null;default:0"`
+	AuthorizedAt          *time.Time `json:"authorizedAt"`
+	// RefundedAmount is how much of a card payment was refunded at the payment gateway
+	RefundedAmount        float64    `json:"refundedAmount" gorm:"type:decimal(19,4);not null;default:0"`
+	// SavedPaymentMethodID is the saved card an off-session payment charged, StripeCustomerID its customer
+	SavedPaymentMethodID  *uint      `json:"savedPaymentMethodId,omitempty" gorm:"index"`
+
+	BusinessID uint     `json:"businessId" gorm:"index"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
 }
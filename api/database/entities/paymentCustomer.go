@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentCustomer is a customer of a business with a customer at the payment gateway, which their saved cards are
+// attached to. Customers are identified by their email, like on orders and reservations.
+type PaymentCustomer struct {
+	gorm.Model
+
+	BusinessID uint     `json:"businessId" gorm:"not null;uniqueIndex:idx_payment_customer_business_email"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
+
+	Email            string `json:"email" gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_customer_business_email"`
+	Name             string `json:"name"`
+	Phone            string `json:"phone"`
+	StripeCustomerID string `json:"stripeCustomerId" gorm:"type:varchar(255);not null;uniqueIndex"`
+
+	SavedPaymentMethods []SavedPaymentMethod `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PaymentCustomerID"`
+}
+
+// SavedPaymentMethod is a card a customer saved with a setup intent so it can be charged without them present, e.g.
+// for a no-show fee. It records the consent they gave to those charges.
+type SavedPaymentMethod struct {
+	gorm.Model
+
+	PaymentCustomerID uint            `json:"paymentCustomerId" gorm:"index;not null"`
+	PaymentCustomer   PaymentCustomer `gorm:"foreignKey:PaymentCustomerID"`
+
+	Status                constants.SavedPaymentMethodStatus `json:"status" gorm:"type:varchar(50);not null;default:'Pending'"`
+	StripeSetupIntentID   string                             `json:"stripeSetupIntentId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	StripePaymentMethodID *string                            `json:"stripePaymentMethodId,omitempty" gorm:"type:varchar(255);index"`
+	Brand                 string                             `json:"brand"`
+	Last4                 string                             `json:"last4" gorm:"type:varchar(4)"`
+	ExpMonth              int                                `json:"expMonth"`
+	ExpYear               int                                `json:"expYear"`
+
+	// ConsentText is the agreement to off-session charges the customer accepted when saving the card, and
+	// ConsentRecordedBy the employee who collected it
+	ConsentText         string    `json:"consentText" gorm:"type:text;not null"`
+	ConsentedAt         time.Time `json:"consentedAt" gorm:"not null"`
+	ConsentRecordedByID uint      `json:"consentRecordedById"`
+	ConsentRecordedBy   Account   `gorm:"foreignKey:ConsentRecordedByID"`
+	// CoversReservationFees is whether the consent covers reservation fees, e.g. for late cancellations and no-shows,
+	// only then can the card be linked to reservations and charged their fees
+	CoversReservationFees bool `json:"coversReservationFees" gorm:"default:false"`
+
+	DetachedAt *time.Time `json:"detachedAt"`
+}
@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentDiscrepancy is a difference between the payments recorded and the payment gateway found when they were
+// reconciled. A discrepancy is stored once per intent and type, and LastSeenAt is updated while it persists.
+type PaymentDiscrepancy struct {
+	gorm.Model
+
+	BusinessID            uint                             `json:"businessId" gorm:"index;not null"`
+	Type                  constants.PaymentDiscrepancyType `json:"type" gorm:"type:varchar(50);not null;uniqueIndex:idx_payment_discrepancy_intent_type"`
+	StripePaymentIntentID string                           `json:"stripePaymentIntentId" gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_discrepancy_intent_type"`
+	// PaymentID is the payment recorded for the intent, nil for orphaned intents
+	PaymentID *uint `json:"paymentId,omitempty" gorm:"index"`
+
+	Amount          float64 `json:"amount" gorm:"type:decimal(19,4);not null;default:0"`
+	Currency        string  `json:"currency" gorm:"type:varchar(3)"`
+	GatewayAmount   float64 `json:"gatewayAmount" gorm:"type:decimal(19,4);not null;default:0"`
+	GatewayCurrency string  `json:"gatewayCurrency" gorm:"type:varchar(3)"`
+	GatewayStatus   string  `json:"gatewayStatus" gorm:"type:varchar(50)"`
+
+	LastSeenAt time.Time `json:"lastSeenAt" gorm:"not null"`
+
+	Business Business `gorm:"foreignKey:BusinessID"`
+	Payment  *Payment `gorm:"foreignKey:PaymentID"`
+}
@@ -11,10 +11,13 @@ type PriceModifier struct {
 	gorm.Model
 	ModifierType constants.ModifierType `json:"modifierType" gorm:"type:varchar(50);not null"`
 	Name         string                 `json:"name" gorm:"type:varchar(255);not null"`
-	Value        float64                `json:"value" gorm:"type:decimal(10,2);not null"`
+	Value        float64                `json:"value" gorm:"type:decimal(19,4);not null"`
 	IsPercentage bool                   `json:"isPercentage" gorm:"default:false"`
 	EndDate      *time.Time             `json:"endDate"`
 
+	// Currency is the currency of Value for absolute modifiers, percentages have none
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:''"`
+
 	BusinessID uint     `json:"businessId"`
 	Business   Business `gorm:"foreignKey:BusinessID"`
 
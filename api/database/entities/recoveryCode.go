@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost.
+// Only a SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	gorm.Model
+
+	AccountID uint   `json:"accountId" gorm:"index"`
+	CodeHash  string `json:"-" gorm:"index"`
+
+	UsedAt *time.Time `json:"usedAt"`
+}
@@ -22,11 +22,35 @@ type Reservation struct {
 	ReservationLength uint32                    `json:"reservationLength"`
 	Status            constants.ReservationStatus `json:"status"`
 	TipAmount         float64                   `json:"tipAmount"`
+	// Currency is the business's currency when the reservation was placed, its bill and payments are in it
+	Currency string `json:"currency" gorm:"type:varchar(3);not null;default:'USD'"`
 
 	Customer      string `json:"customer"`
 	CustomerEmail string `json:"customerEmail"`
 	CustomerPhone string `json:"customerPhone"`
 
+	// Deposit taken at booking, the payment is also linked through ReservationPaymentLinks
+	DepositAmount    float64 `json:"depositAmount" gorm:"type:decimal(19,4);default:0"`
+	DepositPaymentID *uint   `json:"depositPaymentId"`
+	// DepositExpiresAt is when a reservation held Pending for its deposit is released if the deposit is still unpaid
+	DepositExpiresAt *time.Time `json:"depositExpiresAt"`
+
+	// SavedPaymentMethod is the card linked at booking that reservation fees, e.g. for a no-show, are charged to
+	SavedPaymentMethodID *uint               `json:"savedPaymentMethodId"`
+	SavedPaymentMethod   *SavedPaymentMethod `gorm:"foreignKey:SavedPaymentMethodID"`
+
+	// Online bookings stay Pending until the customer confirms them with the code sent to them
+	BookedOnline              bool       `json:"bookedOnline" gorm:"default:false"`
+	ConfirmationCodeHash      string     `json:"-"`
+	ConfirmationCodeExpiresAt *time.Time `json:"-"`
+	ConfirmationAttempts      uint       `json:"-" gorm:"default:0"`
+	// BookedFromIP is the client IP the online booking was placed from, to cap the unconfirmed holds per client
+	BookedFromIP string `json:"-"`
+
+	// Resource allocated to the reservation when its service requires one
+	ResourceID *uint     `json:"resourceId"`
+	Resource   *Resource `gorm:"foreignKey:ResourceID"`
+
 	PriceModifierLinks []PriceModifierReservationLink `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:ReservationID"`
 	ReservationPaymentLinks []ReservationPaymentLink `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:ReservationID"`
 }
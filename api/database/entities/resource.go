@@ -0,0 +1,18 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Resource is a bookable chair, room or machine of a Business.
+// Capacity is the number of reservations it can serve at the same time.
+type Resource struct {
+	gorm.Model
+
+	BusinessID uint     `json:"businessId"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
+
+	Name     string `json:"name" gorm:"not null"`
+	Type     string `json:"type"`
+	Capacity uint   `json:"capacity" gorm:"not null;default:1"`
+
+	Services []Service `gorm:"many2many:service_resources;"`
+}
@@ -13,14 +13,26 @@ type Service struct {
 	Business   Business `gorm:"foreignKey:BusinessID"`
 
 	Name         string  `json:"name"`
-	HourlyPrice  float64 `json:"hourlyPrice" gorm:"type:decimal(10,2);not null"`
-	ServiceCharge float64 `json:"serviceCharge" gorm:"type:decimal(10,2);not null;default:0"`
+	HourlyPrice  float64 `json:"hourlyPrice" gorm:"type:decimal(19,4);not null"`
+	ServiceCharge float64 `json:"serviceCharge" gorm:"type:decimal(19,4);not null;default:0"`
 
 	ProvisioningStartTime time.Time `json:"provisioningStartTime"`
 	ProvisioningEndTime   time.Time `json:"provisioningEndTime"`
 	ProvisioningInterval  uint      `json:"provisioningInterval"` // Duration in minutes
 
+	// Cancellation policy
+	CancellationWindow  uint    `json:"cancellationWindow"` // Minutes before the reservation within which cancelling incurs a fee
+	LateCancellationFee float64 `json:"lateCancellationFee" gorm:"type:decimal(19,4);not null;default:0"`
+	NoShowFee           float64 `json:"noShowFee" gorm:"type:decimal(19,4);not null;default:0"`
+
+	// Deposit required at booking, either a fixed amount or a percentage of HourlyPrice × length
+	DepositValue        float64 `json:"depositValue" gorm:"type:decimal(19,4);not null;default:0"`
+	DepositIsPercentage bool    `json:"depositIsPercentage" gorm:"default:false"`
+
 	Employees []Account `gorm:"many2many:account_services;"`
+
+	// Resources a reservation of this service needs one of, besides an employee
+	RequiredResources []Resource `gorm:"many2many:service_resources;"`
 }
 
 type AccountServices struct {
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a login of an Account. Its refresh token is rotated on every refresh,
+// only SHA-256 hashes of the tokens are stored.
+type Session struct {
+	gorm.Model
+
+	AccountID uint    `json:"accountId"`
+	Account   Account `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AccountID"`
+
+	RefreshTokenHash string `json:"-" gorm:"uniqueIndex;not null"`
+	// PreviousRefreshTokenHash detects reuse of a rotated refresh token, which revokes the session
+	PreviousRefreshTokenHash string `json:"-" gorm:"index"`
+
+	// Access token most recently issued for the session, denylisted when the session is revoked
+	AccessTokenJTI       string    `json:"-"`
+	AccessTokenExpiresAt time.Time `json:"-"`
+
+	// Set for PIN logins, which are scoped to the business and terminal they were made on
+	BusinessID *uint `json:"businessId"`
+	TerminalID *uint `json:"terminalId"`
+
+	UserAgent  string     `json:"userAgent"`
+	IPAddress  string     `json:"ipAddress"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+// RevokedToken is a denylisted access token, kept until the token would have expired anyway
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+
+	"gorm.io/gorm"
+)
+
+// TenderRule adjusts the payments of one type a business takes against orders: cash payments are rounded to an
+// increment, card and digital wallet payments carry a surcharge
+type TenderRule struct {
+	gorm.Model
+
+	BusinessID uint     `json:"businessId" gorm:"not null;uniqueIndex:idx_tender_rule_business_type"`
+	Business   Business `gorm:"foreignKey:BusinessID"`
+
+	PaymentType constants.PaymentType `json:"paymentType" gorm:"type:varchar(50);not null;uniqueIndex:idx_tender_rule_business_type"`
+	// Currency is the business's currency when the rule was set, the rule is not applied to orders in another one
+	Currency string `json:"currency" gorm:"type:varchar(3);not null"`
+
+	// RoundingIncrement rounds cash payments to the nearest multiple of it, e.g. 0.05
+	RoundingIncrement float64 `json:"roundingIncrement" gorm:"type:decimal(19,4);not null;default:0"`
+	// SurchargePercent and SurchargeFixed add a fee of a percentage of the payment plus a fixed amount
+	SurchargePercent float64 `json:"surchargePercent" gorm:"type:decimal(7,4);not null;default:0"`
+	SurchargeFixed   float64 `json:"surchargeFixed" gorm:"type:decimal(19,4);not null;default:0"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Terminal is a device registered to a business on which employees log in with their PIN.
+// Only a SHA-256 hash of its device token is stored.
+type Terminal struct {
+	gorm.Model
+
+	BusinessID uint     `json:"businessId"`
+	Business   Business `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:BusinessID"`
+
+	Name            string `json:"name"`
+	DeviceTokenHash string `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedByID     uint   `json:"createdById"`
+
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WaitlistEntry is a customer waiting for a slot of a Service within a time window
+type WaitlistEntry struct {
+	gorm.Model
+
+	ServiceID uint    `json:"serviceId"`
+	Service   Service `gorm:"foreignKey:ServiceID"`
+
+	WindowStart       time.Time `json:"windowStart" gorm:"not null"`
+	WindowEnd         time.Time `json:"windowEnd" gorm:"not null"`
+	ReservationLength uint32    `json:"reservationLength"`
+
+	Customer      string `json:"customer"`
+	CustomerEmail string `json:"customerEmail"`
+	CustomerPhone string `json:"customerPhone"`
+
+	Status constants.WaitlistStatus `json:"status" gorm:"type:varchar(50);not null;default:'Waiting'"`
+
+	// Slot offered to the customer, held for them until HoldExpiresAt
+	OfferedAccountID     *uint      `json:"offeredAccountId"`
+	OfferedDateOfService *time.Time `json:"offeredDateOfService"`
+	HoldExpiresAt        *time.Time `json:"holdExpiresAt"`
+
+	// Reservation created when the offer is accepted
+	ReservationID *uint `json:"reservationId"`
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEvent is an event received from the payment gateway. Events are stored once per EventID, so replays
+// are ignored, and processed by a worker that retries failed attempts with a backoff.
+type WebhookEvent struct {
+	gorm.Model
+
+	EventID  string `json:"eventId" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Type     string `json:"type" gorm:"type:varchar(100);not null"`
+	IntentID string `json:"intentId" gorm:"type:varchar(255);index"`
+	// Payload is the verified event as JSON
+	Payload string `json:"payload" gorm:"type:text;not null"`
+
+	Status        constants.WebhookEventStatus `json:"status" gorm:"type:varchar(50);not null;default:'Pending';index"`
+	Attempts      int                          `json:"attempts" gorm:"not null;default:0"`
+	LastError     string                       `json:"lastError" gorm:"type:text"`
+	NextAttemptAt time.Time                    `json:"nextAttemptAt" gorm:"index"`
+	ProcessedAt   *time.Time                   `json:"processedAt"`
+}
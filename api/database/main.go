@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -60,21 +61,40 @@ func Connect() {
 		&entities.Account{},
 		&entities.Business{},
 		&entities.BusinessEmployees{},
+		&entities.Location{},
 		&entities.AccountRole{},
 		&entities.AccountRoleLink{},
 		&entities.AccountRoleFunctionLink{},
 		&entities.Function{},
+		&entities.RecoveryCode{},
+		&entities.PasswordResetToken{},
+		&entities.LockoutRecord{},
+		&entities.Session{},
+		&entities.RevokedToken{},
+		&entities.IdempotencyRecord{},
+		&entities.Terminal{},
+		&entities.EmployeePin{},
+		&entities.PaymentCustomer{},
+		&entities.SavedPaymentMethod{},
 		&entities.Payment{},
+		&entities.TenderRule{},
+		&entities.WebhookEvent{},
+		&entities.PaymentDiscrepancy{},
 		&entities.GiftCard{},
+		&entities.GiftCardTransaction{},
 		&entities.PriceModifier{},
 		&entities.PriceModifierOrderLink{},
 		&entities.PriceModifierReservationLink{},
 		&entities.PriceModifierItemLink{},
 		&entities.Reservation{},
 		&entities.ReservationPaymentLink{},
+		&entities.WaitlistEntry{},
+		&entities.CalendarFeed{},
 		&entities.Order{},
 		&entities.OrderItem{},
 		&entities.OrderPaymentLink{},
+		&entities.OrderAdjustment{},
+		&entities.ManagerApproval{},
 		&entities.Item{},
 		&entities.ItemInventory{},
 		&entities.ItemOption{},
@@ -82,6 +102,7 @@ func Connect() {
 		&entities.ItemOptionLink{},
 		&entities.Service{},
 		&entities.AccountServices{},
+		&entities.Resource{},
 		&entities.Tag{},
 		&entities.ItemTagLink{},
 		&entities.ItemOptionTagLink{},
@@ -94,6 +115,11 @@ func Connect() {
 	log.Println("Database migrated.")
 
 	seedFunctions(DB)
+	grantOwnerFunctions(DB, constants.Payments, constants.GiftCards, constants.ApplyDiscount, constants.VoidItem, constants.Refund,
+		constants.OverridePrice, constants.ViewReports, constants.ManageInventory)
+	backfillPaymentBusinesses(DB)
+	backfillGiftCardLedgers(DB)
+	backfillPriceModifierCurrencies(DB)
 	seedSuperAdmin(DB)
 }
 
@@ -109,6 +135,14 @@ func seedFunctions(db *gorm.DB) {
 		{Name: "Manage Item Options", Action: constants.ItemOptions, Description: "Create, update, and delete item options."},
 		{Name: "Manage Orders", Action: constants.Orders, Description: "Create, update, and manage orders."},
 		{Name: "Manage Tags", Action: constants.Tags, Description: "Create, update, and delete tags for categorizing items, item options, and services."},
+		{Name: "Manage Payments", Action: constants.Payments, Description: "Take, view, and complete payments."},
+		{Name: "Manage Gift Cards", Action: constants.GiftCards, Description: "Issue, view, and deactivate gift cards."},
+		{Name: "Apply Discounts", Action: constants.ApplyDiscount, Description: "Apply discounts to orders, optionally up to a limit."},
+		{Name: "Void Items", Action: constants.VoidItem, Description: "Remove items from orders or lower their count."},
+		{Name: "Refund Orders", Action: constants.Refund, Description: "Refund paid orders, optionally up to a limit."},
+		{Name: "Override Prices", Action: constants.OverridePrice, Description: "Change the unit price of items on an order."},
+		{Name: "View Reports", Action: constants.ViewReports, Description: "View payment reconciliation reports and discrepancies."},
+		{Name: "Manage Inventory", Action: constants.ManageInventory, Description: "Change tracked stock of items and item options."},
 	}
 
 	for _, function := range functions {
@@ -127,15 +161,100 @@ func seedFunctions(db *gorm.DB) {
 	}
 }
 
+// grantOwnerFunctions gives existing "Business Owner" roles full access to functions added after they were created
+func grantOwnerFunctions(db *gorm.DB, actions ...constants.Action) {
+	var functions []entities.Function
+	if err := db.Where("action IN ?", actions).Find(&functions).Error; err != nil {
+		log.Printf("failed to load functions to grant: %v\n", err)
+		return
+	}
+
+	var ownerRoles []entities.AccountRole
+	if err := db.Where("name = ?", "Business Owner").Find(&ownerRoles).Error; err != nil {
+		log.Printf("failed to load owner roles: %v\n", err)
+		return
+	}
+
+	for _, role := range ownerRoles {
+		for _, function := range functions {
+			var count int64
+			if err := db.Model(&entities.AccountRoleFunctionLink{}).Where("account_role_id = ? AND function_id = ?", role.ID, function.ID).Count(&count).Error; err != nil {
+				log.Printf("failed to check function %s of role %d: %v\n", function.Action, role.ID, err)
+				continue
+			}
+			if count > 0 {
+				continue
+			}
+
+			link := entities.AccountRoleFunctionLink{
+				AccountRoleID: role.ID,
+				FunctionID:    function.ID,
+				AccessLevels:  pq.StringArray{string(constants.Write), string(constants.Read)},
+			}
+			if err := db.Create(&link).Error; err != nil {
+				log.Printf("failed to grant function %s to role %d: %v\n", function.Action, role.ID, err)
+				continue
+			}
+			log.Printf("Granted function %s to owner role %d\n", function.Action, role.ID)
+		}
+	}
+}
+
+// backfillPaymentBusinesses assigns payments created before they carried a business to the business of their order or reservation
+func backfillPaymentBusinesses(db *gorm.DB) {
+	statements := []string{
+		`UPDATE payments SET business_id = orders.business_id
+			FROM order_payment_links, orders
+			WHERE payments.business_id IS NULL AND order_payment_links.payment_id = payments.id
+				AND order_payment_links.deleted_at IS NULL AND orders.id = order_payment_links.order_id`,
+		`UPDATE payments SET business_id = services.business_id
+			FROM reservation_payment_links, reservations, services
+			WHERE payments.business_id IS NULL AND reservation_payment_links.payment_id = payments.id
+				AND reservation_payment_links.deleted_at IS NULL AND reservations.id = reservation_payment_links.reservation_id
+				AND services.id = reservations.service_id`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Printf("failed to backfill payment businesses: %v\n", err)
+		}
+	}
+}
+
+// backfillGiftCardLedgers opens the ledger of gift cards issued before it existed with their current balance
+func backfillGiftCardLedgers(db *gorm.DB) {
+	statement := `INSERT INTO gift_card_transactions (created_at, updated_at, gift_card_id, type, amount, balance_after)
+		SELECT NOW(), NOW(), gift_cards.id, ?, gift_cards.balance, gift_cards.balance
+		FROM gift_cards
+		WHERE NOT EXISTS (SELECT 1 FROM gift_card_transactions WHERE gift_card_transactions.gift_card_id = gift_cards.id)`
+	if err := db.Exec(statement, constants.GiftCardIssue).Error; err != nil {
+		log.Printf("failed to backfill gift card ledgers: %v\n", err)
+	}
+}
+
+// backfillPriceModifierCurrencies gives absolute price modifiers created before currencies the currency of their business
+func backfillPriceModifierCurrencies(db *gorm.DB) {
+	statement := `UPDATE price_modifiers SET currency = businesses.currency
+		FROM businesses
+		WHERE price_modifiers.business_id = businesses.id AND price_modifiers.is_percentage = false
+			AND price_modifiers.currency = ''`
+	if err := db.Exec(statement).Error; err != nil {
+		log.Printf("failed to backfill price modifier currencies: %v\n", err)
+	}
+}
+
+// defaultAdminPassword is the seeded admin's initial password, which has to be changed on first login
+const defaultAdminPassword = "SuperSecretAdmin123"
+
 func seedSuperAdmin(db *gorm.DB) {
 	var admin entities.Account
 	if err := db.Where("username = ?", "admin").First(&admin).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			passwordHash, _ := bcrypt.GenerateFromPassword([]byte("SuperSecretAdmin123"), bcrypt.DefaultCost)
+			passwordHash, _ := bcrypt.GenerateFromPassword([]byte(defaultAdminPassword), bcrypt.DefaultCost)
 			admin = entities.Account{
-				Name:         "Super Admin",
-				Username:     "admin",
-				PasswordHash: string(passwordHash),
+				Name:               "Super Admin",
+				Username:           "admin",
+				PasswordHash:       string(passwordHash),
+				MustChangePassword: true,
 			}
 			if err := db.Create(&admin).Error; err != nil {
 				log.Printf("failed to create superadmin: %v\n", err)
@@ -147,4 +266,14 @@ func seedSuperAdmin(db *gorm.DB) {
 			return
 		}
 	}
+
+	// Admins seeded before password rotation was enforced may still use the default password
+	if !admin.MustChangePassword && admin.PasswordChangedAt == nil &&
+		bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(defaultAdminPassword)) == nil {
+		if err := db.Model(&admin).Update("must_change_password", true).Error; err != nil {
+			log.Printf("failed to require superadmin password change: %v\n", err)
+			return
+		}
+		log.Println("Superadmin still uses the default password, it has to be changed on next login")
+	}
 }
@@ -13,4 +13,17 @@ const (
 	ItemOptions    Action = "itemOptions"
 	Orders         Action = "orders"
 	Tags           Action = "tags"
+	Payments       Action = "payments"
+	GiftCards      Action = "giftCards"
+
+	// Fine-grained permissions, granted with the Write access level
+	ApplyDiscount   Action = "applyDiscount"
+	VoidItem        Action = "voidItem"
+	Refund          Action = "refund"
+	OverridePrice   Action = "overridePrice"
+	ViewReports     Action = "viewReports"
+	ManageInventory Action = "manageInventory"
 )
+
+// PermissionActions are the fine-grained permissions, which a manager can approve for a single use
+var PermissionActions = []Action{ApplyDiscount, VoidItem, Refund, OverridePrice, ViewReports, ManageInventory}
@@ -0,0 +1,12 @@
+package constants
+
+type GiftCardTransactionType string
+
+const (
+	GiftCardIssue  GiftCardTransactionType = "Issue"
+	GiftCardLoad   GiftCardTransactionType = "Load"
+	GiftCardRedeem GiftCardTransactionType = "Redeem"
+	GiftCardRefund GiftCardTransactionType = "Refund"
+	GiftCardVoid   GiftCardTransactionType = "Void"
+	GiftCardExpire GiftCardTransactionType = "Expire"
+)
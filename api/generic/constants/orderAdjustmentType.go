@@ -0,0 +1,10 @@
+package constants
+
+type OrderAdjustmentType string
+
+const (
+	// AdjustmentCashRounding is the difference a cash payment was rounded by
+	AdjustmentCashRounding OrderAdjustmentType = "CashRounding"
+	// AdjustmentCardSurcharge is a card processing fee added to a payment
+	AdjustmentCardSurcharge OrderAdjustmentType = "CardSurcharge"
+)
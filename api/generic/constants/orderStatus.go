@@ -8,4 +8,6 @@ const (
 	OrderCompleted OrderStatus = "Completed"
 	OrderRefunded  OrderStatus = "Refunded"
 	OrderCancelled OrderStatus = "Cancelled"
+	// OrderDisputed is set when a card payment of the order is disputed by the cardholder
+	OrderDisputed OrderStatus = "Disputed"
 )
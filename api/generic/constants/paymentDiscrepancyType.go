@@ -0,0 +1,14 @@
+package constants
+
+type PaymentDiscrepancyType string
+
+const (
+	// DiscrepancyAmountMismatch is a payment recorded for a different amount than its intent at the gateway
+	DiscrepancyAmountMismatch PaymentDiscrepancyType = "AmountMismatch"
+	// DiscrepancyCurrencyMismatch is a payment recorded in a different currency than its intent at the gateway
+	DiscrepancyCurrencyMismatch PaymentDiscrepancyType = "CurrencyMismatch"
+	// DiscrepancyMissingIntent is a payment whose intent the gateway does not know
+	DiscrepancyMissingIntent PaymentDiscrepancyType = "MissingIntent"
+	// DiscrepancyOrphanedIntent is an intent that took or holds money at the gateway without a payment recorded
+	DiscrepancyOrphanedIntent PaymentDiscrepancyType = "OrphanedIntent"
+)
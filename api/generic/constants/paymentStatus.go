@@ -7,4 +7,41 @@ const (
 	Completed PaymentStatus = "Completed"
 	Failed    PaymentStatus = "Failed"
 	Refunded  PaymentStatus = "Refunded"
+	Disputed  PaymentStatus = "Disputed"
+	// Authorized card payments hold the amount on the card until they are captured or voided
+	Authorized PaymentStatus = "Authorized"
 )
+
+// paymentTransitions lists the statuses a payment may move to from each status, so late or replayed
+// gateway events cannot move a payment backwards. A failed payment can still complete when a later attempt
+// succeeds. Refunded is final.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	Pending:    {Authorized, Completed, Failed},
+	Authorized: {Completed, Failed},
+	Failed:     {Completed},
+	Completed:  {Refunded, Disputed},
+	Disputed:   {Completed, Refunded},
+}
+
+// CanTransitionTo reports whether a payment in this status may move to next
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, allowed := range paymentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviousStatuses lists the statuses a payment may move to s from
+func (s PaymentStatus) PreviousStatuses() []PaymentStatus {
+	var previous []PaymentStatus
+	for from, allowed := range paymentTransitions {
+		for _, next := range allowed {
+			if next == s {
+				previous = append(previous, from)
+			}
+		}
+	}
+	return previous
+}
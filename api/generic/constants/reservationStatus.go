@@ -3,9 +3,45 @@ package constants
 type ReservationStatus string
 
 const (
+	ReservationPending    ReservationStatus = "Pending"
 	ReservationConfirmed  ReservationStatus = "Confirmed"
+	ReservationCheckedIn  ReservationStatus = "CheckedIn"
+	ReservationInProgress ReservationStatus = "InProgress"
 	ReservationCompleted  ReservationStatus = "Completed"
 	ReservationCancelled  ReservationStatus = "Cancelled"
 	ReservationNoShow     ReservationStatus = "NoShow"
 )
 
+// reservationTransitions lists the statuses a reservation may move to from each status.
+// Completed, Cancelled and NoShow are final.
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	ReservationPending:    {ReservationConfirmed, ReservationCancelled},
+	ReservationConfirmed:  {ReservationCheckedIn, ReservationCompleted, ReservationCancelled, ReservationNoShow},
+	ReservationCheckedIn:  {ReservationInProgress, ReservationCompleted, ReservationCancelled},
+	ReservationInProgress: {ReservationCompleted},
+}
+
+// IsValid reports whether the status is one of the known reservation statuses
+func (s ReservationStatus) IsValid() bool {
+	switch s {
+	case ReservationPending, ReservationConfirmed, ReservationCheckedIn, ReservationInProgress,
+		ReservationCompleted, ReservationCancelled, ReservationNoShow:
+		return true
+	}
+	return false
+}
+
+// IsFinal reports whether no further transitions are possible from the status
+func (s ReservationStatus) IsFinal() bool {
+	return len(reservationTransitions[s]) == 0
+}
+
+// CanTransitionTo reports whether a reservation in this status may move to next
+func (s ReservationStatus) CanTransitionTo(next ReservationStatus) bool {
+	for _, allowed := range reservationTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
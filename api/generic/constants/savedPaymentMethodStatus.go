@@ -0,0 +1,14 @@
+package constants
+
+type SavedPaymentMethodStatus string
+
+const (
+	// SavedPaymentMethodPending cards are waiting for the customer to complete the setup intent
+	SavedPaymentMethodPending SavedPaymentMethodStatus = "Pending"
+	// SavedPaymentMethodActive cards can be charged off-session
+	SavedPaymentMethodActive SavedPaymentMethodStatus = "Active"
+	// SavedPaymentMethodFailed cards could not be saved
+	SavedPaymentMethodFailed SavedPaymentMethodStatus = "Failed"
+	// SavedPaymentMethodDetached cards were removed and cannot be charged again
+	SavedPaymentMethodDetached SavedPaymentMethodStatus = "Detached"
+)
@@ -0,0 +1,11 @@
+package constants
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "Waiting"
+	WaitlistOffered   WaitlistStatus = "Offered"
+	WaitlistAccepted  WaitlistStatus = "Accepted"
+	WaitlistExpired   WaitlistStatus = "Expired"
+	WaitlistCancelled WaitlistStatus = "Cancelled"
+)
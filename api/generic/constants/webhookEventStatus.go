@@ -0,0 +1,9 @@
+package constants
+
+type WebhookEventStatus string
+
+const (
+	WebhookEventPending   WebhookEventStatus = "Pending"
+	WebhookEventProcessed WebhookEventStatus = "Processed"
+	WebhookEventFailed    WebhookEventStatus = "Failed"
+)
@@ -0,0 +1,21 @@
+// Package env reads settings from the environment
+package env
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// PositiveInt reads a positive integer setting, falling back when it is unset or invalid. Invalid values are logged.
+func PositiveInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+		return parsed
+	}
+	log.Printf("Warning: invalid %s %q, using %d", name, value, fallback)
+	return fallback
+}
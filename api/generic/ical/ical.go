@@ -0,0 +1,114 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const prodID = "-//VersatilePOS//Reservations//EN"
+
+// Event status values as defined in RFC 5545 section 3.8.1.11
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is a VEVENT component
+type Event struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Calendar is a VCALENDAR object
+type Calendar struct {
+	Name   string
+	Method string
+	Events []Event
+}
+
+// Render serializes the calendar as an RFC 5545 iCalendar stream
+func (c Calendar) Render() string {
+	var b strings.Builder
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+prodID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		writeLine(&b, "METHOD:"+c.Method)
+	}
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	now := time.Now()
+	for _, event := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+formatTime(now))
+		writeLine(&b, "DTSTART:"+formatTime(event.Start))
+		writeLine(&b, "DTEND:"+formatTime(event.End))
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Status != "" {
+			writeLine(&b, "STATUS:"+event.Status)
+		}
+		if !event.Created.IsZero() {
+			writeLine(&b, "CREATED:"+formatTime(event.Created))
+		}
+		if !event.LastModified.IsZero() {
+			writeLine(&b, "LAST-MODIFIED:"+formatTime(event.LastModified))
+			// Clients use SEQUENCE to pick the latest revision of an event
+			writeLine(&b, fmt.Sprintf("SEQUENCE:%d", event.LastModified.Unix()-event.Created.Unix()))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes TEXT values as per RFC 5545 section 3.3.11
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// writeLine writes a content line terminated by CRLF, folding it at 75 octets as per RFC 5545 section 3.1
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Do not split a multi-byte UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,10 @@
+package models
+
+// ApprovalRequired is returned when the user lacks a permission that a manager can approve. The action is retried
+// with the token of a manager approval in the X-Approval-Token header.
+type ApprovalRequired struct {
+	Error      string   `json:"error" example:"approval required"`
+	Action     string   `json:"action" example:"refund"`
+	Amount     *float64 `json:"amount,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"log"
+	"sync"
+)
+
+// Event is a message addressed to a customer or account, delivered by the configured Notifier. Its message and
+// data may hold secrets, e.g. a password reset token, so notifiers must not log them.
+type Event struct {
+	Type    string            `json:"type"`
+	Email   string            `json:"email,omitempty"`
+	Phone   string            `json:"phone,omitempty"`
+	Subject string            `json:"subject"`
+	Message string            `json:"message"`
+	Data    map[string]string `json:"data,omitempty"`
+}
+
+// Notifier delivers events, e.g. by email or SMS
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// LogNotifier records in the server log that an event would have been sent. It is the default until a real
+// notifier is configured. Only the type and recipient are logged, as messages and data carry secrets such as
+// reset links and confirmation codes.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(event Event) error {
+	log.Printf("Notification %s to email=%q phone=%q not delivered, no notifier is configured", event.Type, event.Email, event.Phone)
+	return nil
+}
+
+var (
+	mu       sync.RWMutex
+	notifier Notifier = LogNotifier{}
+)
+
+// SetNotifier replaces the notifier used by Notify
+func SetNotifier(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifier = n
+}
+
+// Notify delivers the event through the configured notifier
+func Notify(event Event) error {
+	mu.RLock()
+	n := notifier
+	mu.RUnlock()
+	return n.Notify(event)
+}
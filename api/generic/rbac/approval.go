@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ApprovalRequiredError reports that the user lacks a permission a manager can approve for this single action
+type ApprovalRequiredError struct {
+	Action     constants.Action
+	Amount     *float64
+	Percentage *float64
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return "approval required"
+}
+
+// HashApprovalToken returns the stored form of a manager approval token
+func HashApprovalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Approval is a validated manager approval for one action. It is used up by Use in the transaction of the write it
+// approves, so a failed write leaves it unused.
+type Approval struct {
+	id         uint
+	amount     *float64
+	percentage *float64
+}
+
+// AuthorizeWithApproval checks a fine-grained permission like HasScopedAccess. When the user lacks it, a manager
+// approval token for the same business, action and order is validated instead, provided the scope's amount and
+// percentage are within what was approved and the approving manager's own permission covers the scope, and returned
+// to be used with the write. The returned approval is nil when the user
+// has the permission. Without a token an ApprovalRequiredError is returned.
+func AuthorizeWithApproval(action constants.Action, businessID uint, userID uint, scope Scope, orderID *uint, approvalToken string) (*Approval, error) {
+	ok, err := HasScopedAccess(action, constants.Write, businessID, userID, scope)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if ok {
+		return nil, nil
+	}
+
+	if approvalToken == "" {
+		return nil, &ApprovalRequiredError{Action: action, Amount: scope.Amount, Percentage: scope.Percentage}
+	}
+
+	var approval entities.ManagerApproval
+	if err := database.DB.Where("token_hash = ? AND used_at IS NULL", HashApprovalToken(approvalToken)).First(&approval).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid approval")
+		}
+		return nil, err
+	}
+
+	if approval.BusinessID != businessID || approval.Action != action || approval.RequestedByID != userID ||
+		!sameOrder(approval.OrderID, orderID) || approval.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("invalid approval")
+	}
+
+	if !withinApproved(approval.ApprovedAmount, scope.Amount) || !withinApproved(approval.ApprovedPercentage, scope.Percentage) {
+		return nil, errors.New("approval does not cover this action")
+	}
+
+	// The manager's limits still apply to what they approved
+	ok, err = HasScopedAccess(action, constants.Write, businessID, approval.ApprovedByID, scope)
+	if err != nil {
+		return nil, errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return nil, errors.New("approval does not cover this action")
+	}
+
+	return &Approval{id: approval.ID, amount: scope.Amount, percentage: scope.Percentage}, nil
+}
+
+// Use marks the approval used and records it on the order, in the transaction of the write it approves. It fails
+// when the approval was used by another request in the meantime. A nil approval, for a user who had the permission,
+// is a no-op.
+func (a *Approval) Use(tx *gorm.DB, orderID uint) error {
+	if a == nil {
+		return nil
+	}
+
+	result := tx.Model(&entities.ManagerApproval{}).
+		Where("id = ? AND used_at IS NULL", a.id).
+		Updates(map[string]interface{}{
+			"used_at":    time.Now(),
+			"amount":     a.amount,
+			"percentage": a.percentage,
+			"order_id":   orderID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid approval")
+	}
+	return nil
+}
+
+// withinApproved reports whether a value is covered by an approval, which covers no value unless one was approved
+func withinApproved(approved *float64, value *float64) bool {
+	return value == nil || (approved != nil && *value <= *approved)
+}
+
+func sameOrder(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
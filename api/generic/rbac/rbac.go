@@ -4,35 +4,238 @@ import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"errors"
 	"fmt"
 	"slices"
+
+	"gorm.io/gorm"
 )
 
+// Scope narrows an access check to a location, to the size of the action and to the business of a terminal token.
+// Fields left nil are not checked.
+type Scope struct {
+	LocationID *uint
+	Amount     *float64
+	Percentage *float64
+	// TokenBusinessID is the business the request's terminal token is limited to, access to others is refused
+	TokenBusinessID *uint
+}
+
+// Permission is the combined grant of one action across the user's roles
+type Permission struct {
+	Action        constants.Action
+	AccessLevels  []constants.AccessLevel
+	MaxAmount     *float64
+	MaxPercentage *float64
+}
+
 func HasAccess(action constants.Action, level constants.AccessLevel, businessID uint, userID uint) (bool, error) {
-	var roleLinks []entities.AccountRoleLink
-	if err := database.DB.Preload("AccountRole").Where("account_id = ? AND status = ?", userID, constants.Active).Find(&roleLinks).Error; err != nil {
-		return false, fmt.Errorf("failed to load role links: %v", err)
+	return HasScopedAccess(action, level, businessID, userID, Scope{})
+}
+
+// HasScopedAccess checks access like HasAccess, additionally requiring a role that applies at the scope's location
+// and whose monetary limits cover the scope's amount and percentage
+func HasScopedAccess(action constants.Action, level constants.AccessLevel, businessID uint, userID uint, scope Scope) (bool, error) {
+	if scope.TokenBusinessID != nil && *scope.TokenBusinessID != businessID {
+		return false, nil
 	}
 
-	for _, rl := range roleLinks {
-		var funcLinks []entities.AccountRoleFunctionLink
-		if err := database.DB.Preload("Function").Where("account_role_id = ?", rl.AccountRoleID).Find(&funcLinks).Error; err != nil {
-			return false, fmt.Errorf("failed to load role-function links: %v", err)
+	funcLinks, err := grantedFunctionLinks(businessID, userID, scope.LocationID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, fl := range funcLinks {
+		if fl.Function.Action != action || !slices.Contains(fl.AccessLevels, string(level)) {
+			continue
+		}
+		if withinLimit(fl.MaxAmount, scope.Amount) && withinLimit(fl.MaxPercentage, scope.Percentage) {
+			return true, nil
 		}
+	}
 
+	return false, nil
+}
+
+// EffectivePermissions lists what the user may do in a business, optionally at a location. When several roles grant
+// the same action the most generous limits apply.
+func EffectivePermissions(businessID uint, userID uint, locationID *uint) ([]Permission, error) {
+	funcLinks, err := grantedFunctionLinks(businessID, userID, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []Permission{}
+	index := make(map[constants.Action]int)
+	for _, fl := range funcLinks {
+		i, ok := index[fl.Function.Action]
+		if !ok {
+			index[fl.Function.Action] = len(permissions)
+			permissions = append(permissions, Permission{
+				Action:        fl.Function.Action,
+				MaxAmount:     fl.MaxAmount,
+				MaxPercentage: fl.MaxPercentage,
+			})
+			i = len(permissions) - 1
+		} else {
+			permissions[i].MaxAmount = widerLimit(permissions[i].MaxAmount, fl.MaxAmount)
+			permissions[i].MaxPercentage = widerLimit(permissions[i].MaxPercentage, fl.MaxPercentage)
+		}
+
+		for _, level := range fl.AccessLevels {
+			if !slices.Contains(permissions[i].AccessLevels, constants.AccessLevel(level)) {
+				permissions[i].AccessLevels = append(permissions[i].AccessLevels, constants.AccessLevel(level))
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+// grantedFunctionLinks returns the function links of the user's roles that are currently in effect in the business.
+// Roles limited to locations are left out unless the location is given and is one of theirs.
+func grantedFunctionLinks(businessID uint, userID uint, locationID *uint) ([]entities.AccountRoleFunctionLink, error) {
+	// Roles do not apply until a required password change is done
+	var account entities.Account
+	if err := database.DB.Select("id", "must_change_password").First(&account, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load account: %v", err)
+	}
+	if account.MustChangePassword {
+		return nil, nil
+	}
+
+	var roleLinks []entities.AccountRoleLink
+	if err := database.DB.Preload("AccountRole.Locations").Where("account_id = ? AND status = ?", userID, constants.Active).Find(&roleLinks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load role links: %v", err)
+	}
+
+	var granted []entities.AccountRoleFunctionLink
+	for _, rl := range roleLinks {
 		if rl.AccountRole.BusinessID != businessID {
 			continue
 		}
+		if !appliesAtLocation(rl.AccountRole, locationID) {
+			continue
+		}
+
+		var funcLinks []entities.AccountRoleFunctionLink
+		if err := database.DB.Preload("Function").Where("account_role_id = ?", rl.AccountRoleID).Find(&funcLinks).Error; err != nil {
+			return nil, fmt.Errorf("failed to load role-function links: %v", err)
+		}
 
-		for _, fl := range funcLinks {
-			if fl.Function.Action != action {
-				continue
+		if grantsAccountControl(funcLinks) {
+			missing, err := missingRequiredTwoFactor(businessID, userID)
+			if err != nil {
+				return nil, err
 			}
-			if slices.Contains(fl.AccessLevels, string(level)) {
-				return true, nil
+			if missing {
+				// Without the required 2FA only the account control is withheld, the rest of the role applies
+				funcLinks = slices.DeleteFunc(funcLinks, isAccountControl)
 			}
 		}
+
+		granted = append(granted, funcLinks...)
+	}
+
+	return granted, nil
+}
+
+// appliesAtLocation reports whether a role is in effect at the location. Roles without locations apply everywhere,
+// roles limited to locations only to actions at one of them.
+func appliesAtLocation(role entities.AccountRole, locationID *uint) bool {
+	if len(role.Locations) == 0 {
+		return true
+	}
+	if locationID == nil {
+		return false
+	}
+	for _, location := range role.Locations {
+		if location.ID == *locationID {
+			return true
+		}
+	}
+	return false
+}
+
+// withinLimit reports whether a value is allowed by a limit, where a nil limit or value means no restriction
+func withinLimit(limit *float64, value *float64) bool {
+	return limit == nil || value == nil || *value <= *limit
+}
+
+// widerLimit returns the more generous of two limits, nil being unlimited
+func widerLimit(a *float64, b *float64) *float64 {
+	if a == nil || b == nil {
+		return nil
+	}
+	if *b > *a {
+		return b
+	}
+	return a
+}
+
+// isAccountControl reports whether a function link grants write access to accounts or roles, for which a business
+// can require 2FA
+func isAccountControl(fl entities.AccountRoleFunctionLink) bool {
+	return (fl.Function.Action == constants.Accounts || fl.Function.Action == constants.Roles) && slices.Contains(fl.AccessLevels, string(constants.Write))
+}
+
+// grantsAccountControl reports whether a role can manage accounts or roles
+func grantsAccountControl(funcLinks []entities.AccountRoleFunctionLink) bool {
+	return slices.ContainsFunc(funcLinks, isAccountControl)
+}
+
+// missingRequiredTwoFactor reports whether the business requires 2FA for account control and the user has not enabled it
+func missingRequiredTwoFactor(businessID uint, userID uint) (bool, error) {
+	var business entities.Business
+	if err := database.DB.Select("id", "require_two_factor").First(&business, businessID).Error; err != nil {
+		return false, fmt.Errorf("failed to load business: %v", err)
+	}
+	if !business.RequireTwoFactor {
+		return false, nil
 	}
 
+	var account entities.Account
+	if err := database.DB.Select("id", "two_factor_enabled").First(&account, userID).Error; err != nil {
+		return false, fmt.Errorf("failed to load account: %v", err)
+	}
+	return !account.TwoFactorEnabled, nil
+}
+
+// TwoFactorSetupRequired reports whether any business the user works for withholds their write access to accounts or
+// roles until 2FA is enabled
+func TwoFactorSetupRequired(userID uint) (bool, error) {
+	var account entities.Account
+	if err := database.DB.Select("id", "two_factor_enabled").First(&account, userID).Error; err != nil {
+		return false, fmt.Errorf("failed to load account: %v", err)
+	}
+	if account.TwoFactorEnabled {
+		return false, nil
+	}
+
+	var roleLinks []entities.AccountRoleLink
+	if err := database.DB.Preload("AccountRole").Where("account_id = ? AND status = ?", userID, constants.Active).Find(&roleLinks).Error; err != nil {
+		return false, fmt.Errorf("failed to load role links: %v", err)
+	}
+
+	for _, rl := range roleLinks {
+		var business entities.Business
+		if err := database.DB.Select("id", "require_two_factor").First(&business, rl.AccountRole.BusinessID).Error; err != nil {
+			return false, fmt.Errorf("failed to load business: %v", err)
+		}
+		if !business.RequireTwoFactor {
+			continue
+		}
+
+		var funcLinks []entities.AccountRoleFunctionLink
+		if err := database.DB.Preload("Function").Where("account_role_id = ?", rl.AccountRoleID).Find(&funcLinks).Error; err != nil {
+			return false, fmt.Errorf("failed to load role-function links: %v", err)
+		}
+		if grantsAccountControl(funcLinks) {
+			return true, nil
+		}
+	}
 	return false, nil
 }
@@ -0,0 +1,54 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 Appendix B test vectors, "12345678901234567890" base32 encoded
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// TestCodeRFC6238 checks Code against the SHA1 test vectors of RFC 6238 Appendix B. The RFC lists 8 digit codes;
+// a 6 digit code is the last 6 of them.
+func TestCodeRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, v := range vectors {
+		step := Step(time.Unix(v.unix, 0))
+		got, err := Code(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("Code(%d): %v", v.unix, err)
+		}
+		want := v.code[len(v.code)-Digits:]
+		if got != want {
+			t.Errorf("Code at T=%d = %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+// TestValidateRFC6238 checks that the code of a test vector is accepted within the allowed skew and not beyond it
+func TestValidateRFC6238(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	code := "14050471"[8-Digits:]
+
+	if step, ok := Validate(rfc6238Secret, code, at); !ok || step != Step(at) {
+		t.Errorf("Validate at the vector's time = (%d, %v), want (%d, true)", step, ok, Step(at))
+	}
+	if _, ok := Validate(rfc6238Secret, code, at.Add(Period)); !ok {
+		t.Errorf("Validate one step later was rejected")
+	}
+	if _, ok := Validate(rfc6238Secret, code, at.Add(time.Duration(Skew+1)*Period)); ok {
+		t.Errorf("Validate beyond the skew was accepted")
+	}
+}
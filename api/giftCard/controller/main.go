@@ -1,53 +1,88 @@
 package controller
 
 import (
+	"VersatilePOS/generic/env"
+	"encoding/csv"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"VersatilePOS/generic/models"
 	giftCardModels "VersatilePOS/giftCard/models"
 	"VersatilePOS/giftCard/service"
+	"VersatilePOS/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Controller struct {
-	service *service.Service
+	service            *service.Service
+	publicBalanceCheck bool
 }
 
 func NewController() *Controller {
 	return &Controller{
-		service: service.NewService(),
+		service:            service.NewService(),
+		publicBalanceCheck: publicBalanceCheckEnabled(),
 	}
 }
 
+// publicBalanceCheckEnabled reports whether balance checks are allowed without authentication, controlled by GIFTCARD_PUBLIC_BALANCE_CHECK
+func publicBalanceCheckEnabled() bool {
+	if value := os.Getenv("GIFTCARD_PUBLIC_BALANCE_CHECK"); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return true
+}
+
 // @Summary Create a gift card
-// @Description Create a new gift card with a unique code and initial value
+// @Description Create a new gift card with a unique code and initial value. The code is generated when left empty. An optional PIN is then required for redemption and balance checks. An expiry date has to be allowed and at least GIFTCARD_MIN_EXPIRY_MONTHS away. Requires authentication and Gift Cards Write permission.
 // @Tags giftcard
 // @Accept  json
 // @Produce  json
 // @Param   giftcard  body  models.CreateGiftCardRequest  true  "Gift card to create"
 // @Success 201 {object} models.GiftCardDto
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /giftcard [post]
 // @Id createGiftCard
 func (ctrl *Controller) CreateGiftCard(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	var req giftCardModels.CreateGiftCardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		return
 	}
 
-	giftCard, err := ctrl.service.CreateGiftCard(req)
+	giftCard, err := ctrl.service.CreateGiftCard(req, userID)
 	if err != nil {
 		log.Println("Failed to create gift card:", err)
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		if err.Error() == "gift card with this code already exists" {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if isValidationError(err) {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
 	}
@@ -56,15 +91,26 @@ func (ctrl *Controller) CreateGiftCard(c *gin.Context) {
 }
 
 // @Summary Get all gift cards
-// @Description Get all gift cards in the system
+// @Description Get all gift cards of a business, or those sold with an order. Requires authentication and Gift Cards Read permission.
 // @Tags giftcard
 // @Produce  json
 // @Param   businessId  query  int  true  "Business ID"
+// @Param   orderId     query  int  false "Order ID the gift cards were sold with"
 // @Success 200 {array} models.GiftCardDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /giftcard [get]
 // @Id getGiftCards
 func (ctrl *Controller) GetGiftCards(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	businessIDStr := c.Query("businessId")
 	if businessIDStr == "" {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId is required"})
@@ -76,8 +122,22 @@ func (ctrl *Controller) GetGiftCards(c *gin.Context) {
 		return
 	}
 
-	giftCards, err := ctrl.service.GetGiftCards(businessID)
+	var orderID *uint
+	if orderIDStr := c.Query("orderId"); orderIDStr != "" {
+		var id uint
+		if _, err := fmt.Sscanf(orderIDStr, "%d", &id); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid orderId"})
+			return
+		}
+		orderID = &id
+	}
+
+	giftCards, err := ctrl.service.GetGiftCards(businessID, orderID, userID)
 	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to get gift cards:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
@@ -87,17 +147,26 @@ func (ctrl *Controller) GetGiftCards(c *gin.Context) {
 }
 
 // @Summary Get gift card by ID
-// @Description Get a specific gift card by its ID
+// @Description Get a specific gift card by its ID. Requires authentication and Gift Cards Read permission.
 // @Tags giftcard
 // @Produce  json
 // @Param   id  path  int  true  "Gift Card ID"
 // @Success 200 {object} models.GiftCardDto
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /giftcard/{id} [get]
 // @Id getGiftCardById
 func (ctrl *Controller) GetGiftCardByID(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	id := c.Param("id")
 	if id == "" {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "gift card ID is required"})
@@ -110,8 +179,12 @@ func (ctrl *Controller) GetGiftCardByID(c *gin.Context) {
 		return
 	}
 
-	giftCard, err := ctrl.service.GetGiftCardByID(giftCardID)
+	giftCard, err := ctrl.service.GetGiftCardByID(giftCardID, userID)
 	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		if err.Error() == "gift card not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -125,14 +198,18 @@ func (ctrl *Controller) GetGiftCardByID(c *gin.Context) {
 }
 
 // @Summary Check gift card balance
-// @Description Check the balance of a gift card by its code
+// @Description Check the balance of a gift card by its code, and its PIN if it has one. The PIN is locked after repeated failures. Requests are rate limited per client and per code. Public unless GIFTCARD_PUBLIC_BALANCE_CHECK is disabled, in which case authentication and Gift Cards Read permission are required.
 // @Tags giftcard
 // @Accept  json
 // @Produce  json
 // @Param   request  body  models.CheckBalanceRequest  true  "Gift card code"
 // @Success 200 {object} models.GiftCardDto
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
+// @Failure 423 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
 // @Router /giftcard/check-balance [post]
 // @Id checkGiftCardBalance
@@ -143,12 +220,31 @@ func (ctrl *Controller) CheckBalance(c *gin.Context) {
 		return
 	}
 
-	giftCard, err := ctrl.service.GetGiftCardByCode(req.Code)
+	var giftCard *giftCardModels.GiftCardDto
+	var err error
+	if ctrl.publicBalanceCheck {
+		giftCard, err = ctrl.service.CheckPublicBalance(req.Code, req.Pin)
+	} else {
+		userID, authErr := middleware.GetUserIDFromContext(c)
+		if authErr != nil {
+			c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: authErr.Error()})
+			return
+		}
+		giftCard, err = ctrl.service.CheckBalance(req.Code, req.Pin, userID)
+	}
 	if err != nil {
 		if err.Error() == "gift card not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if err.Error() == "gift card PIN required" || err.Error() == "invalid gift card PIN" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "gift card PIN is locked" {
+			c.IndentedJSON(http.StatusLocked, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to check gift card balance:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
@@ -158,16 +254,25 @@ func (ctrl *Controller) CheckBalance(c *gin.Context) {
 }
 
 // @Summary Deactivate gift card
-// @Description Deactivate a gift card to prevent further use
+// @Description Deactivate a gift card to prevent further use. Its remaining balance is voided in the card's ledger. Requires authentication and Gift Cards Write permission.
 // @Tags giftcard
 // @Param   id  path  int  true  "Gift Card ID"
 // @Success 200
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /giftcard/{id}/deactivate [post]
 // @Id deactivateGiftCard
 func (ctrl *Controller) DeactivateGiftCard(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	id := c.Param("id")
 	if id == "" {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "gift card ID is required"})
@@ -180,8 +285,12 @@ func (ctrl *Controller) DeactivateGiftCard(c *gin.Context) {
 		return
 	}
 
-	err := ctrl.service.DeactivateGiftCard(giftCardID)
+	err = ctrl.service.DeactivateGiftCard(giftCardID, userID)
 	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		if err.Error() == "gift card not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -194,11 +303,226 @@ func (ctrl *Controller) DeactivateGiftCard(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// @Summary Reload gift card
+// @Description Load an amount onto an active, unexpired gift card. The initial value stays as issued and a set expiry date is pushed out to at least GIFTCARD_MIN_EXPIRY_MONTHS from now. Requires authentication and Gift Cards Write permission.
+// @Tags giftcard
+// @Accept  json
+// @Produce  json
+// @Param   id      path  int                           true  "Gift Card ID"
+// @Param   reload  body  models.ReloadGiftCardRequest  true  "Amount to load"
+// @Success 200 {object} models.GiftCardDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /giftcard/{id}/reload [post]
+// @Id reloadGiftCard
+func (ctrl *Controller) ReloadGiftCard(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var giftCardID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &giftCardID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid gift card ID"})
+		return
+	}
+
+	var req giftCardModels.ReloadGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	giftCard, err := ctrl.service.Reload(giftCardID, req.Amount, userID)
+	if err != nil {
+		switch err.Error() {
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "gift card not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "amount must be greater than 0":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		case "gift card is not active", "gift card has expired":
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to reload gift card:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, giftCard)
+}
+
+// @Summary Issue gift cards in bulk
+// @Description Issue a batch of up to 1000 gift cards of the same value with generated codes and, if requested, generated PINs, in which case the batch is limited to 100 gift cards. The PINs are only returned in this response, a replay of a retry with the same Idempotency-Key leaves them out. With format=csv the batch is returned as a CSV file for printing. Requires authentication and Gift Cards Write permission.
+// @Tags giftcard
+// @Accept  json
+// @Produce  json,text/csv
+// @Param   batch   body   models.BulkIssueGiftCardsRequest  true   "Gift cards to issue"
+// @Param   format  query  string                            false  "Response format, json or csv"
+// @Success 201 {array} models.IssuedGiftCardDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /giftcard/bulk [post]
+// @Id bulkIssueGiftCards
+func (ctrl *Controller) BulkIssueGiftCards(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var req giftCardModels.BulkIssueGiftCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	giftCards, err := ctrl.service.BulkIssue(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if isValidationError(err) {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
+		log.Println("Failed to issue gift cards:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		writeGiftCardsCSV(c, giftCards)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, giftCards)
+}
+
+// writeGiftCardsCSV responds with issued gift cards as a CSV file
+func writeGiftCardsCSV(c *gin.Context, giftCards []giftCardModels.IssuedGiftCardDto) {
+	c.Header("Content-Disposition", `attachment; filename="gift-cards.csv"`)
+	c.Status(http.StatusCreated)
+	c.Header("Content-Type", "text/csv")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"code", "pin", "value", "expiresAt"})
+	for _, giftCard := range giftCards {
+		expiresAt := ""
+		if giftCard.ExpiresAt != nil {
+			expiresAt = giftCard.ExpiresAt.Format(time.RFC3339)
+		}
+		w.Write([]string{giftCard.Code, giftCard.Pin, strconv.FormatFloat(giftCard.InitialValue, 'f', 2, 64), expiresAt})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Println("Failed to write gift card CSV:", err)
+	}
+}
+
+// isValidationError reports whether err rejects the values of a request to issue gift cards
+func isValidationError(err error) bool {
+	switch err.Error() {
+	case "initial value must be greater than 0", "gift card expiry is not allowed", "gift card expiry is too soon",
+		"too many gift cards with PINs":
+		return true
+	}
+	return false
+}
+
+// @Summary Get gift card transactions
+// @Description Get the ledger of a gift card, oldest first: its issue, loads, redemptions, refunds, void and expiry. Requires authentication and Gift Cards Read permission.
+// @Tags giftcard
+// @Produce  json
+// @Param   id  path  int  true  "Gift Card ID"
+// @Success 200 {array} models.GiftCardTransactionDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /giftcard/{id}/transactions [get]
+// @Id getGiftCardTransactions
+func (ctrl *Controller) GetTransactions(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var giftCardID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &giftCardID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid gift card ID"})
+		return
+	}
+
+	transactions, err := ctrl.service.GetTransactions(giftCardID, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "gift card not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+			return
+		}
+		log.Println("Failed to get gift card transactions:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, transactions)
+}
+
+const (
+	defaultBalanceCheckRateLimitPerIP   = 10
+	defaultBalanceCheckRateLimitPerCode = 5
+)
+
+// balanceCheckIPRateLimiter limits balance checks per client IP per minute, which slows down guessing codes
+func balanceCheckIPRateLimiter() *middleware.RateLimiter {
+	return middleware.NewScopedRateLimiter("giftcard-balance-ip", env.PositiveInt("GIFTCARD_BALANCE_RATE_LIMIT_PER_IP", defaultBalanceCheckRateLimitPerIP), time.Minute, false)
+}
+
+// balanceCheckCodeRateLimiter limits balance checks per gift card code per minute
+func balanceCheckCodeRateLimiter() *middleware.RateLimiter {
+	return middleware.NewScopedRateLimiter("giftcard-balance-code", env.PositiveInt("GIFTCARD_BALANCE_RATE_LIMIT_PER_CODE", defaultBalanceCheckRateLimitPerCode), time.Minute, true)
+}
+
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
+	balanceCheckHandlers := []gin.HandlerFunc{
+		middleware.RateLimitMiddleware(balanceCheckIPRateLimiter(), middleware.ClientIPKey),
+		middleware.RateLimitMiddleware(balanceCheckCodeRateLimiter(), middleware.JSONBodyKey("code")),
+		ctrl.CheckBalance,
+	}
+	if ctrl.publicBalanceCheck {
+		r.POST("/giftcard/check-balance", balanceCheckHandlers...)
+	}
+
 	giftCardGroup := r.Group("/giftcard")
-	giftCardGroup.POST("", ctrl.CreateGiftCard)
-	giftCardGroup.GET("", ctrl.GetGiftCards)
-	giftCardGroup.GET("/:id", ctrl.GetGiftCardByID)
-	giftCardGroup.POST("/check-balance", ctrl.CheckBalance)
-	giftCardGroup.POST("/:id/deactivate", ctrl.DeactivateGiftCard)
+	giftCardGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
+	{
+		giftCardGroup.POST("", ctrl.CreateGiftCard)
+		giftCardGroup.POST("/bulk", ctrl.BulkIssueGiftCards)
+		giftCardGroup.GET("", ctrl.GetGiftCards)
+		giftCardGroup.GET("/:id", ctrl.GetGiftCardByID)
+		if !ctrl.publicBalanceCheck {
+			giftCardGroup.POST("/check-balance", balanceCheckHandlers...)
+		}
+		giftCardGroup.POST("/:id/deactivate", ctrl.DeactivateGiftCard)
+		giftCardGroup.GET("/:id/transactions", ctrl.GetTransactions)
+		giftCardGroup.POST("/:id/reload", ctrl.ReloadGiftCard)
+	}
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type BulkIssueGiftCardsRequest struct {
+	BusinessID uint    `json:"businessId" binding:"required"`
+	Count      int     `json:"count" binding:"required,gt=0,lte=1000"`
+	Value      float64 `json:"value" binding:"required,gt=0"`
+	// CodePrefix is prepended to the generated codes
+	CodePrefix string     `json:"codePrefix,omitempty" binding:"omitempty,alphanum,max=10"`
+	WithPin    bool       `json:"withPin"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
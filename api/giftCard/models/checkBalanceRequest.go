@@ -2,4 +2,6 @@ package models
 
 type CheckBalanceRequest struct {
 	Code string `json:"code" validate:"required"`
+	// Pin is required for cards issued with a security code
+	Pin string `json:"pin,omitempty"`
 }
@@ -1,7 +1,13 @@
 package models
 
+import "time"
+
 type CreateGiftCardRequest struct {
-	Code         string  `json:"code" validate:"required"`
+	// Code is generated when left empty
+	Code         string  `json:"code"`
 	InitialValue float64 `json:"initialValue" validate:"required,gt=0"`
 	BusinessID   uint    `json:"businessId" validate:"required"`
+	// Pin is an optional security code required for redemption and balance checks
+	Pin       string     `json:"pin,omitempty" binding:"omitempty,numeric,min=4,max=8"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
@@ -1,21 +1,37 @@
 package models
 
-import "VersatilePOS/database/entities"
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/currency"
+	"time"
+)
 
 type GiftCardDto struct {
-	ID           uint    `json:"id"`
-	Code         string  `json:"code"`
-	InitialValue float64 `json:"initialValue"`
-	Balance      float64 `json:"balance"`
-	IsActive     bool    `json:"isActive"`
+	ID               uint       `json:"id"`
+	Code             string     `json:"code"`
+	InitialValue     float64    `json:"initialValue"`
+	Balance          float64    `json:"balance"`
+	Currency         string     `json:"currency"`
+	FormattedBalance string     `json:"formattedBalance"`
+	IsActive         bool       `json:"isActive"`
+	BusinessID       uint       `json:"businessId"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	HasPin           bool       `json:"hasPin"`
+	OrderItemID      *uint      `json:"orderItemId,omitempty"`
 }
 
 func NewGiftCardDtoFromEntity(gc entities.GiftCard) GiftCardDto {
 	return GiftCardDto{
-		ID:           gc.ID,
-		Code:         gc.Code,
-		InitialValue: gc.InitialValue,
-		Balance:      gc.Balance,
-		IsActive:     gc.IsActive,
+		ID:               gc.ID,
+		Code:             gc.Code,
+		InitialValue:     gc.InitialValue,
+		Balance:          gc.Balance,
+		Currency:         gc.Currency,
+		FormattedBalance: currency.Format(gc.Balance, gc.Currency),
+		IsActive:         gc.IsActive,
+		BusinessID:       gc.BusinessID,
+		ExpiresAt:        gc.ExpiresAt,
+		HasPin:           gc.PinHash != "",
+		OrderItemID:      gc.OrderItemID,
 	}
 }
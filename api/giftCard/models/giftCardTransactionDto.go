@@ -0,0 +1,28 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+type GiftCardTransactionDto struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balanceAfter"`
+	PaymentID    *uint     `json:"paymentId,omitempty"`
+	AccountID    *uint     `json:"accountId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func NewGiftCardTransactionDtoFromEntity(t entities.GiftCardTransaction) GiftCardTransactionDto {
+	return GiftCardTransactionDto{
+		ID:           t.ID,
+		Type:         string(t.Type),
+		Amount:       t.Amount,
+		BalanceAfter: t.BalanceAfter,
+		PaymentID:    t.PaymentID,
+		AccountID:    t.AccountID,
+		CreatedAt:    t.CreatedAt,
+	}
+}
@@ -0,0 +1,7 @@
+package models
+
+// IssuedGiftCardDto is a newly issued gift card. Its PIN is only ever returned here.
+type IssuedGiftCardDto struct {
+	GiftCardDto
+	Pin string `json:"pin,omitempty"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type ReloadGiftCardRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
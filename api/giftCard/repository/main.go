@@ -3,22 +3,93 @@ package repository
 import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct{}
 
-func (r *Repository) CreateGiftCard(giftCard *entities.GiftCard) (*entities.GiftCard, error) {
-	if result := database.DB.Create(giftCard); result.Error != nil {
-		return nil, result.Error
+// CreateGiftCard creates a gift card together with the ledger entry issuing its initial value
+func (r *Repository) CreateGiftCard(giftCard *entities.GiftCard, accountID *uint) (*entities.GiftCard, error) {
+	if err := r.CreateGiftCards([]*entities.GiftCard{giftCard}, accountID); err != nil {
+		return nil, err
 	}
 	return giftCard, nil
 }
 
-func (r *Repository) GetGiftCards(businessID uint) ([]entities.GiftCard, error) {
+// CreateGiftCards creates gift cards and their issue ledger entries, all or none
+func (r *Repository) CreateGiftCards(giftCards []*entities.GiftCard, accountID *uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return createGiftCards(tx, giftCards, accountID)
+	})
+}
+
+// IssueOrderGiftCards creates the gift cards sold on an order under a row lock on the order, so concurrent issuance
+// for the same order waits and sees the cards already issued. build is given the number of cards already issued per
+// order item and returns the cards to create. It returns how many were created.
+func (r *Repository) IssueOrderGiftCards(orderID uint, accountID *uint, build func(issued map[uint]int64) ([]*entities.GiftCard, error)) (int, error) {
+	created := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&entities.Order{}, orderID).Error; err != nil {
+			return err
+		}
+
+		var counts []struct {
+			OrderItemID uint
+			Count       int64
+		}
+		if err := tx.Model(&entities.GiftCard{}).
+			Select("gift_cards.order_item_id, COUNT(*) AS count").
+			Joins("JOIN order_items ON order_items.id = gift_cards.order_item_id").
+			Where("order_items.order_id = ?", orderID).
+			Group("gift_cards.order_item_id").
+			Scan(&counts).Error; err != nil {
+			return err
+		}
+		issued := make(map[uint]int64, len(counts))
+		for _, count := range counts {
+			issued[count.OrderItemID] = count.Count
+		}
+
+		giftCards, err := build(issued)
+		if err != nil {
+			return err
+		}
+		created = len(giftCards)
+		return createGiftCards(tx, giftCards, accountID)
+	})
+	return created, err
+}
+
+func createGiftCards(tx *gorm.DB, giftCards []*entities.GiftCard, accountID *uint) error {
+	for _, giftCard := range giftCards {
+		if err := tx.Omit("Business", "OrderItem").Create(giftCard).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("GiftCard", "Payment", "Account").Create(&entities.GiftCardTransaction{
+			GiftCardID:   giftCard.ID,
+			Type:         constants.GiftCardIssue,
+			Amount:       giftCard.Balance,
+			BalanceAfter: giftCard.Balance,
+			AccountID:    accountID,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetGiftCards lists the gift cards of a business, or only those sold with an order when orderID is set
+func (r *Repository) GetGiftCards(businessID uint, orderID *uint) ([]entities.GiftCard, error) {
 	var giftCards []entities.GiftCard
-	if result := database.DB.Where("business_id = ?", businessID).Find(&giftCards); result.Error != nil {
+	query := database.DB.Where("gift_cards.business_id = ?", businessID)
+	if orderID != nil {
+		query = query.Joins("JOIN order_items ON order_items.id = gift_cards.order_item_id").
+			Where("order_items.order_id = ?", *orderID)
+	}
+	if result := query.Order("gift_cards.id").Find(&giftCards); result.Error != nil {
 		return nil, result.Error
 	}
 	return giftCards, nil
@@ -46,11 +117,54 @@ func (r *Repository) GetGiftCardByCode(code string) (*entities.GiftCard, error)
 	return &giftCard, nil
 }
 
-func (r *Repository) UpdateGiftCard(giftCard *entities.GiftCard) (*entities.GiftCard, error) {
-	if result := database.DB.Save(giftCard); result.Error != nil {
+// ApplyTransaction changes a gift card under a row lock. apply mutates the locked card and returns the ledger entry
+// for the change, both are saved in the same transaction, which apply can also use for its own writes. When apply
+// returns no entry, nothing is saved.
+func (r *Repository) ApplyTransaction(id uint, apply func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error)) (*entities.GiftCard, error) {
+	return r.ApplyTransactionIn(database.DB, id, apply)
+}
+
+// ApplyTransactionIn changes a gift card like ApplyTransaction within db, which can be the transaction of a larger
+// write such as an order refund
+func (r *Repository) ApplyTransactionIn(db *gorm.DB, id uint, apply func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error)) (*entities.GiftCard, error) {
+	var giftCard entities.GiftCard
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&giftCard, id).Error; err != nil {
+			return err
+		}
+
+		entry, err := apply(tx, &giftCard)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return nil
+		}
+
+		if err := tx.Omit("Business", "OrderItem").Save(&giftCard).Error; err != nil {
+			return err
+		}
+		entry.GiftCardID = giftCard.ID
+		entry.BalanceAfter = giftCard.Balance
+		return tx.Omit("GiftCard", "Payment", "Account").Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &giftCard, nil
+}
+
+// UpdatePinState saves the failed PIN attempts of a gift card
+func (r *Repository) UpdatePinState(giftCard *entities.GiftCard) error {
+	return database.DB.Model(giftCard).Select("PinFailedAttempts", "PinLockedUntil").Updates(giftCard).Error
+}
+
+func (r *Repository) GetTransactions(giftCardID uint) ([]entities.GiftCardTransaction, error) {
+	var transactions []entities.GiftCardTransaction
+	if result := database.DB.Where("gift_card_id = ?", giftCardID).Order("id").Find(&transactions); result.Error != nil {
 		return nil, result.Error
 	}
-	return giftCard, nil
+	return transactions, nil
 }
 
 func (r *Repository) DeleteGiftCard(id uint) error {
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/env"
+	giftCardModels "VersatilePOS/giftCard/models"
+	orderModels "VersatilePOS/order/models"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMinExpiryMonths   = 60
+	defaultPinMaxAttempts    = 5
+	defaultPinLockoutMinutes = 30
+	generatedCodeLength      = 16
+	generatedPinLength       = 6
+	codeAlphabet             = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	// Each generated PIN is hashed with bcrypt, which limits how many a batch can have
+	maxBulkPinCount = 100
+)
+
+// expiryAllowed reports whether gift cards may expire at all, controlled by GIFTCARD_EXPIRY_ALLOWED for
+// jurisdictions that forbid expiring gift cards
+func expiryAllowed() bool {
+	if value := os.Getenv("GIFTCARD_EXPIRY_ALLOWED"); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return true
+}
+
+// minimumExpiry is the earliest a gift card issued or reloaded at from may expire, GIFTCARD_MIN_EXPIRY_MONTHS later
+func minimumExpiry(from time.Time) time.Time {
+	return from.AddDate(0, env.PositiveInt("GIFTCARD_MIN_EXPIRY_MONTHS", defaultMinExpiryMonths), 0)
+}
+
+func validateExpiry(expiresAt *time.Time, from time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiryAllowed() {
+		return errors.New("gift card expiry is not allowed")
+	}
+	if expiresAt.Before(minimumExpiry(from)) {
+		return errors.New("gift card expiry is too soon")
+	}
+	return nil
+}
+
+func isExpired(giftCard *entities.GiftCard) bool {
+	return giftCard.ExpiresAt != nil && giftCard.ExpiresAt.Before(time.Now())
+}
+
+// expireIfDue forfeits the balance of an expired gift card and records it in the ledger the first time the card is
+// used after its expiry
+func (s *Service) expireIfDue(giftCard *entities.GiftCard) (*entities.GiftCard, error) {
+	if !isExpired(giftCard) || (!giftCard.IsActive && giftCard.Balance == 0) {
+		return giftCard, nil
+	}
+
+	return s.repo.ApplyTransaction(giftCard.ID, func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error) {
+		if !isExpired(giftCard) || (!giftCard.IsActive && giftCard.Balance == 0) {
+			return nil, nil
+		}
+		entry := &entities.GiftCardTransaction{Type: constants.GiftCardExpire, Amount: -giftCard.Balance}
+		giftCard.Balance = 0
+		giftCard.IsActive = false
+		return entry, nil
+	})
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// generateCode creates a random gift card code without easily confused characters
+func generateCode(prefix string) (string, error) {
+	code, err := randomString(codeAlphabet, generatedCodeLength)
+	if err != nil {
+		return "", err
+	}
+	return prefix + code, nil
+}
+
+func hashPin(pin string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verifyPin checks the PIN of a gift card that has one. The PIN is locked for GIFTCARD_PIN_LOCKOUT_MINUTES after
+// GIFTCARD_PIN_MAX_ATTEMPTS wrong attempts in a row.
+func (s *Service) verifyPin(giftCard *entities.GiftCard, pin string) error {
+	if giftCard.PinHash == "" {
+		return nil
+	}
+
+	now := time.Now()
+	if giftCard.PinLockedUntil != nil && giftCard.PinLockedUntil.After(now) {
+		return errors.New("gift card PIN is locked")
+	}
+	if pin == "" {
+		return errors.New("gift card PIN required")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(giftCard.PinHash), []byte(pin)); err != nil {
+		giftCard.PinFailedAttempts++
+		if giftCard.PinFailedAttempts >= env.PositiveInt("GIFTCARD_PIN_MAX_ATTEMPTS", defaultPinMaxAttempts) {
+			lockedUntil := now.Add(time.Duration(env.PositiveInt("GIFTCARD_PIN_LOCKOUT_MINUTES", defaultPinLockoutMinutes)) * time.Minute)
+			giftCard.PinLockedUntil = &lockedUntil
+			giftCard.PinFailedAttempts = 0
+			log.Printf("PIN of gift card %d locked after repeated failures", giftCard.ID)
+		}
+		if err := s.repo.UpdatePinState(giftCard); err != nil {
+			log.Printf("Warning: Failed to record failed PIN attempt for gift card %d: %v", giftCard.ID, err)
+		}
+		return errors.New("invalid gift card PIN")
+	}
+
+	if giftCard.PinFailedAttempts != 0 || giftCard.PinLockedUntil != nil {
+		giftCard.PinFailedAttempts = 0
+		giftCard.PinLockedUntil = nil
+		if err := s.repo.UpdatePinState(giftCard); err != nil {
+			log.Printf("Warning: Failed to reset PIN attempts for gift card %d: %v", giftCard.ID, err)
+		}
+	}
+	return nil
+}
+
+// VerifyPaymentCard checks that a gift card can pay for a business in the given currency, including its PIN if it
+// has one
+func (s *Service) VerifyPaymentCard(code string, pin string, businessID uint, paymentCurrency string) error {
+	giftCard, err := s.repo.GetGiftCardByCode(code)
+	if err != nil {
+		return err
+	}
+	if giftCard == nil || giftCard.BusinessID != businessID {
+		return errors.New("gift card not found")
+	}
+	if giftCard.Currency != paymentCurrency {
+		return errors.New("gift card currency does not match the payment currency")
+	}
+
+	if err := s.verifyPin(giftCard, pin); err != nil {
+		return err
+	}
+	if giftCard, err = s.expireIfDue(giftCard); err != nil {
+		return err
+	}
+	if isExpired(giftCard) {
+		return errors.New("gift card has expired")
+	}
+	if !giftCard.IsActive {
+		return errors.New("gift card is not active")
+	}
+	return nil
+}
+
+// BulkIssue issues a batch of gift cards with generated codes and, if requested, PINs, which are only returned here
+func (s *Service) BulkIssue(req giftCardModels.BulkIssueGiftCardsRequest, userID uint) ([]giftCardModels.IssuedGiftCardDto, error) {
+	if err := checkGiftCardAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+	if req.WithPin && req.Count > maxBulkPinCount {
+		return nil, errors.New("too many gift cards with PINs")
+	}
+	if err := validateExpiry(req.ExpiresAt, time.Now()); err != nil {
+		return nil, err
+	}
+	cardCurrency, err := s.businessCurrency(req.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	value := currency.Round(req.Value, cardCurrency)
+
+	giftCards := make([]*entities.GiftCard, 0, req.Count)
+	pins := make([]string, 0, req.Count)
+	codes := make(map[string]bool, req.Count)
+	for len(giftCards) < req.Count {
+		code, err := generateCode(req.CodePrefix)
+		if err != nil {
+			return nil, errors.New("failed to generate code")
+		}
+		if codes[code] {
+			continue
+		}
+		codes[code] = true
+
+		giftCard := &entities.GiftCard{
+			Code:         code,
+			InitialValue: value,
+			Balance:      value,
+			Currency:     cardCurrency,
+			IsActive:     true,
+			BusinessID:   req.BusinessID,
+			ExpiresAt:    req.ExpiresAt,
+		}
+
+		var pin string
+		if req.WithPin {
+			if pin, err = randomString("0123456789", generatedPinLength); err != nil {
+				return nil, errors.New("failed to generate PIN")
+			}
+			if giftCard.PinHash, err = hashPin(pin); err != nil {
+				return nil, errors.New("failed to hash PIN")
+			}
+		}
+
+		giftCards = append(giftCards, giftCard)
+		pins = append(pins, pin)
+	}
+
+	if err := s.repo.CreateGiftCards(giftCards, &userID); err != nil {
+		return nil, err
+	}
+
+	dtos := make([]giftCardModels.IssuedGiftCardDto, 0, len(giftCards))
+	for i, giftCard := range giftCards {
+		dtos = append(dtos, giftCardModels.IssuedGiftCardDto{
+			GiftCardDto: giftCardModels.NewGiftCardDtoFromEntity(*giftCard),
+			Pin:         pins[i],
+		})
+	}
+	return dtos, nil
+}
+
+// IssueForOrder issues the gift cards sold on a paid order, one per unit of each gift card line worth its unit
+// price. Nothing is issued until the order's completed payments cover its total. Lines whose cards were already
+// issued are skipped, so it can run again safely, also concurrently.
+func (s *Service) IssueForOrder(orderID uint) error {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return errors.New("order not found")
+	}
+
+	paid := 0.0
+	for _, link := range order.OrderPaymentLinks {
+		if link.Payment.Status == constants.Completed {
+			paid += link.Payment.Amount
+		}
+	}
+	if currency.ToMinor(paid, order.Currency) < currency.ToMinor(orderModels.OrderTotal(*order), order.Currency) {
+		return errors.New("order is not fully paid")
+	}
+
+	issued, err := s.repo.IssueOrderGiftCards(orderID, order.ServicingAccountID, func(issued map[uint]int64) ([]*entities.GiftCard, error) {
+		var giftCards []*entities.GiftCard
+		for _, orderItem := range order.OrderItems {
+			if !orderItem.Item.IsGiftCard {
+				continue
+			}
+
+			value := orderItem.Item.Price
+			if orderItem.UnitPrice != nil {
+				value = *orderItem.UnitPrice
+			}
+			value = currency.Round(value, order.Currency)
+			if value <= 0 {
+				continue
+			}
+
+			for i := issued[orderItem.ID]; i < int64(orderItem.Count); i++ {
+				code, err := generateCode("")
+				if err != nil {
+					return nil, errors.New("failed to generate code")
+				}
+				orderItemID := orderItem.ID
+				giftCards = append(giftCards, &entities.GiftCard{
+					Code:         code,
+					InitialValue: value,
+					Balance:      value,
+					Currency:     order.Currency,
+					IsActive:     true,
+					BusinessID:   order.BusinessID,
+					OrderItemID:  &orderItemID,
+				})
+			}
+		}
+		return giftCards, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if issued > 0 {
+		log.Printf("Issued %d gift cards sold on order %d", issued, orderID)
+	}
+	return nil
+}
+
+// VoidForOrder voids the gift cards sold on a refunded order within db, e.g. the transaction of the refund. accountID
+// is nil when the refund came from the payment gateway.
+func (s *Service) VoidForOrder(db *gorm.DB, businessID uint, orderID uint, accountID *uint) error {
+	giftCards, err := s.repo.GetGiftCards(businessID, &orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, giftCard := range giftCards {
+		if !giftCard.IsActive {
+			continue
+		}
+		if err := s.void(db, giftCard.ID, accountID); err != nil {
+			return fmt.Errorf("failed to void gift card %d: %w", giftCard.ID, err)
+		}
+	}
+	return nil
+}
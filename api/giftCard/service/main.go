@@ -1,25 +1,102 @@
 package service
 
 import (
+	businessRepository "VersatilePOS/business/repository"
+	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/rbac"
 	giftCardModels "VersatilePOS/giftCard/models"
 	"VersatilePOS/giftCard/repository"
+	orderRepository "VersatilePOS/order/repository"
 	"errors"
-	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	repo repository.Repository
+	repo         repository.Repository
+	orderRepo    orderRepository.Repository
+	businessRepo businessRepository.Repository
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: repository.Repository{},
+		repo:         repository.Repository{},
+		orderRepo:    orderRepository.Repository{},
+		businessRepo: businessRepository.Repository{},
+	}
+}
+
+// businessCurrency returns the currency gift cards of a business are issued in
+func (s *Service) businessCurrency(businessID uint) (string, error) {
+	code, err := s.businessRepo.GetBusinessCurrency(businessID)
+	if err != nil {
+		return "", err
+	}
+	if code == "" {
+		return "", errors.New("business not found")
+	}
+	return code, nil
+}
+
+// checkGiftCardAccess verifies the user's access to the gift cards of a business
+func checkGiftCardAccess(businessID uint, userID uint, level constants.AccessLevel) error {
+	ok, err := rbac.HasAccess(constants.GiftCards, level, businessID, userID)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
 	}
+	return nil
 }
 
-func (s *Service) CreateGiftCard(req giftCardModels.CreateGiftCardRequest) (*giftCardModels.GiftCardDto, error) {
-	existingCard, err := s.repo.GetGiftCardByCode(req.Code)
+// getAuthorizedGiftCard loads a gift card the user has the given access to
+func (s *Service) getAuthorizedGiftCard(id uint, userID uint, level constants.AccessLevel) (*entities.GiftCard, error) {
+	giftCard, err := s.repo.GetGiftCardByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if giftCard == nil {
+		return nil, errors.New("gift card not found")
+	}
+
+	if err := checkGiftCardAccess(giftCard.BusinessID, userID, level); err != nil {
+		return nil, err
+	}
+	return giftCard, nil
+}
+
+func (s *Service) CreateGiftCard(req giftCardModels.CreateGiftCardRequest, userID uint) (*giftCardModels.GiftCardDto, error) {
+	if err := checkGiftCardAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+
+	if req.InitialValue <= 0 {
+		return nil, errors.New("initial value must be greater than 0")
+	}
+	if err := validateExpiry(req.ExpiresAt, time.Now()); err != nil {
+		return nil, err
+	}
+	cardCurrency, err := s.businessCurrency(req.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+
+	code := req.Code
+	if code == "" {
+		generated, err := generateCode("")
+		if err != nil {
+			return nil, errors.New("failed to generate code")
+		}
+		code = generated
+	}
+
+	existingCard, err := s.repo.GetGiftCardByCode(code)
 	if err != nil {
 		return nil, err
 	}
@@ -27,15 +104,25 @@ func (s *Service) CreateGiftCard(req giftCardModels.CreateGiftCardRequest) (*gif
 		return nil, errors.New("gift card with this code already exists")
 	}
 
+	value := currency.Round(req.InitialValue, cardCurrency)
 	giftCard := &entities.GiftCard{
-		Code:         req.Code,
-		InitialValue: req.InitialValue,
-		Balance:      req.InitialValue,
+		Code:         code,
+		InitialValue: value,
+		Balance:      value,
+		Currency:     cardCurrency,
 		IsActive:     true,
 		BusinessID:   req.BusinessID,
+		ExpiresAt:    req.ExpiresAt,
+	}
+	if req.Pin != "" {
+		pinHash, err := hashPin(req.Pin)
+		if err != nil {
+			return nil, errors.New("failed to hash PIN")
+		}
+		giftCard.PinHash = pinHash
 	}
 
-	createdCard, err := s.repo.CreateGiftCard(giftCard)
+	createdCard, err := s.repo.CreateGiftCard(giftCard, &userID)
 	if err != nil {
 		return nil, err
 	}
@@ -44,13 +131,18 @@ func (s *Service) CreateGiftCard(req giftCardModels.CreateGiftCardRequest) (*gif
 	return &dto, nil
 }
 
-func (s *Service) GetGiftCards(businessID uint) ([]giftCardModels.GiftCardDto, error) {
-	giftCards, err := s.repo.GetGiftCards(businessID)
+// GetGiftCards lists the gift cards of a business, optionally only those sold with an order
+func (s *Service) GetGiftCards(businessID uint, orderID *uint, userID uint) ([]giftCardModels.GiftCardDto, error) {
+	if err := checkGiftCardAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	giftCards, err := s.repo.GetGiftCards(businessID, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	var dtos []giftCardModels.GiftCardDto
+	dtos := make([]giftCardModels.GiftCardDto, 0, len(giftCards))
 	for _, gc := range giftCards {
 		dtos = append(dtos, giftCardModels.NewGiftCardDtoFromEntity(gc))
 	}
@@ -58,8 +150,19 @@ func (s *Service) GetGiftCards(businessID uint) ([]giftCardModels.GiftCardDto, e
 	return dtos, nil
 }
 
-func (s *Service) GetGiftCardByID(id uint) (*giftCardModels.GiftCardDto, error) {
-	giftCard, err := s.repo.GetGiftCardByID(id)
+func (s *Service) GetGiftCardByID(id uint, userID uint) (*giftCardModels.GiftCardDto, error) {
+	giftCard, err := s.getAuthorizedGiftCard(id, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := giftCardModels.NewGiftCardDtoFromEntity(*giftCard)
+	return &dto, nil
+}
+
+// CheckPublicBalance looks up a gift card by code without authentication, the card's PIN is required if it has one
+func (s *Service) CheckPublicBalance(code string, pin string) (*giftCardModels.GiftCardDto, error) {
+	giftCard, err := s.repo.GetGiftCardByCode(code)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +170,20 @@ func (s *Service) GetGiftCardByID(id uint) (*giftCardModels.GiftCardDto, error)
 		return nil, errors.New("gift card not found")
 	}
 
+	if err := s.verifyPin(giftCard, pin); err != nil {
+		return nil, err
+	}
+	if giftCard, err = s.expireIfDue(giftCard); err != nil {
+		return nil, err
+	}
+
 	dto := giftCardModels.NewGiftCardDtoFromEntity(*giftCard)
 	return &dto, nil
 }
 
-func (s *Service) GetGiftCardByCode(code string) (*giftCardModels.GiftCardDto, error) {
+// CheckBalance looks up a gift card by code for a user with Gift Cards Read access to its business, the card's PIN
+// is required if it has one
+func (s *Service) CheckBalance(code string, pin string, userID uint) (*giftCardModels.GiftCardDto, error) {
 	giftCard, err := s.repo.GetGiftCardByCode(code)
 	if err != nil {
 		return nil, err
@@ -80,82 +192,179 @@ func (s *Service) GetGiftCardByCode(code string) (*giftCardModels.GiftCardDto, e
 		return nil, errors.New("gift card not found")
 	}
 
+	// Cards of other businesses are reported as missing so codes cannot be probed across businesses
+	if err := checkGiftCardAccess(giftCard.BusinessID, userID, constants.Read); err != nil {
+		if err.Error() == "unauthorized" {
+			return nil, errors.New("gift card not found")
+		}
+		return nil, err
+	}
+
+	if err := s.verifyPin(giftCard, pin); err != nil {
+		return nil, err
+	}
+	if giftCard, err = s.expireIfDue(giftCard); err != nil {
+		return nil, err
+	}
+
 	dto := giftCardModels.NewGiftCardDtoFromEntity(*giftCard)
 	return &dto, nil
 }
 
-func (s *Service) DeactivateGiftCard(id uint) error {
-	giftCard, err := s.repo.GetGiftCardByID(id)
+// DeactivateGiftCard voids a gift card, the remaining balance is forfeited and recorded in its ledger
+func (s *Service) DeactivateGiftCard(id uint, userID uint) error {
+	giftCard, err := s.getAuthorizedGiftCard(id, userID, constants.Write)
 	if err != nil {
 		return err
 	}
-	if giftCard == nil {
-		return errors.New("gift card not found")
-	}
 
-	giftCard.IsActive = false
-	_, err = s.repo.UpdateGiftCard(giftCard)
+	return s.void(database.DB, giftCard.ID, &userID)
+}
+
+// void deactivates a gift card and forfeits its balance. accountID is nil when no account voided it.
+func (s *Service) void(db *gorm.DB, id uint, accountID *uint) error {
+	_, err := s.repo.ApplyTransactionIn(db, id, func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error) {
+		entry := &entities.GiftCardTransaction{Type: constants.GiftCardVoid, Amount: -giftCard.Balance, AccountID: accountID}
+		giftCard.Balance = 0
+		giftCard.IsActive = false
+		return entry, nil
+	})
 	return err
 }
 
-func (s *Service) RedeemGiftCard(code string, amount float64) (*entities.GiftCard, float64, error) {
-	giftCard, err := s.repo.GetGiftCardByCode(code)
+// GetTransactions lists the ledger of a gift card, oldest first
+func (s *Service) GetTransactions(id uint, userID uint) ([]giftCardModels.GiftCardTransactionDto, error) {
+	giftCard, err := s.getAuthorizedGiftCard(id, userID, constants.Read)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	if giftCard == nil {
-		return nil, 0, errors.New("gift card not found")
+
+	transactions, err := s.repo.GetTransactions(giftCard.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	if !giftCard.IsActive {
-		return nil, 0, errors.New("gift card is not active")
+	dtos := make([]giftCardModels.GiftCardTransactionDto, 0, len(transactions))
+	for _, t := range transactions {
+		dtos = append(dtos, giftCardModels.NewGiftCardTransactionDtoFromEntity(t))
+	}
+	return dtos, nil
+}
+
+// RedeemForPayment redeems a gift card payment under a lock on the card. The redeemed amount is capped at the
+// available balance, the rest of the payment is left for another tender. complete records the redeemed amount on the
+// payment within the same transaction, so either both or neither are saved.
+func (s *Service) RedeemForPayment(payment entities.Payment, accountID uint, complete func(tx *gorm.DB, redeemed float64) error) (*entities.GiftCard, float64, error) {
+	giftCard, err := s.getPaymentGiftCard(payment)
+	if err != nil {
+		return nil, 0, err
 	}
+	if giftCard, err = s.expireIfDue(giftCard); err != nil {
+		return nil, 0, err
+	}
+
+	var redeemed float64
+	updatedCard, err := s.repo.ApplyTransaction(giftCard.ID, func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error) {
+		if isExpired(giftCard) {
+			return nil, errors.New("gift card has expired")
+		}
+		if !giftCard.IsActive {
+			return nil, errors.New("gift card is not active")
+		}
+		if giftCard.Balance <= 0 {
+			return nil, errors.New("gift card has no balance")
+		}
+
+		redeemed = currency.Round(math.Min(payment.Amount, giftCard.Balance), giftCard.Currency)
+		giftCard.Balance = currency.Round(giftCard.Balance-redeemed, giftCard.Currency)
 
-	if giftCard.Balance <= 0 {
-		return nil, 0, errors.New("gift card has no balance")
+		if err := complete(tx, redeemed); err != nil {
+			return nil, err
+		}
+		return &entities.GiftCardTransaction{Type: constants.GiftCardRedeem, Amount: -redeemed, PaymentID: &payment.ID, AccountID: &accountID}, nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	amountToDeduct := amount
-	remainingPayment := 0.0
+	return updatedCard, redeemed, nil
+}
 
-	if amount > giftCard.Balance {
-		amountToDeduct = giftCard.Balance
-		remainingPayment = amount - giftCard.Balance
+// RefundPayment credits a redeemed gift card payment back to the card within db, e.g. the transaction of the order's
+// refund. refund marks the payment refunded within the same transaction.
+func (s *Service) RefundPayment(db *gorm.DB, payment entities.Payment, accountID uint, refund func(tx *gorm.DB) error) (*entities.GiftCard, error) {
+	giftCard, err := s.getPaymentGiftCard(payment)
+	if err != nil {
+		return nil, err
 	}
 
-	giftCard.Balance -= amountToDeduct
+	return s.repo.ApplyTransactionIn(db, giftCard.ID, func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error) {
+		if !giftCard.IsActive {
+			return nil, errors.New("gift card is not active")
+		}
 
-	if giftCard.Balance <= 0 {
-		giftCard.Balance = 0
-		giftCard.IsActive = false
+		giftCard.Balance = currency.Round(giftCard.Balance+payment.Amount, giftCard.Currency)
+
+		if err := refund(tx); err != nil {
+			return nil, err
+		}
+		return &entities.GiftCardTransaction{Type: constants.GiftCardRefund, Amount: payment.Amount, PaymentID: &payment.ID, AccountID: &accountID}, nil
+	})
+}
+
+// getPaymentGiftCard loads the gift card a payment is made with, which has to belong to the payment's business and
+// be in its currency
+func (s *Service) getPaymentGiftCard(payment entities.Payment) (*entities.GiftCard, error) {
+	if payment.GiftCardCode == nil || *payment.GiftCardCode == "" {
+		return nil, errors.New("gift card code is missing for this payment")
 	}
 
-	updatedCard, err := s.repo.UpdateGiftCard(giftCard)
+	giftCard, err := s.repo.GetGiftCardByCode(*payment.GiftCardCode)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to update gift card: %w", err)
+		return nil, err
 	}
-
-	return updatedCard, remainingPayment, nil
+	if giftCard == nil || giftCard.BusinessID != payment.BusinessID {
+		return nil, errors.New("gift card not found")
+	}
+	if giftCard.Currency != payment.Currency {
+		return nil, errors.New("gift card currency does not match the payment currency")
+	}
+	return giftCard, nil
 }
 
-func (s *Service) AddBalance(id uint, amount float64) (*giftCardModels.GiftCardDto, error) {
+// Reload loads an amount onto an active gift card. The initial value stays as issued, and a set expiry is pushed
+// out to at least the minimum expiry period from now.
+func (s *Service) Reload(id uint, amount float64, userID uint) (*giftCardModels.GiftCardDto, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be greater than 0")
 	}
 
-	giftCard, err := s.repo.GetGiftCardByID(id)
+	giftCard, err := s.getAuthorizedGiftCard(id, userID, constants.Write)
 	if err != nil {
 		return nil, err
 	}
-	if giftCard == nil {
-		return nil, errors.New("gift card not found")
+	if giftCard, err = s.expireIfDue(giftCard); err != nil {
+		return nil, err
 	}
 
-	giftCard.Balance += amount
-	giftCard.InitialValue += amount
-	giftCard.IsActive = true
+	now := time.Now()
+	updatedCard, err := s.repo.ApplyTransaction(giftCard.ID, func(tx *gorm.DB, giftCard *entities.GiftCard) (*entities.GiftCardTransaction, error) {
+		if isExpired(giftCard) {
+			return nil, errors.New("gift card has expired")
+		}
+		if !giftCard.IsActive {
+			return nil, errors.New("gift card is not active")
+		}
 
-	updatedCard, err := s.repo.UpdateGiftCard(giftCard)
+		amount = currency.Round(amount, giftCard.Currency)
+		giftCard.Balance = currency.Round(giftCard.Balance+amount, giftCard.Currency)
+		if giftCard.ExpiresAt != nil {
+			if minimum := minimumExpiry(now); giftCard.ExpiresAt.Before(minimum) {
+				giftCard.ExpiresAt = &minimum
+			}
+		}
+		return &entities.GiftCardTransaction{Type: constants.GiftCardLoad, Amount: amount, AccountID: &userID}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
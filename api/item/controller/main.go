@@ -135,7 +135,7 @@ func (ctrl *Controller) GetItemByID(c *gin.Context) {
 }
 
 // @Summary Update item
-// @Description Edit item
+// @Description Edit item. Changing trackInventory or quantityInStock requires the Manage Inventory permission.
 // @Tags item
 // @Accept  json
 // @Produce  json
@@ -328,7 +328,7 @@ func (ctrl *Controller) GetItemOptionByID(c *gin.Context) {
 }
 
 // @Summary Update item option
-// @Description Update an item option
+// @Description Update an item option. Changing trackInventory or quantityInStock requires the Manage Inventory permission.
 // @Tags item-option
 // @Accept  json
 // @Produce  json
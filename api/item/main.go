@@ -11,7 +11,7 @@ func RegisterHandlers(r *gin.Engine) {
 	ctrl := controller.NewController()
 
 	itemGroup := r.Group("/item")
-	itemGroup.Use(middleware.AuthMiddleware())
+	itemGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		itemGroup.POST("", ctrl.CreateItem)
 		itemGroup.GET("", ctrl.GetItems)
@@ -25,7 +25,7 @@ func RegisterHandlers(r *gin.Engine) {
 	}
 
 	itemOptionGroup := r.Group("/item-option")
-	itemOptionGroup.Use(middleware.AuthMiddleware())
+	itemOptionGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		itemOptionGroup.POST("", ctrl.CreateItemOption)
 		itemOptionGroup.GET("", ctrl.GetItemOptions)
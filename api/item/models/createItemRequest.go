@@ -6,4 +6,5 @@ type CreateItemRequest struct {
 	Price           float64 `json:"price" binding:"required"`
 	TrackInventory  bool    `json:"trackInventory"`
 	QuantityInStock int     `json:"quantityInStock"`
+	IsGiftCard      bool    `json:"isGiftCard"`
 }
@@ -6,4 +6,5 @@ type ItemDto struct {
 	Name            string  `json:"name"`
 	Price           float64 `json:"price"`
 	QuantityInStock *int    `json:"quantityInStock,omitempty"`
+	IsGiftCard      bool    `json:"isGiftCard"`
 }
@@ -5,4 +5,5 @@ type UpdateItemRequest struct {
 	Price           float64 `json:"price"`
 	TrackInventory  *bool   `json:"trackInventory"`
 	QuantityInStock *int    `json:"quantityInStock"`
+	IsGiftCard      *bool   `json:"isGiftCard"`
 }
@@ -36,6 +36,7 @@ func (s *Service) CreateItem(req models.CreateItemRequest, userID uint) (*models
 		BusinessID: req.BusinessID,
 		Name:       req.Name,
 		Price:      req.Price,
+		IsGiftCard: req.IsGiftCard,
 	}
 
 	var inventory *entities.ItemInventory
@@ -55,6 +56,7 @@ func (s *Service) CreateItem(req models.CreateItemRequest, userID uint) (*models
 		BusinessID: createdItem.BusinessID,
 		Name:       createdItem.Name,
 		Price:      createdItem.Price,
+		IsGiftCard: createdItem.IsGiftCard,
 	}
 	if req.TrackInventory {
 		dto.QuantityInStock = &req.QuantityInStock
@@ -84,6 +86,7 @@ func (s *Service) GetItems(businessID uint, userID uint) ([]models.ItemDto, erro
 			BusinessID: item.BusinessID,
 			Name:       item.Name,
 			Price:      item.Price,
+			IsGiftCard: item.IsGiftCard,
 		}
 		if inv, exists := inventoryMap[item.ID]; exists {
 			dtos[i].QuantityInStock = &inv.QuantityInStock
@@ -115,6 +118,7 @@ func (s *Service) GetItemByID(id uint, userID uint) (*models.ItemDto, error) {
 		BusinessID: item.BusinessID,
 		Name:       item.Name,
 		Price:      item.Price,
+		IsGiftCard: item.IsGiftCard,
 	}
 	if inventory != nil {
 		dto.QuantityInStock = &inventory.QuantityInStock
@@ -140,12 +144,26 @@ func (s *Service) UpdateItem(id uint, req models.UpdateItemRequest, userID uint)
 		return nil, errors.New("unauthorized to update this item")
 	}
 
+	// Changing tracked stock needs the separate Manage Inventory permission
+	if req.TrackInventory != nil || req.QuantityInStock != nil {
+		ok, err := rbac.HasAccess(constants.ManageInventory, constants.Write, item.BusinessID, userID)
+		if err != nil {
+			return nil, errors.New("failed to verify permissions")
+		}
+		if !ok {
+			return nil, errors.New("unauthorized to manage inventory")
+		}
+	}
+
 	if req.Name != "" {
 		item.Name = req.Name
 	}
 	if req.Price != 0 {
 		item.Price = req.Price
 	}
+	if req.IsGiftCard != nil {
+		item.IsGiftCard = *req.IsGiftCard
+	}
 
 	if req.TrackInventory != nil && *req.TrackInventory {
 		if inventory == nil {
@@ -167,6 +185,7 @@ func (s *Service) UpdateItem(id uint, req models.UpdateItemRequest, userID uint)
 		BusinessID: item.BusinessID,
 		Name:       item.Name,
 		Price:      item.Price,
+		IsGiftCard: item.IsGiftCard,
 	}
 
 	if req.TrackInventory != nil && !*req.TrackInventory {
@@ -325,6 +344,17 @@ func (s *Service) UpdateItemOption(id uint, req models.UpdateItemOptionRequest,
 		return nil, errors.New("unauthorized to update this item option")
 	}
 
+	// Changing tracked stock needs the separate Manage Inventory permission
+	if req.TrackInventory != nil || req.QuantityInStock != nil {
+		ok, err := rbac.HasAccess(constants.ManageInventory, constants.Write, option.Item.BusinessID, userID)
+		if err != nil {
+			return nil, errors.New("failed to verify permissions")
+		}
+		if !ok {
+			return nil, errors.New("unauthorized to manage inventory")
+		}
+	}
+
 	if req.Name != "" {
 		option.Name = req.Name
 	}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"VersatilePOS/generic/models"
+	locationModels "VersatilePOS/location/models"
+	"VersatilePOS/location/service"
+	"VersatilePOS/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	service *service.Service
+}
+
+func NewController() *Controller {
+	return &Controller{
+		service: service.NewService(),
+	}
+}
+
+// @Summary Create a location
+// @Description Create a location of a business. Requires authentication and Businesses Write permission.
+// @Tags location
+// @Accept  json
+// @Produce  json
+// @Param   location  body  models.CreateLocationRequest  true  "Location to create"
+// @Success 201 {object} models.LocationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /location [post]
+// @Id createLocation
+func (ctrl *Controller) CreateLocation(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var req locationModels.CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	location, err := ctrl.service.CreateLocation(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		log.Println("Failed to create location:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, location)
+}
+
+// @Summary Get locations
+// @Description Get all locations of a business. Requires authentication and Businesses Read permission.
+// @Tags location
+// @Produce  json
+// @Param   businessId  query  int  true  "Business ID"
+// @Success 200 {array} models.LocationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /location [get]
+// @Id getLocations
+func (ctrl *Controller) GetLocations(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessIDStr := c.Query("businessId")
+	if businessIDStr == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId query parameter is required"})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(businessIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	locations, err := ctrl.service.GetLocations(uint(businessID), userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		log.Println("Failed to get locations:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, locations)
+}
+
+// @Summary Get location by ID
+// @Description Get a location by its ID. Requires authentication and Businesses Read permission.
+// @Tags location
+// @Produce  json
+// @Param   id  path  int  true  "Location ID"
+// @Success 200 {object} models.LocationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /location/{id} [get]
+// @Id getLocationById
+func (ctrl *Controller) GetLocationByID(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid location id"})
+		return
+	}
+
+	location, err := ctrl.service.GetLocationByID(uint(id), userID)
+	if err != nil {
+		switch err.Error() {
+		case "location not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to get location:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, location)
+}
+
+// @Summary Update a location
+// @Description Update the name or address of a location. Requires authentication and Businesses Write permission.
+// @Tags location
+// @Accept  json
+// @Produce  json
+// @Param   id  path  int  true  "Location ID"
+// @Param   location  body  models.UpdateLocationRequest  true  "Location changes"
+// @Success 200 {object} models.LocationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /location/{id} [put]
+// @Id updateLocation
+func (ctrl *Controller) UpdateLocation(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid location id"})
+		return
+	}
+
+	var req locationModels.UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	location, err := ctrl.service.UpdateLocation(uint(id), req, userID)
+	if err != nil {
+		switch err.Error() {
+		case "location not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "name cannot be empty":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to update location:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, location)
+}
+
+// @Summary Delete a location
+// @Description Delete a location that no role is limited to. Requires authentication and Businesses Write permission.
+// @Tags location
+// @Param   id  path  int  true  "Location ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /location/{id} [delete]
+// @Id deleteLocation
+func (ctrl *Controller) DeleteLocation(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid location id"})
+		return
+	}
+
+	if err := ctrl.service.DeleteLocation(uint(id), userID); err != nil {
+		switch err.Error() {
+		case "location not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "location is assigned to roles":
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to delete location:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
+	locationGroup := r.Group("/location")
+	locationGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
+	{
+		locationGroup.POST("", ctrl.CreateLocation)
+		locationGroup.GET("", ctrl.GetLocations)
+		locationGroup.GET("/:id", ctrl.GetLocationByID)
+		locationGroup.PUT("/:id", ctrl.UpdateLocation)
+		locationGroup.DELETE("/:id", ctrl.DeleteLocation)
+	}
+}
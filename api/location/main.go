@@ -0,0 +1,12 @@
+package location
+
+import (
+	"VersatilePOS/location/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterHandlers(r *gin.Engine) {
+	locationController := controller.NewController()
+	locationController.RegisterRoutes(r)
+}
@@ -0,0 +1,7 @@
+package models
+
+type CreateLocationRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	Address    string `json:"address"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "VersatilePOS/database/entities"
+
+type LocationDto struct {
+	ID         uint   `json:"id"`
+	BusinessID uint   `json:"businessId"`
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+}
+
+// NewLocationDtoFromEntity constructs a LocationDto from the DB entity.
+func NewLocationDtoFromEntity(l entities.Location) LocationDto {
+	return LocationDto{
+		ID:         l.ID,
+		BusinessID: l.BusinessID,
+		Name:       l.Name,
+		Address:    l.Address,
+	}
+}
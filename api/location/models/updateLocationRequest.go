@@ -0,0 +1,6 @@
+package models
+
+type UpdateLocationRequest struct {
+	Name    *string `json:"name,omitempty"`
+	Address *string `json:"address,omitempty"`
+}
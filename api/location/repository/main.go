@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct{}
+
+func (r *Repository) CreateLocation(location *entities.Location) error {
+	return database.DB.Create(location).Error
+}
+
+func (r *Repository) GetLocations(businessID uint) ([]entities.Location, error) {
+	var locations []entities.Location
+	if err := database.DB.Where("business_id = ?", businessID).Order("name").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func (r *Repository) GetLocationByID(id uint) (*entities.Location, error) {
+	var location entities.Location
+	if err := database.DB.First(&location, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *Repository) UpdateLocation(location *entities.Location) error {
+	return database.DB.Omit("Business").Save(location).Error
+}
+
+// CountRoleAssignments returns how many roles are limited to the location
+func (r *Repository) CountRoleAssignments(locationID uint) (int64, error) {
+	var count int64
+	if err := database.DB.Table("account_role_locations").Where("location_id = ?", locationID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) DeleteLocation(location *entities.Location) error {
+	return database.DB.Delete(location).Error
+}
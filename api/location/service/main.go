@@ -0,0 +1,135 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/rbac"
+	locationModels "VersatilePOS/location/models"
+	"VersatilePOS/location/repository"
+	"errors"
+)
+
+type Service struct {
+	repo repository.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: repository.Repository{},
+	}
+}
+
+// checkLocationAccess verifies the user's access to the locations of a business, which are part of its setup
+func checkLocationAccess(businessID uint, userID uint, level constants.AccessLevel) error {
+	ok, err := rbac.HasAccess(constants.Businesses, level, businessID, userID)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
+	}
+	return nil
+}
+
+// getAuthorizedLocation loads a location the user has the given access to
+func (s *Service) getAuthorizedLocation(id uint, userID uint, level constants.AccessLevel) (*entities.Location, error) {
+	location, err := s.repo.GetLocationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		return nil, errors.New("location not found")
+	}
+
+	if err := checkLocationAccess(location.BusinessID, userID, level); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (s *Service) CreateLocation(req locationModels.CreateLocationRequest, userID uint) (*locationModels.LocationDto, error) {
+	if err := checkLocationAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+
+	location := &entities.Location{
+		BusinessID: req.BusinessID,
+		Name:       req.Name,
+		Address:    req.Address,
+	}
+	if err := s.repo.CreateLocation(location); err != nil {
+		return nil, err
+	}
+
+	dto := locationModels.NewLocationDtoFromEntity(*location)
+	return &dto, nil
+}
+
+func (s *Service) GetLocations(businessID uint, userID uint) ([]locationModels.LocationDto, error) {
+	if err := checkLocationAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	locations, err := s.repo.GetLocations(businessID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]locationModels.LocationDto, 0, len(locations))
+	for _, location := range locations {
+		dtos = append(dtos, locationModels.NewLocationDtoFromEntity(location))
+	}
+	return dtos, nil
+}
+
+func (s *Service) GetLocationByID(id uint, userID uint) (*locationModels.LocationDto, error) {
+	location, err := s.getAuthorizedLocation(id, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := locationModels.NewLocationDtoFromEntity(*location)
+	return &dto, nil
+}
+
+func (s *Service) UpdateLocation(id uint, req locationModels.UpdateLocationRequest, userID uint) (*locationModels.LocationDto, error) {
+	location, err := s.getAuthorizedLocation(id, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, errors.New("name cannot be empty")
+		}
+		location.Name = *req.Name
+	}
+	if req.Address != nil {
+		location.Address = *req.Address
+	}
+
+	if err := s.repo.UpdateLocation(location); err != nil {
+		return nil, err
+	}
+
+	dto := locationModels.NewLocationDtoFromEntity(*location)
+	return &dto, nil
+}
+
+func (s *Service) DeleteLocation(id uint, userID uint) error {
+	location, err := s.getAuthorizedLocation(id, userID, constants.Write)
+	if err != nil {
+		return err
+	}
+
+	// A role whose last location disappeared would apply everywhere, so its locations have to be changed first
+	count, err := s.repo.CountRoleAssignments(location.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("location is assigned to roles")
+	}
+
+	return s.repo.DeleteLocation(location)
+}
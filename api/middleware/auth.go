@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"VersatilePOS/generic/env"
 	"VersatilePOS/generic/models"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,19 +16,60 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(username string, id uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+const defaultAccessTokenTTLMinutes = 15
+
+// AccessTokenTTL is how long access tokens are valid, configured by ACCESS_TOKEN_TTL_MINUTES
+func AccessTokenTTL() time.Duration {
+	return time.Duration(env.PositiveInt("ACCESS_TOKEN_TTL_MINUTES", defaultAccessTokenTTLMinutes)) * time.Minute
+}
+
+// GenerateToken issues a short-lived access token for the session. It returns the token, its jti and expiry.
+func GenerateToken(username string, id uint, sessionID uint) (string, string, time.Time, error) {
+	return signToken(jwt.MapClaims{
 		"sub": username,
 		"id":  id,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	})
+		"sid": sessionID,
+	}, AccessTokenTTL())
+}
+
+// GenerateTerminalToken issues an access token for a PIN login, which is only valid for the given business and terminal
+func GenerateTerminalToken(username string, id uint, sessionID uint, businessID uint, terminalID uint, ttl time.Duration) (string, string, time.Time, error) {
+	return signToken(jwt.MapClaims{
+		"sub": username,
+		"id":  id,
+		"sid": sessionID,
+		"bid": businessID,
+		"tid": terminalID,
+	}, ttl)
+}
+
+func signToken(claims jwt.MapClaims, ttl time.Duration) (string, string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
-		return "", err
+		return "", "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, jti, expiresAt, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -67,12 +112,106 @@ func AuthorizeAndGetClaims(c *gin.Context) (jwt.MapClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			return nil, fmt.Errorf("invalid token")
+		}
+
+		revoked, err := IsTokenRevoked(jti)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify token")
+		}
+		if revoked {
+			return nil, fmt.Errorf("token has been revoked")
+		}
+
+		if err := checkBusinessScope(c, claims); err != nil {
+			return nil, err
+		}
+
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
+// terminalRoutes are the routes a terminal token may use without naming a business, as they only act on the
+// token's own account and session
+var terminalRoutes = map[string]bool{
+	"/account/me":     true,
+	"/account/logout": true,
+}
+
+// terminalEntityRoutes are the routes acting on an entity identified in their path that a terminal token may use.
+// Their services pass the token's business as rbac.Scope.TokenBusinessID, so access to an entity of another
+// business is refused.
+var terminalEntityRoutes = map[string]bool{
+	"/order/:id":                               true,
+	"/order/:id/item":                          true,
+	"/order/:id/item/:itemId":                  true,
+	"/order/:id/item/:itemId/option":           true,
+	"/order/:id/item/:itemId/option/:optionId": true,
+	"/order/:id/price-modifier":                true,
+	"/order/:id/payment/:paymentId":            true,
+	"/payment/:id":                             true,
+	"/payment/:id/complete":                    true,
+	"/payment/:id/capture":                     true,
+	"/payment/:id/increment-authorization":     true,
+	"/payment/:id/void":                        true,
+}
+
+// checkBusinessScope limits terminal tokens to the terminal's business. The business a request is for has to be
+// named by a businessId path parameter, query parameter or JSON body field, and all of them have to match. Routes
+// acting on an entity identified in their path are only allowed when listed in terminalEntityRoutes, whose services
+// check the entity's business against the token; requests naming no business are rejected otherwise.
+func checkBusinessScope(c *gin.Context, claims jwt.MapClaims) error {
+	scopedBusinessID, ok := claims["bid"].(float64)
+	if !ok {
+		return nil
+	}
+
+	entityRoute := false
+	for _, param := range c.Params {
+		if param.Key == "businessId" {
+			continue
+		}
+		if !terminalEntityRoutes[c.FullPath()] {
+			return fmt.Errorf("token is not valid for this route")
+		}
+		entityRoute = true
+	}
+
+	var values []string
+	for _, value := range []string{c.Param("businessId"), c.Query("businessId")} {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	field, err := peekJSONField(c, "businessId")
+	if err != nil {
+		return fmt.Errorf("token is not valid for this business")
+	}
+	if field != nil {
+		values = append(values, strings.Trim(string(field), `"`))
+	}
+
+	if len(values) == 0 {
+		if entityRoute || terminalRoutes[c.FullPath()] {
+			return nil
+		}
+		return fmt.Errorf("token is not valid for this route")
+	}
+
+	for _, value := range values {
+		businessID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || uint(businessID) != uint(scopedBusinessID) {
+			return fmt.Errorf("token is not valid for this business")
+		}
+	}
+	return nil
+}
+
 func GetUserIDFromContext(c *gin.Context) (uint, error) {
 	userClaims, exists := c.Get("user")
 	if !exists {
@@ -91,3 +230,44 @@ func GetUserIDFromContext(c *gin.Context) (uint, error) {
 
 	return uint(id), nil
 }
+
+// GetTokenBusinessIDFromContext returns the business the request's terminal token is limited to, or nil for tokens
+// of a regular login
+func GetTokenBusinessIDFromContext(c *gin.Context) *uint {
+	userClaims, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+
+	claims, ok := userClaims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+
+	bid, ok := claims["bid"].(float64)
+	if !ok {
+		return nil
+	}
+	businessID := uint(bid)
+	return &businessID
+}
+
+// GetSessionIDFromContext returns the session the request's access token was issued for
+func GetSessionIDFromContext(c *gin.Context) (uint, error) {
+	userClaims, exists := c.Get("user")
+	if !exists {
+		return 0, fmt.Errorf("user not found in context")
+	}
+
+	claims, ok := userClaims.(jwt.MapClaims)
+	if !ok {
+		return 0, fmt.Errorf("invalid user claims in context")
+	}
+
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("session ID not found in claims or is of invalid type")
+	}
+
+	return uint(sid), nil
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxInspectedBodyBytes is the largest request body middleware reads to inspect it
+const maxInspectedBodyBytes = 1 << 20
+
+var errBodyTooLarge = errors.New("request body is too large")
+
+// peekBody reads the request body and restores it for the handler. Bodies larger than maxInspectedBodyBytes are
+// not read and fail with errBodyTooLarge.
+func peekBody(c *gin.Context) ([]byte, error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInspectedBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxInspectedBodyBytes {
+		return nil, errBodyTooLarge
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// peekJSONField returns the raw value of a top-level field of the JSON request body, or nil when the body is not
+// a JSON object or has no such field
+func peekJSONField(c *gin.Context, field string) (json.RawMessage, error) {
+	body, err := peekBody(c)
+	if err != nil || len(body) == 0 {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil
+	}
+	return fields[field], nil
+}
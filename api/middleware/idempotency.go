@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	idempotencyKeyHeader       = "Idempotency-Key"
+	idempotencyReplayHeader    = "Idempotent-Replayed"
+	maxIdempotencyKeyLength    = 255
+	defaultIdempotencyTTLHours = 24
+	// idempotencyInProgressTimeout is when a request that never finished, e.g. because the server stopped,
+	// no longer holds its key
+	idempotencyInProgressTimeout = time.Minute
+)
+
+// idempotencyRedactedFields are the JSON fields and CSV columns of responses that hold secrets: payment intent client
+// secrets and gift card PINs. They are left out of stored responses, so only the original response carries them.
+var idempotencyRedactedFields = map[string]bool{
+	"clientSecret":        true,
+	"depositClientSecret": true,
+	"pin":                 true,
+}
+
+// IdempotencyTTL is how long responses are kept for retries, configured by IDEMPOTENCY_KEY_TTL_HOURS
+func IdempotencyTTL() time.Duration {
+	return time.Duration(env.PositiveInt("IDEMPOTENCY_KEY_TTL_HOURS", defaultIdempotencyTTLHours)) * time.Hour
+}
+
+// idempotencyWriter keeps a copy of the response so it can be stored
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes POST, PUT and PATCH requests carrying an Idempotency-Key header safe to retry. The
+// first response for a key is stored per account and replayed for retries with the same method, URL and body;
+// reusing the key for a different request is rejected with 409. Only final outcomes, successes and conflicts, are
+// stored, so requests that were refused, e.g. for a missing approval or a rate limit, or failed can be retried.
+// Secret fields are removed from stored responses, so a replay answers without them; routes returning other secrets,
+// such as tokens, must not use it. It must run after AuthMiddleware.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		method := c.Request.Method
+		if key == "" || (method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.HTTPError{Error: "idempotency key is too long"})
+			return
+		}
+
+		accountID, err := GetUserIDFromContext(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.HTTPError{Error: "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(method, c.Request.URL.RequestURI(), body)
+
+		record, claimed, err := claimIdempotencyKey(accountID, key, fingerprint)
+		if err != nil {
+			log.Printf("Failed to claim idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+			return
+		}
+
+		if !claimed {
+			switch {
+			case record.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, models.HTTPError{Error: "idempotency key was used for a different request"})
+			case record.StatusCode == 0:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, models.HTTPError{Error: "a request with this idempotency key is in progress"})
+			default:
+				c.Header(idempotencyReplayHeader, "true")
+				c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if !isFinalOutcome(status) {
+			if err := database.DB.Delete(&entities.IdempotencyRecord{}, record.ID).Error; err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+
+		if err := database.DB.Model(&entities.IdempotencyRecord{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+			"status_code":   status,
+			"content_type":  writer.Header().Get("Content-Type"),
+			"response_body": redactSecrets(writer.Header().Get("Content-Type"), writer.body.Bytes()),
+		}).Error; err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// isFinalOutcome reports whether a response is the outcome a retry of the request would get again
+func isFinalOutcome(status int) bool {
+	return (status >= http.StatusOK && status < http.StatusMultipleChoices) || status == http.StatusConflict
+}
+
+// redactSecrets returns the response body without its secret fields. Bodies that are neither JSON nor CSV, or hold
+// no secrets, are returned as they are.
+func redactSecrets(contentType string, body []byte) []byte {
+	if strings.HasPrefix(contentType, "text/csv") {
+		return redactSecretColumns(body)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	if !removeSecretFields(value) {
+		return body
+	}
+
+	redacted, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactSecretColumns blanks the secret columns of a CSV body, named in its header row
+func redactSecretColumns(body []byte) []byte {
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		// A body that cannot be checked for secrets is not stored
+		return nil
+	}
+	if len(rows) == 0 {
+		return body
+	}
+
+	var secret []int
+	for i, name := range rows[0] {
+		if idempotencyRedactedFields[name] {
+			secret = append(secret, i)
+		}
+	}
+	if len(secret) == 0 {
+		return body
+	}
+
+	for _, row := range rows[1:] {
+		for _, i := range secret {
+			if i < len(row) {
+				row[i] = ""
+			}
+		}
+	}
+	var redacted bytes.Buffer
+	w := csv.NewWriter(&redacted)
+	if err := w.WriteAll(rows); err != nil {
+		return body
+	}
+	return redacted.Bytes()
+}
+
+// removeSecretFields deletes the secret fields from a decoded JSON value and reports whether any were found
+func removeSecretFields(value interface{}) bool {
+	removed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if idempotencyRedactedFields[key] {
+				delete(v, key)
+				removed = true
+			} else if removeSecretFields(field) {
+				removed = true
+			}
+		}
+	case []interface{}:
+		for _, element := range v {
+			if removeSecretFields(element) {
+				removed = true
+			}
+		}
+	}
+	return removed
+}
+
+// requestFingerprint identifies a request by its method, URL and body
+func requestFingerprint(method string, uri string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + uri + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// claimIdempotencyKey records a key for the account unless it is held. It returns the record and whether this
+// request claimed it; otherwise the record is the one stored for the earlier request.
+func claimIdempotencyKey(accountID uint, key string, fingerprint string) (*entities.IdempotencyRecord, bool, error) {
+	now := time.Now()
+
+	// Keys that have expired, or whose request never finished, may be used again
+	if err := database.DB.Where("account_id = ? AND key = ? AND (expires_at <= ? OR (status_code = 0 AND created_at <= ?))",
+		accountID, key, now, now.Add(-idempotencyInProgressTimeout)).
+		Delete(&entities.IdempotencyRecord{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	record := &entities.IdempotencyRecord{
+		AccountID:   accountID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(IdempotencyTTL()),
+	}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		// Take the chance to drop the records of other keys that have expired
+		if err := database.DB.Where("expires_at <= ?", now).Delete(&entities.IdempotencyRecord{}).Error; err != nil {
+			log.Printf("Failed to delete expired idempotency keys: %v", err)
+		}
+		return record, true, nil
+	}
+
+	var existing entities.IdempotencyRecord
+	if err := database.DB.Where("account_id = ? AND key = ?", accountID, key).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"log"
+	"strings"
+	"time"
+)
+
+// RecordLockout writes an audit record of a lockout. Failures are logged, they must not fail the request.
+func RecordLockout(scope string, key string, ipAddress string, accountID *uint, lockedUntil time.Time) {
+	log.Printf("Lockout of %s %q from %s until %s", scope, key, ipAddress, lockedUntil.Format(time.RFC3339))
+
+	record := entities.LockoutRecord{
+		Scope:       scope,
+		Key:         key,
+		AccountID:   accountID,
+		IPAddress:   ipAddress,
+		LockedUntil: lockedUntil,
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		log.Printf("Warning: Failed to record lockout of %s %q: %v", scope, key, err)
+	}
+}
+
+// MaskKey hides all but the last four characters of a secret key, such as a gift card code
+func MaskKey(key string) string {
+	runes := []rune(key)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
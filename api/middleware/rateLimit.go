@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"VersatilePOS/generic/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore counts requests per key in fixed windows. Stores shared between instances,
+// e.g. backed by Redis, can be plugged in with SetRateLimitStore.
+type RateLimitStore interface {
+	// Increment records a request for key and returns the requests in its current window and when the window ends
+	Increment(key string, window time.Duration) (int, time.Time, error)
+}
+
+// MemoryRateLimitStore keeps counters in memory, it is only shared within one instance
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start  time.Time
+	window time.Duration
+	count  int
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateWindow)}
+}
+
+func (s *MemoryRateLimitStore) Increment(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	w, ok := s.windows[key]
+	if !ok || now.Sub(w.start) >= w.window {
+		s.prune(now)
+		w = &rateWindow{start: now, window: window}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.start.Add(w.window), nil
+}
+
+// prune drops windows that have run out so the map does not grow unbounded
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= w.window {
+			delete(s.windows, key)
+		}
+	}
+}
+
+var (
+	sharedStoreMu sync.RWMutex
+	sharedStore   RateLimitStore = NewMemoryRateLimitStore()
+)
+
+// SetRateLimitStore replaces the store used by scoped rate limiters
+func SetRateLimitStore(store RateLimitStore) {
+	sharedStoreMu.Lock()
+	defer sharedStoreMu.Unlock()
+	sharedStore = store
+}
+
+func sharedRateLimitStore() RateLimitStore {
+	sharedStoreMu.RLock()
+	defer sharedStoreMu.RUnlock()
+	return sharedStore
+}
+
+// RateLimiter allows up to limit requests per key within a fixed window
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	store  RateLimitStore
+
+	// scope prefixes keys in the shared store and names the limiter in lockout audit records, empty for unscoped limiters
+	scope   string
+	maskKey bool
+}
+
+// NewRateLimiter creates a limiter with its own in-memory counters
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		store:  NewMemoryRateLimitStore(),
+	}
+}
+
+// NewScopedRateLimiter creates a limiter counting in the shared store. Keys that hit the limit are recorded
+// as lockouts under scope; maskKey hides all but the end of the key in those records, e.g. for gift card codes.
+func NewScopedRateLimiter(scope string, limit int, window time.Duration, maskKey bool) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		scope:   scope,
+		maskKey: maskKey,
+	}
+}
+
+// Allow records a request for key and reports whether it is within the limit, and if not, how long until the window resets
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	allowed, retryAfter, _ := l.allow(key)
+	return allowed, retryAfter
+}
+
+// allow additionally reports whether this request is the one that hit the limit
+func (l *RateLimiter) allow(key string) (bool, time.Duration, bool) {
+	store := l.store
+	if store == nil {
+		store = sharedRateLimitStore()
+		key = l.scope + ":" + key
+	}
+
+	count, resetAt, err := store.Increment(key, l.window)
+	if err != nil {
+		// Failing open keeps the API usable when a shared store is unavailable
+		log.Printf("Warning: Rate limit store failed for %s: %v", l.scope, err)
+		return true, 0, false
+	}
+
+	if count > l.limit {
+		return false, time.Until(resetAt), count == l.limit+1
+	}
+	return true, 0, false
+}
+
+// RateLimitMiddleware rejects requests with 429 once the limiter's limit for the request's key is reached.
+// Requests without a key are not limited, and key functions may reject a request themselves by aborting it.
+func RateLimitMiddleware(limiter *RateLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if c.IsAborted() {
+			return
+		}
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, limitHit := limiter.allow(k)
+		if !allowed {
+			if limitHit && limiter.scope != "" {
+				auditKey := k
+				if limiter.maskKey {
+					auditKey = MaskKey(k)
+				}
+				RecordLockout(limiter.scope, auditKey, c.ClientIP(), nil, time.Now().Add(retryAfter))
+			}
+
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.HTTPError{Error: "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClientIPKey limits requests per client IP
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// JSONBodyKey limits requests per value of a string field of the JSON request body, compared case-insensitively.
+// The body is restored for the handler. Bodies too large to inspect are rejected with 413.
+func JSONBodyKey(field string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		raw, err := peekJSONField(c, field)
+		if errors.Is(err, errBodyTooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.HTTPError{Error: err.Error()})
+			return ""
+		}
+		if err != nil || raw == nil {
+			return ""
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// IsTokenRevoked reports whether the access token with the given jti is on the denylist
+func IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&entities.RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now()).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeToken denylists an access token until it expires
+func RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	// Entries of tokens that have expired anyway are no longer needed
+	if err := database.DB.Where("expires_at <= ?", time.Now()).Delete(&entities.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&entities.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
@@ -2,12 +2,15 @@ package controller
 
 import (
 	"VersatilePOS/generic/models"
+	"VersatilePOS/generic/rbac"
 	"VersatilePOS/middleware"
 	orderModels "VersatilePOS/order/models"
 	"VersatilePOS/order/service"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +19,8 @@ type Controller struct {
 	service *service.Service
 }
 
+const approvalTokenHeader = "X-Approval-Token"
+
 func NewController() *Controller {
 	return &Controller{
 		service: service.NewService(),
@@ -23,15 +28,18 @@ func NewController() *Controller {
 }
 
 // @Summary Create order
-// @Description Create a new order. Requires authentication and Orders Write permission for the business.
+// @Description Create a new order, at one of the business's locations once it has any. The order is in the business's currency, and absolute price modifiers have to be in it too. Requires authentication and Orders Write permission for the business, plus Apply Discounts with limits covering the combined discount for discount modifiers.
 // @Tags order
 // @Accept  json
 // @Produce  json
 // @Param   order  body  models.CreateOrderRequest  true  "Order to create"
+// @Param   X-Approval-Token  header  string  false  "Token of a manager approval, when the user lacks the permission"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
 // @Success 201 {object} models.OrderDto
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
-// @Failure 403 {object} models.HTTPError
+// @Failure 403 {object} models.ApprovalRequired
+// @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
 // @Security BearerAuth
 // @Router /order [post]
@@ -54,13 +62,17 @@ func (ctrl *Controller) CreateOrder(c *gin.Context) {
 		req.ServicingAccountID = &userID
 	}
 
-	order, err := ctrl.service.CreateOrder(req, userID)
+	order, err := ctrl.service.CreateOrder(req, userID, middleware.GetTokenBusinessIDFromContext(c), c.GetHeader(approvalTokenHeader))
 	if err != nil {
-		if err.Error() == "unauthorized to create orders for this business" {
+		if writeApprovalError(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "insufficient stock for item" {
+		if err.Error() == "insufficient stock for item" || err.Error() == "location not found" || err.Error() == "locationId is required" ||
+			err.Error() == "price modifier currency does not match the order currency" {
 			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -73,7 +85,7 @@ func (ctrl *Controller) CreateOrder(c *gin.Context) {
 }
 
 // @Summary Get orders
-// @Description Get all orders for a business. Requires authentication and Orders Read permission.
+// @Description Get all orders for a business. Requires authentication and Orders Read permission. Orders of locations the user's roles do not apply at are left out.
 // @Tags order
 // @Produce  json
 // @Param   businessId  query  int  true  "Business ID to filter by"
@@ -104,7 +116,7 @@ func (ctrl *Controller) GetOrders(c *gin.Context) {
 		return
 	}
 
-	orders, err := ctrl.service.GetOrders(uint(businessID), userID)
+	orders, err := ctrl.service.GetOrders(uint(businessID), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "unauthorized to view orders for this business" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
@@ -145,7 +157,7 @@ func (ctrl *Controller) GetOrderByID(c *gin.Context) {
 		return
 	}
 
-	order, err := ctrl.service.GetOrderByID(uint(id), userID)
+	order, err := ctrl.service.GetOrderByID(uint(id), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
@@ -164,16 +176,17 @@ func (ctrl *Controller) GetOrderByID(c *gin.Context) {
 }
 
 // @Summary Update order
-// @Description Update order details (status, etc.). Requires authentication and Orders Write permission.
+// @Description Update order details (status, etc.). Requires authentication and Orders Write permission. Marking an order refunded also requires Refund within its limit, or a manager approval, and credits its gift card payments back to their cards.
 // @Tags order
 // @Accept  json
 // @Produce  json
 // @Param   id  path  int  true  "Order ID"
 // @Param   order  body  models.UpdateOrderRequest  true  "Order updates"
+// @Param   X-Approval-Token  header  string  false  "Token of a manager approval, when the user lacks the permission"
 // @Success 200 {object} models.OrderDto
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
-// @Failure 403 {object} models.HTTPError
+// @Failure 403 {object} models.ApprovalRequired
 // @Failure 404 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
 // @Security BearerAuth
@@ -198,8 +211,11 @@ func (ctrl *Controller) UpdateOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := ctrl.service.UpdateOrder(uint(id), req, userID)
+	order, err := ctrl.service.UpdateOrder(uint(id), req, userID, middleware.GetTokenBusinessIDFromContext(c), c.GetHeader(approvalTokenHeader))
 	if err != nil {
+		if writeApprovalError(c, err) {
+			return
+		}
 		if err.Error() == "order not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -208,7 +224,7 @@ func (ctrl *Controller) UpdateOrder(c *gin.Context) {
 			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to update this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -256,7 +272,7 @@ func (ctrl *Controller) AddItemToOrder(c *gin.Context) {
 		return
 	}
 
-	orderItem, err := ctrl.service.AddItemToOrder(uint(orderID), req, userID)
+	orderItem, err := ctrl.service.AddItemToOrder(uint(orderID), req, userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "item not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
@@ -266,7 +282,7 @@ func (ctrl *Controller) AddItemToOrder(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -309,7 +325,7 @@ func (ctrl *Controller) GetOrderItems(c *gin.Context) {
 		return
 	}
 
-	orderItems, err := ctrl.service.GetOrderItems(uint(orderID), userID)
+	orderItems, err := ctrl.service.GetOrderItems(uint(orderID), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "unauthorized to view this order" {
 			if err.Error() == "unauthorized to view this order" {
@@ -328,17 +344,18 @@ func (ctrl *Controller) GetOrderItems(c *gin.Context) {
 }
 
 // @Summary Update order item
-// @Description Update an order item. Requires authentication and Orders Write permission.
+// @Description Update an order item. Requires authentication and Orders Write permission. Lowering the count requires Void Items and changing the unit price requires Override Prices, each within its limits, or a manager approval.
 // @Tags order
 // @Accept  json
 // @Produce  json
 // @Param   orderId  path  int  true  "Order ID"
 // @Param   itemId  path  int  true  "Item ID"
 // @Param   item  body  models.UpdateOrderItemRequest  true  "Item updates"
+// @Param   X-Approval-Token  header  string  false  "Token of a manager approval, when the user lacks the permission"
 // @Success 200 {object} models.OrderItemDto
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
-// @Failure 403 {object} models.HTTPError
+// @Failure 403 {object} models.ApprovalRequired
 // @Failure 404 {object} models.HTTPError
 // @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
@@ -370,8 +387,11 @@ func (ctrl *Controller) UpdateOrderItem(c *gin.Context) {
 		return
 	}
 
-	orderItem, err := ctrl.service.UpdateOrderItem(uint(orderID), uint(itemID), req, userID)
+	orderItem, err := ctrl.service.UpdateOrderItem(uint(orderID), uint(itemID), req, userID, middleware.GetTokenBusinessIDFromContext(c), c.GetHeader(approvalTokenHeader))
 	if err != nil {
+		if writeApprovalError(c, err) {
+			return
+		}
 		if err.Error() == "order not found" || err.Error() == "order item not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -380,7 +400,7 @@ func (ctrl *Controller) UpdateOrderItem(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -397,14 +417,15 @@ func (ctrl *Controller) UpdateOrderItem(c *gin.Context) {
 }
 
 // @Summary Remove item from order
-// @Description Remove an item from an order. Requires authentication and Orders Write permission.
+// @Description Remove an item from an order. Requires authentication, Orders Write permission and Void Items within its limit, or a manager approval.
 // @Tags order
 // @Param   orderId  path  int  true  "Order ID"
 // @Param   itemId  path  int  true  "Item ID"
+// @Param   X-Approval-Token  header  string  false  "Token of a manager approval, when the user lacks the permission"
 // @Success 204
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
-// @Failure 403 {object} models.HTTPError
+// @Failure 403 {object} models.ApprovalRequired
 // @Failure 404 {object} models.HTTPError
 // @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
@@ -430,8 +451,11 @@ func (ctrl *Controller) RemoveItemFromOrder(c *gin.Context) {
 		return
 	}
 
-	err = ctrl.service.RemoveItemFromOrder(uint(orderID), uint(itemID), userID)
+	err = ctrl.service.RemoveItemFromOrder(uint(orderID), uint(itemID), userID, middleware.GetTokenBusinessIDFromContext(c), c.GetHeader(approvalTokenHeader))
 	if err != nil {
+		if writeApprovalError(c, err) {
+			return
+		}
 		if err.Error() == "order not found" || err.Error() == "order item not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -440,7 +464,7 @@ func (ctrl *Controller) RemoveItemFromOrder(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -453,16 +477,17 @@ func (ctrl *Controller) RemoveItemFromOrder(c *gin.Context) {
 }
 
 // @Summary Apply price modifier to order
-// @Description Apply a price modifier to the full order. Requires authentication and Orders Write permission.
+// @Description Apply a price modifier to the full order. Absolute modifiers have to be in the order's currency. Requires authentication and Orders Write permission, plus Apply Discounts with limits covering the order's combined discount for discounts.
 // @Tags order
 // @Accept  json
 // @Produce  json
 // @Param   orderId  path  int  true  "Order ID"
 // @Param   modifier  body  models.ApplyPriceModifierRequest  true  "Price modifier to apply"
+// @Param   X-Approval-Token  header  string  false  "Token of a manager approval, when the user lacks the permission"
 // @Success 201
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
-// @Failure 403 {object} models.HTTPError
+// @Failure 403 {object} models.ApprovalRequired
 // @Failure 404 {object} models.HTTPError
 // @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
@@ -488,13 +513,16 @@ func (ctrl *Controller) ApplyPriceModifierToOrder(c *gin.Context) {
 		return
 	}
 
-	err = ctrl.service.ApplyPriceModifierToOrder(uint(orderID), req, userID)
+	err = ctrl.service.ApplyPriceModifierToOrder(uint(orderID), req, userID, middleware.GetTokenBusinessIDFromContext(c), c.GetHeader(approvalTokenHeader))
 	if err != nil {
+		if writeApprovalError(c, err) {
+			return
+		}
 		if err.Error() == "order not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -502,6 +530,10 @@ func (ctrl *Controller) ApplyPriceModifierToOrder(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if err.Error() == "price modifier currency does not match the order currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to apply price modifier:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
@@ -553,7 +585,7 @@ func (ctrl *Controller) AddOptionToOrderItem(c *gin.Context) {
 		return
 	}
 
-	link, err := ctrl.service.AddOptionToOrderItem(uint(orderID), uint(itemID), req, userID)
+	link, err := ctrl.service.AddOptionToOrderItem(uint(orderID), uint(itemID), req, userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "order item not found" || err.Error() == "item option not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
@@ -563,7 +595,7 @@ func (ctrl *Controller) AddOptionToOrderItem(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -613,7 +645,7 @@ func (ctrl *Controller) GetItemOptionsInOrder(c *gin.Context) {
 		return
 	}
 
-	links, err := ctrl.service.GetItemOptionsInOrder(uint(orderID), uint(itemID), userID)
+	links, err := ctrl.service.GetItemOptionsInOrder(uint(orderID), uint(itemID), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "order item not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
@@ -672,7 +704,7 @@ func (ctrl *Controller) RemoveOptionFromOrderItem(c *gin.Context) {
 		return
 	}
 
-	err = ctrl.service.RemoveOptionFromOrderItem(uint(orderID), uint(itemID), uint(optionID), userID)
+	err = ctrl.service.RemoveOptionFromOrderItem(uint(orderID), uint(itemID), uint(optionID), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "order item not found" || err.Error() == "item option link not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
@@ -682,7 +714,7 @@ func (ctrl *Controller) RemoveOptionFromOrderItem(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -695,7 +727,7 @@ func (ctrl *Controller) RemoveOptionFromOrderItem(c *gin.Context) {
 }
 
 // @Summary Link payment to order
-// @Description Link a payment to an order. Requires authentication and Orders Write permission.
+// @Description Link a payment to an order. The payment has to be in the order's currency, and a payment taken against an order can only be linked to that order. Requires authentication and Orders Write permission.
 // @Tags order
 // @Param   orderId  path  int  true  "Order ID"
 // @Param   paymentId  path  int  true  "Payment ID"
@@ -728,17 +760,21 @@ func (ctrl *Controller) LinkPaymentToOrder(c *gin.Context) {
 		return
 	}
 
-	err = ctrl.service.LinkPaymentToOrder(uint(orderID), uint(paymentID), userID)
+	err = ctrl.service.LinkPaymentToOrder(uint(orderID), uint(paymentID), userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
 		if err.Error() == "order not found" || err.Error() == "payment not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "payment is already linked to this order" {
+		if err.Error() == "payment is already linked to this order" || err.Error() == "payment was taken against another order" {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-		if err.Error() == "unauthorized to modify this order" {
+		if err.Error() == "payment currency does not match the order currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "unauthorized") {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -752,7 +788,7 @@ func (ctrl *Controller) LinkPaymentToOrder(c *gin.Context) {
 
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	orderGroup := r.Group("/order")
-	orderGroup.Use(middleware.AuthMiddleware())
+	orderGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		orderGroup.POST("", ctrl.CreateOrder)
 		orderGroup.GET("", ctrl.GetOrders)
@@ -769,3 +805,24 @@ func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 		orderGroup.DELETE("/:id/item/:itemId/option/:optionId", ctrl.RemoveOptionFromOrderItem)
 	}
 }
+
+// writeApprovalError responds to a missing or rejected manager approval, reporting whether err was one
+func writeApprovalError(c *gin.Context, err error) bool {
+	var approvalErr *rbac.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		c.IndentedJSON(http.StatusForbidden, models.ApprovalRequired{
+			Error:      approvalErr.Error(),
+			Action:     string(approvalErr.Action),
+			Amount:     approvalErr.Amount,
+			Percentage: approvalErr.Percentage,
+		})
+		return true
+	}
+
+	switch err.Error() {
+	case "invalid approval", "approval does not cover this action":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		return true
+	}
+	return false
+}
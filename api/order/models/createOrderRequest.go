@@ -3,6 +3,7 @@ package models
 type CreateOrderRequest struct {
 	BusinessID         uint    `json:"businessId" validate:"required"`
 	ServicingAccountID *uint   `json:"servicingAccountId,omitempty"`
+	LocationID         *uint   `json:"locationId,omitempty"`
 	Customer           string  `json:"customer"`
 	CustomerEmail      string  `json:"customerEmail"`
 	CustomerPhone      string  `json:"customerPhone"`
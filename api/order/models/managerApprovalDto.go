@@ -0,0 +1,29 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+// ManagerApprovalDto is a manager approval that was used on the order
+type ManagerApprovalDto struct {
+	ID            uint       `json:"id"`
+	Action        string     `json:"action"`
+	RequestedByID uint       `json:"requestedById"`
+	ApprovedByID  uint       `json:"approvedById"`
+	Amount        *float64   `json:"amount,omitempty"`
+	Percentage    *float64   `json:"percentage,omitempty"`
+	UsedAt        *time.Time `json:"usedAt"`
+}
+
+func NewManagerApprovalDtoFromEntity(a entities.ManagerApproval) ManagerApprovalDto {
+	return ManagerApprovalDto{
+		ID:            a.ID,
+		Action:        string(a.Action),
+		RequestedByID: a.RequestedByID,
+		ApprovedByID:  a.ApprovedByID,
+		Amount:        a.Amount,
+		Percentage:    a.Percentage,
+		UsedAt:        a.UsedAt,
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/currency"
+)
+
+// OrderAdjustmentDto is a line a tender rule added to the order when a payment was taken against it
+type OrderAdjustmentDto struct {
+	ID        uint    `json:"id"`
+	PaymentID uint    `json:"paymentId"`
+	Type      string  `json:"type"`
+	Amount    float64 `json:"amount"`
+	// FormattedAmount is the amount with the order's currency, e.g. "USD -0.02"
+	FormattedAmount string `json:"formattedAmount"`
+}
+
+func NewOrderAdjustmentDtoFromEntity(a entities.OrderAdjustment, code string) OrderAdjustmentDto {
+	return OrderAdjustmentDto{
+		ID:              a.ID,
+		PaymentID:       a.PaymentID,
+		Type:            string(a.Type),
+		Amount:          a.Amount,
+		FormattedAmount: currency.Format(a.Amount, code),
+	}
+}
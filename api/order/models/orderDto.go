@@ -10,10 +10,12 @@ type OrderDto struct {
 	ID                 uint                                `json:"id"`
 	BusinessID         uint                                `json:"businessId"`
 	ServicingAccountID *uint                               `json:"servicingAccountId,omitempty"`
+	LocationID         *uint                               `json:"locationId,omitempty"`
 	DatePlaced         time.Time                           `json:"datePlaced"`
 	Status             string                              `json:"status"`
 	TipAmount          float64                             `json:"tipAmount"`
 	ServiceCharge      float64                             `json:"serviceCharge"`
+	Currency           string                              `json:"currency"`
 	Customer           string                              `json:"customer"`
 	CustomerEmail      string                              `json:"customerEmail"`
 	CustomerPhone      string                              `json:"customerPhone"`
@@ -21,13 +23,16 @@ type OrderDto struct {
 	ValidTo            *time.Time                          `json:"validTo,omitempty"`
 	PriceModifiers     []modelsas.PriceModifierDto         `json:"priceModifiers"`
 	Items              []OrderItemWithDetailsDto           `json:"items"`
+	Approvals          []ManagerApprovalDto                `json:"approvals,omitempty"`
+	Adjustments        []OrderAdjustmentDto                `json:"adjustments,omitempty"`
 }
 
 type OrderItemWithDetailsDto struct {
-	ID      uint                      `json:"id"`
-	ItemID  uint                      `json:"itemId"`
-	Count   uint32                    `json:"count"`
-	Options []ItemOptionLinkDto       `json:"options,omitempty"`
+	ID        uint                `json:"id"`
+	ItemID    uint                `json:"itemId"`
+	Count     uint32              `json:"count"`
+	UnitPrice *float64            `json:"unitPrice,omitempty"`
+	Options   []ItemOptionLinkDto `json:"options,omitempty"`
 }
 
 
@@ -46,21 +51,34 @@ func NewOrderDtoFromEntity(o entities.Order) OrderDto {
 		}
 
 		items = append(items, OrderItemWithDetailsDto{
-			ID:      orderItem.ID,
-			ItemID:  orderItem.ItemID,
-			Count:   orderItem.Count,
-			Options: options,
+			ID:        orderItem.ID,
+			ItemID:    orderItem.ItemID,
+			Count:     orderItem.Count,
+			UnitPrice: orderItem.UnitPrice,
+			Options:   options,
 		})
 	}
 
+	var approvals []ManagerApprovalDto
+	for _, approval := range o.ManagerApprovals {
+		approvals = append(approvals, NewManagerApprovalDtoFromEntity(approval))
+	}
+
+	var adjustments []OrderAdjustmentDto
+	for _, adjustment := range o.OrderAdjustments {
+		adjustments = append(adjustments, NewOrderAdjustmentDtoFromEntity(adjustment, o.Currency))
+	}
+
 	return OrderDto{
 		ID:                 o.ID,
 		BusinessID:         o.BusinessID,
 		ServicingAccountID: o.ServicingAccountID,
+		LocationID:         o.LocationID,
 		DatePlaced:         o.DatePlaced,
 		Status:             string(o.Status),
 		TipAmount:          o.TipAmount,
 		ServiceCharge:      o.ServiceCharge,
+		Currency:           o.Currency,
 		Customer:           o.Customer,
 		CustomerEmail:      o.CustomerEmail,
 		CustomerPhone:      o.CustomerPhone,
@@ -68,5 +86,7 @@ func NewOrderDtoFromEntity(o entities.Order) OrderDto {
 		ValidTo:            o.ValidTo,
 		PriceModifiers:     priceModifiers,
 		Items:              items,
+		Approvals:          approvals,
+		Adjustments:        adjustments,
 	}
 }
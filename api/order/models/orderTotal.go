@@ -0,0 +1,58 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"math"
+)
+
+// OrderTotal is what an order costs: its items with their options, less its discounts, plus its service charge, tip
+// and the adjustments tender rules made to it. Percentage discounts apply to the items subtotal, and order-level taxes
+// and surcharges are already part of the service charge, matching how the UI totals orders. The total is rounded to
+// the minor units of the order's currency.
+// The OrderItems.Item, OrderItems.ItemOptionLinks.ItemOption.PriceModifier, PriceModifierOrderLinks.PriceModifier and
+// OrderAdjustments relations must be loaded.
+func OrderTotal(order entities.Order) float64 {
+	subtotal := 0.0
+	for _, orderItem := range order.OrderItems {
+		price := orderItem.Item.Price
+		if orderItem.UnitPrice != nil {
+			price = *orderItem.UnitPrice
+		}
+
+		options := 0.0
+		for _, link := range orderItem.ItemOptionLinks {
+			pm := link.ItemOption.PriceModifier
+			change := pm.Value
+			if pm.IsPercentage {
+				change = price * pm.Value / 100
+			}
+			if pm.ModifierType == constants.Discount {
+				change = -math.Abs(change)
+			}
+			options += change * float64(link.Count)
+		}
+
+		subtotal += (price + options) * float64(orderItem.Count)
+	}
+
+	discounts := 0.0
+	for _, link := range order.PriceModifierOrderLinks {
+		pm := link.PriceModifier
+		if pm.ModifierType != constants.Discount {
+			continue
+		}
+		if pm.IsPercentage {
+			discounts += subtotal * pm.Value / 100
+		} else {
+			discounts += pm.Value
+		}
+	}
+
+	total := math.Max(0, subtotal-discounts+order.ServiceCharge+order.TipAmount)
+	for _, adjustment := range order.OrderAdjustments {
+		total += adjustment.Amount
+	}
+	return currency.Round(total, order.Currency)
+}
@@ -1,5 +1,6 @@
 package models
 
 type UpdateOrderItemRequest struct {
-	Count *uint32 `json:"count,omitempty" validate:"omitempty,gt=0"`
+	Count     *uint32  `json:"count,omitempty" validate:"omitempty,gt=0"`
+	UnitPrice *float64 `json:"unitPrice,omitempty" validate:"omitempty,gte=0"`
 }
@@ -9,13 +9,6 @@ import (
 
 type Repository struct{}
 
-func (r *Repository) CreateOrder(order *entities.Order) (*entities.Order, error) {
-	if result := database.DB.Create(order); result.Error != nil {
-		return nil, result.Error
-	}
-	return order, nil
-}
-
 func (r *Repository) GetOrders(businessID uint) ([]entities.Order, error) {
 	var orders []entities.Order
 	query := database.DB.Preload("OrderItems.Item").
@@ -28,7 +21,9 @@ func (r *Repository) GetOrders(businessID uint) ([]entities.Order, error) {
 		Preload("OrderPaymentLinks.Payment").
 		Preload("PriceModifierOrderLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
-		})
+		}).
+		Preload("ManagerApprovals", "used_at IS NOT NULL").
+		Preload("OrderAdjustments")
 	if businessID != 0 {
 		query = query.Where("business_id = ?", businessID)
 	}
@@ -50,7 +45,9 @@ func (r *Repository) GetOrderByID(id uint) (*entities.Order, error) {
 		Preload("OrderPaymentLinks.Payment").
 		Preload("PriceModifierOrderLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
-		}).First(&order, id); result.Error != nil {
+		}).
+		Preload("ManagerApprovals", "used_at IS NOT NULL").
+		Preload("OrderAdjustments").First(&order, id); result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -119,27 +116,6 @@ func (r *Repository) GetOrderItemByID(orderID, itemID uint) (*entities.OrderItem
 	return &orderItem, nil
 }
 
-func (r *Repository) UpdateOrderItem(orderItem *entities.OrderItem) error {
-	if result := database.DB.Save(orderItem); result.Error != nil {
-		return result.Error
-	}
-	return nil
-}
-
-func (r *Repository) DeleteOrderItem(orderItem *entities.OrderItem) error {
-	if result := database.DB.Delete(orderItem); result.Error != nil {
-		return result.Error
-	}
-	return nil
-}
-
-func (r *Repository) CreatePriceModifierOrderLink(link *entities.PriceModifierOrderLink) (*entities.PriceModifierOrderLink, error) {
-	if result := database.DB.Create(link); result.Error != nil {
-		return nil, result.Error
-	}
-	return link, nil
-}
-
 func (r *Repository) CreateItemOptionLink(link *entities.ItemOptionLink) (*entities.ItemOptionLink, error) {
 	if result := database.DB.Create(link); result.Error != nil {
 		return nil, result.Error
@@ -192,6 +168,15 @@ func (r *Repository) CreateOrderPaymentLink(link *entities.OrderPaymentLink) (*e
 	return link, nil
 }
 
+// GetOrderAdjustmentsByPaymentID gets the adjustments tender rules made to orders for a payment
+func (r *Repository) GetOrderAdjustmentsByPaymentID(paymentID uint) ([]entities.OrderAdjustment, error) {
+	var adjustments []entities.OrderAdjustment
+	if result := database.DB.Where("payment_id = ?", paymentID).Find(&adjustments); result.Error != nil {
+		return nil, result.Error
+	}
+	return adjustments, nil
+}
+
 // GetOrdersByPaymentID gets all orders linked to a specific payment
 func (r *Repository) GetOrdersByPaymentID(paymentID uint) ([]entities.Order, error) {
 	// First get the payment links for this payment
@@ -221,7 +206,14 @@ func (r *Repository) GetOrdersByPaymentID(paymentID uint) ([]entities.Order, err
 		Preload("OrderItems.ItemOptionLinks.ItemOption", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).
+		Preload("OrderItems.ItemOptionLinks.ItemOption.PriceModifier", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
 		Preload("OrderPaymentLinks.Payment").
+		Preload("PriceModifierOrderLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("OrderAdjustments").
 		Find(&orders); result.Error != nil {
 		return nil, result.Error
 	}
@@ -1,16 +1,21 @@
 package service
 
 import (
+	businessRepository "VersatilePOS/business/repository"
 	orderModels "VersatilePOS/order/models"
 	"VersatilePOS/order/repository"
 	itemRepository "VersatilePOS/item/repository"
+	giftCardService "VersatilePOS/giftCard/service"
 	paymentRepository "VersatilePOS/payment/repository"
 	priceModifierRepository "VersatilePOS/priceModifier/repository"
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
 	"VersatilePOS/generic/rbac"
+	locationRepository "VersatilePOS/location/repository"
 	"errors"
+	"fmt"
 	"log"
 	"gorm.io/gorm"
 	"time"
@@ -21,6 +26,9 @@ type Service struct {
 	itemRepo            itemRepository.Repository
 	paymentRepo         paymentRepository.Repository
 	priceModifierRepo   priceModifierRepository.Repository
+	locationRepo        locationRepository.Repository
+	businessRepo        businessRepository.Repository
+	giftCardService     *giftCardService.Service
 }
 
 func NewService() *Service {
@@ -29,9 +37,18 @@ func NewService() *Service {
 		itemRepo:          itemRepository.Repository{},
 		paymentRepo:       paymentRepository.Repository{},
 		priceModifierRepo: priceModifierRepository.Repository{},
+		locationRepo:      locationRepository.Repository{},
+		businessRepo:      businessRepository.Repository{},
+		giftCardService:   giftCardService.NewService(),
 	}
 }
 
+// priceModifierMatchesCurrency reports whether a price modifier can apply to an order in the given currency.
+// Percentages apply in any currency, absolute amounts only in their own.
+func priceModifierMatchesCurrency(pm *entities.PriceModifier, code string) bool {
+	return pm.IsPercentage || pm.Currency == code
+}
+
 // isOrderInFinalState checks if an order is in a final state (cannot be modified)
 func isOrderInFinalState(status constants.OrderStatus) bool {
 	return status == constants.OrderConfirmed || status == constants.OrderCompleted || status == constants.OrderRefunded
@@ -77,9 +94,9 @@ func (s *Service) checkItemOptionStockAvailability(itemOptionID uint, requestedQ
 	return nil
 }
 
-func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint) (*orderModels.OrderDto, error) {
+func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint, tokenBusinessID *uint, approvalToken string) (*orderModels.OrderDto, error) {
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, req.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, req.BusinessID, userID, rbac.Scope{LocationID: req.LocationID, TokenBusinessID: tokenBusinessID})
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -87,6 +104,25 @@ func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint) (
 		return nil, errors.New("unauthorized to create orders for this business")
 	}
 
+	if req.LocationID != nil {
+		location, err := s.locationRepo.GetLocationByID(*req.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if location == nil || location.BusinessID != req.BusinessID {
+			return nil, errors.New("location not found")
+		}
+	} else {
+		// Once a business has locations every order is placed at one, so location-limited roles apply to it
+		locations, err := s.locationRepo.GetLocations(req.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		if len(locations) > 0 {
+			return nil, errors.New("locationId is required")
+		}
+	}
+
 	// Validate items belong to the same business and check stock availability
 	for _, itemID := range req.ItemIDs {
 		itemEntity, _, err := s.itemRepo.GetItemByID(itemID)
@@ -109,7 +145,18 @@ func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint) (
 		}
 	}
 
-	// Validate price modifiers belong to the same business
+	// Orders are in the currency the business uses when they are placed
+	orderCurrency, err := s.businessRepo.GetBusinessCurrency(req.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	if orderCurrency == "" {
+		return nil, errors.New("business not found")
+	}
+
+	// Validate price modifiers belong to the same business and discounts are within the user's permissions
+	draft := &entities.Order{BusinessID: req.BusinessID, LocationID: req.LocationID, Currency: orderCurrency}
+	var priceModifiers []*entities.PriceModifier
 	for _, priceModifierID := range req.PriceModifierIDs {
 		priceModifierEntity, err := s.priceModifierRepo.GetPriceModifierByID(priceModifierID, req.BusinessID)
 		if err != nil {
@@ -121,56 +168,60 @@ func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint) (
 		if priceModifierEntity.EndDate != nil && time.Now().After(*priceModifierEntity.EndDate) {
 			return nil, errors.New("cannot apply expired price modifier")
 		}
+		if !priceModifierMatchesCurrency(priceModifierEntity, orderCurrency) {
+			return nil, errors.New("price modifier currency does not match the order currency")
+		}
+		priceModifiers = append(priceModifiers, priceModifierEntity)
+	}
+	approval, err := checkDiscountPermission(priceModifiers, draft, userID, tokenBusinessID, approvalToken)
+	if err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
 	order := &entities.Order{
 		BusinessID:         req.BusinessID,
 		ServicingAccountID: req.ServicingAccountID,
+		LocationID:         req.LocationID,
 		DatePlaced:         now,
 		Status:             constants.OrderPending,
 		TipAmount:          req.TipAmount,
 		ServiceCharge:      req.ServiceCharge,
+		Currency:           orderCurrency,
 		Customer:           req.Customer,
 		CustomerEmail:      req.CustomerEmail,
 		CustomerPhone:      req.CustomerPhone,
 		ValidFrom:          &now,
 	}
 
-	createdOrder, err := s.repo.CreateOrder(order)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create order items (default count of 1 for each item)
-	for _, itemID := range req.ItemIDs {
-		orderItem := &entities.OrderItem{
-			OrderID: createdOrder.ID,
-			ItemID:  itemID,
-			Count:   1, // Default count of 1
+	// The order, its lines and the approval they were made with are saved together
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
 		}
 
-		_, err := s.repo.CreateOrderItem(orderItem)
-		if err != nil {
-			return nil, err
+		// Create order items (default count of 1 for each item)
+		for _, itemID := range req.ItemIDs {
+			if err := tx.Create(&entities.OrderItem{OrderID: order.ID, ItemID: itemID, Count: 1}).Error; err != nil {
+				return err
+			}
 		}
-	}
 
-	// Create price modifier links
-	for _, priceModifierID := range req.PriceModifierIDs {
-		link := &entities.PriceModifierOrderLink{
-			PriceModifierID: priceModifierID,
-			OrderID:         createdOrder.ID,
+		// Create price modifier links
+		for _, priceModifierID := range req.PriceModifierIDs {
+			if err := tx.Create(&entities.PriceModifierOrderLink{PriceModifierID: priceModifierID, OrderID: order.ID}).Error; err != nil {
+				return err
+			}
 		}
 
-		_, err := s.repo.CreatePriceModifierOrderLink(link)
-		if err != nil {
-			return nil, err
-		}
+		return approval.Use(tx, order.ID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Fetch the complete order with all relationships
-	completeOrder, err := s.repo.GetOrderByID(createdOrder.ID)
+	completeOrder, err := s.repo.GetOrderByID(order.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -179,9 +230,9 @@ func (s *Service) CreateOrder(req orderModels.CreateOrderRequest, userID uint) (
 	return &dto, nil
 }
 
-func (s *Service) GetOrders(businessID uint, userID uint) ([]orderModels.OrderDto, error) {
+func (s *Service) GetOrders(businessID uint, userID uint, tokenBusinessID *uint) ([]orderModels.OrderDto, error) {
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Read, businessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Read, businessID, userID, rbac.Scope{TokenBusinessID: tokenBusinessID})
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -194,15 +245,30 @@ func (s *Service) GetOrders(businessID uint, userID uint) ([]orderModels.OrderDt
 		return nil, err
 	}
 
+	// Orders of locations none of the user's roles apply at are left out
+	locationAccess := make(map[uint]bool)
 	var orderDtos []orderModels.OrderDto
 	for _, order := range orders {
+		if order.LocationID != nil {
+			allowed, checked := locationAccess[*order.LocationID]
+			if !checked {
+				allowed, err = rbac.HasScopedAccess(constants.Orders, constants.Read, businessID, userID, orderScope(&order, tokenBusinessID))
+				if err != nil {
+					return nil, errors.New("failed to verify permissions")
+				}
+				locationAccess[*order.LocationID] = allowed
+			}
+			if !allowed {
+				continue
+			}
+		}
 		orderDtos = append(orderDtos, orderModels.NewOrderDtoFromEntity(order))
 	}
 
 	return orderDtos, nil
 }
 
-func (s *Service) GetOrderByID(id uint, userID uint) (*orderModels.OrderDto, error) {
+func (s *Service) GetOrderByID(id uint, userID uint, tokenBusinessID *uint) (*orderModels.OrderDto, error) {
 	order, err := s.repo.GetOrderByID(id)
 	if err != nil {
 		return nil, err
@@ -212,7 +278,7 @@ func (s *Service) GetOrderByID(id uint, userID uint) (*orderModels.OrderDto, err
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Read, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Read, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -224,7 +290,7 @@ func (s *Service) GetOrderByID(id uint, userID uint) (*orderModels.OrderDto, err
 	return &dto, nil
 }
 
-func (s *Service) UpdateOrder(id uint, req orderModels.UpdateOrderRequest, userID uint) (*orderModels.OrderDto, error) {
+func (s *Service) UpdateOrder(id uint, req orderModels.UpdateOrderRequest, userID uint, tokenBusinessID *uint, approvalToken string) (*orderModels.OrderDto, error) {
 	order, err := s.repo.GetOrderByID(id)
 	if err != nil {
 		return nil, err
@@ -234,7 +300,7 @@ func (s *Service) UpdateOrder(id uint, req orderModels.UpdateOrderRequest, userI
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -243,13 +309,36 @@ func (s *Service) UpdateOrder(id uint, req orderModels.UpdateOrderRequest, userI
 	}
 
 	if req.Status != nil {
-		order.Status = constants.OrderStatus(*req.Status)
+		status := constants.OrderStatus(*req.Status)
 		// Validate status
-		if order.Status != constants.OrderPending && order.Status != constants.OrderConfirmed &&
-			order.Status != constants.OrderCompleted && order.Status != constants.OrderRefunded &&
-			order.Status != constants.OrderCancelled {
+		if status != constants.OrderPending && status != constants.OrderConfirmed &&
+			status != constants.OrderCompleted && status != constants.OrderRefunded &&
+			status != constants.OrderCancelled {
 			return nil, errors.New("invalid order status")
 		}
+		if status == constants.OrderRefunded && order.Status != constants.OrderRefunded {
+			approval, err := checkRefundPermission(order, userID, tokenBusinessID, approvalToken)
+			if err != nil {
+				return nil, err
+			}
+			// The approval is used up before any money moves, in the same transaction as the gift card ledger, so a
+			// failed refund leaves it unused and a used approval cannot refund twice
+			if err := database.DB.Transaction(func(tx *gorm.DB) error {
+				if err := approval.Use(tx, order.ID); err != nil {
+					return err
+				}
+				if err := s.refundGiftCardPayments(tx, order, userID); err != nil {
+					return err
+				}
+				if err := s.giftCardService.VoidForOrder(tx, order.BusinessID, order.ID, &userID); err != nil {
+					return err
+				}
+				return tx.Model(&entities.Order{}).Where("id = ?", order.ID).Update("status", constants.OrderRefunded).Error
+			}); err != nil {
+				return nil, err
+			}
+		}
+		order.Status = status
 	}
 	if req.TipAmount != nil {
 		order.TipAmount = *req.TipAmount
@@ -276,7 +365,27 @@ func (s *Service) UpdateOrder(id uint, req orderModels.UpdateOrderRequest, userI
 	return &dto, nil
 }
 
-func (s *Service) AddItemToOrder(orderID uint, req orderModels.CreateOrderItemRequest, userID uint) (*orderModels.OrderItemDto, error) {
+// refundGiftCardPayments credits the completed gift card payments of a refunded order back to their cards within the
+// refund's transaction
+func (s *Service) refundGiftCardPayments(tx *gorm.DB, order *entities.Order, userID uint) error {
+	for i, link := range order.OrderPaymentLinks {
+		payment := link.Payment
+		if payment.Type != constants.GiftCard || payment.Status != constants.Completed {
+			continue
+		}
+
+		_, err := s.giftCardService.RefundPayment(tx, payment, userID, func(tx *gorm.DB) error {
+			return s.paymentRepo.RefundCompletedPayment(tx, payment.ID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refund gift card payment %d: %w", payment.ID, err)
+		}
+		order.OrderPaymentLinks[i].Payment.Status = constants.Refunded
+	}
+	return nil
+}
+
+func (s *Service) AddItemToOrder(orderID uint, req orderModels.CreateOrderItemRequest, userID uint, tokenBusinessID *uint) (*orderModels.OrderItemDto, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
@@ -286,7 +395,7 @@ func (s *Service) AddItemToOrder(orderID uint, req orderModels.CreateOrderItemRe
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -334,7 +443,7 @@ func (s *Service) AddItemToOrder(orderID uint, req orderModels.CreateOrderItemRe
 	return &dto, nil
 }
 
-func (s *Service) GetOrderItems(orderID uint, userID uint) ([]orderModels.OrderItemDto, error) {
+func (s *Service) GetOrderItems(orderID uint, userID uint, tokenBusinessID *uint) ([]orderModels.OrderItemDto, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
@@ -344,7 +453,7 @@ func (s *Service) GetOrderItems(orderID uint, userID uint) ([]orderModels.OrderI
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Read, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Read, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -365,7 +474,7 @@ func (s *Service) GetOrderItems(orderID uint, userID uint) ([]orderModels.OrderI
 	return orderItemDtos, nil
 }
 
-func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOrderItemRequest, userID uint) (*orderModels.OrderItemDto, error) {
+func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOrderItemRequest, userID uint, tokenBusinessID *uint, approvalToken string) (*orderModels.OrderItemDto, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
@@ -375,7 +484,7 @@ func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOr
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -396,6 +505,7 @@ func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOr
 		return nil, errors.New("order item not found")
 	}
 
+	var voidApproval, overrideApproval *rbac.Approval
 	if req.Count != nil {
 		// If increasing the count, check if there's enough stock for the additional quantity
 		if *req.Count > orderItem.Count {
@@ -404,10 +514,38 @@ func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOr
 				return nil, err
 			}
 		}
+		// Lowering the count voids the removed units
+		if *req.Count < orderItem.Count {
+			voidApproval, err = checkVoidPermission(order, orderItem, orderItem.Count-*req.Count, userID, tokenBusinessID, approvalToken)
+			if err != nil {
+				return nil, err
+			}
+		}
 		orderItem.Count = *req.Count
 	}
 
-	err = s.repo.UpdateOrderItem(orderItem)
+	if req.UnitPrice != nil {
+		overrideApproval, err = checkPriceOverridePermission(order, orderItem, *req.UnitPrice, userID, tokenBusinessID, approvalToken)
+		if err != nil {
+			return nil, err
+		}
+		// Setting the item's own price clears the override
+		if *req.UnitPrice == orderItem.Item.Price {
+			orderItem.UnitPrice = nil
+		} else {
+			orderItem.UnitPrice = req.UnitPrice
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := voidApproval.Use(tx, orderID); err != nil {
+			return err
+		}
+		if err := overrideApproval.Use(tx, orderID); err != nil {
+			return err
+		}
+		return tx.Save(orderItem).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -416,7 +554,7 @@ func (s *Service) UpdateOrderItem(orderID, itemID uint, req orderModels.UpdateOr
 	return &dto, nil
 }
 
-func (s *Service) RemoveItemFromOrder(orderID, itemID uint, userID uint) error {
+func (s *Service) RemoveItemFromOrder(orderID, itemID uint, userID uint, tokenBusinessID *uint, approvalToken string) error {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
@@ -426,7 +564,7 @@ func (s *Service) RemoveItemFromOrder(orderID, itemID uint, userID uint) error {
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return errors.New("failed to verify permissions")
 	}
@@ -447,10 +585,20 @@ func (s *Service) RemoveItemFromOrder(orderID, itemID uint, userID uint) error {
 		return errors.New("order item not found")
 	}
 
-	return s.repo.DeleteOrderItem(orderItem)
+	approval, err := checkVoidPermission(order, orderItem, orderItem.Count, userID, tokenBusinessID, approvalToken)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := approval.Use(tx, orderID); err != nil {
+			return err
+		}
+		return tx.Delete(orderItem).Error
+	})
 }
 
-func (s *Service) ApplyPriceModifierToOrder(orderID uint, req orderModels.ApplyPriceModifierRequest, userID uint) error {
+func (s *Service) ApplyPriceModifierToOrder(orderID uint, req orderModels.ApplyPriceModifierRequest, userID uint, tokenBusinessID *uint, approvalToken string) error {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
@@ -460,7 +608,7 @@ func (s *Service) ApplyPriceModifierToOrder(orderID uint, req orderModels.ApplyP
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return errors.New("failed to verify permissions")
 	}
@@ -484,17 +632,28 @@ func (s *Service) ApplyPriceModifierToOrder(orderID uint, req orderModels.ApplyP
 	if pm.EndDate != nil && time.Now().After(*pm.EndDate) {
 		return errors.New("cannot apply expired price modifier")
 	}
+	if !priceModifierMatchesCurrency(pm, order.Currency) {
+		return errors.New("price modifier currency does not match the order currency")
+	}
+	approval, err := checkDiscountPermission([]*entities.PriceModifier{pm}, order, userID, tokenBusinessID, approvalToken)
+	if err != nil {
+		return err
+	}
 
 	link := &entities.PriceModifierOrderLink{
 		PriceModifierID: req.PriceModifierID,
 		OrderID:         orderID,
 	}
 
-	_, err = s.repo.CreatePriceModifierOrderLink(link)
-	return err
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := approval.Use(tx, orderID); err != nil {
+			return err
+		}
+		return tx.Create(link).Error
+	})
 }
 
-func (s *Service) AddOptionToOrderItem(orderID, itemID uint, req orderModels.CreateItemOptionLinkRequest, userID uint) (*orderModels.ItemOptionLinkDto, error) {
+func (s *Service) AddOptionToOrderItem(orderID, itemID uint, req orderModels.CreateItemOptionLinkRequest, userID uint, tokenBusinessID *uint) (*orderModels.ItemOptionLinkDto, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
@@ -504,7 +663,7 @@ func (s *Service) AddOptionToOrderItem(orderID, itemID uint, req orderModels.Cre
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -561,7 +720,7 @@ func (s *Service) AddOptionToOrderItem(orderID, itemID uint, req orderModels.Cre
 	return &dto, nil
 }
 
-func (s *Service) GetItemOptionsInOrder(orderID, itemID uint, userID uint) ([]orderModels.ItemOptionLinkDto, error) {
+func (s *Service) GetItemOptionsInOrder(orderID, itemID uint, userID uint, tokenBusinessID *uint) ([]orderModels.ItemOptionLinkDto, error) {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
@@ -571,7 +730,7 @@ func (s *Service) GetItemOptionsInOrder(orderID, itemID uint, userID uint) ([]or
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Read, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Read, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return nil, errors.New("failed to verify permissions")
 	}
@@ -601,7 +760,7 @@ func (s *Service) GetItemOptionsInOrder(orderID, itemID uint, userID uint) ([]or
 	return linkDtos, nil
 }
 
-func (s *Service) RemoveOptionFromOrderItem(orderID, itemID, optionID uint, userID uint) error {
+func (s *Service) RemoveOptionFromOrderItem(orderID, itemID, optionID uint, userID uint, tokenBusinessID *uint) error {
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
@@ -611,7 +770,7 @@ func (s *Service) RemoveOptionFromOrderItem(orderID, itemID, optionID uint, user
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return errors.New("failed to verify permissions")
 	}
@@ -644,7 +803,7 @@ func (s *Service) RemoveOptionFromOrderItem(orderID, itemID, optionID uint, user
 	return s.repo.DeleteItemOptionLink(link)
 }
 
-func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint) error {
+func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint, tokenBusinessID *uint) error {
 	// Verify order exists
 	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
@@ -655,7 +814,7 @@ func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint) error
 	}
 
 	// Check RBAC permissions
-	ok, err := rbac.HasAccess(constants.Orders, constants.Write, order.BusinessID, userID)
+	ok, err := rbac.HasScopedAccess(constants.Orders, constants.Write, order.BusinessID, userID, orderScope(order, tokenBusinessID))
 	if err != nil {
 		return errors.New("failed to verify permissions")
 	}
@@ -671,9 +830,23 @@ func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint) error
 		}
 		return err
 	}
-	if payment == nil {
+	if payment == nil || payment.BusinessID != order.BusinessID {
 		return errors.New("payment not found")
 	}
+	if payment.Currency != order.Currency {
+		return errors.New("payment currency does not match the order currency")
+	}
+
+	// A payment taken against an order was adjusted for it, so it cannot pay for another one
+	adjustments, err := s.repo.GetOrderAdjustmentsByPaymentID(paymentID)
+	if err != nil {
+		return err
+	}
+	for _, adjustment := range adjustments {
+		if adjustment.OrderID != orderID {
+			return errors.New("payment was taken against another order")
+		}
+	}
 
 	// Check if link already exists
 	var existingLink entities.OrderPaymentLink
@@ -693,8 +866,17 @@ func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint) error
 		return err
 	}
 
-	// Update order status if payment is already completed and order is pending
+	// Confirm a pending order once its completed payments, including this one, cover its total
 	if payment.Status == constants.Completed && order.Status == constants.OrderPending {
+		order, err = s.repo.GetOrderByID(orderID)
+		if err != nil || order == nil {
+			log.Printf("Warning: Failed to reload order %d after linking completed payment: %v", orderID, err)
+			return nil
+		}
+		if currency.ToMinor(paidAmount(order), order.Currency) < currency.ToMinor(orderModels.OrderTotal(*order), order.Currency) {
+			return nil
+		}
+
 		order.Status = constants.OrderConfirmed
 		if err := s.repo.UpdateOrder(order); err != nil {
 			log.Printf("Warning: Failed to update order %d status after linking completed payment: %v", orderID, err)
@@ -703,6 +885,10 @@ func (s *Service) LinkPaymentToOrder(orderID, paymentID uint, userID uint) error
 			if err := s.decreaseOrderStock(orderID); err != nil {
 				log.Printf("Warning: Failed to decrease stock for order %d: %v", orderID, err)
 			}
+			// Issue the gift cards sold with the order
+			if err := s.giftCardService.IssueForOrder(orderID); err != nil {
+				log.Printf("Warning: Failed to issue gift cards for order %d: %v", orderID, err)
+			}
 		}
 	}
 
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/rbac"
+	"math"
+)
+
+// orderScope limits permission checks to the order's location and, for a terminal token, to the token's business
+func orderScope(order *entities.Order, tokenBusinessID *uint) rbac.Scope {
+	return rbac.Scope{LocationID: order.LocationID, TokenBusinessID: tokenBusinessID}
+}
+
+// checkOrderPermission requires a fine-grained permission at the order's location, within the role's limits. Without
+// it, the action needs a manager approval for this order, passed as approvalToken. The returned approval has to be
+// used in the transaction of the write.
+func checkOrderPermission(action constants.Action, order *entities.Order, userID uint, tokenBusinessID *uint, amount *float64, percentage *float64, approvalToken string) (*rbac.Approval, error) {
+	scope := orderScope(order, tokenBusinessID)
+	scope.Amount = amount
+	scope.Percentage = percentage
+
+	// Orders that are still being created have no ID, their approvals are attached once they exist
+	var orderID *uint
+	if order.ID != 0 {
+		orderID = &order.ID
+	}
+	return rbac.AuthorizeWithApproval(action, order.BusinessID, userID, scope, orderID, approvalToken)
+}
+
+// checkDiscountPermission requires Apply Discounts for adding discount modifiers to an order. The limits apply to
+// the order's combined discount, its existing discounts and the added ones, so discounts cannot be stacked past them.
+func checkDiscountPermission(added []*entities.PriceModifier, order *entities.Order, userID uint, tokenBusinessID *uint, approvalToken string) (*rbac.Approval, error) {
+	hasDiscount := false
+	for _, pm := range added {
+		if pm.ModifierType == constants.Discount {
+			hasDiscount = true
+		}
+	}
+	if !hasDiscount {
+		return nil, nil
+	}
+
+	discounts := added
+	for i := range order.PriceModifierOrderLinks {
+		discounts = append(discounts, &order.PriceModifierOrderLinks[i].PriceModifier)
+	}
+
+	var amount, percentage float64
+	var hasAmount, hasPercentage bool
+	for _, pm := range discounts {
+		if pm.ModifierType != constants.Discount {
+			continue
+		}
+		if pm.IsPercentage {
+			percentage += pm.Value
+			hasPercentage = true
+		} else {
+			amount += pm.Value
+			hasAmount = true
+		}
+	}
+
+	var scopeAmount, scopePercentage *float64
+	if hasAmount {
+		scopeAmount = &amount
+	}
+	if hasPercentage {
+		scopePercentage = &percentage
+	}
+	return checkOrderPermission(constants.ApplyDiscount, order, userID, tokenBusinessID, scopeAmount, scopePercentage, approvalToken)
+}
+
+// checkVoidPermission requires Void Items for removing count units of an order item, limited by their value
+func checkVoidPermission(order *entities.Order, orderItem *entities.OrderItem, count uint32, userID uint, tokenBusinessID *uint, approvalToken string) (*rbac.Approval, error) {
+	amount := unitPrice(orderItem) * float64(count)
+	return checkOrderPermission(constants.VoidItem, order, userID, tokenBusinessID, &amount, nil, approvalToken)
+}
+
+// checkPriceOverridePermission requires Override Prices for charging an order item at a different unit price,
+// limited by the total difference and the difference relative to the item's price
+func checkPriceOverridePermission(order *entities.Order, orderItem *entities.OrderItem, price float64, userID uint, tokenBusinessID *uint, approvalToken string) (*rbac.Approval, error) {
+	difference := math.Abs(orderItem.Item.Price - price)
+	amount := difference * float64(orderItem.Count)
+
+	var percentage *float64
+	if orderItem.Item.Price > 0 {
+		p := difference / orderItem.Item.Price * 100
+		percentage = &p
+	}
+	return checkOrderPermission(constants.OverridePrice, order, userID, tokenBusinessID, &amount, percentage, approvalToken)
+}
+
+// checkRefundPermission requires Refund, limited by the amount the order was paid
+func checkRefundPermission(order *entities.Order, userID uint, tokenBusinessID *uint, approvalToken string) (*rbac.Approval, error) {
+	amount := paidAmount(order)
+	return checkOrderPermission(constants.Refund, order, userID, tokenBusinessID, &amount, nil, approvalToken)
+}
+
+// unitPrice is the price an order item is charged at
+func unitPrice(orderItem *entities.OrderItem) float64 {
+	if orderItem.UnitPrice != nil {
+		return *orderItem.UnitPrice
+	}
+	return orderItem.Item.Price
+}
+
+// paidAmount sums the completed payments of an order
+func paidAmount(order *entities.Order) float64 {
+	total := 0.0
+	for _, link := range order.OrderPaymentLinks {
+		if link.Payment.Status == constants.Completed {
+			total += link.Payment.Amount
+		}
+	}
+	return total
+}
@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	paymentModels "VersatilePOS/payment/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Create a payment customer
+// @Description Create a customer at the payment gateway for a customer of a business, identified by their email, so cards can be saved for them. An existing customer with the email is returned with 200 instead. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   customer  body  models.CreatePaymentCustomerRequest  true  "Customer to create"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 201 {object} models.PaymentCustomerDto
+// @Success 200 {object} models.PaymentCustomerDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer [post]
+// @Id createPaymentCustomer
+func (ctrl *Controller) CreatePaymentCustomer(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var req paymentModels.CreatePaymentCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	customer, created, err := ctrl.service.CreatePaymentCustomer(req, userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	if !created {
+		c.IndentedJSON(http.StatusOK, customer)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, customer)
+}
+
+// @Summary Get payment customers
+// @Description Get the payment customers of a business with their saved cards, or only the one with an email. Requires authentication and Payments Read permission.
+// @Tags payment
+// @Produce  json
+// @Param   businessId  query  int     true   "Business ID to filter by"
+// @Param   email       query  string  false  "Customer email to filter by"
+// @Success 200 {array} models.PaymentCustomerDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer [get]
+// @Id getPaymentCustomers
+func (ctrl *Controller) GetPaymentCustomers(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessIDStr := c.Query("businessId")
+	if businessIDStr == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId query parameter is required"})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(businessIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	customers, err := ctrl.service.GetPaymentCustomers(uint(businessID), c.Query("email"), userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, customers)
+}
+
+// @Summary Get a payment customer
+// @Description Get a payment customer with their saved cards and the consent recorded for each. Requires authentication and Payments Read permission.
+// @Tags payment
+// @Produce  json
+// @Param   customerId  path  int  true  "Payment customer ID"
+// @Success 200 {object} models.PaymentCustomerDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer/{customerId} [get]
+// @Id getPaymentCustomer
+func (ctrl *Controller) GetPaymentCustomer(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var customerID uint
+	if _, err := fmt.Sscanf(c.Param("customerId"), "%d", &customerID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid customer ID"})
+		return
+	}
+
+	customer, err := ctrl.service.GetPaymentCustomer(customerID, userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, customer)
+}
+
+// @Summary Start saving a card
+// @Description Create a setup intent to save a card for a customer, recording the consent they gave to it being charged without them present and the staff member who recorded it. The client confirms the setup intent with the card using the returned client secret, which a replay of a retry with the same Idempotency-Key leaves out; the card is Pending until then and Active once the gateway reports it saved. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   customerId  path  int                              true  "Payment customer ID"
+// @Param   consent     body  models.CreateSetupIntentRequest  true  "Customer consent"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 201 {object} models.CreateSetupIntentResponse
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer/{customerId}/setup-intent [post]
+// @Id createPaymentSetupIntent
+func (ctrl *Controller) CreateSetupIntent(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var customerID uint
+	if _, err := fmt.Sscanf(c.Param("customerId"), "%d", &customerID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid customer ID"})
+		return
+	}
+
+	var req paymentModels.CreateSetupIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	response, err := ctrl.service.CreateSetupIntent(customerID, req, userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, response)
+}
+
+// @Summary Complete saving a card
+// @Description Record the card a confirmed setup intent saved without waiting for its webhook. The saved card is returned as it is at the gateway, still Pending if the customer has not confirmed it. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Produce  json
+// @Param   setupIntentId  path  string  true  "Setup intent ID"
+// @Success 200 {object} models.SavedPaymentMethodDto
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/setup-intent/{setupIntentId}/complete [post]
+// @Id completePaymentSetupIntent
+func (ctrl *Controller) CompleteSetupIntent(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	method, err := ctrl.service.CompleteSetupIntent(c.Param("setupIntentId"), userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, method)
+}
+
+// @Summary Detach a saved card
+// @Description Remove a saved card from its customer at the gateway so it cannot be charged again. The card stays listed as Detached with its consent record. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Produce  json
+// @Param   customerId  path  int  true  "Payment customer ID"
+// @Param   methodId    path  int  true  "Saved payment method ID"
+// @Success 200 {object} models.SavedPaymentMethodDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 502 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer/{customerId}/payment-method/{methodId} [delete]
+// @Id detachPaymentMethod
+func (ctrl *Controller) DetachPaymentMethod(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var customerID, methodID uint
+	if _, err := fmt.Sscanf(c.Param("customerId"), "%d", &customerID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid customer ID"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param("methodId"), "%d", &methodID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid payment method ID"})
+		return
+	}
+
+	method, err := ctrl.service.DetachPaymentMethod(customerID, methodID, userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, method)
+}
+
+// @Summary Charge a saved card
+// @Description Charge a customer's saved card without them present, for an order, e.g. a repeat order, or a reservation, e.g. a no-show fee; exactly one of orderId and reservationId is required. The payment is in the order's or reservation's currency and linked to it; an order's CreditCard tender rule adds its surcharge. Fails with 402 if the card is declined or would need the customer to authenticate. With the fake gateway, amounts ending in .01 are declined and amounts ending in .02 require authentication. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   customerId  path  int                            true  "Payment customer ID"
+// @Param   charge      body  models.ChargeSavedCardRequest  true  "Charge to make"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 201 {object} models.PaymentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 402 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/customer/{customerId}/charge [post]
+// @Id chargeSavedCard
+func (ctrl *Controller) ChargeSavedCard(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var customerID uint
+	if _, err := fmt.Sscanf(c.Param("customerId"), "%d", &customerID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid customer ID"})
+		return
+	}
+
+	var req paymentModels.ChargeSavedCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	payment, err := ctrl.service.ChargeSavedCard(customerID, req, userID)
+	if err != nil {
+		writeCustomerError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, payment)
+}
+
+// writeCustomerError responds with the error of managing a payment customer or their saved cards
+func writeCustomerError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "unauthorized":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+	case err.Error() == "customer not found" || err.Error() == "saved payment method not found":
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+	case err.Error() == "customer consent is required to save a card" || err.Error() == "either an order or a reservation is required" ||
+		err.Error() == "order not found" || err.Error() == "reservation not found" || err.Error() == "amount must be greater than 0":
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+	case err.Error() == "saved payment method is not active" || err.Error() == "saved payment method is already detached":
+		c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+	case err.Error() == "payment gateway is not configured":
+		c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+	case strings.HasPrefix(err.Error(), "charge declined"):
+		c.IndentedJSON(http.StatusPaymentRequired, models.HTTPError{Error: err.Error()})
+	case strings.HasPrefix(err.Error(), "detach failed"):
+		c.IndentedJSON(http.StatusBadGateway, models.HTTPError{Error: err.Error()})
+	default:
+		log.Println("Failed to manage payment customer:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+	}
+}
+
+func (ctrl *Controller) RegisterCustomerRoutes(rg *gin.RouterGroup) {
+	rg.POST("/customer", ctrl.CreatePaymentCustomer)
+	rg.GET("/customer", ctrl.GetPaymentCustomers)
+	rg.GET("/customer/:customerId", ctrl.GetPaymentCustomer)
+	rg.POST("/customer/:customerId/setup-intent", ctrl.CreateSetupIntent)
+	rg.DELETE("/customer/:customerId/payment-method/:methodId", ctrl.DetachPaymentMethod)
+	rg.POST("/customer/:customerId/charge", ctrl.ChargeSavedCard)
+	rg.POST("/setup-intent/:setupIntentId/complete", ctrl.CompleteSetupIntent)
+}
@@ -1,18 +1,19 @@
 package controller
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	paymentModels "VersatilePOS/payment/models"
 	"VersatilePOS/payment/service"
 	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
 
 	"github.com/gin-gonic/gin"
-	"github.com/stripe/stripe-go/v78"
 )
 
 type Controller struct {
@@ -26,30 +27,59 @@ func NewController() *Controller {
 }
 
 // @Summary Create a payment
-// @Description Create a payment with the provided details
+// @Description Create a payment with the provided details. The payment is in the business's currency, or the order's when it is taken against an order with orderId; the business's tender rule for the payment type then rounds a cash amount or adds a card surcharge, recorded on the order. Requires authentication and Payments Write permission. Gift card payments need the card's PIN if it has one and a card in the same currency. A gift card payment created as completed is redeemed like completing it, capped at the card's balance.
 // @Tags payment
 // @Accept  json
 // @Produce  json
 // @Param   payment  body  models.CreatePaymentRequest  true  "Payment to create"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
 // @Success 201 {object} models.PaymentDto
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 423 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /payment [post]
 // @Id createPayment
 func (ctrl *Controller) CreatePayment(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	var req paymentModels.CreatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		return
 	}
 
-	payment, err := ctrl.service.CreatePayment(req)
+	payment, err := ctrl.service.CreatePayment(req, userID)
 	if err != nil {
 		log.Println("Failed to create payment:", err)
-		if err.Error() == "invalid payment type" || err.Error() == "invalid payment status" {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "invalid payment type" || err.Error() == "invalid payment status" || err.Error() == "gift card not found" ||
+			err.Error() == "gift card currency does not match the payment currency" || err.Error() == "order not found" {
 			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if err.Error() == "gift card is not active" || err.Error() == "gift card has no balance" || err.Error() == "gift card has expired" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "gift card PIN required" || err.Error() == "invalid gift card PIN" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "gift card PIN is locked" {
+			c.IndentedJSON(http.StatusLocked, models.HTTPError{Error: err.Error()})
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
 	}
@@ -58,16 +88,43 @@ func (ctrl *Controller) CreatePayment(c *gin.Context) {
 }
 
 // @Summary Get all payments
-// @Description Get all payments
+// @Description Get all payments for a business. Requires authentication and Payments Read permission.
 // @Tags payment
 // @Produce  json
+// @Param   businessId  query  int  true  "Business ID to filter by"
 // @Success 200 {array} models.PaymentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /payment [get]
 // @Id getPayments
 func (ctrl *Controller) GetPayments(c *gin.Context) {
-	payments, err := ctrl.service.GetPayments()
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessIDStr := c.Query("businessId")
+	if businessIDStr == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId query parameter is required"})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(businessIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	payments, err := ctrl.service.GetPayments(uint(businessID), userID)
 	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to get payments:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
@@ -77,27 +134,46 @@ func (ctrl *Controller) GetPayments(c *gin.Context) {
 }
 
 // @Summary Create a Stripe payment intent
-// @Description Create a Stripe payment intent for card payments
+// @Description Create a payment intent with the configured payment gateway for card payments. The payment is in the order's currency, or the business's without an order; a given currency has to match it. The business's CreditCard tender rule adds its surcharge to the amount of an order's payment, or for a manually captured one to the amount captured, and the response has the resulting amount. A replay of a retry with the same Idempotency-Key leaves out the client secret. With the fake gateway, amounts ending in .01 (01 in minor units) are declined, amounts ending in .02 require 3DS authentication and other amounts succeed. With manualCapture the card is only authorized, e.g. to open a tab, and the payment is Authorized until it is captured or voided. Requires authentication and Payments Write permission.
 // @Tags payment
 // @Accept  json
 // @Produce  json
 // @Param   request  body  models.CreateStripePaymentRequest  true  "Stripe payment request"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
 // @Success 201 {object} models.CreateStripePaymentResponse
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /payment/stripe/create-intent [post]
 // @Id createStripePaymentIntent
 func (ctrl *Controller) CreateStripePaymentIntent(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	var req paymentModels.CreateStripePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		return
 	}
 
-	response, err := ctrl.service.CreateStripePaymentIntent(req)
+	response, err := ctrl.service.CreateStripePaymentIntent(req, userID)
 	if err != nil {
 		log.Println("Failed to create Stripe payment intent:", err)
-		if err.Error() == "Stripe service is not configured" {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "order not found" || err.Error() == "currency does not match the business currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "payment gateway is not configured" {
 			c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
 			return
 		}
@@ -108,14 +184,15 @@ func (ctrl *Controller) CreateStripePaymentIntent(c *gin.Context) {
 	c.IndentedJSON(http.StatusCreated, response)
 }
 
-// @Summary Handle Stripe webhook
-// @Description Handle webhook events from Stripe
+// @Summary Handle payment gateway webhook
+// @Description Receive webhook events from the payment gateway. The signature is verified by the configured gateway, and the event is stored and applied asynchronously with retries. Events are deduplicated by ID, so replays are acknowledged without effect. Payment intent outcomes, refunds and disputes update the payment and its orders; out-of-order events never move a payment back.
 // @Tags payment
 // @Accept  json
 // @Produce  json
 // @Success 200
 // @Failure 400 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
 // @Router /payment/stripe/webhook [post]
 // @Id handleStripeWebhook
 func (ctrl *Controller) HandleStripeWebhook(c *gin.Context) {
@@ -126,90 +203,83 @@ func (ctrl *Controller) HandleStripeWebhook(c *gin.Context) {
 		return
 	}
 
-	signature := c.GetHeader("Stripe-Signature")
-	
-	// Verify webhook signature and get event
-	// For local development, signature may be empty when using Stripe CLI without --forward-connect-to
-	event, err := ctrl.service.VerifyWebhookSignature(body, signature)
-	if err != nil {
-		log.Printf("Failed to verify webhook signature (this is OK for local dev): %v\n", err)
-		// For local development, try to parse event directly
-		if signature == "" {
-			var eventData stripe.Event
-			if jsonErr := json.Unmarshal(body, &eventData); jsonErr != nil {
-				c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "failed to parse webhook event"})
-				return
-			}
-			event = &eventData
-		} else {
-			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid signature"})
-			return
+	if err := ctrl.service.HandleWebhook(body, c.GetHeader("Stripe-Signature")); err != nil {
+		switch err.Error() {
+		case "invalid webhook signature":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		case "payment gateway is not configured":
+			c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+		default:
+			log.Println("Failed to handle webhook:", err)
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		}
+		return
 	}
 
-	// Handle the event
-	switch event.Type {
-	case "payment_intent.succeeded":
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		if err != nil {
-			log.Println("Failed to unmarshal payment intent:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
-			return
-		}
-		if err := ctrl.service.ConfirmStripePayment(paymentIntent.ID); err != nil {
-			log.Println("Failed to confirm payment:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
-			return
-		}
+	c.IndentedJSON(http.StatusOK, gin.H{"received": true})
+}
 
-	case "payment_intent.payment_failed":
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		if err != nil {
-			log.Println("Failed to unmarshal payment intent:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
-			return
-		}
-		if err := ctrl.service.UpdatePaymentStatusByString(paymentIntent.ID, "Failed"); err != nil {
-			log.Println("Failed to update payment status:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
-			return
-		}
+// @Summary Authenticate a fake payment intent
+// @Description Answer the 3DS challenge of a payment intent of the fake gateway, as the cardholder's bank would. Only available when PAYMENT_GATEWAY is fake. The resulting webhook is delivered asynchronously.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   intentId  path  string                                true  "Payment intent ID"
+// @Param   request   body  models.AuthenticateFakeIntentRequest  true  "Whether the cardholder approves"
+// @Success 200 {object} models.PaymentIntentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Router /payment/fake/{intentId}/authenticate [post]
+// @Id authenticateFakePaymentIntent
+func (ctrl *Controller) AuthenticateFakeIntent(c *gin.Context) {
+	var req paymentModels.AuthenticateFakeIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
 
-	case "payment_intent.canceled":
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		if err != nil {
-			log.Println("Failed to unmarshal payment intent:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
-			return
-		}
-		if err := ctrl.service.UpdatePaymentStatusByString(paymentIntent.ID, "Failed"); err != nil {
-			log.Println("Failed to update payment status:", err)
-			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+	intent, err := ctrl.service.AuthenticateFakeIntent(c.Param("intentId"), req.Approve)
+	if err != nil {
+		if err.Error() == "payment intent does not require authentication" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
-
-	default:
-		log.Printf("Unhandled event type: %s\n", event.Type)
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		return
 	}
 
-	c.IndentedJSON(http.StatusOK, gin.H{"received": true})
+	c.IndentedJSON(http.StatusOK, paymentModels.PaymentIntentDto{
+		ID:             intent.ID,
+		Status:         string(intent.Status),
+		Amount:         intent.Amount,
+		AmountReceived: intent.AmountReceived,
+		Currency:       intent.Currency,
+		LastError:      intent.LastError,
+	})
 }
 
 // @Summary Get payment by ID
-// @Description Get a payment by its ID
+// @Description Get a payment by its ID. Requires authentication and Payments Read permission.
 // @Tags payment
 // @Produce  json
 // @Param   id  path  int  true  "Payment ID"
 // @Success 200 {object} models.PaymentDto
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /payment/{id} [get]
 // @Id getPaymentById
 func (ctrl *Controller) GetPaymentByID(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	id := c.Param("id")
 	if id == "" {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "payment ID is required"})
@@ -222,8 +292,12 @@ func (ctrl *Controller) GetPaymentByID(c *gin.Context) {
 		return
 	}
 
-	payment, err := ctrl.service.GetPaymentByID(paymentID)
+	payment, err := ctrl.service.GetPaymentByID(paymentID, userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
 		if err.Error() == "payment not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
@@ -237,16 +311,28 @@ func (ctrl *Controller) GetPaymentByID(c *gin.Context) {
 }
 
 // @Summary Complete a payment
-// @Description Complete a payment and update linked order status
+// @Description Complete a payment and update linked order status. Requires authentication and Payments Write permission. A gift card payment is redeemed in one step up to the card's balance: when the balance does not cover it, the payment is completed for the balance and the remaining amount is returned, to be paid with another tender.
 // @Tags payment
+// @Produce  json
 // @Param   id  path  int  true  "Payment ID"
-// @Success 200
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 200 {object} models.CompletePaymentResponse
 // @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
 // @Router /payment/{id}/complete [post]
 // @Id completePayment
 func (ctrl *Controller) CompletePayment(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
 	id := c.Param("id")
 	if id == "" {
 		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "payment ID is required"})
@@ -259,26 +345,209 @@ func (ctrl *Controller) CompletePayment(c *gin.Context) {
 		return
 	}
 
-	err := ctrl.service.CompletePayment(paymentID)
+	response, err := ctrl.service.CompletePayment(paymentID, userID, middleware.GetTokenBusinessIDFromContext(c))
 	if err != nil {
-		if err.Error() == "payment not found" {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "payment not found" || err.Error() == "gift card not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if err.Error() == "payment is not pending" || err.Error() == "invalid payment status transition" || err.Error() == "authorized payments are completed by capturing them" || err.Error() == "gift card is not active" || err.Error() == "gift card has no balance" || err.Error() == "gift card has expired" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+			return
+		}
+		if err.Error() == "gift card code is missing for this payment" || err.Error() == "gift card currency does not match the payment currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		log.Println("Failed to complete payment:", err)
 		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
 		return
 	}
 
-	c.Status(http.StatusOK)
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// @Summary Capture an authorized payment
+// @Description Collect a payment that was only authorized, e.g. to close a tab. The final amount, including any tip, defaults to the authorized amount; for a payment of an order the business's CreditCard tender rule adds its surcharge to it. A smaller amount releases the rest of the hold; a larger amount is authorized first and fails if the card declines the increment. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   id       path  int                           true  "Payment ID"
+// @Param   capture  body  models.CapturePaymentRequest  true  "Final amount"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 200 {object} models.PaymentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 402 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/{id}/capture [post]
+// @Id capturePayment
+func (ctrl *Controller) CapturePayment(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var paymentID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &paymentID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid payment ID"})
+		return
+	}
+
+	var req paymentModels.CapturePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	payment, err := ctrl.service.CapturePayment(paymentID, req.Amount, userID, middleware.GetTokenBusinessIDFromContext(c))
+	if err != nil {
+		writeAuthorizationError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, payment)
+}
+
+// @Summary Increment an authorization
+// @Description Raise the amount authorized for a payment awaiting capture to a new total, e.g. as a tab grows. Fails with 402 if the card declines the increment. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Accept  json
+// @Produce  json
+// @Param   id         path  int                                   true  "Payment ID"
+// @Param   increment  body  models.IncrementAuthorizationRequest  true  "New total to authorize"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 200 {object} models.PaymentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 402 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/{id}/increment-authorization [post]
+// @Id incrementPaymentAuthorization
+func (ctrl *Controller) IncrementAuthorization(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var paymentID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &paymentID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid payment ID"})
+		return
+	}
+
+	var req paymentModels.IncrementAuthorizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	payment, err := ctrl.service.IncrementAuthorization(paymentID, req.Amount, userID, middleware.GetTokenBusinessIDFromContext(c))
+	if err != nil {
+		writeAuthorizationError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, payment)
+}
+
+// @Summary Void an authorized payment
+// @Description Release the hold of a payment that was only authorized, without collecting it. The payment is marked Failed. Authorizations held longer than PAYMENT_AUTHORIZATION_MAX_HOURS are voided automatically. Requires authentication and Payments Write permission.
+// @Tags payment
+// @Produce  json
+// @Param   id  path  int  true  "Payment ID"
+// @Success 200 {object} models.PaymentDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/{id}/void [post]
+// @Id voidPayment
+func (ctrl *Controller) VoidAuthorization(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	var paymentID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &paymentID); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid payment ID"})
+		return
+	}
+
+	payment, err := ctrl.service.VoidAuthorization(paymentID, userID, middleware.GetTokenBusinessIDFromContext(c))
+	if err != nil {
+		writeAuthorizationError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, payment)
+}
+
+// writeAuthorizationError responds with the error of capturing, incrementing or voiding an authorization
+func writeAuthorizationError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "unauthorized":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+	case err.Error() == "payment not found":
+		c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+	case err.Error() == "payment is not authorized":
+		c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+	case err.Error() == "amount must be greater than 0" || err.Error() == "amount must be greater than the authorized amount":
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+	case err.Error() == "payment gateway is not configured":
+		c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+	case strings.HasPrefix(err.Error(), "authorization increment declined"):
+		c.IndentedJSON(http.StatusPaymentRequired, models.HTTPError{Error: err.Error()})
+	case strings.HasPrefix(err.Error(), "capture failed") || strings.HasPrefix(err.Error(), "void failed"):
+		c.IndentedJSON(http.StatusBadGateway, models.HTTPError{Error: err.Error()})
+	default:
+		log.Println("Failed to change payment authorization:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+	}
 }
 
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
+	// Stripe authenticates webhooks through their signature rather than a bearer token
+	r.POST("/payment/stripe/webhook", ctrl.HandleStripeWebhook)
+	if ctrl.service.FakeGatewayEnabled() {
+		// Stands in for the cardholder's bank, so it is not authenticated either
+		r.POST("/payment/fake/:intentId/authenticate", ctrl.AuthenticateFakeIntent)
+	}
+
 	paymentGroup := r.Group("/payment")
-	paymentGroup.POST("", ctrl.CreatePayment)
-	paymentGroup.GET("", ctrl.GetPayments)
-	paymentGroup.GET("/:id", ctrl.GetPaymentByID)
-	paymentGroup.POST("/:id/complete", ctrl.CompletePayment)
-	paymentGroup.POST("/stripe/create-intent", ctrl.CreateStripePaymentIntent)
-	paymentGroup.POST("/stripe/webhook", ctrl.HandleStripeWebhook)
+	paymentGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
+	{
+		paymentGroup.POST("", ctrl.CreatePayment)
+		paymentGroup.GET("", ctrl.GetPayments)
+		paymentGroup.GET("/:id", ctrl.GetPaymentByID)
+		paymentGroup.POST("/:id/complete", ctrl.CompletePayment)
+		paymentGroup.POST("/:id/capture", ctrl.CapturePayment)
+		paymentGroup.POST("/:id/increment-authorization", ctrl.IncrementAuthorization)
+		paymentGroup.POST("/:id/void", ctrl.VoidAuthorization)
+		paymentGroup.POST("/stripe/create-intent", ctrl.CreateStripePaymentIntent)
+	}
+	ctrl.RegisterCustomerRoutes(paymentGroup)
+	ctrl.RegisterReconciliationRoutes(paymentGroup)
 }
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Reconcile payments with the gateway
+// @Description Compare the business's Pending and Authorized card payments with their intents at the payment gateway now, correcting their status the way a missed webhook would have, and report mismatched amounts or currencies, payments whose intent the gateway does not know and intents that took or hold money without a payment recorded. The same reconciliation runs for all businesses every PAYMENT_RECONCILIATION_MINUTES, looking for orphaned intents created within PAYMENT_RECONCILIATION_LOOKBACK_HOURS. Requires authentication, Payments Write permission and View Reports.
+// @Tags payment
+// @Produce  json
+// @Param   businessId  query  int  true  "Business ID to reconcile"
+// @Param   Idempotency-Key  header  string  false  "Key making retries of the request safe"
+// @Success 200 {object} models.PaymentReconciliationReport
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/reconciliation [post]
+// @Id reconcilePayments
+func (ctrl *Controller) ReconcilePayments(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessID, ok := businessIDQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := ctrl.service.ReconcilePayments(businessID, userID)
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, report)
+}
+
+// @Summary Get payment discrepancies
+// @Description Get the discrepancies reconciliation found between the business's payments and the payment gateway, the most recently seen first. A discrepancy is listed once per intent and type, with when it was first and last seen. Requires authentication, Payments Read permission and View Reports.
+// @Tags payment
+// @Produce  json
+// @Param   businessId  query  int  true  "Business ID to filter by"
+// @Success 200 {array} models.PaymentDiscrepancyDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /payment/reconciliation/discrepancies [get]
+// @Id getPaymentDiscrepancies
+func (ctrl *Controller) GetPaymentDiscrepancies(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessID, ok := businessIDQuery(c)
+	if !ok {
+		return
+	}
+
+	discrepancies, err := ctrl.service.GetPaymentDiscrepancies(businessID, userID)
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, discrepancies)
+}
+
+// businessIDQuery reads the required businessId query parameter, responding with 400 when it is missing or invalid
+func businessIDQuery(c *gin.Context) (uint, bool) {
+	businessIDStr := c.Query("businessId")
+	if businessIDStr == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId query parameter is required"})
+		return 0, false
+	}
+
+	businessID, err := strconv.ParseUint(businessIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return 0, false
+	}
+	return uint(businessID), true
+}
+
+// writeReconciliationError responds with the error of reconciling payments or listing their discrepancies
+func writeReconciliationError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "unauthorized":
+		c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+	case "payment gateway is not configured":
+		c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+	default:
+		log.Println("Failed to reconcile payments:", err)
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: "internal server error"})
+	}
+}
+
+func (ctrl *Controller) RegisterReconciliationRoutes(rg *gin.RouterGroup) {
+	rg.POST("/reconciliation", ctrl.ReconcilePayments)
+	rg.GET("/reconciliation/discrepancies", ctrl.GetPaymentDiscrepancies)
+}
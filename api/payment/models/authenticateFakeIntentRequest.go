@@ -0,0 +1,6 @@
+package models
+
+// AuthenticateFakeIntentRequest is the cardholder's answer to a 3DS challenge simulated by the fake gateway
+type AuthenticateFakeIntentRequest struct {
+	Approve bool `json:"approve"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// CapturePaymentRequest is the final amount of an authorized payment, including any tip. The authorized amount
+// is captured when it is left out, and a larger amount is authorized first.
+type CapturePaymentRequest struct {
+	Amount *float64 `json:"amount,omitempty" binding:"omitempty,gt=0"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// ChargeSavedCardRequest charges a customer's saved card without them present, for an order or a reservation
+type ChargeSavedCardRequest struct {
+	SavedPaymentMethodID uint    `json:"savedPaymentMethodId" binding:"required"`
+	Amount               float64 `json:"amount" binding:"required,gt=0"`
+	OrderID              *uint   `json:"orderId,omitempty"`
+	ReservationID        *uint   `json:"reservationId,omitempty"`
+}
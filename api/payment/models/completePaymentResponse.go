@@ -0,0 +1,8 @@
+package models
+
+// CompletePaymentResponse is a completed payment. A gift card payment is capped at the card's balance, the
+// remaining amount is left to be paid with another tender.
+type CompletePaymentResponse struct {
+	Payment         PaymentDto `json:"payment"`
+	RemainingAmount float64    `json:"remainingAmount"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// CreatePaymentCustomerRequest identifies a customer of a business by email, as on orders and reservations
+type CreatePaymentCustomerRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone"`
+}
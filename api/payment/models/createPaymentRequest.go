@@ -5,4 +5,8 @@ type CreatePaymentRequest struct {
 	Type         string  `json:"type" validate:"required"`
 	Status       string  `json:"status"`
 	GiftCardCode *string `json:"giftCardCode,omitempty"`
+	GiftCardPin  string  `json:"giftCardPin,omitempty"`
+	BusinessID   uint    `json:"businessId" binding:"required"`
+	// OrderID is the order the payment is taken against, whose tender rule for the payment type adjusts the amount
+	OrderID *uint `json:"orderId,omitempty"`
 }
@@ -0,0 +1,18 @@
+package models
+
+// CreateSetupIntentRequest records the customer's consent to having a card saved and charged without them present
+type CreateSetupIntentRequest struct {
+	// ConsentText is the agreement shown to the customer, e.g. which fees the card may be charged for
+	ConsentText string `json:"consentText" binding:"required"`
+	// CustomerConsented confirms the customer accepted it, cards are not saved otherwise
+	CustomerConsented bool `json:"customerConsented"`
+	// CoversReservationFees records that the consent covers reservation fees, e.g. for late cancellations and no-shows
+	CoversReservationFees bool `json:"coversReservationFees"`
+}
+
+type CreateSetupIntentResponse struct {
+	// ClientSecret confirms the setup intent with the card on the client
+	ClientSecret       string                `json:"clientSecret"`
+	SetupIntentID      string                `json:"setupIntentId"`
+	SavedPaymentMethod SavedPaymentMethodDto `json:"savedPaymentMethod"`
+}
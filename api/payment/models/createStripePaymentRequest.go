@@ -1,12 +1,18 @@
 package models
 
 type CreateStripePaymentRequest struct {
-	Amount   float64 `json:"amount" validate:"required,gt=0"`
-	Currency string  `json:"currency" validate:"required"`
-	OrderID  *uint   `json:"orderId,omitempty"`
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	// Currency defaults to the currency of the order or business, and has to match it when given
+	Currency   string `json:"currency,omitempty"`
+	OrderID    *uint  `json:"orderId,omitempty"`
+	BusinessID uint   `json:"businessId" binding:"required"`
+	// ManualCapture only authorizes the amount, e.g. to open a tab, and it is captured later
+	ManualCapture bool `json:"manualCapture,omitempty"`
 }
 
 type CreateStripePaymentResponse struct {
-	ClientSecret string `json:"clientSecret"`
+	ClientSecret    string `json:"clientSecret"`
 	PaymentIntentID string `json:"paymentIntentId"`
+	// Amount is the amount of the intent, including the card surcharge of an order
+	Amount float64 `json:"amount"`
 }
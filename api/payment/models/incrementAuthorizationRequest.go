@@ -0,0 +1,6 @@
+package models
+
+// IncrementAuthorizationRequest is the new total to authorize for a payment awaiting capture
+type IncrementAuthorizationRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
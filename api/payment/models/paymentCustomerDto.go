@@ -0,0 +1,69 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+type PaymentCustomerDto struct {
+	ID                  uint                    `json:"id"`
+	BusinessID          uint                    `json:"businessId"`
+	Email               string                  `json:"email"`
+	Name                string                  `json:"name"`
+	Phone               string                  `json:"phone"`
+	StripeCustomerID    string                  `json:"stripeCustomerId"`
+	SavedPaymentMethods []SavedPaymentMethodDto `json:"savedPaymentMethods"`
+}
+
+// SavedPaymentMethodDto is a saved card and the consent its customer gave to charging it off-session
+type SavedPaymentMethodDto struct {
+	ID                  uint      `json:"id"`
+	Status              string    `json:"status"`
+	StripeSetupIntentID string    `json:"stripeSetupIntentId"`
+	Brand               string    `json:"brand,omitempty"`
+	Last4               string    `json:"last4,omitempty"`
+	ExpMonth            int       `json:"expMonth,omitempty"`
+	ExpYear             int       `json:"expYear,omitempty"`
+	ConsentText         string    `json:"consentText"`
+	ConsentedAt         time.Time `json:"consentedAt"`
+	ConsentRecordedByID uint      `json:"consentRecordedById"`
+	// CoversReservationFees is whether the card can be linked to reservations and charged their fees
+	CoversReservationFees bool       `json:"coversReservationFees"`
+	DetachedAt            *time.Time `json:"detachedAt,omitempty"`
+}
+
+// NewPaymentCustomerDtoFromEntity constructs a PaymentCustomerDto from the DB entity.
+func NewPaymentCustomerDtoFromEntity(c entities.PaymentCustomer) PaymentCustomerDto {
+	methods := make([]SavedPaymentMethodDto, 0, len(c.SavedPaymentMethods))
+	for _, method := range c.SavedPaymentMethods {
+		methods = append(methods, NewSavedPaymentMethodDtoFromEntity(method))
+	}
+
+	return PaymentCustomerDto{
+		ID:                  c.ID,
+		BusinessID:          c.BusinessID,
+		Email:               c.Email,
+		Name:                c.Name,
+		Phone:               c.Phone,
+		StripeCustomerID:    c.StripeCustomerID,
+		SavedPaymentMethods: methods,
+	}
+}
+
+// NewSavedPaymentMethodDtoFromEntity constructs a SavedPaymentMethodDto from the DB entity.
+func NewSavedPaymentMethodDtoFromEntity(m entities.SavedPaymentMethod) SavedPaymentMethodDto {
+	return SavedPaymentMethodDto{
+		ID:                    m.ID,
+		Status:                string(m.Status),
+		StripeSetupIntentID:   m.StripeSetupIntentID,
+		Brand:                 m.Brand,
+		Last4:                 m.Last4,
+		ExpMonth:              m.ExpMonth,
+		ExpYear:               m.ExpYear,
+		ConsentText:           m.ConsentText,
+		ConsentedAt:           m.ConsentedAt,
+		ConsentRecordedByID:   m.ConsentRecordedByID,
+		CoversReservationFees: m.CoversReservationFees,
+		DetachedAt:            m.DetachedAt,
+	}
+}
@@ -1,15 +1,27 @@
 package models
 
-import "VersatilePOS/database/entities"
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/currency"
+	"time"
+)
 
 type PaymentDto struct {
-	ID                    uint    `json:"id"`
-	Amount                float64 `json:"amount"`
-	Type                  string  `json:"type"`
-	Status                string  `json:"status"`
-	StripePaymentIntentID *string `json:"stripePaymentIntentId,omitempty"`
-	StripeCustomerID      *string `json:"stripeCustomerId,omitempty"`
-	GiftCardCode          *string `json:"giftCardCode,omitempty"`
+	ID                    uint       `json:"id"`
+	Amount                float64    `json:"amount"`
+	Currency              string     `json:"currency"`
+	FormattedAmount       string     `json:"formattedAmount"`
+	Type                  string     `json:"type"`
+	Status                string     `json:"status"`
+	StripePaymentIntentID *string    `json:"stripePaymentIntentId,omitempty"`
+	StripeCustomerID      *string    `json:"stripeCustomerId,omitempty"`
+	SavedPaymentMethodID  *uint      `json:"savedPaymentMethodId,omitempty"`
+	GiftCardCode          *string    `json:"giftCardCode,omitempty"`
+	RefundedAmount        float64    `json:"refundedAmount"`
+	ManualCapture         bool       `json:"manualCapture"`
+	AuthorizedAmount      float64    `json:"authorizedAmount"`
+	AuthorizedAt          *time.Time `json:"authorizedAt,omitempty"`
+	BusinessID            uint       `json:"businessId"`
 }
 
 // NewPaymentDtoFromEntity constructs a PaymentDto from the DB entity.
@@ -17,10 +29,18 @@ func NewPaymentDtoFromEntity(p entities.Payment) PaymentDto {
 	return PaymentDto{
 		ID:                    p.ID,
 		Amount:                p.Amount,
+		Currency:              p.Currency,
+		FormattedAmount:       currency.Format(p.Amount, p.Currency),
 		Type:                  string(p.Type),
 		Status:                string(p.Status),
 		StripePaymentIntentID: p.StripePaymentIntentID,
 		StripeCustomerID:      p.StripeCustomerID,
+		SavedPaymentMethodID:  p.SavedPaymentMethodID,
 		GiftCardCode:          p.GiftCardCode,
+		RefundedAmount:        p.RefundedAmount,
+		ManualCapture:         p.ManualCapture,
+		AuthorizedAmount:      p.AuthorizedAmount,
+		AuthorizedAt:          p.AuthorizedAt,
+		BusinessID:            p.BusinessID,
 	}
 }
@@ -0,0 +1,11 @@
+package models
+
+// PaymentIntentDto is the state of a card payment at the payment gateway
+type PaymentIntentDto struct {
+	ID             string  `json:"id"`
+	Status         string  `json:"status"`
+	Amount         float64 `json:"amount"`
+	AmountReceived float64 `json:"amountReceived"`
+	Currency       string  `json:"currency"`
+	LastError      string  `json:"lastError,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+// PaymentDiscrepancyDto is a difference between a recorded payment and the payment gateway
+type PaymentDiscrepancyDto struct {
+	ID                    uint      `json:"id"`
+	BusinessID            uint      `json:"businessId"`
+	Type                  string    `json:"type"`
+	StripePaymentIntentID string    `json:"stripePaymentIntentId"`
+	PaymentID             *uint     `json:"paymentId,omitempty"`
+	Amount                float64   `json:"amount"`
+	Currency              string    `json:"currency,omitempty"`
+	GatewayAmount         float64   `json:"gatewayAmount"`
+	GatewayCurrency       string    `json:"gatewayCurrency,omitempty"`
+	GatewayStatus         string    `json:"gatewayStatus,omitempty"`
+	FirstSeenAt           time.Time `json:"firstSeenAt"`
+	LastSeenAt            time.Time `json:"lastSeenAt"`
+}
+
+// PaymentReconciliationReport is the outcome of reconciling the unsettled card payments with the payment gateway
+type PaymentReconciliationReport struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Checked is how many Pending or Authorized card payments were compared with their intent
+	Checked int `json:"checked"`
+	// Corrected is how many of them changed status to match their intent
+	Corrected int `json:"corrected"`
+	// Failed is how many could not be checked, e.g. because the gateway was unavailable
+	Failed        int                     `json:"failed"`
+	Discrepancies []PaymentDiscrepancyDto `json:"discrepancies"`
+}
+
+// NewPaymentDiscrepancyDtoFromEntity constructs a PaymentDiscrepancyDto from the DB entity.
+func NewPaymentDiscrepancyDtoFromEntity(d entities.PaymentDiscrepancy) PaymentDiscrepancyDto {
+	return PaymentDiscrepancyDto{
+		ID:                    d.ID,
+		BusinessID:            d.BusinessID,
+		Type:                  string(d.Type),
+		StripePaymentIntentID: d.StripePaymentIntentID,
+		PaymentID:             d.PaymentID,
+		Amount:                d.Amount,
+		Currency:              d.Currency,
+		GatewayAmount:         d.GatewayAmount,
+		GatewayCurrency:       d.GatewayCurrency,
+		GatewayStatus:         d.GatewayStatus,
+		FirstSeenAt:           d.CreatedAt,
+		LastSeenAt:            d.LastSeenAt,
+	}
+}
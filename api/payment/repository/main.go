@@ -3,6 +3,9 @@ package repository
 import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,15 +13,36 @@ import (
 type Repository struct{}
 
 func (r *Repository) CreatePayment(payment *entities.Payment) (*entities.Payment, error) {
-	if result := database.DB.Create(payment); result.Error != nil {
+	if result := database.DB.Omit("Business").Create(payment); result.Error != nil {
 		return nil, result.Error
 	}
 	return payment, nil
 }
 
-func (r *Repository) GetPayments() ([]entities.Payment, error) {
+// CreatePaymentWithAdjustment creates a payment together with the adjustment a tender rule made to the order it was
+// taken against
+func (r *Repository) CreatePaymentWithAdjustment(payment *entities.Payment, adjustment *entities.OrderAdjustment) (*entities.Payment, error) {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Business").Create(payment).Error; err != nil {
+			return err
+		}
+		adjustment.PaymentID = payment.ID
+		return tx.Omit("Order", "Payment").Create(adjustment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// DeleteOrderAdjustments removes the adjustments tender rules made to orders for a payment
+func (r *Repository) DeleteOrderAdjustments(paymentID uint) error {
+	return database.DB.Where("payment_id = ?", paymentID).Delete(&entities.OrderAdjustment{}).Error
+}
+
+func (r *Repository) GetPayments(businessID uint) ([]entities.Payment, error) {
 	var payments []entities.Payment
-	if result := database.DB.Find(&payments); result.Error != nil {
+	if result := database.DB.Where("business_id = ?", businessID).Find(&payments); result.Error != nil {
 		return nil, result.Error
 	}
 	return payments, nil
@@ -47,8 +71,107 @@ func (r *Repository) GetPaymentByStripePaymentIntentID(paymentIntentID string) (
 }
 
 func (r *Repository) UpdatePayment(payment *entities.Payment) (*entities.Payment, error) {
-	if result := database.DB.Save(payment); result.Error != nil {
+	if result := database.DB.Omit("Business").Save(payment); result.Error != nil {
 		return nil, result.Error
 	}
 	return payment, nil
 }
+
+// CompletePendingPayment completes a pending payment with the amount actually collected, within tx. Completing a
+// payment that is no longer pending fails, so it cannot be collected twice.
+func (r *Repository) CompletePendingPayment(tx *gorm.DB, id uint, amount float64) error {
+	result := tx.Model(&entities.Payment{}).
+		Where("id = ? AND status = ?", id, constants.Pending).
+		Updates(map[string]interface{}{"amount": amount, "status": constants.Completed})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("payment is not pending")
+	}
+	return nil
+}
+
+// RefundCompletedPayment marks a completed payment refunded, within tx
+func (r *Repository) RefundCompletedPayment(tx *gorm.DB, id uint) error {
+	result := tx.Model(&entities.Payment{}).
+		Where("id = ? AND status = ?", id, constants.Completed).
+		Update("status", constants.Refunded)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("payment is not completed")
+	}
+	return nil
+}
+
+// TransitionPaymentStatus moves a payment to status if it is still in a status that may move there. It reports
+// whether the payment moved, so concurrent or replayed updates apply once.
+func (r *Repository) TransitionPaymentStatus(id uint, status constants.PaymentStatus) (bool, error) {
+	result := database.DB.Model(&entities.Payment{}).
+		Where("id = ? AND status IN ?", id, status.PreviousStatuses()).
+		Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RaiseRefundedAmount records the amount refunded of a payment at the payment gateway. The amount only grows, so
+// refund events arriving out of order cannot lower it.
+func (r *Repository) RaiseRefundedAmount(id uint, amount float64) error {
+	return database.DB.Model(&entities.Payment{}).
+		Where("id = ? AND refunded_amount < ?", id, amount).
+		Update("refunded_amount", amount).Error
+}
+
+// UpdateAuthorization records the amount authorized for a card payment. The time it was first authorized is kept.
+func (r *Repository) UpdateAuthorization(id uint, amount float64, at time.Time) error {
+	return database.DB.Model(&entities.Payment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"amount":            amount,
+		"authorized_amount": amount,
+		"authorized_at":     gorm.Expr("COALESCE(authorized_at, ?)", at),
+	}).Error
+}
+
+// SetPaymentAmount records the amount actually collected for a payment
+func (r *Repository) SetPaymentAmount(id uint, amount float64) error {
+	return database.DB.Model(&entities.Payment{}).Where("id = ?", id).Update("amount", amount).Error
+}
+
+// SetPaymentAmountWithAdjustment records the amount collected for a payment together with the adjustment a tender
+// rule made to its order for it
+func (r *Repository) SetPaymentAmountWithAdjustment(id uint, amount float64, adjustment *entities.OrderAdjustment) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Payment{}).Where("id = ?", id).Update("amount", amount).Error; err != nil {
+			return err
+		}
+		adjustment.PaymentID = id
+		return tx.Omit("Order", "Payment").Create(adjustment).Error
+	})
+}
+
+// GetStaleAuthorizations lists the authorized payments that were authorized before the given time
+func (r *Repository) GetStaleAuthorizations(before time.Time) ([]entities.Payment, error) {
+	var payments []entities.Payment
+	if result := database.DB.Where("status = ? AND authorized_at < ?", constants.Authorized, before).Find(&payments); result.Error != nil {
+		return nil, result.Error
+	}
+	return payments, nil
+}
+
+// GetUnsettledCardPayments returns the card payments still Pending or Authorized, of a business or of all when
+// businessID is nil
+func (r *Repository) GetUnsettledCardPayments(businessID *uint) ([]entities.Payment, error) {
+	query := database.DB.Where("status IN ? AND stripe_payment_intent_id IS NOT NULL", []constants.PaymentStatus{constants.Pending, constants.Authorized})
+	if businessID != nil {
+		query = query.Where("business_id = ?", *businessID)
+	}
+
+	var payments []entities.Payment
+	if result := query.Order("id").Find(&payments); result.Error != nil {
+		return nil, result.Error
+	}
+	return payments, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+
+	"gorm.io/gorm"
+)
+
+type PaymentCustomerRepository struct{}
+
+// preloadSavedPaymentMethods loads a customer's saved cards, the most recently saved first
+func preloadSavedPaymentMethods(db *gorm.DB) *gorm.DB {
+	return db.Order("id DESC")
+}
+
+func (r *PaymentCustomerRepository) CreatePaymentCustomer(customer *entities.PaymentCustomer) (*entities.PaymentCustomer, error) {
+	if result := database.DB.Omit("Business").Create(customer); result.Error != nil {
+		return nil, result.Error
+	}
+	return customer, nil
+}
+
+func (r *PaymentCustomerRepository) GetPaymentCustomers(businessID uint) ([]entities.PaymentCustomer, error) {
+	var customers []entities.PaymentCustomer
+	if result := database.DB.Where("business_id = ?", businessID).
+		Preload("SavedPaymentMethods", preloadSavedPaymentMethods).
+		Order("email").Find(&customers); result.Error != nil {
+		return nil, result.Error
+	}
+	return customers, nil
+}
+
+func (r *PaymentCustomerRepository) GetPaymentCustomerByID(id uint) (*entities.PaymentCustomer, error) {
+	var customer entities.PaymentCustomer
+	if result := database.DB.Preload("SavedPaymentMethods", preloadSavedPaymentMethods).First(&customer, id); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &customer, nil
+}
+
+// GetPaymentCustomerByEmail finds the customer of a business with an email, which is stored in lower case
+func (r *PaymentCustomerRepository) GetPaymentCustomerByEmail(businessID uint, email string) (*entities.PaymentCustomer, error) {
+	var customer entities.PaymentCustomer
+	if result := database.DB.Where("business_id = ? AND email = ?", businessID, email).
+		Preload("SavedPaymentMethods", preloadSavedPaymentMethods).
+		First(&customer); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &customer, nil
+}
+
+func (r *PaymentCustomerRepository) CreateSavedPaymentMethod(method *entities.SavedPaymentMethod) (*entities.SavedPaymentMethod, error) {
+	if result := database.DB.Omit("PaymentCustomer", "ConsentRecordedBy").Create(method); result.Error != nil {
+		return nil, result.Error
+	}
+	return method, nil
+}
+
+// GetSavedPaymentMethodByID gets a saved card together with its customer
+func (r *PaymentCustomerRepository) GetSavedPaymentMethodByID(id uint) (*entities.SavedPaymentMethod, error) {
+	var method entities.SavedPaymentMethod
+	if result := database.DB.Preload("PaymentCustomer").First(&method, id); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &method, nil
+}
+
+func (r *PaymentCustomerRepository) GetSavedPaymentMethodBySetupIntentID(setupIntentID string) (*entities.SavedPaymentMethod, error) {
+	var method entities.SavedPaymentMethod
+	if result := database.DB.Where("stripe_setup_intent_id = ?", setupIntentID).First(&method); result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &method, nil
+}
+
+func (r *PaymentCustomerRepository) UpdateSavedPaymentMethod(method *entities.SavedPaymentMethod) error {
+	return database.DB.Omit("PaymentCustomer", "ConsentRecordedBy").Save(method).Error
+}
+
+// SettleSavedPaymentMethod records the outcome of a pending setup intent. It reports whether the card was still
+// pending, so a setup completed by both its webhook and the client is only recorded once.
+func (r *PaymentCustomerRepository) SettleSavedPaymentMethod(method *entities.SavedPaymentMethod) (bool, error) {
+	result := database.DB.Model(&entities.SavedPaymentMethod{}).
+		Where("id = ? AND status = ?", method.ID, constants.SavedPaymentMethodPending).
+		Updates(map[string]interface{}{
+			"status":                   method.Status,
+			"stripe_payment_method_id": method.StripePaymentMethodID,
+			"brand":                    method.Brand,
+			"last4":                    method.Last4,
+			"exp_month":                method.ExpMonth,
+			"exp_year":                 method.ExpYear,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
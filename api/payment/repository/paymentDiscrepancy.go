@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm/clause"
+)
+
+type PaymentDiscrepancyRepository struct{}
+
+// RecordPaymentDiscrepancy stores a discrepancy, or refreshes the one stored for the same intent and type. The
+// discrepancy is updated with the stored one, e.g. when it was first seen.
+func (r *PaymentDiscrepancyRepository) RecordPaymentDiscrepancy(discrepancy *entities.PaymentDiscrepancy) error {
+	return database.DB.Clauses(clause.Returning{}, clause.OnConflict{
+		Columns: []clause.Column{{Name: "stripe_payment_intent_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"business_id", "payment_id", "amount", "currency", "gateway_amount", "gateway_currency", "gateway_status",
+			"last_seen_at", "updated_at",
+		}),
+	}).Create(discrepancy).Error
+}
+
+// GetPaymentDiscrepancies returns the discrepancies of a business, the most recently seen first
+func (r *PaymentDiscrepancyRepository) GetPaymentDiscrepancies(businessID uint) ([]entities.PaymentDiscrepancy, error) {
+	var discrepancies []entities.PaymentDiscrepancy
+	if result := database.DB.Where("business_id = ?", businessID).Order("last_seen_at DESC").Find(&discrepancies); result.Error != nil {
+		return nil, result.Error
+	}
+	return discrepancies, nil
+}
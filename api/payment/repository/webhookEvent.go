@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookEventRepository struct{}
+
+// RecordWebhookEvent stores a received event unless one with the same EventID was stored before. It reports
+// whether the event is new.
+func (r *WebhookEventRepository) RecordWebhookEvent(event *entities.WebhookEvent) (bool, error) {
+	result := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ClaimDueWebhookEvents returns up to limit pending events that are due and defers them by lease, so other
+// workers skip them while they are processed
+func (r *WebhookEventRepository) ClaimDueWebhookEvents(limit int, lease time.Duration) ([]entities.WebhookEvent, error) {
+	var events []entities.WebhookEvent
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", constants.WebhookEventPending, now).
+			Order("id").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return tx.Model(&entities.WebhookEvent{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkWebhookEventProcessed records that an event was applied
+func (r *WebhookEventRepository) MarkWebhookEventProcessed(id uint, attempts int) error {
+	now := time.Now()
+	return database.DB.Model(&entities.WebhookEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       constants.WebhookEventProcessed,
+		"attempts":     attempts,
+		"last_error":   "",
+		"processed_at": &now,
+	}).Error
+}
+
+// MarkWebhookEventAttemptFailed records a failed attempt. The event is retried at nextAttemptAt, or given up on
+// when it is nil.
+func (r *WebhookEventRepository) MarkWebhookEventAttemptFailed(id uint, attempts int, lastError string, nextAttemptAt *time.Time) error {
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": lastError,
+	}
+	if nextAttemptAt != nil {
+		updates["next_attempt_at"] = *nextAttemptAt
+	} else {
+		updates["status"] = constants.WebhookEventFailed
+	}
+	return database.DB.Model(&entities.WebhookEvent{}).Where("id = ?", id).Updates(updates).Error
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/env"
+	paymentModels "VersatilePOS/payment/models"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var authorizationSweeperOnce sync.Once
+
+// authorizationMaxAge is how long an authorization is held before it is voided, configured by
+// PAYMENT_AUTHORIZATION_MAX_HOURS. Card networks release authorizations after about a week.
+func authorizationMaxAge() time.Duration {
+	return time.Duration(env.PositiveInt("PAYMENT_AUTHORIZATION_MAX_HOURS", 144)) * time.Hour
+}
+
+// syncCapturedAmounts records the amounts the gateway reports for a manually captured payment: what is authorized
+// while it awaits capture, and what was collected once it is captured
+func (s *Service) syncCapturedAmounts(paymentIntentID string, pi *GatewayIntent) error {
+	payment, err := s.repo.GetPaymentByStripePaymentIntentID(paymentIntentID)
+	if err != nil || payment == nil || !payment.ManualCapture {
+		return err
+	}
+
+	switch pi.Status {
+	case IntentRequiresCapture:
+		// A late authorization event must not overwrite the amount of a payment that was captured already
+		if payment.Status != constants.Pending && payment.Status != constants.Authorized {
+			return nil
+		}
+		return s.repo.UpdateAuthorization(payment.ID, pi.AmountCapturable, time.Now())
+	case IntentSucceeded:
+		return s.repo.SetPaymentAmount(payment.ID, pi.AmountReceived)
+	}
+	return nil
+}
+
+// getAuthorization returns an authorized card payment the user may change
+func (s *Service) getAuthorization(paymentID uint, userID uint, tokenBusinessID *uint) (*entities.Payment, error) {
+	payment, err := s.getAuthorizedPayment(paymentID, userID, tokenBusinessID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+	if !payment.ManualCapture || payment.StripePaymentIntentID == nil || payment.Status != constants.Authorized {
+		return nil, errors.New("payment is not authorized")
+	}
+	return payment, nil
+}
+
+// IncrementAuthorization raises the amount authorized for a payment awaiting capture, e.g. as a tab grows
+func (s *Service) IncrementAuthorization(paymentID uint, amount float64, userID uint, tokenBusinessID *uint) (*paymentModels.PaymentDto, error) {
+	payment, err := s.getAuthorization(paymentID, userID, tokenBusinessID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.incrementAuthorization(payment, amount); err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
+	return &dto, nil
+}
+
+func (s *Service) incrementAuthorization(payment *entities.Payment, amount float64) error {
+	amount = currency.Round(amount, payment.Currency)
+	if amount <= payment.AuthorizedAmount {
+		return errors.New("amount must be greater than the authorized amount")
+	}
+
+	pi, err := s.gateway.IncrementAuthorization(*payment.StripePaymentIntentID, amount, payment.Currency)
+	if err != nil {
+		log.Printf("Failed to increment authorization of payment %d: %v", payment.ID, err)
+		return fmt.Errorf("authorization increment declined: %w", err)
+	}
+
+	if err := s.repo.UpdateAuthorization(payment.ID, pi.AmountCapturable, time.Now()); err != nil {
+		return err
+	}
+	payment.Amount = pi.AmountCapturable
+	payment.AuthorizedAmount = pi.AmountCapturable
+	return nil
+}
+
+// CapturePayment collects an authorized payment. The final amount, e.g. including a tip, defaults to the authorized
+// amount; a smaller amount releases the rest and a larger one is authorized first. For a payment taken against an
+// order, the business's card tender rule applies to the final amount, as it was left out when authorizing.
+func (s *Service) CapturePayment(paymentID uint, amount *float64, userID uint, tokenBusinessID *uint) (*paymentModels.PaymentDto, error) {
+	payment, err := s.getAuthorization(paymentID, userID, tokenBusinessID)
+	if err != nil {
+		return nil, err
+	}
+
+	final := payment.AuthorizedAmount
+	if amount != nil {
+		final = currency.Round(*amount, payment.Currency)
+	}
+	if final <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	order, err := s.intentOrder(payment)
+	if err != nil {
+		return nil, err
+	}
+	var adjustment *entities.OrderAdjustment
+	if order != nil {
+		final, adjustment, err = s.applyTenderRule(order, constants.CreditCard, final)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if final > payment.AuthorizedAmount {
+		if err := s.incrementAuthorization(payment, final); err != nil {
+			return nil, err
+		}
+	}
+
+	pi, err := s.gateway.CaptureIntent(*payment.StripePaymentIntentID, &final, payment.Currency)
+	if err != nil {
+		log.Printf("Failed to capture payment %d: %v", payment.ID, err)
+		return nil, fmt.Errorf("capture failed: %w", err)
+	}
+
+	if adjustment != nil {
+		err = s.repo.SetPaymentAmountWithAdjustment(payment.ID, pi.AmountReceived, adjustment)
+	} else {
+		err = s.repo.SetPaymentAmount(payment.ID, pi.AmountReceived)
+	}
+	if err != nil {
+		return nil, err
+	}
+	payment.Amount = pi.AmountReceived
+
+	if err := s.transitionPaymentStatus(payment, constants.Completed); err != nil {
+		// The succeeded webhook may have completed it already
+		if err.Error() != "invalid payment status transition" {
+			return nil, err
+		}
+	}
+
+	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
+	return &dto, nil
+}
+
+// intentOrder returns the order a card payment was taken against, as named by its intent, or nil when it has none
+func (s *Service) intentOrder(payment *entities.Payment) (*entities.Order, error) {
+	pi, err := s.gateway.GetIntent(*payment.StripePaymentIntentID)
+	if err != nil {
+		return nil, err
+	}
+	orderID, err := strconv.ParseUint(pi.Metadata["order_id"], 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	order, err := s.orderRepo.GetOrderByID(uint(orderID))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.BusinessID != payment.BusinessID {
+		return nil, nil
+	}
+	return order, nil
+}
+
+// VoidAuthorization releases an authorized payment without collecting it
+func (s *Service) VoidAuthorization(paymentID uint, userID uint, tokenBusinessID *uint) (*paymentModels.PaymentDto, error) {
+	payment, err := s.getAuthorization(paymentID, userID, tokenBusinessID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.voidAuthorization(payment); err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
+	return &dto, nil
+}
+
+func (s *Service) voidAuthorization(payment *entities.Payment) error {
+	if _, err := s.gateway.CancelIntent(*payment.StripePaymentIntentID); err != nil {
+		// An authorization the gateway already canceled, e.g. because it lapsed, only needs to be recorded
+		pi, getErr := s.gateway.GetIntent(*payment.StripePaymentIntentID)
+		if getErr != nil || pi.Status != IntentCanceled {
+			log.Printf("Failed to void authorization of payment %d: %v", payment.ID, err)
+			return fmt.Errorf("void failed: %w", err)
+		}
+	}
+
+	if err := s.transitionPaymentStatus(payment, constants.Failed); err != nil {
+		// The canceled webhook may have failed it already
+		if err.Error() != "invalid payment status transition" {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartAuthorizationSweeper voids authorizations older than PAYMENT_AUTHORIZATION_MAX_HOURS in the background,
+// every PAYMENT_AUTHORIZATION_SWEEP_MINUTES, so holds do not lapse at the card network unnoticed
+func (s *Service) StartAuthorizationSweeper() {
+	authorizationSweeperOnce.Do(func() {
+		interval := time.Duration(env.PositiveInt("PAYMENT_AUTHORIZATION_SWEEP_MINUTES", 60)) * time.Minute
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				s.voidStaleAuthorizations()
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+// voidStaleAuthorizations voids the authorizations that have been held too long
+func (s *Service) voidStaleAuthorizations() {
+	if s.gateway == nil {
+		return
+	}
+
+	payments, err := s.repo.GetStaleAuthorizations(time.Now().Add(-authorizationMaxAge()))
+	if err != nil {
+		log.Printf("Failed to load stale authorizations: %v", err)
+		return
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		if payment.StripePaymentIntentID == nil {
+			continue
+		}
+		if err := s.voidAuthorization(payment); err != nil {
+			log.Printf("Failed to void stale authorization of payment %d: %v", payment.ID, err)
+			continue
+		}
+		log.Printf("Voided stale authorization of payment %d (%s authorized at %s)", payment.ID, currency.Format(payment.AuthorizedAmount, payment.Currency), payment.AuthorizedAt.Format(time.RFC3339))
+	}
+}
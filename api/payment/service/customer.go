@@ -0,0 +1,460 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	paymentModels "VersatilePOS/payment/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// normalizeEmail returns an email in the lower case form customers are identified by
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// getAuthorizedCustomer loads a payment customer the user has the given access to
+func (s *Service) getAuthorizedCustomer(id uint, userID uint, level constants.AccessLevel) (*entities.PaymentCustomer, error) {
+	customer, err := s.customerRepo.GetPaymentCustomerByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, errors.New("customer not found")
+	}
+
+	if err := checkPaymentAccess(customer.BusinessID, userID, level); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// CreatePaymentCustomer creates a customer at the payment gateway for a customer of a business, so cards can be
+// saved for them. A customer with the same email is returned as is, reporting that it was not created.
+func (s *Service) CreatePaymentCustomer(req paymentModels.CreatePaymentCustomerRequest, userID uint) (*paymentModels.PaymentCustomerDto, bool, error) {
+	if err := checkPaymentAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, false, err
+	}
+	if s.gateway == nil {
+		return nil, false, errNoGateway
+	}
+
+	email := normalizeEmail(req.Email)
+	existing, err := s.customerRepo.GetPaymentCustomerByEmail(req.BusinessID, email)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		dto := paymentModels.NewPaymentCustomerDtoFromEntity(*existing)
+		return &dto, false, nil
+	}
+
+	stripeCustomerID, err := s.gateway.CreateCustomer(req.Name, email, req.Phone, map[string]string{
+		"business_id": fmt.Sprintf("%d", req.BusinessID),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	customer, err := s.customerRepo.CreatePaymentCustomer(&entities.PaymentCustomer{
+		BusinessID:       req.BusinessID,
+		Email:            email,
+		Name:             req.Name,
+		Phone:            req.Phone,
+		StripeCustomerID: stripeCustomerID,
+	})
+	if err != nil {
+		// Created concurrently, the gateway customer made here is left unused
+		if existing, lookupErr := s.customerRepo.GetPaymentCustomerByEmail(req.BusinessID, email); lookupErr == nil && existing != nil {
+			dto := paymentModels.NewPaymentCustomerDtoFromEntity(*existing)
+			return &dto, false, nil
+		}
+		return nil, false, err
+	}
+
+	dto := paymentModels.NewPaymentCustomerDtoFromEntity(*customer)
+	return &dto, true, nil
+}
+
+// GetPaymentCustomers lists the payment customers of a business, or the one with an email when it is given
+func (s *Service) GetPaymentCustomers(businessID uint, email string, userID uint) ([]paymentModels.PaymentCustomerDto, error) {
+	if err := checkPaymentAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	var customers []entities.PaymentCustomer
+	if email != "" {
+		customer, err := s.customerRepo.GetPaymentCustomerByEmail(businessID, normalizeEmail(email))
+		if err != nil {
+			return nil, err
+		}
+		if customer != nil {
+			customers = append(customers, *customer)
+		}
+	} else {
+		all, err := s.customerRepo.GetPaymentCustomers(businessID)
+		if err != nil {
+			return nil, err
+		}
+		customers = all
+	}
+
+	dtos := make([]paymentModels.PaymentCustomerDto, 0, len(customers))
+	for _, customer := range customers {
+		dtos = append(dtos, paymentModels.NewPaymentCustomerDtoFromEntity(customer))
+	}
+	return dtos, nil
+}
+
+func (s *Service) GetPaymentCustomer(id uint, userID uint) (*paymentModels.PaymentCustomerDto, error) {
+	customer, err := s.getAuthorizedCustomer(id, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewPaymentCustomerDtoFromEntity(*customer)
+	return &dto, nil
+}
+
+// CreateSetupIntent starts saving a card for a customer who consented to it being charged without them present.
+// The consent is recorded with the card, which becomes Active once the client confirmed the setup intent.
+func (s *Service) CreateSetupIntent(customerID uint, req paymentModels.CreateSetupIntentRequest, userID uint) (*paymentModels.CreateSetupIntentResponse, error) {
+	customer, err := s.getAuthorizedCustomer(customerID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+	if !req.CustomerConsented || strings.TrimSpace(req.ConsentText) == "" {
+		return nil, errors.New("customer consent is required to save a card")
+	}
+
+	si, err := s.gateway.CreateSetupIntent(customer.StripeCustomerID, map[string]string{
+		"payment_customer_id": fmt.Sprintf("%d", customer.ID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := s.customerRepo.CreateSavedPaymentMethod(&entities.SavedPaymentMethod{
+		PaymentCustomerID:     customer.ID,
+		Status:                constants.SavedPaymentMethodPending,
+		StripeSetupIntentID:   si.ID,
+		ConsentText:           req.ConsentText,
+		ConsentedAt:           time.Now(),
+		ConsentRecordedByID:   userID,
+		CoversReservationFees: req.CoversReservationFees,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &paymentModels.CreateSetupIntentResponse{
+		ClientSecret:       si.ClientSecret,
+		SetupIntentID:      si.ID,
+		SavedPaymentMethod: paymentModels.NewSavedPaymentMethodDtoFromEntity(*method),
+	}, nil
+}
+
+// CompleteSetupIntent records the card a confirmed setup intent saved, for clients that do not wait for its webhook
+func (s *Service) CompleteSetupIntent(setupIntentID string, userID uint) (*paymentModels.SavedPaymentMethodDto, error) {
+	method, err := s.customerRepo.GetSavedPaymentMethodBySetupIntentID(setupIntentID)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil {
+		return nil, errors.New("saved payment method not found")
+	}
+	if _, err := s.getAuthorizedCustomer(method.PaymentCustomerID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+
+	method, err = s.syncSetupIntent(setupIntentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewSavedPaymentMethodDtoFromEntity(*method)
+	return &dto, nil
+}
+
+// syncSetupIntent records the outcome of a setup intent at the gateway on its pending card. Cards that were saved
+// or failed already are left as they are.
+func (s *Service) syncSetupIntent(setupIntentID string) (*entities.SavedPaymentMethod, error) {
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+
+	method, err := s.customerRepo.GetSavedPaymentMethodBySetupIntentID(setupIntentID)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil {
+		return nil, errors.New("saved payment method not found")
+	}
+	if method.Status != constants.SavedPaymentMethodPending {
+		return method, nil
+	}
+
+	si, err := s.gateway.GetSetupIntent(setupIntentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch si.Status {
+	case IntentSucceeded:
+		card, err := s.gateway.GetCard(si.PaymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+		method.Status = constants.SavedPaymentMethodActive
+		method.StripePaymentMethodID = &card.ID
+		method.Brand = card.Brand
+		method.Last4 = card.Last4
+		method.ExpMonth = card.ExpMonth
+		method.ExpYear = card.ExpYear
+	case IntentCanceled:
+		method.Status = constants.SavedPaymentMethodFailed
+	default:
+		// Still waiting for the customer, who may retry a declined card
+		if si.LastError != "" {
+			log.Printf("Setup intent %s of saved payment method %d was declined: %s", setupIntentID, method.ID, si.LastError)
+		}
+		return method, nil
+	}
+
+	settled, err := s.customerRepo.SettleSavedPaymentMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	if !settled {
+		// Settled concurrently by the webhook or the client
+		return s.customerRepo.GetSavedPaymentMethodBySetupIntentID(setupIntentID)
+	}
+	log.Printf("Saved payment method %d of customer %d is %s", method.ID, method.PaymentCustomerID, method.Status)
+	return method, nil
+}
+
+// findSavedPaymentMethod returns the customer's saved card with an ID
+func findSavedPaymentMethod(customer *entities.PaymentCustomer, id uint) (*entities.SavedPaymentMethod, error) {
+	for i := range customer.SavedPaymentMethods {
+		if customer.SavedPaymentMethods[i].ID == id {
+			return &customer.SavedPaymentMethods[i], nil
+		}
+	}
+	return nil, errors.New("saved payment method not found")
+}
+
+// DetachPaymentMethod removes a saved card from its customer at the gateway, so it cannot be charged again. The
+// record and its consent are kept.
+func (s *Service) DetachPaymentMethod(customerID uint, methodID uint, userID uint) (*paymentModels.SavedPaymentMethodDto, error) {
+	customer, err := s.getAuthorizedCustomer(customerID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	method, err := findSavedPaymentMethod(customer, methodID)
+	if err != nil {
+		return nil, err
+	}
+	if method.Status == constants.SavedPaymentMethodDetached {
+		return nil, errors.New("saved payment method is already detached")
+	}
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+
+	if method.StripePaymentMethodID != nil {
+		if err := s.gateway.DetachCard(*method.StripePaymentMethodID); err != nil {
+			log.Printf("Failed to detach saved payment method %d: %v", method.ID, err)
+			return nil, fmt.Errorf("detach failed: %w", err)
+		}
+	} else if method.Status == constants.SavedPaymentMethodPending {
+		// A card saved after this would still be attached, so the setup intent is left to expire unrecorded
+		log.Printf("Detaching saved payment method %d before its setup intent %s completed", method.ID, method.StripeSetupIntentID)
+	}
+
+	now := time.Now()
+	method.Status = constants.SavedPaymentMethodDetached
+	method.DetachedAt = &now
+	if err := s.customerRepo.UpdateSavedPaymentMethod(method); err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewSavedPaymentMethodDtoFromEntity(*method)
+	return &dto, nil
+}
+
+// ChargeSavedCard charges a customer's saved card without them present, for an order, e.g. a repeat order, or a
+// reservation, e.g. a no-show fee. The payment is linked to it.
+func (s *Service) ChargeSavedCard(customerID uint, req paymentModels.ChargeSavedCardRequest, userID uint) (*paymentModels.PaymentDto, error) {
+	customer, err := s.getAuthorizedCustomer(customerID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	method, err := findSavedPaymentMethod(customer, req.SavedPaymentMethodID)
+	if err != nil {
+		return nil, err
+	}
+	if (req.OrderID == nil) == (req.ReservationID == nil) {
+		return nil, errors.New("either an order or a reservation is required")
+	}
+
+	var payment *entities.Payment
+	if req.OrderID != nil {
+		order, err := s.getPaymentOrder(*req.OrderID, customer.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		payment, err = s.chargeSavedCard(customer, method, req.Amount, order.Currency, order, nil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		reservation, err := s.reservationRepo.GetReservationByID(*req.ReservationID)
+		if err != nil {
+			return nil, err
+		}
+		if reservation == nil || reservation.Service.BusinessID != customer.BusinessID {
+			return nil, errors.New("reservation not found")
+		}
+		payment, err = s.chargeSavedCard(customer, method, req.Amount, reservation.Currency, nil, &reservation.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
+	return &dto, nil
+}
+
+// GetReservationFeeCard returns the saved card of a business's customer that reservation fees can be charged to: an
+// active card of the customer with the email whose consent covers reservation fees
+func (s *Service) GetReservationFeeCard(businessID uint, methodID uint, email string) (*entities.SavedPaymentMethod, error) {
+	method, err := s.customerRepo.GetSavedPaymentMethodByID(methodID)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil || method.PaymentCustomer.BusinessID != businessID {
+		return nil, errors.New("saved payment method not found")
+	}
+	if method.PaymentCustomer.Email != normalizeEmail(email) {
+		return nil, errors.New("saved payment method belongs to another customer")
+	}
+	if method.Status != constants.SavedPaymentMethodActive {
+		return nil, errors.New("saved payment method is not active")
+	}
+	if !method.CoversReservationFees {
+		return nil, errors.New("saved payment method does not cover reservation fees")
+	}
+	return method, nil
+}
+
+// ChargeReservationFee charges a reservation fee, e.g. for a no-show, to the card linked to the reservation when it
+// was booked and links the payment to the reservation. It returns an error when no card was linked.
+func (s *Service) ChargeReservationFee(reservationID uint, businessID uint, methodID *uint, email string, amount float64, code string) (*paymentModels.PaymentDto, error) {
+	if methodID == nil {
+		return nil, errors.New("reservation has no card for fees")
+	}
+	method, err := s.GetReservationFeeCard(businessID, *methodID, email)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.chargeSavedCard(&method.PaymentCustomer, method, amount, code, nil, &reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
+	return &dto, nil
+}
+
+// chargeSavedCard charges a saved card off-session and records the payment, linked to the order or reservation it
+// is for. An order's card surcharge is added to the amount.
+func (s *Service) chargeSavedCard(customer *entities.PaymentCustomer, method *entities.SavedPaymentMethod, amount float64, code string, order *entities.Order, reservationID *uint) (*entities.Payment, error) {
+	if method.Status != constants.SavedPaymentMethodActive || method.StripePaymentMethodID == nil {
+		return nil, errors.New("saved payment method is not active")
+	}
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+
+	amount = currency.Round(amount, code)
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	metadata := map[string]string{
+		"business_id":             fmt.Sprintf("%d", customer.BusinessID),
+		"payment_customer_id":     fmt.Sprintf("%d", customer.ID),
+		"saved_payment_method_id": fmt.Sprintf("%d", method.ID),
+	}
+	var adjustment *entities.OrderAdjustment
+	if order != nil {
+		metadata["order_id"] = fmt.Sprintf("%d", order.ID)
+		adjusted, orderAdjustment, err := s.applyTenderRule(order, constants.CreditCard, amount)
+		if err != nil {
+			return nil, err
+		}
+		amount, adjustment = adjusted, orderAdjustment
+	}
+	if reservationID != nil {
+		metadata["reservation_id"] = fmt.Sprintf("%d", *reservationID)
+	}
+
+	pi, err := s.gateway.CreateIntent(amount, code, metadata, IntentOptions{
+		CustomerID:      customer.StripeCustomerID,
+		PaymentMethodID: *method.StripePaymentMethodID,
+		OffSession:      true,
+	})
+	if err != nil {
+		log.Printf("Failed to charge saved payment method %d: %v", method.ID, err)
+		return nil, fmt.Errorf("charge declined: %w", err)
+	}
+
+	paymentIntentID := pi.ID
+	stripeCustomerID := customer.StripeCustomerID
+	payment := &entities.Payment{
+		Amount:                amount,
+		Currency:              pi.Currency,
+		Type:                  constants.CreditCard,
+		Status:                constants.Pending,
+		StripePaymentIntentID: &paymentIntentID,
+		StripeCustomerID:      &stripeCustomerID,
+		SavedPaymentMethodID:  &method.ID,
+		BusinessID:            customer.BusinessID,
+	}
+	if adjustment != nil {
+		_, err = s.repo.CreatePaymentWithAdjustment(payment, adjustment)
+	} else {
+		_, err = s.repo.CreatePayment(payment)
+	}
+	if err != nil {
+		// The card was charged, so the payment is left for the webhook to report rather than refunded here
+		log.Printf("Failed to record the off-session payment of intent %s: %v", pi.ID, err)
+		return nil, err
+	}
+
+	if order != nil {
+		_, err = s.orderRepo.CreateOrderPaymentLink(&entities.OrderPaymentLink{OrderID: order.ID, PaymentID: payment.ID})
+	} else {
+		_, err = s.reservationRepo.CreateReservationPaymentLink(&entities.ReservationPaymentLink{ReservationID: *reservationID, PaymentID: payment.ID})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Completed once linked, so the order or reservation is updated too
+	if pi.Status == IntentSucceeded {
+		if err := s.transitionPaymentStatus(payment, constants.Completed); err != nil && err.Error() != "invalid payment status transition" {
+			return nil, err
+		}
+	}
+	log.Printf("Charged %s off-session to saved payment method %d of customer %d", currency.Format(amount, payment.Currency), method.ID, customer.ID)
+	return payment, nil
+}
@@ -0,0 +1,586 @@
+package service
+
+import (
+	"VersatilePOS/generic/currency"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Outcomes the fake gateway simulates. Without a fake_outcome metadata entry the outcome follows the last two digits
+// of the amount in minor units, e.g. the cents: 01 declines, 02 requires 3DS authentication and anything else
+// succeeds. Incrementing an authorization to a total ending in 01 is declined too. Off-session charges of saved cards
+// return declines as errors, and 02 is declined too as nobody is there to authenticate. Setup intents save a card
+// unless their metadata has fake_outcome decline.
+const (
+	FakeOutcomeSuccess        = "success"
+	FakeOutcomeDecline        = "decline"
+	FakeOutcomeRequiresAction = "requires_action"
+)
+
+const fakeDeclineMessage = "Your card was declined."
+
+// FakeGateway simulates a payment gateway in process so checkout can be developed and tested offline. Intents
+// and setup intents are confirmed as if by the client shortly after they are created and the resulting webhooks
+// are delivered to the handler set with SetWebhookHandler, signed like real ones.
+type FakeGateway struct {
+	mu            sync.Mutex
+	intents       map[string]*fakeIntent
+	customers     map[string]bool
+	setupIntents  map[string]*GatewaySetupIntent
+	cards         map[string]*GatewayCard
+	run           string
+	sequence      int
+	delay         time.Duration
+	webhookSecret string
+	handler       func(payload []byte, signature string)
+}
+
+type fakeIntent struct {
+	intent        GatewayIntent
+	outcome       string
+	manualCapture bool
+	refunded      float64
+}
+
+type fakeEvent struct {
+	ID             string              `json:"id"`
+	Type           string              `json:"type"`
+	Intent         GatewayIntent       `json:"intent"`
+	SetupIntent    *GatewaySetupIntent `json:"setupIntent,omitempty"`
+	AmountRefunded float64             `json:"amountRefunded,omitempty"`
+}
+
+func NewFakeGateway() *FakeGateway {
+	delay := time.Second
+	if value, err := strconv.Atoi(os.Getenv("FAKE_GATEWAY_WEBHOOK_DELAY_MS")); err == nil && value >= 0 {
+		delay = time.Duration(value) * time.Millisecond
+	}
+
+	webhookSecret := os.Getenv("FAKE_GATEWAY_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		webhookSecret = "fake_whsec"
+	}
+
+	return &FakeGateway{
+		intents:       make(map[string]*fakeIntent),
+		customers:     make(map[string]bool),
+		setupIntents:  make(map[string]*GatewaySetupIntent),
+		cards:         make(map[string]*GatewayCard),
+		run:           strconv.FormatInt(time.Now().Unix(), 36),
+		delay:         delay,
+		webhookSecret: webhookSecret,
+	}
+}
+
+// SetWebhookHandler sets where simulated webhooks are delivered
+func (g *FakeGateway) SetWebhookHandler(handler func(payload []byte, signature string)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handler = handler
+}
+
+// fakeOutcome picks the simulated outcome of an intent
+func fakeOutcome(amount float64, code string, metadata map[string]string) string {
+	switch metadata["fake_outcome"] {
+	case FakeOutcomeSuccess, FakeOutcomeDecline, FakeOutcomeRequiresAction:
+		return metadata["fake_outcome"]
+	}
+
+	switch currency.ToMinor(amount, code) % 100 {
+	case 1:
+		return FakeOutcomeDecline
+	case 2:
+		return FakeOutcomeRequiresAction
+	}
+	return FakeOutcomeSuccess
+}
+
+// nextID returns a sequential ID with the given prefix. IDs include when the gateway started, so they do not
+// collide with those stored before a restart. The caller must hold g.mu.
+func (g *FakeGateway) nextID(prefix string) string {
+	g.sequence++
+	return fmt.Sprintf("%s_%s_%06d", prefix, g.run, g.sequence)
+}
+
+// getIntent looks an intent up. The caller must hold g.mu.
+func (g *FakeGateway) getIntent(intentID string) (*fakeIntent, error) {
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	return intent, nil
+}
+
+// CreateIntent creates a simulated intent and schedules its confirmation. Off-session intents are confirmed with
+// the saved card right away.
+func (g *FakeGateway) CreateIntent(amount float64, code string, metadata map[string]string, options IntentOptions) (*GatewayIntent, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if options.OffSession {
+		return g.chargeOffSession(amount, code, metadata, options)
+	}
+
+	id := g.nextID("fake_pi")
+	intent := &fakeIntent{
+		intent: GatewayIntent{
+			ID:           id,
+			ClientSecret: id + "_secret",
+			Status:       IntentRequiresPaymentMethod,
+			Amount:       amount,
+			Currency:     currency.Normalize(code),
+			Metadata:     metadata,
+			Created:      time.Now(),
+		},
+		outcome:       fakeOutcome(amount, code, metadata),
+		manualCapture: options.ManualCapture,
+	}
+	g.intents[id] = intent
+
+	time.AfterFunc(g.delay, func() { g.confirm(id) })
+
+	result := intent.intent
+	return &result, nil
+}
+
+// chargeOffSession charges a saved card without the customer present. The caller must hold g.mu.
+func (g *FakeGateway) chargeOffSession(amount float64, code string, metadata map[string]string, options IntentOptions) (*GatewayIntent, error) {
+	card, ok := g.cards[options.PaymentMethodID]
+	if !ok || card.CustomerID != options.CustomerID {
+		return nil, fmt.Errorf("no such payment method: %s", options.PaymentMethodID)
+	}
+
+	switch fakeOutcome(amount, code, metadata) {
+	case FakeOutcomeDecline:
+		return nil, errors.New(fakeDeclineMessage)
+	case FakeOutcomeRequiresAction:
+		return nil, errors.New("This card requires authentication, which is not possible off-session.")
+	}
+
+	id := g.nextID("fake_pi")
+	intent := &fakeIntent{
+		intent: GatewayIntent{
+			ID:       id,
+			Status:   IntentRequiresPaymentMethod,
+			Amount:   amount,
+			Currency: currency.Normalize(code),
+			Metadata: metadata,
+			Created:  time.Now(),
+		},
+		outcome:       FakeOutcomeSuccess,
+		manualCapture: options.ManualCapture,
+	}
+	g.intents[id] = intent
+
+	event := g.newEvent(g.approve(intent), intent)
+	go g.deliver(event)
+
+	result := intent.intent
+	return &result, nil
+}
+
+// confirm simulates the client confirming an intent with a card
+func (g *FakeGateway) confirm(intentID string) {
+	g.mu.Lock()
+	intent, err := g.getIntent(intentID)
+	if err != nil || intent.intent.Status != IntentRequiresPaymentMethod || intent.intent.LastError != "" {
+		g.mu.Unlock()
+		return
+	}
+
+	var eventType string
+	switch intent.outcome {
+	case FakeOutcomeDecline:
+		intent.intent.LastError = fakeDeclineMessage
+		eventType = EventIntentPaymentFailed
+	case FakeOutcomeRequiresAction:
+		intent.intent.Status = IntentRequiresAction
+		eventType = EventIntentRequiresAction
+	default:
+		eventType = g.approve(intent)
+	}
+	event := g.newEvent(eventType, intent)
+	g.mu.Unlock()
+
+	g.deliver(event)
+}
+
+// approve authorizes an intent the card was accepted for, and captures it unless it is captured manually. It
+// returns the type of the resulting event. The caller must hold g.mu.
+func (g *FakeGateway) approve(intent *fakeIntent) string {
+	if intent.manualCapture {
+		intent.intent.Status = IntentRequiresCapture
+		intent.intent.AmountCapturable = intent.intent.Amount
+		intent.intent.LastError = ""
+		return EventIntentAuthorized
+	}
+	g.succeed(intent, intent.intent.Amount)
+	return EventIntentSucceeded
+}
+
+// succeed marks an intent as paid for amount. The caller must hold g.mu.
+func (g *FakeGateway) succeed(intent *fakeIntent, amount float64) {
+	intent.intent.Status = IntentSucceeded
+	intent.intent.AmountReceived = amount
+	intent.intent.AmountCapturable = 0
+	intent.intent.LastError = ""
+}
+
+// Authenticate completes the simulated 3DS challenge of an intent, approving or failing it
+func (g *FakeGateway) Authenticate(intentID string, approve bool) (*GatewayIntent, error) {
+	g.mu.Lock()
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	if intent.intent.Status != IntentRequiresAction {
+		g.mu.Unlock()
+		return nil, errors.New("payment intent does not require authentication")
+	}
+
+	var eventType string
+	if approve {
+		eventType = g.approve(intent)
+	} else {
+		intent.intent.Status = IntentRequiresPaymentMethod
+		intent.intent.LastError = "The cardholder failed authentication."
+		eventType = EventIntentPaymentFailed
+	}
+	event := g.newEvent(eventType, intent)
+	result := intent.intent
+	g.mu.Unlock()
+
+	go g.deliver(event)
+	return &result, nil
+}
+
+// IncrementAuthorization raises the amount authorized for an intent awaiting capture
+func (g *FakeGateway) IncrementAuthorization(intentID string, amount float64, code string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		return nil, err
+	}
+	if intent.intent.Status != IntentRequiresCapture {
+		return nil, errors.New("payment intent is not awaiting capture")
+	}
+	if amount <= intent.intent.Amount {
+		return nil, errors.New("amount must be greater than the authorized amount")
+	}
+	if currency.ToMinor(amount, code)%100 == 1 {
+		return nil, errors.New(fakeDeclineMessage)
+	}
+
+	intent.intent.Amount = amount
+	intent.intent.AmountCapturable = amount
+	result := intent.intent
+	return &result, nil
+}
+
+// CaptureIntent captures an intent awaiting capture
+func (g *FakeGateway) CaptureIntent(intentID string, amount *float64, code string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	if intent.intent.Status != IntentRequiresCapture {
+		g.mu.Unlock()
+		return nil, errors.New("payment intent cannot be captured")
+	}
+	captured := intent.intent.Amount
+	if amount != nil {
+		if *amount <= 0 || *amount > intent.intent.Amount {
+			g.mu.Unlock()
+			return nil, errors.New("amount to capture exceeds the authorized amount")
+		}
+		captured = *amount
+	}
+
+	g.succeed(intent, captured)
+	event := g.newEvent(EventIntentSucceeded, intent)
+	result := intent.intent
+	g.mu.Unlock()
+
+	go g.deliver(event)
+	return &result, nil
+}
+
+// CancelIntent cancels an intent that has not succeeded
+func (g *FakeGateway) CancelIntent(intentID string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	if intent.intent.Status == IntentSucceeded || intent.intent.Status == IntentCanceled {
+		g.mu.Unlock()
+		return nil, fmt.Errorf("payment intent cannot be canceled in status %s", intent.intent.Status)
+	}
+
+	intent.intent.Status = IntentCanceled
+	event := g.newEvent(EventIntentCanceled, intent)
+	result := intent.intent
+	g.mu.Unlock()
+
+	go g.deliver(event)
+	return &result, nil
+}
+
+// RefundIntent refunds a succeeded intent, at most what is left of it
+func (g *FakeGateway) RefundIntent(intentID string, amount *float64, code string) (*GatewayRefund, error) {
+	g.mu.Lock()
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	if intent.intent.Status != IntentSucceeded {
+		g.mu.Unlock()
+		return nil, errors.New("payment intent has not succeeded")
+	}
+
+	remaining := currency.Round(intent.intent.AmountReceived-intent.refunded, intent.intent.Currency)
+	refundAmount := remaining
+	if amount != nil {
+		refundAmount = *amount
+	}
+	if refundAmount <= 0 || refundAmount > remaining {
+		g.mu.Unlock()
+		return nil, errors.New("refund amount exceeds the amount left to refund")
+	}
+
+	intent.refunded = currency.Round(intent.refunded+refundAmount, intent.intent.Currency)
+	refund := &GatewayRefund{
+		ID:       g.nextID("fake_re"),
+		IntentID: intentID,
+		Amount:   refundAmount,
+		Status:   "succeeded",
+	}
+	event := g.newEvent(EventChargeRefunded, intent)
+	event.AmountRefunded = intent.refunded
+	g.mu.Unlock()
+
+	go g.deliver(event)
+	return refund, nil
+}
+
+// GetIntent returns the current state of an intent
+func (g *FakeGateway) GetIntent(intentID string) (*GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, err := g.getIntent(intentID)
+	if err != nil {
+		return nil, err
+	}
+	result := intent.intent
+	return &result, nil
+}
+
+// ListIntents lists the simulated intents created since a time. Intents do not outlive the process.
+func (g *FakeGateway) ListIntents(since time.Time) ([]GatewayIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var intents []GatewayIntent
+	for _, intent := range g.intents {
+		if !intent.intent.Created.Before(since) {
+			intents = append(intents, intent.intent)
+		}
+	}
+	return intents, nil
+}
+
+// CreateCustomer creates a simulated customer
+func (g *FakeGateway) CreateCustomer(name string, email string, phone string, metadata map[string]string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := g.nextID("fake_cus")
+	g.customers[id] = true
+	return id, nil
+}
+
+// CreateSetupIntent creates a simulated setup intent and schedules its confirmation
+func (g *FakeGateway) CreateSetupIntent(customerID string, metadata map[string]string) (*GatewaySetupIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.customers[customerID] {
+		return nil, fmt.Errorf("no such customer: %s", customerID)
+	}
+
+	id := g.nextID("fake_seti")
+	intent := &GatewaySetupIntent{
+		ID:           id,
+		ClientSecret: id + "_secret",
+		Status:       IntentRequiresPaymentMethod,
+		CustomerID:   customerID,
+		Metadata:     metadata,
+	}
+	g.setupIntents[id] = intent
+
+	time.AfterFunc(g.delay, func() { g.confirmSetup(id) })
+
+	result := *intent
+	return &result, nil
+}
+
+// confirmSetup simulates the client confirming a setup intent with a card, which is saved to the customer
+func (g *FakeGateway) confirmSetup(setupIntentID string) {
+	g.mu.Lock()
+	intent, ok := g.setupIntents[setupIntentID]
+	if !ok || intent.Status != IntentRequiresPaymentMethod || intent.LastError != "" {
+		g.mu.Unlock()
+		return
+	}
+
+	eventType := EventSetupIntentSucceeded
+	if intent.Metadata["fake_outcome"] == FakeOutcomeDecline {
+		intent.LastError = fakeDeclineMessage
+		eventType = EventSetupIntentFailed
+	} else {
+		card := &GatewayCard{
+			ID:         g.nextID("fake_pm"),
+			CustomerID: intent.CustomerID,
+			Brand:      "visa",
+			Last4:      "4242",
+			ExpMonth:   12,
+			ExpYear:    time.Now().Year() + 3,
+		}
+		g.cards[card.ID] = card
+		intent.Status = IntentSucceeded
+		intent.PaymentMethodID = card.ID
+	}
+	snapshot := *intent
+	event := fakeEvent{
+		ID:          g.nextID("fake_evt"),
+		Type:        eventType,
+		SetupIntent: &snapshot,
+	}
+	g.mu.Unlock()
+
+	g.deliver(event)
+}
+
+// GetSetupIntent returns the current state of a setup intent
+func (g *FakeGateway) GetSetupIntent(setupIntentID string) (*GatewaySetupIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.setupIntents[setupIntentID]
+	if !ok {
+		return nil, fmt.Errorf("no such setup intent: %s", setupIntentID)
+	}
+	result := *intent
+	return &result, nil
+}
+
+// GetCard returns a saved card
+func (g *FakeGateway) GetCard(paymentMethodID string) (*GatewayCard, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	card, ok := g.cards[paymentMethodID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment method: %s", paymentMethodID)
+	}
+	result := *card
+	return &result, nil
+}
+
+// DetachCard removes a saved card
+func (g *FakeGateway) DetachCard(paymentMethodID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.cards[paymentMethodID]; !ok {
+		return fmt.Errorf("no such payment method: %s", paymentMethodID)
+	}
+	delete(g.cards, paymentMethodID)
+	return nil
+}
+
+// sign computes the signature of a webhook payload
+func (g *FakeGateway) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(g.webhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a webhook delivered by the fake gateway
+func (g *FakeGateway) VerifyWebhook(payload []byte, signature string) (*GatewayEvent, error) {
+	if !hmac.Equal([]byte(g.sign(payload)), []byte(signature)) {
+		return nil, errors.New("invalid webhook signature")
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	var object interface{} = event.Intent
+	intentID := event.Intent.ID
+	if event.SetupIntent != nil {
+		object = event.SetupIntent
+		intentID = event.SetupIntent.ID
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GatewayEvent{
+		ID:             event.ID,
+		Type:           event.Type,
+		IntentID:       intentID,
+		AmountRefunded: event.AmountRefunded,
+		Data:           data,
+	}, nil
+}
+
+// newEvent snapshots an intent into a webhook event. The caller must hold g.mu.
+func (g *FakeGateway) newEvent(eventType string, intent *fakeIntent) fakeEvent {
+	return fakeEvent{
+		ID:     g.nextID("fake_evt"),
+		Type:   eventType,
+		Intent: intent.intent,
+	}
+}
+
+// deliver signs an event and hands it to the webhook handler
+func (g *FakeGateway) deliver(event fakeEvent) {
+	g.mu.Lock()
+	handler := g.handler
+	g.mu.Unlock()
+	if handler == nil {
+		log.Printf("Fake gateway: no webhook handler for event %s (%s)", event.ID, event.Type)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Fake gateway: failed to encode event %s: %v", event.ID, err)
+		return
+	}
+	handler(payload, g.sign(payload))
+}
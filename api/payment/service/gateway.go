@@ -0,0 +1,204 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntentStatus is the state of a payment or setup intent at the gateway. The values follow Stripe's naming.
+type IntentStatus string
+
+const (
+	IntentRequiresPaymentMethod IntentStatus = "requires_payment_method"
+	IntentRequiresConfirmation  IntentStatus = "requires_confirmation"
+	IntentRequiresAction        IntentStatus = "requires_action"
+	IntentProcessing            IntentStatus = "processing"
+	IntentRequiresCapture       IntentStatus = "requires_capture"
+	IntentSucceeded             IntentStatus = "succeeded"
+	IntentCanceled              IntentStatus = "canceled"
+)
+
+// Webhook event types, named after their Stripe counterparts
+const (
+	EventIntentSucceeded      = "payment_intent.succeeded"
+	EventIntentPaymentFailed  = "payment_intent.payment_failed"
+	EventIntentCanceled       = "payment_intent.canceled"
+	EventIntentRequiresAction = "payment_intent.requires_action"
+	EventIntentAuthorized     = "payment_intent.amount_capturable_updated"
+	EventChargeRefunded       = "charge.refunded"
+	EventDisputeCreated       = "charge.dispute.created"
+	EventSetupIntentSucceeded = "setup_intent.succeeded"
+	EventSetupIntentFailed    = "setup_intent.setup_failed"
+)
+
+// GatewayIntent is a card payment as reported by the gateway. Amounts are in major units of Currency, an upper case
+// ISO 4217 code.
+type GatewayIntent struct {
+	ID             string
+	ClientSecret   string
+	Status         IntentStatus
+	Amount         float64
+	AmountReceived float64
+	// AmountCapturable is what is authorized and can still be captured
+	AmountCapturable float64
+	Currency         string
+	Metadata         map[string]string
+	// LastError is the reason the last attempt was declined, if it was
+	LastError string
+	Created   time.Time
+}
+
+// IntentOptions are how an intent is to be processed
+type IntentOptions struct {
+	// ManualCapture only authorizes the amount, it is collected with CaptureIntent
+	ManualCapture bool
+	// CustomerID and PaymentMethodID charge a card saved to a customer
+	CustomerID      string
+	PaymentMethodID string
+	// OffSession confirms the intent right away without the customer present, relying on the consent they gave
+	// when saving the card. Declines, including cards that require authentication, are returned as errors.
+	OffSession bool
+}
+
+// GatewaySetupIntent saves a customer's card for later payments without charging it
+type GatewaySetupIntent struct {
+	ID           string
+	ClientSecret string
+	Status       IntentStatus
+	CustomerID   string
+	// PaymentMethodID is the card that was saved, once the intent succeeded
+	PaymentMethodID string
+	Metadata        map[string]string
+	// LastError is the reason the card could not be saved, if it could not
+	LastError string
+}
+
+// GatewayCard is a card saved to a customer at the gateway
+type GatewayCard struct {
+	ID         string
+	CustomerID string
+	Brand      string
+	Last4      string
+	ExpMonth   int
+	ExpYear    int
+}
+
+// GatewayRefund is a refund of a captured intent
+type GatewayRefund struct {
+	ID       string
+	IntentID string
+	Amount   float64
+	Status   string
+}
+
+// GatewayEvent is a verified webhook event
+type GatewayEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// IntentID is the payment intent the event is about, or the setup intent for setup events
+	IntentID string `json:"intentId"`
+	// AmountRefunded is the total refunded of the intent so far, for refund events
+	AmountRefunded float64 `json:"amountRefunded,omitempty"`
+	// Data is the raw object the event is about
+	Data json.RawMessage `json:"data"`
+}
+
+// PaymentGateway processes card payments. Stripe is the production implementation, FakeGateway simulates one offline.
+type PaymentGateway interface {
+	// CreateIntent starts a payment of amount in currency, to be confirmed by the client with the intent's client secret
+	CreateIntent(amount float64, currency string, metadata map[string]string, options IntentOptions) (*GatewayIntent, error)
+	// IncrementAuthorization raises the amount authorized for an intent awaiting capture to a new total in the
+	// intent's currency
+	IncrementAuthorization(intentID string, amount float64, currency string) (*GatewayIntent, error)
+	// CaptureIntent captures an authorized intent, in full when amount is nil
+	CaptureIntent(intentID string, amount *float64, currency string) (*GatewayIntent, error)
+	// CancelIntent cancels an intent that has not succeeded
+	CancelIntent(intentID string) (*GatewayIntent, error)
+	// RefundIntent refunds a succeeded intent, in full when amount is nil
+	RefundIntent(intentID string, amount *float64, currency string) (*GatewayRefund, error)
+	// GetIntent fetches the current state of an intent, failing with ErrIntentNotFound if the gateway has none
+	GetIntent(intentID string) (*GatewayIntent, error)
+	// ListIntents lists the intents created since a time
+	ListIntents(since time.Time) ([]GatewayIntent, error)
+	// VerifyWebhook checks the signature of a webhook payload and parses its event
+	VerifyWebhook(payload []byte, signature string) (*GatewayEvent, error)
+
+	// CreateCustomer creates a customer cards can be saved to and returns its ID
+	CreateCustomer(name string, email string, phone string, metadata map[string]string) (string, error)
+	// CreateSetupIntent starts saving a card to a customer for off-session payments, to be confirmed by the client
+	// with the intent's client secret
+	CreateSetupIntent(customerID string, metadata map[string]string) (*GatewaySetupIntent, error)
+	// GetSetupIntent fetches the current state of a setup intent
+	GetSetupIntent(setupIntentID string) (*GatewaySetupIntent, error)
+	// GetCard fetches a saved card
+	GetCard(paymentMethodID string) (*GatewayCard, error)
+	// DetachCard removes a saved card from its customer, so it cannot be charged again
+	DetachCard(paymentMethodID string) error
+}
+
+var (
+	gatewayMu    sync.RWMutex
+	gateway      PaymentGateway
+	gatewayOnce  sync.Once
+	errNoGateway = errors.New("payment gateway is not configured")
+)
+
+// ErrIntentNotFound is returned for intents the gateway does not know, e.g. ones created with another account
+var ErrIntentNotFound = errors.New("payment intent not found")
+
+// newGatewayFromEnv builds the gateway selected by PAYMENT_GATEWAY. When it is unset Stripe is used if
+// STRIPE_SECRET_KEY is set, otherwise card payments are unavailable.
+func newGatewayFromEnv() PaymentGateway {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("PAYMENT_GATEWAY")))
+	switch name {
+	case "fake":
+		log.Println("Using the fake payment gateway. Card payments are simulated and no money is moved.")
+		return NewFakeGateway()
+	case "stripe":
+		stripeGateway, err := NewStripeGateway()
+		if err != nil {
+			log.Fatalf("PAYMENT_GATEWAY is stripe but the Stripe gateway could not be initialized: %v", err)
+		}
+		return stripeGateway
+	case "":
+		stripeGateway, err := NewStripeGateway()
+		if err != nil {
+			log.Printf("Warning: No payment gateway configured (%v). Card payments and deposits will not be available. Set PAYMENT_GATEWAY=fake to simulate them.", err)
+			return nil
+		}
+		return stripeGateway
+	default:
+		log.Fatalf("Unknown PAYMENT_GATEWAY %q, expected stripe or fake", name)
+		return nil
+	}
+}
+
+// SetGateway replaces the gateway returned by Gateway
+func SetGateway(g PaymentGateway) {
+	gatewayOnce.Do(func() {})
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	gateway = g
+}
+
+// Gateway returns the configured payment gateway, or an error if card payments are unavailable
+func Gateway() (PaymentGateway, error) {
+	gatewayOnce.Do(func() {
+		g := newGatewayFromEnv()
+		gatewayMu.Lock()
+		gateway = g
+		gatewayMu.Unlock()
+	})
+
+	gatewayMu.RLock()
+	defer gatewayMu.RUnlock()
+	if gateway == nil {
+		return nil, errNoGateway
+	}
+	return gateway, nil
+}
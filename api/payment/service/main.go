@@ -1,19 +1,24 @@
 package service
 
 import (
+	businessRepository "VersatilePOS/business/repository"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/rbac"
 	giftCardService "VersatilePOS/giftCard/service"
 	itemRepository "VersatilePOS/item/repository"
+	orderModels "VersatilePOS/order/models"
 	orderRepository "VersatilePOS/order/repository"
 	paymentModels "VersatilePOS/payment/models"
 	"VersatilePOS/payment/repository"
+	reservationModels "VersatilePOS/reservation/models"
 	reservationRepository "VersatilePOS/reservation/repository"
 	"errors"
 	"fmt"
 	"log"
 
-	"github.com/stripe/stripe-go/v78"
+	"gorm.io/gorm"
 )
 
 type Service struct {
@@ -21,28 +26,93 @@ type Service struct {
 	orderRepo         orderRepository.Repository
 	reservationRepo   reservationRepository.Repository
 	itemRepo          itemRepository.Repository
-	stripeService     *StripeService
+	businessRepo      businessRepository.Repository
+	webhookRepo       repository.WebhookEventRepository
+	customerRepo      repository.PaymentCustomerRepository
+	discrepancyRepo   repository.PaymentDiscrepancyRepository
+	gateway           PaymentGateway
 	giftCardService   *giftCardService.Service
 }
 
 func NewService() *Service {
-	stripeService, err := NewStripeService()
-	if err != nil {
-		log.Printf("Warning: Stripe service initialization failed: %v. Stripe payments will not be available.", err)
-		stripeService = nil
-	}
+	gateway, _ := Gateway()
 
-	return &Service{
+	s := &Service{
 		repo:            repository.Repository{},
 		orderRepo:       orderRepository.Repository{},
 		reservationRepo: reservationRepository.Repository{},
 		itemRepo:        itemRepository.Repository{},
-		stripeService:   stripeService,
+		businessRepo:    businessRepository.Repository{},
+		webhookRepo:     repository.WebhookEventRepository{},
+		customerRepo:    repository.PaymentCustomerRepository{},
+		discrepancyRepo: repository.PaymentDiscrepancyRepository{},
+		gateway:         gateway,
 		giftCardService: giftCardService.NewService(),
 	}
+
+	// Simulated webhooks go through the same verification and handling as delivered ones
+	if fake, ok := gateway.(*FakeGateway); ok {
+		fake.SetWebhookHandler(func(payload []byte, signature string) {
+			if err := s.HandleWebhook(payload, signature); err != nil {
+				log.Printf("Failed to handle fake gateway webhook: %v", err)
+			}
+		})
+	}
+
+	return s
+}
+
+// checkPaymentAccess verifies the user's access to the payments of a business
+func checkPaymentAccess(businessID uint, userID uint, level constants.AccessLevel) error {
+	return checkScopedPaymentAccess(businessID, userID, level, rbac.Scope{})
 }
 
-func (s *Service) CreatePayment(req paymentModels.CreatePaymentRequest) (*paymentModels.PaymentDto, error) {
+// checkScopedPaymentAccess verifies access like checkPaymentAccess within the scope, e.g. of a terminal token
+func checkScopedPaymentAccess(businessID uint, userID uint, level constants.AccessLevel, scope rbac.Scope) error {
+	ok, err := rbac.HasScopedAccess(constants.Payments, level, businessID, userID, scope)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
+	}
+	return nil
+}
+
+// businessCurrency returns the currency new payments of a business are in
+func (s *Service) businessCurrency(businessID uint) (string, error) {
+	code, err := s.businessRepo.GetBusinessCurrency(businessID)
+	if err != nil {
+		return "", err
+	}
+	if code == "" {
+		return "", errors.New("business not found")
+	}
+	return code, nil
+}
+
+// getAuthorizedPayment loads a payment the user has the given access to. tokenBusinessID is the business the
+// request's terminal token is limited to, if any.
+func (s *Service) getAuthorizedPayment(id uint, userID uint, tokenBusinessID *uint, level constants.AccessLevel) (*entities.Payment, error) {
+	payment, err := s.repo.GetPaymentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, errors.New("payment not found")
+	}
+
+	if err := checkScopedPaymentAccess(payment.BusinessID, userID, level, rbac.Scope{TokenBusinessID: tokenBusinessID}); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *Service) CreatePayment(req paymentModels.CreatePaymentRequest, userID uint) (*paymentModels.PaymentDto, error) {
+	if err := checkPaymentAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+
 	paymentType := constants.PaymentType(req.Type)
 	// Validate payment type
 	if paymentType != constants.Cash && paymentType != constants.CreditCard &&
@@ -62,44 +132,96 @@ func (s *Service) CreatePayment(req paymentModels.CreatePaymentRequest) (*paymen
 		paymentStatus = status
 	}
 
+	// Payments taken against an order are in the order's currency, others in the business's
+	var order *entities.Order
+	var paymentCurrency string
+	if req.OrderID != nil {
+		paymentOrder, err := s.getPaymentOrder(*req.OrderID, req.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		order = paymentOrder
+		paymentCurrency = order.Currency
+	} else {
+		code, err := s.businessCurrency(req.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		paymentCurrency = code
+	}
+
 	if paymentType == constants.GiftCard {
 		if req.GiftCardCode == nil || *req.GiftCardCode == "" {
 			return nil, errors.New("gift card code is required for gift card payments")
 		}
+		if err := s.giftCardService.VerifyPaymentCard(*req.GiftCardCode, req.GiftCardPin, req.BusinessID, paymentCurrency); err != nil {
+			return nil, err
+		}
+	}
+
+	// Gift card payments are created pending and then redeemed, so the card's balance is only used through its ledger
+	redeemGiftCard := paymentType == constants.GiftCard && paymentStatus == constants.Completed
+	if redeemGiftCard {
+		paymentStatus = constants.Pending
+	}
+
+	amount := currency.Round(req.Amount, paymentCurrency)
+	var adjustment *entities.OrderAdjustment
+	if order != nil && (paymentStatus == constants.Pending || paymentStatus == constants.Completed) {
+		adjusted, orderAdjustment, err := s.applyTenderRule(order, paymentType, amount)
+		if err != nil {
+			return nil, err
+		}
+		amount, adjustment = adjusted, orderAdjustment
 	}
 
 	payment := &entities.Payment{
-		Amount:       req.Amount,
+		Amount:       amount,
+		Currency:     paymentCurrency,
 		Type:         paymentType,
 		Status:       paymentStatus,
 		GiftCardCode: req.GiftCardCode,
+		BusinessID:   req.BusinessID,
 	}
 
-	createdPayment, err := s.repo.CreatePayment(payment)
+	var createdPayment *entities.Payment
+	var err error
+	if adjustment != nil {
+		createdPayment, err = s.repo.CreatePaymentWithAdjustment(payment, adjustment)
+	} else {
+		createdPayment, err = s.repo.CreatePayment(payment)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if createdPayment.Status == constants.Completed {
-		if err := s.updateOrderStatusAfterPayment(createdPayment.ID); err != nil {
-			log.Printf("Warning: Failed to update order status after creating completed payment: %v", err)
-		}
-		if err := s.updateReservationStatusAfterPayment(createdPayment.ID); err != nil {
-			log.Printf("Warning: Failed to update reservation status after creating completed payment: %v", err)
+	if redeemGiftCard {
+		response, err := s.completeGiftCardPayment(createdPayment, userID)
+		if err != nil {
+			return nil, err
 		}
+		return &response.Payment, nil
+	}
+
+	if createdPayment.Status == constants.Completed {
+		s.afterPaymentCompleted(createdPayment.ID)
 	}
 
 	dto := paymentModels.NewPaymentDtoFromEntity(*createdPayment)
 	return &dto, nil
 }
 
-func (s *Service) GetPayments() ([]paymentModels.PaymentDto, error) {
-	payments, err := s.repo.GetPayments()
+func (s *Service) GetPayments(businessID uint, userID uint) ([]paymentModels.PaymentDto, error) {
+	if err := checkPaymentAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.repo.GetPayments(businessID)
 	if err != nil {
 		return nil, err
 	}
 
-	var paymentDtos []paymentModels.PaymentDto
+	paymentDtos := make([]paymentModels.PaymentDto, 0, len(payments))
 	for _, payment := range payments {
 		paymentDtos = append(paymentDtos, paymentModels.NewPaymentDtoFromEntity(payment))
 	}
@@ -107,37 +229,66 @@ func (s *Service) GetPayments() ([]paymentModels.PaymentDto, error) {
 	return paymentDtos, nil
 }
 
-func (s *Service) GetPaymentByID(id uint) (*paymentModels.PaymentDto, error) {
-	payment, err := s.repo.GetPaymentByID(id)
+func (s *Service) GetPaymentByID(id uint, userID uint, tokenBusinessID *uint) (*paymentModels.PaymentDto, error) {
+	payment, err := s.getAuthorizedPayment(id, userID, tokenBusinessID, constants.Read)
 	if err != nil {
 		return nil, err
 	}
-	if payment == nil {
-		return nil, errors.New("payment not found")
-	}
 
 	dto := paymentModels.NewPaymentDtoFromEntity(*payment)
 	return &dto, nil
 }
 
-// CreateStripePaymentIntent creates a Stripe payment intent and a pending payment record
-func (s *Service) CreateStripePaymentIntent(req paymentModels.CreateStripePaymentRequest) (*paymentModels.CreateStripePaymentResponse, error) {
-	if s.stripeService == nil {
-		return nil, errors.New("Stripe service is not configured")
+// CreateStripePaymentIntent creates a payment intent with the payment gateway and a pending payment record
+func (s *Service) CreateStripePaymentIntent(req paymentModels.CreateStripePaymentRequest, userID uint) (*paymentModels.CreateStripePaymentResponse, error) {
+	if err := checkPaymentAccess(req.BusinessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+
+	// Card payments of an order are in the order's currency, others in the business's
+	var order *entities.Order
+	var paymentCurrency string
+	if req.OrderID != nil {
+		paymentOrder, err := s.getPaymentOrder(*req.OrderID, req.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		order = paymentOrder
+		paymentCurrency = order.Currency
+	} else {
+		code, err := s.businessCurrency(req.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+		paymentCurrency = code
+	}
+	if req.Currency != "" && currency.Normalize(req.Currency) != paymentCurrency {
+		return nil, errors.New("currency does not match the business currency")
 	}
+	amount := currency.Round(req.Amount, paymentCurrency)
 
-	currency := req.Currency
-	if currency == "" {
-		currency = "usd" // Default currency
+	// The surcharge of a tab is left to the amount captured, as its final amount is not known yet
+	var adjustment *entities.OrderAdjustment
+	if order != nil && !req.ManualCapture {
+		adjusted, orderAdjustment, err := s.applyTenderRule(order, constants.CreditCard, amount)
+		if err != nil {
+			return nil, err
+		}
+		amount, adjustment = adjusted, orderAdjustment
 	}
 
-	metadata := make(map[string]string)
+	metadata := map[string]string{
+		"business_id": fmt.Sprintf("%d", req.BusinessID),
+	}
 	if req.OrderID != nil {
 		metadata["order_id"] = fmt.Sprintf("%d", *req.OrderID)
 	}
 
-	// Create Stripe payment intent
-	pi, err := s.stripeService.CreatePaymentIntent(req.Amount, currency, metadata)
+	pi, err := s.gateway.CreateIntent(amount, paymentCurrency, metadata, IntentOptions{ManualCapture: req.ManualCapture})
 	if err != nil {
 		return nil, err
 	}
@@ -145,26 +296,34 @@ func (s *Service) CreateStripePaymentIntent(req paymentModels.CreateStripePaymen
 	// Create pending payment record in database
 	paymentIntentID := pi.ID
 	payment := &entities.Payment{
-		Amount:                req.Amount,
+		Amount:                amount,
+		Currency:              paymentCurrency,
 		Type:                  constants.CreditCard,
 		Status:                constants.Pending,
 		StripePaymentIntentID: &paymentIntentID,
+		ManualCapture:         req.ManualCapture,
+		BusinessID:            req.BusinessID,
 	}
 
-	_, err = s.repo.CreatePayment(payment)
+	if adjustment != nil {
+		_, err = s.repo.CreatePaymentWithAdjustment(payment, adjustment)
+	} else {
+		_, err = s.repo.CreatePayment(payment)
+	}
 	if err != nil {
 		// If database save fails, try to cancel the payment intent
-		_, _ = s.stripeService.CancelPaymentIntent(pi.ID)
+		_, _ = s.gateway.CancelIntent(pi.ID)
 		return nil, err
 	}
 
 	return &paymentModels.CreateStripePaymentResponse{
 		ClientSecret:     pi.ClientSecret,
 		PaymentIntentID: pi.ID,
+		Amount:          amount,
 	}, nil
 }
 
-// updateOrderStatusAfterPayment updates order status to Confirmed when payment is completed
+// updateOrderStatusAfterPayment updates order status to Confirmed once its completed payments cover its total
 func (s *Service) updateOrderStatusAfterPayment(paymentID uint) error {
 	orders, err := s.orderRepo.GetOrdersByPaymentID(paymentID)
 	if err != nil {
@@ -173,28 +332,34 @@ func (s *Service) updateOrderStatusAfterPayment(paymentID uint) error {
 
 	for _, order := range orders {
 		if order.Status == constants.OrderPending {
-			allPaymentsCompleted := true
+			paid := 0.0
 			for _, link := range order.OrderPaymentLinks {
-				if link.Payment.Status != constants.Completed {
-					allPaymentsCompleted = false
-					break
+				if link.Payment.Status == constants.Completed {
+					paid += link.Payment.Amount
 				}
 			}
 
-			if allPaymentsCompleted && len(order.OrderPaymentLinks) > 0 {
+			// Partial payments, e.g. a gift card capped at its balance, leave the order Pending for another tender
+			total := orderModels.OrderTotal(order)
+			if paid > 0 && currency.ToMinor(paid, order.Currency) >= currency.ToMinor(total, order.Currency) {
 				order.Status = constants.OrderConfirmed
 				if err := s.orderRepo.UpdateOrder(&order); err != nil {
 					log.Printf("Failed to update order %d status after payment: %v", order.ID, err)
 					return err
 				}
-				log.Printf("Order %d status updated to Confirmed (all payments completed)", order.ID)
-				
+				log.Printf("Order %d status updated to Confirmed (paid %s of %s)", order.ID, currency.Format(paid, order.Currency), currency.Format(total, order.Currency))
+
 				// Decrease stock when order is confirmed
 				if err := s.decreaseOrderStock(order.ID); err != nil {
 					log.Printf("Warning: Failed to decrease stock for order %d: %v", order.ID, err)
 				}
+
+				// Issue the gift cards sold with the order
+				if err := s.giftCardService.IssueForOrder(order.ID); err != nil {
+					log.Printf("Warning: Failed to issue gift cards for order %d: %v", order.ID, err)
+				}
 			} else {
-				log.Printf("Order %d has incomplete payments, status remains Pending", order.ID)
+				log.Printf("Order %d is paid %s of %s, status remains Pending", order.ID, currency.Format(paid, order.Currency), currency.Format(total, order.Currency))
 			}
 		}
 	}
@@ -240,24 +405,30 @@ func (s *Service) updateReservationStatusAfterPayment(paymentID uint) error {
 	}
 
 	for _, reservation := range reservations {
-		if reservation.Status == constants.ReservationConfirmed {
-			allPaymentsCompleted := true
-			for _, link := range reservation.ReservationPaymentLinks {
-				if link.Payment.Status != constants.Completed {
-					allPaymentsCompleted = false
-					break
+		// A completed deposit confirms a reservation held as Pending, the deposit stays credited against the bill
+		if reservation.Status == constants.ReservationPending {
+			if reservation.DepositPaymentID != nil && *reservation.DepositPaymentID == paymentID {
+				reservation.Status = constants.ReservationConfirmed
+				if err := s.reservationRepo.UpdateReservation(&reservation); err != nil {
+					log.Printf("Failed to confirm reservation %d after deposit: %v", reservation.ID, err)
+					return err
 				}
+				log.Printf("Reservation %d status updated to Confirmed (deposit paid)", reservation.ID)
 			}
+			continue
+		}
 
-			if allPaymentsCompleted && len(reservation.ReservationPaymentLinks) > 0 {
+		if reservation.Status.CanTransitionTo(constants.ReservationCompleted) {
+			bill := reservationModels.NewReservationBillDtoFromEntity(reservation)
+			if bill.IsSettled() {
 				reservation.Status = constants.ReservationCompleted
 				if err := s.reservationRepo.UpdateReservation(&reservation); err != nil {
 					log.Printf("Failed to update reservation %d status after payment: %v", reservation.ID, err)
 					return err
 				}
-				log.Printf("Reservation %d status updated to Completed (all payments completed)", reservation.ID)
+				log.Printf("Reservation %d status updated to Completed (bill settled)", reservation.ID)
 			} else {
-				log.Printf("Reservation %d has incomplete payments, status remains Confirmed", reservation.ID)
+				log.Printf("Reservation %d has an outstanding balance of %s, status remains %s", reservation.ID, currency.Format(bill.Balance, reservation.Currency), reservation.Status)
 			}
 		}
 	}
@@ -265,7 +436,8 @@ func (s *Service) updateReservationStatusAfterPayment(paymentID uint) error {
 	return nil
 }
 
-// UpdatePaymentStatus updates the payment status (typically called from webhook)
+// UpdatePaymentStatus updates the status of a card payment from a gateway event. Events that would move the
+// payment backwards, e.g. a late failure after it completed, are ignored.
 func (s *Service) UpdatePaymentStatus(paymentIntentID string, status constants.PaymentStatus) error {
 	payment, err := s.repo.GetPaymentByStripePaymentIntentID(paymentIntentID)
 	if err != nil {
@@ -275,31 +447,16 @@ func (s *Service) UpdatePaymentStatus(paymentIntentID string, status constants.P
 		return errors.New("payment not found")
 	}
 
-	oldStatus := payment.Status
-	payment.Status = status
-	_, err = s.repo.UpdatePayment(payment)
-	if err != nil {
-	return err
-	}
-
-	if status == constants.Completed && oldStatus != constants.Completed {
-		if err := s.updateOrderStatusAfterPayment(payment.ID); err != nil {
-			log.Printf("Warning: Failed to update order status after payment completion: %v", err)
-		}
-		if err := s.updateReservationStatusAfterPayment(payment.ID); err != nil {
-			log.Printf("Warning: Failed to update reservation status after payment completion: %v", err)
+	if err := s.transitionPaymentStatus(payment, status); err != nil {
+		if err.Error() == "invalid payment status transition" {
+			log.Printf("Ignoring %s for payment %d, it is already %s", status, payment.ID, payment.Status)
+			return nil
 		}
+		return err
 	}
-
 	return nil
 }
 
-// UpdatePaymentStatusByString updates the payment status using a string (for webhook convenience)
-func (s *Service) UpdatePaymentStatusByString(paymentIntentID string, status string) error {
-	paymentStatus := constants.PaymentStatus(status)
-	return s.UpdatePaymentStatus(paymentIntentID, paymentStatus)
-}
-
 // UpdatePaymentStatusByID updates the payment status by payment ID (for non-Stripe payments)
 func (s *Service) UpdatePaymentStatusByID(paymentID uint, status constants.PaymentStatus) error {
 	payment, err := s.repo.GetPaymentByID(paymentID)
@@ -310,88 +467,132 @@ func (s *Service) UpdatePaymentStatusByID(paymentID uint, status constants.Payme
 		return errors.New("payment not found")
 	}
 
-	oldStatus := payment.Status
-	payment.Status = status
-	_, err = s.repo.UpdatePayment(payment)
+	return s.transitionPaymentStatus(payment, status)
+}
+
+// transitionPaymentStatus moves the payment to status if the state machine allows it and runs what follows
+// completion. A payment already in status is left as is.
+func (s *Service) transitionPaymentStatus(payment *entities.Payment, status constants.PaymentStatus) error {
+	if payment.Status == status {
+		return nil
+	}
+	if !payment.Status.CanTransitionTo(status) {
+		return errors.New("invalid payment status transition")
+	}
+
+	moved, err := s.repo.TransitionPaymentStatus(payment.ID, status)
 	if err != nil {
 		return err
 	}
+	if !moved {
+		// Another update got there first
+		return errors.New("invalid payment status transition")
+	}
+	payment.Status = status
 
-	if status == constants.Completed && oldStatus != constants.Completed {
-		if err := s.updateOrderStatusAfterPayment(payment.ID); err != nil {
-			log.Printf("Warning: Failed to update order status after payment completion: %v", err)
-		}
-		if err := s.updateReservationStatusAfterPayment(payment.ID); err != nil {
-			log.Printf("Warning: Failed to update reservation status after payment completion: %v", err)
+	if status == constants.Completed {
+		s.afterPaymentCompleted(payment.ID)
+	}
+	// A payment that was not taken does not round or surcharge its order
+	if status == constants.Failed {
+		if err := s.repo.DeleteOrderAdjustments(payment.ID); err != nil {
+			log.Printf("Warning: Failed to remove the order adjustments of failed payment %d: %v", payment.ID, err)
 		}
 	}
-
 	return nil
 }
 
-// CompletePayment completes a payment and updates linked order status
-func (s *Service) CompletePayment(paymentID uint) error {
-	payment, err := s.repo.GetPaymentByID(paymentID)
-	if err != nil {
-		return err
+// afterPaymentCompleted confirms the orders and reservations the payment settles
+func (s *Service) afterPaymentCompleted(paymentID uint) {
+	if err := s.updateOrderStatusAfterPayment(paymentID); err != nil {
+		log.Printf("Warning: Failed to update order status after payment completion: %v", err)
 	}
-	if payment == nil {
-		return errors.New("payment not found")
+	if err := s.updateReservationStatusAfterPayment(paymentID); err != nil {
+		log.Printf("Warning: Failed to update reservation status after payment completion: %v", err)
+	}
+}
+
+// CompletePayment completes a payment and updates linked order status. Gift card payments are redeemed up to the
+// card's balance and report the remaining amount.
+func (s *Service) CompletePayment(paymentID uint, userID uint, tokenBusinessID *uint) (*paymentModels.CompletePaymentResponse, error) {
+	payment, err := s.getAuthorizedPayment(paymentID, userID, tokenBusinessID, constants.Write)
+	if err != nil {
+		return nil, err
 	}
 
 	if payment.Type == constants.GiftCard {
-		if payment.GiftCardCode == nil || *payment.GiftCardCode == "" {
-			return errors.New("gift card code is missing for this payment")
-		}
+		return s.completeGiftCardPayment(payment, userID)
+	}
+	if payment.Status == constants.Authorized {
+		return nil, errors.New("authorized payments are completed by capturing them")
+	}
 
-		updatedCard, remainingPayment, err := s.giftCardService.RedeemGiftCard(*payment.GiftCardCode, payment.Amount)
-		if err != nil {
-			return fmt.Errorf("failed to redeem gift card: %w", err)
-		}
+	if err := s.UpdatePaymentStatusByID(paymentID, constants.Completed); err != nil {
+		return nil, err
+	}
+	payment.Status = constants.Completed
+	return &paymentModels.CompletePaymentResponse{Payment: paymentModels.NewPaymentDtoFromEntity(*payment)}, nil
+}
 
-		log.Printf("Gift card %s redeemed. Previous balance: %.2f, Amount deducted: %.2f, New balance: %.2f, Remaining payment: %.2f",
-			updatedCard.Code, updatedCard.Balance+payment.Amount, payment.Amount, updatedCard.Balance, remainingPayment)
+// completeGiftCardPayment redeems a pending gift card payment. When the balance does not cover it, the payment is
+// completed for the balance and the rest is left for another tender.
+func (s *Service) completeGiftCardPayment(payment *entities.Payment, userID uint) (*paymentModels.CompletePaymentResponse, error) {
+	if payment.Status != constants.Pending {
+		return nil, errors.New("payment is not pending")
+	}
 
-		if remainingPayment > 0 {
-			return fmt.Errorf("gift card has insufficient balance. Remaining amount to pay: %.2f", remainingPayment)
-		}
+	requested := payment.Amount
+	giftCard, redeemed, err := s.giftCardService.RedeemForPayment(*payment, userID, func(tx *gorm.DB, redeemed float64) error {
+		return s.repo.CompletePendingPayment(tx, payment.ID, redeemed)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.UpdatePaymentStatusByID(paymentID, constants.Completed)
+	log.Printf("Gift card %s redeemed %.2f of %.2f for payment %d. New balance: %.2f", giftCard.Code, redeemed, requested, payment.ID, giftCard.Balance)
+
+	payment.Amount = redeemed
+	payment.Status = constants.Completed
+	s.afterPaymentCompleted(payment.ID)
+
+	return &paymentModels.CompletePaymentResponse{
+		Payment:         paymentModels.NewPaymentDtoFromEntity(*payment),
+		RemainingAmount: currency.Round(requested-redeemed, payment.Currency),
+	}, nil
 }
 
-// ConfirmStripePayment confirms a Stripe payment and updates the payment status
+// ConfirmStripePayment fetches a card payment's intent from the payment gateway and updates the payment status
 func (s *Service) ConfirmStripePayment(paymentIntentID string) error {
-	if s.stripeService == nil {
-		return errors.New("Stripe service is not configured")
+	if s.gateway == nil {
+		return errNoGateway
 	}
 
-	// Get payment intent from Stripe
-	pi, err := s.stripeService.GetPaymentIntent(paymentIntentID)
+	pi, err := s.gateway.GetIntent(paymentIntentID)
 	if err != nil {
 		return err
 	}
+	return s.applyIntent(pi)
+}
 
-	// Update payment status based on Stripe payment intent status
+// applyIntent updates the payment of an intent to the intent's state at the gateway
+func (s *Service) applyIntent(pi *GatewayIntent) error {
+	// Update payment status based on the payment intent status
 	var status constants.PaymentStatus
 	switch pi.Status {
-	case "succeeded":
+	case IntentSucceeded:
 		status = constants.Completed
-	case "canceled":
+	case IntentRequiresCapture:
+		status = constants.Authorized
+	case IntentCanceled:
 		status = constants.Failed
-	case "requires_payment_method", "requires_confirmation", "requires_action", "processing":
+	case IntentRequiresPaymentMethod, IntentRequiresConfirmation, IntentRequiresAction, IntentProcessing:
 		status = constants.Pending
 	default:
 		status = constants.Failed
 	}
 
-	return s.UpdatePaymentStatus(paymentIntentID, status)
-}
-
-// VerifyWebhookSignature verifies a Stripe webhook signature
-func (s *Service) VerifyWebhookSignature(payload []byte, signature string) (*stripe.Event, error) {
-	if s.stripeService == nil {
-		return nil, errors.New("Stripe service is not configured")
+	if err := s.syncCapturedAmounts(pi.ID, pi); err != nil {
+		return err
 	}
-	return s.stripeService.VerifyWebhookSignature(payload, signature)
+	return s.UpdatePaymentStatus(pi.ID, status)
 }
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/rbac"
+	paymentModels "VersatilePOS/payment/models"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// orphanGrace is how old an intent has to be before it is reported as orphaned, so intents whose payment is still
+// being recorded are not
+const orphanGrace = 10 * time.Minute
+
+var reconcilerOnce sync.Once
+
+// StartReconciler reconciles the unsettled card payments with the payment gateway in the background, every
+// PAYMENT_RECONCILIATION_MINUTES, so payments whose webhook never arrived do not stay Pending
+func (s *Service) StartReconciler() {
+	reconcilerOnce.Do(func() {
+		interval := time.Duration(env.PositiveInt("PAYMENT_RECONCILIATION_MINUTES", 30)) * time.Minute
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				if s.gateway != nil {
+					if report, err := s.reconcile(nil); err != nil {
+						log.Printf("Failed to reconcile payments: %v", err)
+					} else {
+						log.Printf("Reconciled %d card payments: %d corrected, %d failed, %d discrepancies", report.Checked, report.Corrected, report.Failed, len(report.Discrepancies))
+					}
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+// checkReportAccess verifies the user's access to the payments of a business and to its reports
+func checkReportAccess(businessID uint, userID uint, level constants.AccessLevel) error {
+	if err := checkPaymentAccess(businessID, userID, level); err != nil {
+		return err
+	}
+
+	ok, err := rbac.HasAccess(constants.ViewReports, constants.Write, businessID, userID)
+	if err != nil {
+		return errors.New("failed to verify permissions")
+	}
+	if !ok {
+		return errors.New("unauthorized")
+	}
+	return nil
+}
+
+// ReconcilePayments reconciles the unsettled card payments of a business with the payment gateway now
+func (s *Service) ReconcilePayments(businessID uint, userID uint) (*paymentModels.PaymentReconciliationReport, error) {
+	if err := checkReportAccess(businessID, userID, constants.Write); err != nil {
+		return nil, err
+	}
+	return s.reconcile(&businessID)
+}
+
+// GetPaymentDiscrepancies lists the discrepancies reconciliation found for a business
+func (s *Service) GetPaymentDiscrepancies(businessID uint, userID uint) ([]paymentModels.PaymentDiscrepancyDto, error) {
+	if err := checkReportAccess(businessID, userID, constants.Read); err != nil {
+		return nil, err
+	}
+
+	discrepancies, err := s.discrepancyRepo.GetPaymentDiscrepancies(businessID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]paymentModels.PaymentDiscrepancyDto, 0, len(discrepancies))
+	for _, discrepancy := range discrepancies {
+		dtos = append(dtos, paymentModels.NewPaymentDiscrepancyDtoFromEntity(discrepancy))
+	}
+	return dtos, nil
+}
+
+// reconcile compares the Pending and Authorized card payments of a business, or of all when businessID is nil,
+// with their intents and corrects their status the way a webhook would have. Intents that took or hold money
+// without a payment recorded are reported as orphaned.
+func (s *Service) reconcile(businessID *uint) (*paymentModels.PaymentReconciliationReport, error) {
+	if s.gateway == nil {
+		return nil, errNoGateway
+	}
+
+	report := &paymentModels.PaymentReconciliationReport{
+		StartedAt:     time.Now(),
+		Discrepancies: []paymentModels.PaymentDiscrepancyDto{},
+	}
+
+	payments, err := s.repo.GetUnsettledCardPayments(businessID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		pi, err := s.gateway.GetIntent(*payment.StripePaymentIntentID)
+		if errors.Is(err, ErrIntentNotFound) {
+			report.Checked++
+			s.recordDiscrepancy(report, &entities.PaymentDiscrepancy{
+				BusinessID:            payment.BusinessID,
+				Type:                  constants.DiscrepancyMissingIntent,
+				StripePaymentIntentID: *payment.StripePaymentIntentID,
+				PaymentID:             &payment.ID,
+				Amount:                payment.Amount,
+				Currency:              payment.Currency,
+			})
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			log.Printf("Failed to fetch the intent of payment %d: %v", payment.ID, err)
+			continue
+		}
+		report.Checked++
+
+		if discrepancy := compareIntent(payment, pi); discrepancy != nil {
+			s.recordDiscrepancy(report, discrepancy)
+		}
+
+		if err := s.applyIntent(pi); err != nil {
+			report.Failed++
+			log.Printf("Failed to reconcile payment %d: %v", payment.ID, err)
+			continue
+		}
+		updated, err := s.repo.GetPaymentByID(payment.ID)
+		if err == nil && updated != nil && updated.Status != payment.Status {
+			report.Corrected++
+			log.Printf("Reconciled payment %d from %s to %s", payment.ID, payment.Status, updated.Status)
+		}
+	}
+
+	if err := s.findOrphanedIntents(report, businessID); err != nil {
+		report.Failed++
+		log.Printf("Failed to look for orphaned intents: %v", err)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// gatewayAmount is the amount an intent collected, holds or is for, depending on its status
+func gatewayAmount(pi *GatewayIntent) float64 {
+	switch pi.Status {
+	case IntentSucceeded:
+		return pi.AmountReceived
+	case IntentRequiresCapture:
+		return pi.AmountCapturable
+	}
+	return pi.Amount
+}
+
+// compareIntent returns the discrepancy between a payment and its intent, or nil if they match
+func compareIntent(payment *entities.Payment, pi *GatewayIntent) *entities.PaymentDiscrepancy {
+	discrepancy := &entities.PaymentDiscrepancy{
+		BusinessID:            payment.BusinessID,
+		StripePaymentIntentID: pi.ID,
+		PaymentID:             &payment.ID,
+		Amount:                payment.Amount,
+		Currency:              payment.Currency,
+		GatewayAmount:         gatewayAmount(pi),
+		GatewayCurrency:       currency.Normalize(pi.Currency),
+		GatewayStatus:         string(pi.Status),
+	}
+
+	if discrepancy.GatewayCurrency != payment.Currency {
+		discrepancy.Type = constants.DiscrepancyCurrencyMismatch
+		return discrepancy
+	}
+	if currency.ToMinor(payment.Amount, payment.Currency) != currency.ToMinor(discrepancy.GatewayAmount, payment.Currency) {
+		discrepancy.Type = constants.DiscrepancyAmountMismatch
+		return discrepancy
+	}
+	return nil
+}
+
+// findOrphanedIntents reports the intents created within PAYMENT_RECONCILIATION_LOOKBACK_HOURS that took or hold
+// money without a payment recorded, e.g. because recording it failed after the intent was created
+func (s *Service) findOrphanedIntents(report *paymentModels.PaymentReconciliationReport, businessID *uint) error {
+	lookback := time.Duration(env.PositiveInt("PAYMENT_RECONCILIATION_LOOKBACK_HOURS", 72)) * time.Hour
+	intents, err := s.gateway.ListIntents(time.Now().Add(-lookback))
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-orphanGrace)
+	for i := range intents {
+		pi := &intents[i]
+		if pi.Created.After(cutoff) {
+			continue
+		}
+		switch pi.Status {
+		case IntentSucceeded, IntentRequiresCapture, IntentProcessing:
+		default:
+			continue
+		}
+
+		payment, err := s.repo.GetPaymentByStripePaymentIntentID(pi.ID)
+		if err != nil {
+			return err
+		}
+		if payment != nil {
+			continue
+		}
+
+		owner, ok := s.intentBusinessID(pi)
+		if !ok {
+			log.Printf("Orphaned intent %s of %s (%s) does not belong to a known business", pi.ID, currency.Format(gatewayAmount(pi), pi.Currency), pi.Status)
+			continue
+		}
+		if businessID != nil && owner != *businessID {
+			continue
+		}
+
+		s.recordDiscrepancy(report, &entities.PaymentDiscrepancy{
+			BusinessID:            owner,
+			Type:                  constants.DiscrepancyOrphanedIntent,
+			StripePaymentIntentID: pi.ID,
+			GatewayAmount:         gatewayAmount(pi),
+			GatewayCurrency:       currency.Normalize(pi.Currency),
+			GatewayStatus:         string(pi.Status),
+		})
+	}
+	return nil
+}
+
+// intentBusinessID returns the business an intent was created for, from its metadata
+func (s *Service) intentBusinessID(pi *GatewayIntent) (uint, bool) {
+	if id, err := strconv.ParseUint(pi.Metadata["business_id"], 10, 32); err == nil {
+		return uint(id), true
+	}
+
+	// Older intents only name their order or reservation
+	if id, err := strconv.ParseUint(pi.Metadata["order_id"], 10, 32); err == nil {
+		if order, err := s.orderRepo.GetOrderByID(uint(id)); err == nil && order != nil {
+			return order.BusinessID, true
+		}
+	}
+	if id, err := strconv.ParseUint(pi.Metadata["reservation_id"], 10, 32); err == nil {
+		if reservation, err := s.reservationRepo.GetReservationByID(uint(id)); err == nil && reservation != nil {
+			return reservation.Service.BusinessID, true
+		}
+	}
+	return 0, false
+}
+
+// recordDiscrepancy stores a discrepancy and adds it to the report
+func (s *Service) recordDiscrepancy(report *paymentModels.PaymentReconciliationReport, discrepancy *entities.PaymentDiscrepancy) {
+	discrepancy.LastSeenAt = time.Now()
+	if err := s.discrepancyRepo.RecordPaymentDiscrepancy(discrepancy); err != nil {
+		log.Printf("Failed to record %s discrepancy of intent %s: %v", discrepancy.Type, discrepancy.StripePaymentIntentID, err)
+	}
+	log.Printf("Found %s discrepancy of intent %s for business %d", discrepancy.Type, discrepancy.StripePaymentIntentID, discrepancy.BusinessID)
+	report.Discrepancies = append(report.Discrepancies, paymentModels.NewPaymentDiscrepancyDtoFromEntity(*discrepancy))
+}
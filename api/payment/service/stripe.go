@@ -1,21 +1,53 @@
 package service
 
 import (
+	"VersatilePOS/generic/currency"
 	"encoding/json"
 	"errors"
+	"math"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/customer"
 	"github.com/stripe/stripe-go/v78/paymentintent"
+	"github.com/stripe/stripe-go/v78/paymentmethod"
+	"github.com/stripe/stripe-go/v78/refund"
+	"github.com/stripe/stripe-go/v78/setupintent"
 	"github.com/stripe/stripe-go/v78/webhook"
 )
 
-type StripeService struct {
+// stripeMinorUnits are the currencies whose amounts Stripe expresses with a different number of decimal places than
+// ISO 4217: ISK amounts are sent in hundredths though they have to be whole krónur, and MGA amounts in whole ariary
+var stripeMinorUnits = map[string]int{
+	"ISK": 2,
+	"MGA": 0,
+}
+
+// stripeToMinor converts an amount to the integer form Stripe expects for its currency
+func stripeToMinor(amount float64, code string) int64 {
+	if units, ok := stripeMinorUnits[currency.Normalize(code)]; ok {
+		return int64(math.Round(amount * math.Pow10(units)))
+	}
+	return currency.ToMinor(amount, code)
+}
+
+// stripeFromMinor converts an amount Stripe returned back to major units of its currency
+func stripeFromMinor(amount int64, code string) float64 {
+	if units, ok := stripeMinorUnits[currency.Normalize(code)]; ok {
+		return currency.Round(float64(amount)/math.Pow10(units), code)
+	}
+	return currency.FromMinor(amount, code)
+}
+
+// StripeGateway processes card payments with Stripe
+type StripeGateway struct {
 	secretKey     string
 	webhookSecret string
 }
 
-func NewStripeService() (*StripeService, error) {
+func NewStripeGateway() (*StripeGateway, error) {
 	secretKey := os.Getenv("STRIPE_SECRET_KEY")
 	if secretKey == "" {
 		return nil, errors.New("STRIPE_SECRET_KEY environment variable is not set")
@@ -26,78 +58,287 @@ func NewStripeService() (*StripeService, error) {
 
 	stripe.Key = secretKey
 
-	return &StripeService{
+	return &StripeGateway{
 		secretKey:     secretKey,
 		webhookSecret: webhookSecret,
 	}, nil
 }
 
-// CreatePaymentIntent creates a Stripe payment intent for the given amount
-func (s *StripeService) CreatePaymentIntent(amount float64, currency string, metadata map[string]string) (*stripe.PaymentIntent, error) {
-	// Convert amount to cents (Stripe uses smallest currency unit)
-	amountInCents := int64(amount * 100)
+// newGatewayIntent converts a Stripe payment intent
+func newGatewayIntent(pi *stripe.PaymentIntent) *GatewayIntent {
+	code := currency.Normalize(string(pi.Currency))
+	intent := &GatewayIntent{
+		ID:               pi.ID,
+		ClientSecret:     pi.ClientSecret,
+		Status:           IntentStatus(pi.Status),
+		Amount:           stripeFromMinor(pi.Amount, code),
+		AmountReceived:   stripeFromMinor(pi.AmountReceived, code),
+		AmountCapturable: stripeFromMinor(pi.AmountCapturable, code),
+		Currency:         code,
+		Metadata:         pi.Metadata,
+		Created:          time.Unix(pi.Created, 0),
+	}
+	if pi.LastPaymentError != nil {
+		intent.LastError = pi.LastPaymentError.Msg
+	}
+	return intent
+}
 
+// CreateIntent creates a Stripe payment intent for the given amount
+func (s *StripeGateway) CreateIntent(amount float64, code string, metadata map[string]string, options IntentOptions) (*GatewayIntent, error) {
 	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(amountInCents),
-		Currency: stripe.String(currency),
+		Amount: stripe.Int64(stripeToMinor(amount, code)),
+		// Stripe takes currency codes in lower case
+		Currency: stripe.String(strings.ToLower(code)),
 		Metadata: metadata,
 		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
 			Enabled: stripe.Bool(true),
 		},
 	}
+	if options.ManualCapture {
+		params.CaptureMethod = stripe.String(string(stripe.PaymentIntentCaptureMethodManual))
+		// Tabs grow after they are opened, so ask for authorizations that can be incremented where cards allow it
+		params.PaymentMethodOptions = &stripe.PaymentIntentPaymentMethodOptionsParams{
+			Card: &stripe.PaymentIntentPaymentMethodOptionsCardParams{
+				RequestIncrementalAuthorization: stripe.String("if_available"),
+			},
+		}
+	}
+	if options.CustomerID != "" {
+		params.Customer = stripe.String(options.CustomerID)
+	}
+	if options.PaymentMethodID != "" {
+		params.PaymentMethod = stripe.String(options.PaymentMethodID)
+	}
+	if options.OffSession {
+		params.OffSession = stripe.Bool(true)
+		params.Confirm = stripe.Bool(true)
+		// Nobody is there to follow a redirect
+		params.AutomaticPaymentMethods.AllowRedirects = stripe.String(string(stripe.PaymentIntentAutomaticPaymentMethodsAllowRedirectsNever))
+	}
 
 	pi, err := paymentintent.New(params)
 	if err != nil {
 		return nil, err
 	}
+	return newGatewayIntent(pi), nil
+}
+
+// IncrementAuthorization raises the authorized amount of a payment intent awaiting capture
+func (s *StripeGateway) IncrementAuthorization(intentID string, amount float64, code string) (*GatewayIntent, error) {
+	pi, err := paymentintent.IncrementAuthorization(intentID, &stripe.PaymentIntentIncrementAuthorizationParams{
+		Amount: stripe.Int64(stripeToMinor(amount, code)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newGatewayIntent(pi), nil
+}
 
-	return pi, nil
+// CaptureIntent captures an authorized payment intent
+func (s *StripeGateway) CaptureIntent(intentID string, amount *float64, code string) (*GatewayIntent, error) {
+	params := &stripe.PaymentIntentCaptureParams{}
+	if amount != nil {
+		params.AmountToCapture = stripe.Int64(stripeToMinor(*amount, code))
+	}
+
+	pi, err := paymentintent.Capture(intentID, params)
+	if err != nil {
+		return nil, err
+	}
+	return newGatewayIntent(pi), nil
 }
 
-// ConfirmPaymentIntent confirms a payment intent
-func (s *StripeService) ConfirmPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	pi, err := paymentintent.Confirm(paymentIntentID, nil)
+// CancelIntent cancels a payment intent
+func (s *StripeGateway) CancelIntent(intentID string) (*GatewayIntent, error) {
+	pi, err := paymentintent.Cancel(intentID, nil)
 	if err != nil {
 		return nil, err
 	}
-	return pi, nil
+	return newGatewayIntent(pi), nil
 }
 
-// GetPaymentIntent retrieves a payment intent by ID
-func (s *StripeService) GetPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	pi, err := paymentintent.Get(paymentIntentID, nil)
+// RefundIntent refunds a succeeded payment intent
+func (s *StripeGateway) RefundIntent(intentID string, amount *float64, code string) (*GatewayRefund, error) {
+	params := &stripe.RefundParams{PaymentIntent: stripe.String(intentID)}
+	if amount != nil {
+		params.Amount = stripe.Int64(stripeToMinor(*amount, code))
+	}
+
+	r, err := refund.New(params)
 	if err != nil {
 		return nil, err
 	}
-	return pi, nil
+	return &GatewayRefund{
+		ID:       r.ID,
+		IntentID: intentID,
+		Amount:   stripeFromMinor(r.Amount, string(r.Currency)),
+		Status:   string(r.Status),
+	}, nil
 }
 
-// CancelPaymentIntent cancels a payment intent
-func (s *StripeService) CancelPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	pi, err := paymentintent.Cancel(paymentIntentID, nil)
+// GetIntent retrieves a payment intent by ID
+func (s *StripeGateway) GetIntent(intentID string) (*GatewayIntent, error) {
+	pi, err := paymentintent.Get(intentID, nil)
 	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			return nil, ErrIntentNotFound
+		}
+		return nil, err
+	}
+	return newGatewayIntent(pi), nil
+}
+
+// ListIntents lists the payment intents created since a time, fetching every page
+func (s *StripeGateway) ListIntents(since time.Time) ([]GatewayIntent, error) {
+	params := &stripe.PaymentIntentListParams{
+		CreatedRange: &stripe.RangeQueryParams{GreaterThanOrEqual: since.Unix()},
+	}
+
+	var intents []GatewayIntent
+	i := paymentintent.List(params)
+	for i.Next() {
+		intents = append(intents, *newGatewayIntent(i.PaymentIntent()))
+	}
+	if err := i.Err(); err != nil {
 		return nil, err
 	}
-	return pi, nil
+	return intents, nil
 }
 
-// VerifyWebhookSignature verifies the webhook signature from Stripe
+// VerifyWebhook verifies the webhook signature from Stripe
 // For local development, if webhook secret is not set, it will skip verification
-func (s *StripeService) VerifyWebhookSignature(payload []byte, signature string) (*stripe.Event, error) {
+func (s *StripeGateway) VerifyWebhook(payload []byte, signature string) (*GatewayEvent, error) {
+	var event stripe.Event
 	if s.webhookSecret == "" {
 		// For local development, try to parse the event without verification
 		// This allows testing with Stripe CLI without webhook secret
-		var event stripe.Event
 		if err := json.Unmarshal(payload, &event); err != nil {
 			return nil, errors.New("webhook secret not configured and failed to parse event")
 		}
-		return &event, nil
+	} else {
+		verified, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+		if err != nil {
+			return nil, err
+		}
+		event = verified
 	}
 
-	event, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+	if event.Data == nil {
+		return nil, errors.New("webhook event has no data")
+	}
+
+	// Charges, refunds and disputes refer to their payment intent, payment and setup intents are the object itself
+	var object struct {
+		Object         string `json:"object"`
+		ID             string `json:"id"`
+		PaymentIntent  string `json:"payment_intent"`
+		AmountRefunded int64  `json:"amount_refunded"`
+		Currency       string `json:"currency"`
+	}
+	_ = json.Unmarshal(event.Data.Raw, &object)
+	intentID := object.PaymentIntent
+	if object.Object == "payment_intent" || object.Object == "setup_intent" {
+		intentID = object.ID
+	}
+
+	return &GatewayEvent{
+		ID:             event.ID,
+		Type:           string(event.Type),
+		IntentID:       intentID,
+		AmountRefunded: stripeFromMinor(object.AmountRefunded, object.Currency),
+		Data:           event.Data.Raw,
+	}, nil
+}
+
+// CreateCustomer creates a Stripe customer
+func (s *StripeGateway) CreateCustomer(name string, email string, phone string, metadata map[string]string) (string, error) {
+	params := &stripe.CustomerParams{
+		Email:    stripe.String(email),
+		Metadata: metadata,
+	}
+	if name != "" {
+		params.Name = stripe.String(name)
+	}
+	if phone != "" {
+		params.Phone = stripe.String(phone)
+	}
+
+	c, err := customer.New(params)
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// newGatewaySetupIntent converts a Stripe setup intent
+func newGatewaySetupIntent(si *stripe.SetupIntent) *GatewaySetupIntent {
+	intent := &GatewaySetupIntent{
+		ID:           si.ID,
+		ClientSecret: si.ClientSecret,
+		Status:       IntentStatus(si.Status),
+		Metadata:     si.Metadata,
+	}
+	if si.Customer != nil {
+		intent.CustomerID = si.Customer.ID
+	}
+	if si.PaymentMethod != nil {
+		intent.PaymentMethodID = si.PaymentMethod.ID
+	}
+	if si.LastSetupError != nil {
+		intent.LastError = si.LastSetupError.Msg
+	}
+	return intent
+}
+
+// CreateSetupIntent creates a Stripe setup intent saving a card to a customer for off-session payments
+func (s *StripeGateway) CreateSetupIntent(customerID string, metadata map[string]string) (*GatewaySetupIntent, error) {
+	si, err := setupintent.New(&stripe.SetupIntentParams{
+		Customer: stripe.String(customerID),
+		Usage:    stripe.String(string(stripe.SetupIntentUsageOffSession)),
+		Metadata: metadata,
+		AutomaticPaymentMethods: &stripe.SetupIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newGatewaySetupIntent(si), nil
+}
+
+// GetSetupIntent retrieves a setup intent by ID
+func (s *StripeGateway) GetSetupIntent(setupIntentID string) (*GatewaySetupIntent, error) {
+	si, err := setupintent.Get(setupIntentID, nil)
 	if err != nil {
 		return nil, err
 	}
+	return newGatewaySetupIntent(si), nil
+}
+
+// GetCard retrieves a saved card by its payment method ID
+func (s *StripeGateway) GetCard(paymentMethodID string) (*GatewayCard, error) {
+	pm, err := paymentmethod.Get(paymentMethodID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	card := &GatewayCard{ID: pm.ID}
+	if pm.Customer != nil {
+		card.CustomerID = pm.Customer.ID
+	}
+	if pm.Card != nil {
+		card.Brand = string(pm.Card.Brand)
+		card.Last4 = pm.Card.Last4
+		card.ExpMonth = int(pm.Card.ExpMonth)
+		card.ExpYear = int(pm.Card.ExpYear)
+	}
+	return card, nil
+}
 
-	return &event, nil
+// DetachCard detaches a payment method from its customer
+func (s *StripeGateway) DetachCard(paymentMethodID string) error {
+	_, err := paymentmethod.Detach(paymentMethodID, nil)
+	return err
 }
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"errors"
+)
+
+// getPaymentOrder loads the order of a business a payment is taken against
+func (s *Service) getPaymentOrder(orderID uint, businessID uint) (*entities.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.BusinessID != businessID {
+		return nil, errors.New("order not found")
+	}
+	return order, nil
+}
+
+// applyTenderRule adjusts the amount of a payment taken against an order by the business's tender rule for the
+// payment type: cash is rounded to the rule's increment, cards carry its surcharge. It returns the amount to take
+// and the adjustment to record on the order, which is nil when the amount stays as it is.
+func (s *Service) applyTenderRule(order *entities.Order, paymentType constants.PaymentType, amount float64) (float64, *entities.OrderAdjustment, error) {
+	rule, err := s.businessRepo.GetTenderRule(order.BusinessID, paymentType)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Rules are in the currency the business had when they were set, orders in another currency are left alone
+	if rule == nil || rule.Currency != order.Currency {
+		return amount, nil, nil
+	}
+
+	minor := currency.ToMinor(amount, order.Currency)
+	var adjustmentType constants.OrderAdjustmentType
+	var difference int64
+	switch paymentType {
+	case constants.Cash:
+		increment := currency.ToMinor(rule.RoundingIncrement, order.Currency)
+		if increment <= 1 {
+			return amount, nil, nil
+		}
+		// Half up to the nearest increment, an amount smaller than half of it is not rounded away
+		rounded := (minor + increment/2) / increment * increment
+		if rounded == 0 {
+			return amount, nil, nil
+		}
+		adjustmentType, difference = constants.AdjustmentCashRounding, rounded-minor
+	case constants.CreditCard, constants.DigitalWallet:
+		surcharge := amount*rule.SurchargePercent/100 + rule.SurchargeFixed
+		adjustmentType, difference = constants.AdjustmentCardSurcharge, currency.ToMinor(surcharge, order.Currency)
+	}
+	if difference == 0 {
+		return amount, nil, nil
+	}
+
+	return currency.FromMinor(minor+difference, order.Currency), &entities.OrderAdjustment{
+		OrderID: order.ID,
+		Type:    adjustmentType,
+		Amount:  currency.FromMinor(difference, order.Currency),
+	}, nil
+}
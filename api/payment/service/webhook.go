@@ -0,0 +1,271 @@
+package service
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/env"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	webhookBatchSize = 20
+	// webhookLease is how long a claimed event is hidden from other workers while it is processed
+	webhookLease = 5 * time.Minute
+)
+
+var (
+	webhookWorkerOnce sync.Once
+	// webhookWake wakes the worker when an event was received, so it is processed without waiting for the next poll
+	webhookWake = make(chan struct{}, 1)
+)
+
+// webhookRetryDelay is how long to wait before the next attempt after the given number of failed attempts,
+// doubling from WEBHOOK_RETRY_BASE_SECONDS up to an hour
+func webhookRetryDelay(attempts int) time.Duration {
+	base := time.Duration(env.PositiveInt("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second
+	delay := base * time.Duration(math.Pow(2, float64(attempts-1)))
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}
+
+// HandleWebhook verifies a webhook from the payment gateway and stores its event for the worker. Events that
+// were received before are acknowledged without being stored again.
+func (s *Service) HandleWebhook(payload []byte, signature string) error {
+	if s.gateway == nil {
+		return errNoGateway
+	}
+
+	event, err := s.gateway.VerifyWebhook(payload, signature)
+	if err != nil {
+		log.Printf("Failed to verify webhook: %v", err)
+		return errors.New("invalid webhook signature")
+	}
+
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	created, err := s.webhookRepo.RecordWebhookEvent(&entities.WebhookEvent{
+		EventID:       event.ID,
+		Type:          event.Type,
+		IntentID:      event.IntentID,
+		Payload:       string(encoded),
+		Status:        constants.WebhookEventPending,
+		NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if !created {
+		log.Printf("Ignoring replayed webhook event %s (%s)", event.ID, event.Type)
+		return nil
+	}
+
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// StartWebhookWorker starts processing stored webhook events in the background. Events are picked up when they
+// arrive and every WEBHOOK_POLL_SECONDS; failed events are retried with a backoff until WEBHOOK_MAX_ATTEMPTS.
+func (s *Service) StartWebhookWorker() {
+	webhookWorkerOnce.Do(func() {
+		interval := time.Duration(env.PositiveInt("WEBHOOK_POLL_SECONDS", 10)) * time.Second
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				s.processDueWebhookEvents()
+				select {
+				case <-ticker.C:
+				case <-webhookWake:
+				}
+			}
+		}()
+	})
+}
+
+// processDueWebhookEvents processes the stored events that are due until none are left
+func (s *Service) processDueWebhookEvents() {
+	maxAttempts := env.PositiveInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	for {
+		events, err := s.webhookRepo.ClaimDueWebhookEvents(webhookBatchSize, webhookLease)
+		if err != nil {
+			log.Printf("Failed to load webhook events: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		for _, stored := range events {
+			attempts := stored.Attempts + 1
+			err := s.processWebhookEvent(stored)
+			if err == nil {
+				if err := s.webhookRepo.MarkWebhookEventProcessed(stored.ID, attempts); err != nil {
+					log.Printf("Failed to mark webhook event %s processed: %v", stored.EventID, err)
+				}
+				continue
+			}
+
+			var nextAttemptAt *time.Time
+			if attempts < maxAttempts {
+				next := time.Now().Add(webhookRetryDelay(attempts))
+				nextAttemptAt = &next
+				log.Printf("Failed to process webhook event %s (%s), attempt %d, retrying at %s: %v", stored.EventID, stored.Type, attempts, next.Format(time.RFC3339), err)
+			} else {
+				log.Printf("Giving up on webhook event %s (%s) after %d attempts: %v", stored.EventID, stored.Type, attempts, err)
+			}
+			if err := s.webhookRepo.MarkWebhookEventAttemptFailed(stored.ID, attempts, err.Error(), nextAttemptAt); err != nil {
+				log.Printf("Failed to record webhook event %s attempt: %v", stored.EventID, err)
+			}
+		}
+	}
+}
+
+// processWebhookEvent applies a stored event to the payment it is about
+func (s *Service) processWebhookEvent(stored entities.WebhookEvent) error {
+	var event GatewayEvent
+	if err := json.Unmarshal([]byte(stored.Payload), &event); err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case EventIntentSucceeded, EventIntentAuthorized:
+		return s.ConfirmStripePayment(event.IntentID)
+	case EventIntentPaymentFailed, EventIntentCanceled:
+		return s.UpdatePaymentStatus(event.IntentID, constants.Failed)
+	case EventChargeRefunded:
+		return s.applyGatewayRefund(event.IntentID, event.AmountRefunded)
+	case EventDisputeCreated:
+		return s.applyGatewayDispute(event.IntentID)
+	case EventSetupIntentSucceeded, EventSetupIntentFailed:
+		_, err := s.syncSetupIntent(event.IntentID)
+		return err
+	default:
+		log.Printf("Unhandled event type: %s\n", event.Type)
+	}
+	return nil
+}
+
+// applyGatewayRefund records a refund made at the payment gateway. A payment refunded in full is marked Refunded,
+// and so are its orders once none of their payments are still collected.
+func (s *Service) applyGatewayRefund(paymentIntentID string, amountRefunded float64) error {
+	payment, err := s.repo.GetPaymentByStripePaymentIntentID(paymentIntentID)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		return errors.New("payment not found")
+	}
+
+	if err := s.repo.RaiseRefundedAmount(payment.ID, amountRefunded); err != nil {
+		return err
+	}
+	if currency.ToMinor(amountRefunded, payment.Currency) < currency.ToMinor(payment.Amount, payment.Currency) {
+		log.Printf("Payment %d partially refunded, %s of %s", payment.ID, currency.Format(amountRefunded, payment.Currency), currency.Format(payment.Amount, payment.Currency))
+		return nil
+	}
+
+	if err := s.transitionPaymentStatus(payment, constants.Refunded); err != nil {
+		if err.Error() == "invalid payment status transition" {
+			log.Printf("Ignoring refund of payment %d, it is %s", payment.ID, payment.Status)
+			return nil
+		}
+		return err
+	}
+
+	orders, err := s.orderRepo.GetOrdersByPaymentID(payment.ID)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if order.Status == constants.OrderRefunded || order.Status == constants.OrderCancelled {
+			continue
+		}
+
+		collected := false
+		for _, link := range order.OrderPaymentLinks {
+			if link.Payment.Status == constants.Completed || link.Payment.Status == constants.Disputed {
+				collected = true
+				break
+			}
+		}
+		if collected {
+			continue
+		}
+
+		order.Status = constants.OrderRefunded
+		if err := s.orderRepo.UpdateOrder(&order); err != nil {
+			return err
+		}
+		log.Printf("Order %d status updated to Refunded (all payments refunded)", order.ID)
+
+		if err := s.giftCardService.VoidForOrder(database.DB, order.BusinessID, order.ID, nil); err != nil {
+			log.Printf("Warning: Failed to void gift cards sold on order %d: %v", order.ID, err)
+		}
+	}
+	return nil
+}
+
+// applyGatewayDispute marks a payment the cardholder disputed, and its orders
+func (s *Service) applyGatewayDispute(paymentIntentID string) error {
+	payment, err := s.repo.GetPaymentByStripePaymentIntentID(paymentIntentID)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		return errors.New("payment not found")
+	}
+
+	if err := s.transitionPaymentStatus(payment, constants.Disputed); err != nil {
+		if err.Error() == "invalid payment status transition" {
+			log.Printf("Ignoring dispute of payment %d, it is %s", payment.ID, payment.Status)
+			return nil
+		}
+		return err
+	}
+
+	orders, err := s.orderRepo.GetOrdersByPaymentID(payment.ID)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if order.Status == constants.OrderRefunded || order.Status == constants.OrderCancelled || order.Status == constants.OrderDisputed {
+			continue
+		}
+		order.Status = constants.OrderDisputed
+		if err := s.orderRepo.UpdateOrder(&order); err != nil {
+			return err
+		}
+		log.Printf("Order %d status updated to Disputed (payment %d disputed)", order.ID, payment.ID)
+	}
+	return nil
+}
+
+// AuthenticateFakeIntent completes the simulated 3DS challenge of a payment intent of the fake gateway
+func (s *Service) AuthenticateFakeIntent(intentID string, approve bool) (*GatewayIntent, error) {
+	fake, ok := s.gateway.(*FakeGateway)
+	if !ok {
+		return nil, errors.New("fake gateway is not enabled")
+	}
+	return fake.Authenticate(intentID, approve)
+}
+
+// FakeGatewayEnabled reports whether card payments are simulated by the fake gateway
+func (s *Service) FakeGatewayEnabled() bool {
+	_, ok := s.gateway.(*FakeGateway)
+	return ok
+}
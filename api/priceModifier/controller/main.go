@@ -23,7 +23,7 @@ func NewController() *Controller {
 }
 
 // @Summary Create a price modifier
-// @Description Create a price modifier with the provided details
+// @Description Create a price modifier with the provided details. Absolute values are in the business's currency
 // @Tags price-modifier
 // @Accept  json
 // @Produce  json
@@ -179,7 +179,7 @@ func (ctrl *Controller) GetPriceModifierById(c *gin.Context) {
 }
 
 // @Summary Update price modifier details
-// @Description Update price modifier details
+// @Description Update price modifier details. A changed absolute value is in the business's current currency
 // @Tags price-modifier
 // @Accept  json
 // @Produce  json
@@ -310,7 +310,7 @@ func (ctrl *Controller) DeletePriceModifier(c *gin.Context) {
 
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	priceModifierGroup := r.Group("/price-modifier")
-	priceModifierGroup.Use(middleware.AuthMiddleware())
+	priceModifierGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	priceModifierGroup.POST("", ctrl.CreatePriceModifier)
 	priceModifierGroup.GET("", ctrl.GetPriceModifiers)
 	priceModifierGroup.GET("/:id", ctrl.GetPriceModifierById)
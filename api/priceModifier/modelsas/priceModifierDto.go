@@ -2,18 +2,22 @@ package modelsas
 
 import (
 	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/currency"
+	"strconv"
 	"time"
 )
 
 type PriceModifierDto struct {
-	ID           uint       `json:"id"`
-	BusinessID   uint       `json:"businessId"`
-	ModifierType string     `json:"modifierType"`
-	Name         string     `json:"name"`
-	Value        float64    `json:"value"`
-	IsPercentage bool       `json:"isPercentage"`
-	ValidFrom    time.Time  `json:"validFrom"`
-	ValidTo      *time.Time `json:"validTo"`
+	ID             uint       `json:"id"`
+	BusinessID     uint       `json:"businessId"`
+	ModifierType   string     `json:"modifierType"`
+	Name           string     `json:"name"`
+	Value          float64    `json:"value"`
+	IsPercentage   bool       `json:"isPercentage"`
+	Currency       string     `json:"currency,omitempty"`
+	FormattedValue string     `json:"formattedValue"`
+	ValidFrom      time.Time  `json:"validFrom"`
+	ValidTo        *time.Time `json:"validTo"`
 }
 
 // NewPriceModifierDtoFromEntity constructs a PriceModifierDto from the DB entity.
@@ -25,14 +29,21 @@ func NewPriceModifierDtoFromEntity(pm entities.PriceModifier) PriceModifierDto {
 		validTo = &pm.DeletedAt.Time
 	}
 
+	formattedValue := strconv.FormatFloat(pm.Value, 'f', -1, 64) + "%"
+	if !pm.IsPercentage {
+		formattedValue = currency.Format(pm.Value, pm.Currency)
+	}
+
 	return PriceModifierDto{
-		ID:           pm.ID,
-		BusinessID:   pm.BusinessID,
-		ModifierType: string(pm.ModifierType),
-		Name:         pm.Name,
-		Value:        pm.Value,
-		IsPercentage: pm.IsPercentage,
-		ValidFrom:    pm.CreatedAt,
-		ValidTo:      validTo,
+		ID:             pm.ID,
+		BusinessID:     pm.BusinessID,
+		ModifierType:   string(pm.ModifierType),
+		Name:           pm.Name,
+		Value:          pm.Value,
+		IsPercentage:   pm.IsPercentage,
+		Currency:       pm.Currency,
+		FormattedValue: formattedValue,
+		ValidFrom:      pm.CreatedAt,
+		ValidTo:        validTo,
 	}
 }
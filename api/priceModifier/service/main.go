@@ -1,8 +1,10 @@
 package service
 
 import (
+	businessRepository "VersatilePOS/business/repository"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
 	"VersatilePOS/generic/rbac"
 	"VersatilePOS/priceModifier/modelsas"
 	"VersatilePOS/priceModifier/repository"
@@ -10,15 +12,37 @@ import (
 )
 
 type Service struct {
-	repo repository.Repository
+	repo         repository.Repository
+	businessRepo businessRepository.Repository
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: repository.Repository{},
+		repo:         repository.Repository{},
+		businessRepo: businessRepository.Repository{},
 	}
 }
 
+// setCurrency puts an absolute modifier in the business's current currency and rounds its value to it. Percentages
+// have no currency.
+func (s *Service) setCurrency(priceModifier *entities.PriceModifier) error {
+	if priceModifier.IsPercentage {
+		priceModifier.Currency = ""
+		return nil
+	}
+
+	code, err := s.businessRepo.GetBusinessCurrency(priceModifier.BusinessID)
+	if err != nil {
+		return err
+	}
+	if code == "" {
+		return errors.New("business not found")
+	}
+	priceModifier.Currency = code
+	priceModifier.Value = currency.Round(priceModifier.Value, code)
+	return nil
+}
+
 func (s *Service) CreatePriceModifier(req modelsas.CreatePriceModifierRequest, userID uint) (*modelsas.PriceModifierDto, error) {
 	// Check RBAC permissions
 	ok, err := rbac.HasAccess(constants.PriceModifiers, constants.Write, req.BusinessID, userID)
@@ -44,6 +68,9 @@ func (s *Service) CreatePriceModifier(req modelsas.CreatePriceModifierRequest, u
 		IsPercentage: req.IsPercentage,
 		EndDate:      req.EndDate,
 	}
+	if err := s.setCurrency(priceModifier); err != nil {
+		return nil, err
+	}
 
 	createdPriceModifier, err := s.repo.CreatePriceModifier(priceModifier)
 	if err != nil {
@@ -139,6 +166,12 @@ func (s *Service) UpdatePriceModifier(id uint, businessID uint, req modelsas.Upd
 	if req.EndDate != nil {
 		priceModifier.EndDate = req.EndDate
 	}
+	// A changed amount is in the currency the business uses now
+	if req.Value != nil || req.IsPercentage != nil {
+		if err := s.setCurrency(priceModifier); err != nil {
+			return nil, err
+		}
+	}
 
 	updatedPriceModifier, err := s.repo.UpdatePriceModifier(priceModifier)
 	if err != nil {
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	reservationModels "VersatilePOS/reservation/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// calendarFeedURL builds the public URL of a feed from the host the request was made to
+func calendarFeedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s/calendar/%s.ics", scheme, c.Request.Host, token)
+}
+
+// @Summary Create calendar feed
+// @Description Create a secret iCalendar feed of a business' reservations, or of a single employee's when accountId is given. The returned URL contains the feed's token and is shown only once.
+// @Tags reservation
+// @Accept  json
+// @Produce  json
+// @Param   feed  body  models.CreateCalendarFeedRequest  true  "Calendar feed to create"
+// @Success 201 {object} models.CalendarFeedDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/calendar-feed [post]
+// @Id createCalendarFeed
+func (ctrl *Controller) CreateCalendarFeed(c *gin.Context) {
+	var req reservationModels.CreateCalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	feed, token, err := ctrl.service.CreateCalendarFeed(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "account does not belong to this business" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	feed.URL = calendarFeedURL(c, token)
+	c.IndentedJSON(http.StatusCreated, feed)
+}
+
+// @Summary Get calendar feeds
+// @Description Get the calendar feeds of a business
+// @Tags reservation
+// @Produce  json
+// @Param   businessId query int true "Business ID"
+// @Success 200 {array} models.CalendarFeedDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/calendar-feed [get]
+// @Id getCalendarFeeds
+func (ctrl *Controller) GetCalendarFeeds(c *gin.Context) {
+	businessID, err := strconv.ParseUint(c.Query("businessId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	feeds, err := ctrl.service.GetCalendarFeeds(uint(businessID), userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, feeds)
+}
+
+// @Summary Revoke calendar feed
+// @Description Delete a calendar feed, its URL stops working
+// @Tags reservation
+// @Param   id  path  int  true  "Calendar feed ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/calendar-feed/{id} [delete]
+// @Id deleteCalendarFeed
+func (ctrl *Controller) DeleteCalendarFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid calendar feed ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.DeleteCalendarFeed(uint(id), userID); err != nil {
+		if err.Error() == "calendar feed not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get calendar feed
+// @Description Get an iCalendar (RFC 5545) feed by its secret token. Does not require authentication, cancelled reservations are included with STATUS:CANCELLED. A feed stops working once its creator loses read access to the business' reservations.
+// @Tags reservation
+// @Produce  text/calendar
+// @Param   token  path  string  true  "Feed token followed by .ics"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /calendar/{token} [get]
+// @Id getCalendarFeed
+func (ctrl *Controller) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := ctrl.service.GetCalendarFeed(token)
+	if err != nil {
+		if err.Error() == "calendar feed not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
+// @Summary Download reservation as iCalendar
+// @Description Get a reservation as an .ics attachment
+// @Tags reservation
+// @Produce  text/calendar
+// @Param   id  path  int  true  "Reservation ID"
+// @Success 200 {string} string "iCalendar file"
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/{id}/ics [get]
+// @Id getReservationIcs
+func (ctrl *Controller) GetReservationICS(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid reservation ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	calendar, err := ctrl.service.GetReservationICS(uint(id), userID)
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="reservation-%d.ics"`, id))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
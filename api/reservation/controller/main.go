@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,7 +24,7 @@ func NewController() *Controller {
 }
 
 // @Summary Create reservation
-// @Description Create a new reservation
+// @Description Create a new reservation. A given account has to be an employee assigned to the service and working for its business; when no account is given, a free employee assigned to the service is allocated; a free resource is allocated when the service requires one. Fails with 409 if no employee or resource is available, or the slot is held for a waitlist offer. If the service requires a deposit, the reservation is held as Pending and the response includes the client secret of the deposit's payment intent, which a replay of a retry with the same Idempotency-Key leaves out; a deposit unpaid by depositExpiresAt cancels the reservation and offers its slot to the waitlist. Late-cancellation and no-show fees are charged to the saved card given, which has to be an active card of the customer whose consent covers reservation fees.
 // @Tags reservation
 // @Accept  json
 // @Produce  json
@@ -32,7 +33,9 @@ func NewController() *Controller {
 // @Failure 400 {object} models.HTTPError
 // @Failure 401 {object} models.HTTPError
 // @Failure 403 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
 // @Security BearerAuth
 // @Router /reservation [post]
 // @Id createReservation
@@ -53,6 +56,13 @@ func (ctrl *Controller) CreateReservation(c *gin.Context) {
 	if err != nil {
 		if err.Error() == "unauthorized" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "invalid reservation status" || err.Error() == "service not found" ||
+			strings.HasPrefix(err.Error(), "employee ") || strings.HasPrefix(err.Error(), "saved payment method") {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "time slot is not available" || err.Error() == "no employee available" || err.Error() == "no resource available" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "deposit required but payment gateway is not configured" {
+			c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
 		} else {
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
@@ -132,7 +142,7 @@ func (ctrl *Controller) GetReservationById(c *gin.Context) {
 }
 
 // @Summary Update reservation details
-// @Description Update reservation details
+// @Description Update reservation details. Status changes must follow the reservation state machine, and a reservation held Pending for a deposit cannot be confirmed until the deposit is paid; cancelling inside the service's cancellation window or marking a no-show charges the service's fee, recorded together with the status change; what the reservation's payments, such as its deposit, do not cover is charged to a card the customer saved and otherwise stays outstanding. A new account or service has to keep the employee assigned to the service and working for its business. Cancelling or moving a reservation offers its slot to the next matching waitlist entry.
 // @Tags reservation
 // @Accept  json
 // @Produce  json
@@ -143,6 +153,7 @@ func (ctrl *Controller) GetReservationById(c *gin.Context) {
 // @Failure 401 {object} models.HTTPError
 // @Failure 403 {object} models.HTTPError
 // @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
 // @Failure 500 {object} models.HTTPError
 // @Security BearerAuth
 // @Router /reservation/{id} [put]
@@ -173,6 +184,11 @@ func (ctrl *Controller) UpdateReservation(c *gin.Context) {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 		} else if err.Error() == "unauthorized" || err.Error() == "unauthorized to assign reservation to this account" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "invalid reservation status" || err.Error() == "service not found" || strings.HasPrefix(err.Error(), "employee ") {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "invalid reservation status transition" || err.Error() == "reservation deposit is not paid" ||
+			err.Error() == "time slot is not available" || err.Error() == "no resource available" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 		} else {
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
@@ -183,7 +199,7 @@ func (ctrl *Controller) UpdateReservation(c *gin.Context) {
 }
 
 // @Summary Link payment to reservation
-// @Description Link a payment to a reservation. Requires authentication and Reservations Write permission.
+// @Description Link a payment to a reservation. Requires authentication and Reservations Write permission. The payment has to be in the reservation's currency. The reservation is marked as Completed once its bill balance reaches zero.
 // @Tags reservation
 // @Param   reservationId  path  int  true  "Reservation ID"
 // @Param   paymentId  path  int  true  "Payment ID"
@@ -226,6 +242,10 @@ func (ctrl *Controller) LinkPaymentToReservation(c *gin.Context) {
 			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
 			return
 		}
+		if err.Error() == "payment currency does not match the reservation currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+			return
+		}
 		if err.Error() == "unauthorized to modify this reservation" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
 			return
@@ -240,7 +260,7 @@ func (ctrl *Controller) LinkPaymentToReservation(c *gin.Context) {
 
 
 // @Summary Apply price modifier to reservation
-// @Description Apply a price modifier to a reservation
+// @Description Apply a price modifier to a reservation. Absolute price modifiers have to be in the reservation's currency.
 // @Tags reservation
 // @Accept  json
 // @Produce  json
@@ -276,10 +296,12 @@ func (ctrl *Controller) ApplyPriceModifierToReservation(c *gin.Context) {
 
 	err = ctrl.service.ApplyPriceModifierToReservation(uint(reservationID), req, userID)
 	if err != nil {
-		if err.Error() == "reservation not found" {
+		if err.Error() == "reservation not found" || err.Error() == "price modifier not found" {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 		} else if err.Error() == "unauthorized" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "price modifier currency does not match the reservation currency" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		} else {
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
@@ -291,7 +313,7 @@ func (ctrl *Controller) ApplyPriceModifierToReservation(c *gin.Context) {
 
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	reservationGroup := r.Group("/reservation")
-	reservationGroup.Use(middleware.AuthMiddleware())
+	reservationGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		reservationGroup.POST("", ctrl.CreateReservation)
 		reservationGroup.GET("", ctrl.GetReservations)
@@ -299,6 +321,33 @@ func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 		reservationGroup.PUT("/:id", ctrl.UpdateReservation)
 		reservationGroup.POST("/:id/price-modifier", ctrl.ApplyPriceModifierToReservation)
 		reservationGroup.POST("/:id/payment/:paymentId", ctrl.LinkPaymentToReservation)
+		reservationGroup.POST("/waitlist", ctrl.CreateWaitlistEntry)
+		reservationGroup.GET("/waitlist", ctrl.GetWaitlistEntries)
+		reservationGroup.DELETE("/waitlist/:id", ctrl.CancelWaitlistEntry)
+		reservationGroup.POST("/waitlist/:id/accept", ctrl.AcceptWaitlistOffer)
+		reservationGroup.GET("/calendar-feed", ctrl.GetCalendarFeeds)
+		reservationGroup.DELETE("/calendar-feed/:id", ctrl.DeleteCalendarFeed)
+		reservationGroup.GET("/:id/ics", ctrl.GetReservationICS)
+	}
+
+	// The created feed's URL holds its secret token, so the response is not stored for idempotent retries
+	r.POST("/reservation/calendar-feed", middleware.AuthMiddleware(), ctrl.CreateCalendarFeed)
+
+	// Calendar feeds are authorized by the secret token in their URL
+	r.GET("/calendar/:token", ctrl.GetCalendarFeed)
+
+	// Public self-service booking, unauthenticated and rate limited per client IP and per business
+	publicGroup := r.Group("/public/business/:businessId")
+	publicGroup.Use(middleware.RateLimitMiddleware(publicBookingIPRateLimiter(), middleware.ClientIPKey))
+	publicGroup.Use(middleware.RateLimitMiddleware(publicBookingRateLimiter(), func(c *gin.Context) string {
+		return c.Param("businessId")
+	}))
+	{
+		publicGroup.GET("/service", ctrl.GetBookableServices)
+		publicGroup.GET("/service/:serviceId/availability", ctrl.GetServiceAvailability)
+		publicGroup.POST("/reservation", ctrl.CreatePublicReservation)
+		publicGroup.POST("/reservation/:id/confirm", ctrl.ConfirmPublicReservation)
+		publicGroup.POST("/reservation/:id/cancel", ctrl.CancelPublicReservation)
 	}
 }
 
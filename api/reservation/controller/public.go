@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	reservationModels "VersatilePOS/reservation/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPublicBookingRateLimit   = 60
+	defaultPublicBookingIPRateLimit = 20
+)
+
+// publicBookingRateLimiter limits public booking requests per business per minute
+func publicBookingRateLimiter() *middleware.RateLimiter {
+	return middleware.NewRateLimiter(env.PositiveInt("PUBLIC_BOOKING_RATE_LIMIT", defaultPublicBookingRateLimit), time.Minute)
+}
+
+// publicBookingIPRateLimiter limits public booking requests per client IP per minute, so a single client cannot use
+// up a business's limit
+func publicBookingIPRateLimiter() *middleware.RateLimiter {
+	return middleware.NewRateLimiter(env.PositiveInt("PUBLIC_BOOKING_RATE_LIMIT_PER_IP", defaultPublicBookingIPRateLimit), time.Minute)
+}
+
+func parseBusinessID(c *gin.Context) (uint, bool) {
+	businessID, err := strconv.ParseUint(c.Param("businessId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid business ID"})
+		return 0, false
+	}
+	return uint(businessID), true
+}
+
+// @Summary List bookable services
+// @Description List the services of a business that customers can book online. Does not require authentication, requests are rate limited per client IP and per business.
+// @Tags public
+// @Produce  json
+// @Param   businessId  path  int  true  "Business ID"
+// @Success 200 {array} models.PublicServiceDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /public/business/{businessId}/service [get]
+// @Id getBookableServices
+func (ctrl *Controller) GetBookableServices(c *gin.Context) {
+	businessID, ok := parseBusinessID(c)
+	if !ok {
+		return
+	}
+
+	services, err := ctrl.service.GetBookableServices(businessID)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, services)
+}
+
+// @Summary Get service availability
+// @Description List the free slots of a service on a day (UTC). A slot is free when an employee and, if the service requires one, a resource are available.
+// @Tags public
+// @Produce  json
+// @Param   businessId  path  int  true  "Business ID"
+// @Param   serviceId  path  int  true  "Service ID"
+// @Param   date  query  string  true  "Day in YYYY-MM-DD format"
+// @Param   length  query  int  false  "Reservation length in minutes, defaults to the service's provisioning interval"
+// @Success 200 {array} models.AvailableSlotDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /public/business/{businessId}/service/{serviceId}/availability [get]
+// @Id getServiceAvailability
+func (ctrl *Controller) GetServiceAvailability(c *gin.Context) {
+	businessID, ok := parseBusinessID(c)
+	if !ok {
+		return
+	}
+
+	serviceID, err := strconv.ParseUint(c.Param("serviceId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid service ID"})
+		return
+	}
+
+	day, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	var length uint64
+	if lengthStr := c.Query("length"); lengthStr != "" {
+		length, err = strconv.ParseUint(lengthStr, 10, 32)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid length"})
+			return
+		}
+	}
+
+	slots, err := ctrl.service.GetAvailability(businessID, uint(serviceID), day, uint32(length))
+	if err != nil {
+		if err.Error() == "service not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "reservationLength is required" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, slots)
+}
+
+// @Summary Book a reservation
+// @Description Book a service as a customer. The reservation is held as Pending and a confirmation code with a cancel link is sent to the customer's email or phone; unconfirmed bookings are released once the code expires. Bookings awaiting their code or deposit count toward the per-client limit.
+// @Tags public
+// @Accept  json
+// @Produce  json
+// @Param   businessId  path  int  true  "Business ID"
+// @Param   reservation  body  models.CreatePublicReservationRequest  true  "Booking details"
+// @Success 201 {object} models.PublicReservationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Router /public/business/{businessId}/reservation [post]
+// @Id createPublicReservation
+func (ctrl *Controller) CreatePublicReservation(c *gin.Context) {
+	businessID, ok := parseBusinessID(c)
+	if !ok {
+		return
+	}
+
+	var req reservationModels.CreatePublicReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	reservation, err := ctrl.service.CreatePublicReservation(businessID, c.ClientIP(), req)
+	if err != nil {
+		switch err.Error() {
+		case "service not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "customer email or phone is required", "reservationLength is required", "requested time is in the past", "requested time is outside the service's booking hours":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		case "time slot is not available", "no employee available", "no resource available":
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		case "too many unconfirmed bookings":
+			c.IndentedJSON(http.StatusTooManyRequests, models.HTTPError{Error: err.Error()})
+		case "deposit required but payment gateway is not configured":
+			c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, reservation)
+}
+
+// @Summary Confirm a booking
+// @Description Confirm an online booking with the code sent to the customer. If the service requires a deposit, the response includes the client secret of the deposit's payment intent and the booking is confirmed once it is paid. A deposit unpaid by depositExpiresAt releases the booking.
+// @Tags public
+// @Accept  json
+// @Produce  json
+// @Param   businessId  path  int  true  "Business ID"
+// @Param   id  path  int  true  "Reservation ID"
+// @Param   request  body  models.ConfirmPublicReservationRequest  true  "Confirmation code"
+// @Success 200 {object} models.PublicReservationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /public/business/{businessId}/reservation/{id}/confirm [post]
+// @Id confirmPublicReservation
+func (ctrl *Controller) ConfirmPublicReservation(c *gin.Context) {
+	businessID, ok := parseBusinessID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid reservation ID"})
+		return
+	}
+
+	var req reservationModels.ConfirmPublicReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	reservation, err := ctrl.service.ConfirmPublicReservation(businessID, uint(id), req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "reservation not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "invalid confirmation code":
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		case "reservation is not awaiting confirmation", "too many confirmation attempts":
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, reservation)
+}
+
+// @Summary Cancel a booking
+// @Description Cancel an online booking through the signed link sent to the customer. The link expires when the reservation starts.
+// @Tags public
+// @Produce  json
+// @Param   businessId  path  int  true  "Business ID"
+// @Param   id  path  int  true  "Reservation ID"
+// @Param   expires  query  int  true  "Expiry from the cancel link, as a Unix timestamp"
+// @Param   signature  query  string  true  "Signature from the cancel link"
+// @Success 200 {object} models.PublicReservationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 429 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Router /public/business/{businessId}/reservation/{id}/cancel [post]
+// @Id cancelPublicReservation
+func (ctrl *Controller) CancelPublicReservation(c *gin.Context) {
+	businessID, ok := parseBusinessID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid reservation ID"})
+		return
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid link expiry"})
+		return
+	}
+
+	reservation, err := ctrl.service.CancelPublicReservation(businessID, uint(id), expires, c.Query("signature"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid signature", "cancel link has expired":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		case "reservation not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "invalid reservation status transition":
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, reservation)
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	reservationModels "VersatilePOS/reservation/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Join service waitlist
+// @Description Add a customer to the waitlist of a service for a time window. When a slot that fits within the window for the entry's reservation length is freed, the entry is offered the slot with an expiring hold and a notification is sent.
+// @Tags reservation
+// @Accept  json
+// @Produce  json
+// @Param   entry  body  models.CreateWaitlistEntryRequest  true  "Waitlist entry to create"
+// @Success 201 {object} models.WaitlistEntryDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/waitlist [post]
+// @Id createWaitlistEntry
+func (ctrl *Controller) CreateWaitlistEntry(c *gin.Context) {
+	var req reservationModels.CreateWaitlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	entry, err := ctrl.service.CreateWaitlistEntry(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "service not found" || err.Error() == "waitlist window end must be after its start" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, entry)
+}
+
+// @Summary Get service waitlist
+// @Description Get the waitlist entries of a service, oldest first
+// @Tags reservation
+// @Produce  json
+// @Param   serviceId  query  int  true  "Service ID"
+// @Success 200 {array} models.WaitlistEntryDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/waitlist [get]
+// @Id getWaitlistEntries
+func (ctrl *Controller) GetWaitlistEntries(c *gin.Context) {
+	serviceID, err := strconv.ParseUint(c.Query("serviceId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid service ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	entries, err := ctrl.service.GetWaitlistEntries(uint(serviceID), userID)
+	if err != nil {
+		if err.Error() == "service not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, entries)
+}
+
+// @Summary Leave service waitlist
+// @Description Cancel a waitlist entry. If the entry holds an offered slot, the slot is offered to the next matching entry.
+// @Tags reservation
+// @Param   id  path  int  true  "Waitlist entry ID"
+// @Success 204
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/waitlist/{id} [delete]
+// @Id cancelWaitlistEntry
+func (ctrl *Controller) CancelWaitlistEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid waitlist entry ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.CancelWaitlistEntry(uint(id), userID); err != nil {
+		if err.Error() == "waitlist entry not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "waitlist entry is no longer active" {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Accept waitlist offer
+// @Description Book the slot offered to a waitlist entry while its hold has not expired
+// @Tags reservation
+// @Produce  json
+// @Param   id  path  int  true  "Waitlist entry ID"
+// @Success 201 {object} models.ReservationDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 409 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Failure 503 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /reservation/waitlist/{id}/accept [post]
+// @Id acceptWaitlistOffer
+func (ctrl *Controller) AcceptWaitlistOffer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid waitlist entry ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	reservation, err := ctrl.service.AcceptWaitlistOffer(uint(id), userID)
+	if err != nil {
+		if err.Error() == "waitlist entry not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "waitlist entry has no active offer" || err.Error() == "time slot is not available" || err.Error() == "no resource available" ||
+			strings.HasPrefix(err.Error(), "employee ") {
+			c.IndentedJSON(http.StatusConflict, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "deposit required but payment gateway is not configured" {
+			c.IndentedJSON(http.StatusServiceUnavailable, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, reservation)
+}
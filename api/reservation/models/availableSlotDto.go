@@ -0,0 +1,8 @@
+package models
+
+import "time"
+
+type AvailableSlotDto struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"time"
+)
+
+type CalendarFeedDto struct {
+	ID         uint  `json:"id"`
+	BusinessID uint  `json:"businessId"`
+	AccountID  *uint `json:"accountId,omitempty"`
+	// URL contains the feed's secret token and is only returned when the feed is created
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewCalendarFeedDtoFromEntity(feed entities.CalendarFeed) CalendarFeedDto {
+	return CalendarFeedDto{
+		ID:         feed.ID,
+		BusinessID: feed.BusinessID,
+		AccountID:  feed.AccountID,
+		CreatedAt:  feed.CreatedAt,
+	}
+}
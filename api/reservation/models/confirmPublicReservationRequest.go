@@ -0,0 +1,5 @@
+package models
+
+type ConfirmPublicReservationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type CreateCalendarFeedRequest struct {
+	BusinessID uint `json:"businessId" binding:"required"`
+	// AccountID limits the feed to an employee's reservations, omit it for the whole business
+	AccountID *uint `json:"accountId"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type CreatePublicReservationRequest struct {
+	ServiceID     uint      `json:"serviceId" binding:"required"`
+	DateOfService time.Time `json:"dateOfService" binding:"required"`
+	// ReservationLength in minutes, defaults to the service's provisioning interval
+	ReservationLength uint32 `json:"reservationLength"`
+	Customer          string `json:"customer" binding:"required"`
+	CustomerEmail     string `json:"customerEmail"`
+	CustomerPhone     string `json:"customerPhone"`
+}
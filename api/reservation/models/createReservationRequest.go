@@ -6,15 +6,18 @@ import (
 )
 
 type CreateReservationRequest struct {
-	AccountID         uint                      `json:"accountId" validate:"required"`
-	ServiceID         uint                      `json:"serviceId" validate:"required"`
-	DatePlaced        time.Time                 `json:"datePlaced" validate:"required"`
-	DateOfService     time.Time                 `json:"dateOfService" validate:"required"`
-	ReservationLength uint32                    `json:"reservationLength" validate:"required"`
+	// AccountID is the employee to book, when omitted a free employee assigned to the service is allocated
+	AccountID         uint                        `json:"accountId"`
+	ServiceID         uint                        `json:"serviceId" validate:"required"`
+	DatePlaced        time.Time                   `json:"datePlaced" validate:"required"`
+	DateOfService     time.Time                   `json:"dateOfService" validate:"required"`
+	ReservationLength uint32                      `json:"reservationLength" validate:"required"`
 	Status            constants.ReservationStatus `json:"status"`
-	TipAmount         float64                   `json:"tipAmount"`
-	Customer          string                    `json:"customer" validate:"required"`
-	CustomerEmail     string                    `json:"customerEmail"`
-	CustomerPhone     string                    `json:"customerPhone"`
+	TipAmount         float64                     `json:"tipAmount"`
+	Customer          string                      `json:"customer" validate:"required"`
+	CustomerEmail     string                      `json:"customerEmail"`
+	CustomerPhone     string                      `json:"customerPhone"`
+	// SavedPaymentMethodID is a card of the customer, saved with consent covering reservation fees, that late
+	// cancellation and no-show fees are charged to
+	SavedPaymentMethodID *uint `json:"savedPaymentMethodId,omitempty"`
 }
-
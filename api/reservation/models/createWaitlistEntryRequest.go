@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type CreateWaitlistEntryRequest struct {
+	ServiceID         uint      `json:"serviceId" validate:"required"`
+	WindowStart       time.Time `json:"windowStart" validate:"required"`
+	WindowEnd         time.Time `json:"windowEnd" validate:"required"`
+	ReservationLength uint32    `json:"reservationLength" validate:"required"`
+	Customer          string    `json:"customer" validate:"required"`
+	CustomerEmail     string    `json:"customerEmail"`
+	CustomerPhone     string    `json:"customerPhone"`
+}
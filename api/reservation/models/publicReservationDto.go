@@ -0,0 +1,41 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"time"
+)
+
+// PublicReservationDto is the customer facing view of a reservation booked online
+type PublicReservationDto struct {
+	ID                uint                        `json:"id"`
+	ServiceID         uint                        `json:"serviceId"`
+	ServiceName       string                      `json:"serviceName"`
+	DateOfService     time.Time                   `json:"dateOfService"`
+	ReservationLength uint32                      `json:"reservationLength"`
+	Status            constants.ReservationStatus `json:"status"`
+	Customer          string                      `json:"customer"`
+	DepositAmount     float64                     `json:"depositAmount"`
+	Currency          string                      `json:"currency"`
+	// DepositClientSecret is only returned when the booking is confirmed and a deposit is due
+	DepositClientSecret   string     `json:"depositClientSecret,omitempty"`
+	ConfirmationExpiresAt *time.Time `json:"confirmationExpiresAt,omitempty"`
+	// DepositExpiresAt is when the booking is released if its deposit is still unpaid
+	DepositExpiresAt *time.Time `json:"depositExpiresAt,omitempty"`
+}
+
+func NewPublicReservationDtoFromEntity(reservation entities.Reservation) PublicReservationDto {
+	return PublicReservationDto{
+		ID:                    reservation.ID,
+		ServiceID:             reservation.ServiceID,
+		ServiceName:           reservation.Service.Name,
+		DateOfService:         reservation.DateOfService,
+		ReservationLength:     reservation.ReservationLength,
+		Status:                reservation.Status,
+		Customer:              reservation.Customer,
+		DepositAmount:         reservation.DepositAmount,
+		Currency:              reservation.Currency,
+		ConfirmationExpiresAt: reservation.ConfirmationCodeExpiresAt,
+		DepositExpiresAt:      reservation.DepositExpiresAt,
+	}
+}
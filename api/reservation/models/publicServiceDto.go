@@ -0,0 +1,36 @@
+package models
+
+import "VersatilePOS/database/entities"
+
+// PublicServiceDto is the customer facing view of a bookable service
+type PublicServiceDto struct {
+	ID                    uint    `json:"id"`
+	Name                  string  `json:"name"`
+	HourlyPrice           float64 `json:"hourlyPrice"`
+	ServiceCharge         float64 `json:"serviceCharge"`
+	ProvisioningStartTime string  `json:"provisioningStartTime"`
+	ProvisioningEndTime   string  `json:"provisioningEndTime"`
+	ProvisioningInterval  uint    `json:"provisioningInterval"`
+	CancellationWindow    uint    `json:"cancellationWindow"`
+	LateCancellationFee   float64 `json:"lateCancellationFee"`
+	NoShowFee             float64 `json:"noShowFee"`
+	DepositValue          float64 `json:"depositValue"`
+	DepositIsPercentage   bool    `json:"depositIsPercentage"`
+}
+
+func NewPublicServiceDtoFromEntity(s entities.Service) PublicServiceDto {
+	return PublicServiceDto{
+		ID:                    s.ID,
+		Name:                  s.Name,
+		HourlyPrice:           s.HourlyPrice,
+		ServiceCharge:         s.ServiceCharge,
+		ProvisioningStartTime: s.ProvisioningStartTime.UTC().Format("15:04"),
+		ProvisioningEndTime:   s.ProvisioningEndTime.UTC().Format("15:04"),
+		ProvisioningInterval:  s.ProvisioningInterval,
+		CancellationWindow:    s.CancellationWindow,
+		LateCancellationFee:   s.LateCancellationFee,
+		NoShowFee:             s.NoShowFee,
+		DepositValue:          s.DepositValue,
+		DepositIsPercentage:   s.DepositIsPercentage,
+	}
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"math"
+)
+
+type ReservationBillLineDto struct {
+	PriceModifierID uint    `json:"priceModifierId"`
+	Name            string  `json:"name"`
+	ModifierType    string  `json:"modifierType"`
+	Amount          float64 `json:"amount"`
+}
+
+type ReservationBillDto struct {
+	Hours            float64                  `json:"hours"`
+	HourlyPrice      float64                  `json:"hourlyPrice"`
+	BaseAmount       float64                  `json:"baseAmount"`
+	ServiceCharge    float64                  `json:"serviceCharge"`
+	Subtotal         float64                  `json:"subtotal"`
+	DiscountTotal    float64                  `json:"discountTotal"`
+	SurchargeTotal   float64                  `json:"surchargeTotal"`
+	TaxTotal         float64                  `json:"taxTotal"`
+	TipAmount        float64                  `json:"tipAmount"`
+	Total            float64                  `json:"total"`
+	PaidAmount       float64                  `json:"paidAmount"`
+	Balance          float64                  `json:"balance"`
+	Currency         string                   `json:"currency"`
+	FormattedTotal   string                   `json:"formattedTotal"`
+	FormattedBalance string                   `json:"formattedBalance"`
+	Lines            []ReservationBillLineDto `json:"lines"`
+}
+
+// NewReservationBillDtoFromEntity prices a reservation from its service, length, tip and linked
+// price modifiers. Percentage modifiers apply to the subtotal, matching how order totals are computed.
+// Cancelled and no-show reservations are only billed their surcharges (late-cancellation and no-show fees).
+// Amounts are rounded to the minor units of the reservation's currency.
+// The Service, PriceModifierLinks.PriceModifier and ReservationPaymentLinks.Payment relations must be loaded.
+func NewReservationBillDtoFromEntity(reservation entities.Reservation) ReservationBillDto {
+	round := func(amount float64) float64 {
+		return currency.Round(amount, reservation.Currency)
+	}
+
+	bill := ReservationBillDto{
+		Currency:    reservation.Currency,
+		Hours:       float64(reservation.ReservationLength) / 60,
+		HourlyPrice: reservation.Service.HourlyPrice,
+		Lines:       make([]ReservationBillLineDto, 0),
+	}
+
+	feesOnly := reservation.Status == constants.ReservationCancelled || reservation.Status == constants.ReservationNoShow
+	if !feesOnly {
+		bill.BaseAmount = round(bill.HourlyPrice * bill.Hours)
+		bill.ServiceCharge = reservation.Service.ServiceCharge
+		bill.TipAmount = reservation.TipAmount
+	}
+	bill.Subtotal = round(bill.BaseAmount + bill.ServiceCharge)
+
+	for _, link := range reservation.PriceModifierLinks {
+		pm := link.PriceModifier
+		if feesOnly && pm.ModifierType != constants.Surcharge {
+			continue
+		}
+
+		amount := pm.Value
+		if pm.IsPercentage {
+			amount = bill.Subtotal * pm.Value / 100
+		}
+		amount = round(math.Abs(amount))
+
+		switch pm.ModifierType {
+		case constants.Discount:
+			bill.DiscountTotal += amount
+		case constants.Surcharge:
+			bill.SurchargeTotal += amount
+		case constants.Tax:
+			bill.TaxTotal += amount
+		case constants.Tip:
+			bill.TipAmount += amount
+		}
+
+		bill.Lines = append(bill.Lines, ReservationBillLineDto{
+			PriceModifierID: pm.ID,
+			Name:            pm.Name,
+			ModifierType:    string(pm.ModifierType),
+			Amount:          amount,
+		})
+	}
+
+	adjusted := math.Max(0, bill.Subtotal-bill.DiscountTotal+bill.SurchargeTotal+bill.TaxTotal)
+	bill.DiscountTotal = round(bill.DiscountTotal)
+	bill.SurchargeTotal = round(bill.SurchargeTotal)
+	bill.TaxTotal = round(bill.TaxTotal)
+	bill.TipAmount = round(bill.TipAmount)
+	bill.Total = round(adjusted + bill.TipAmount)
+
+	for _, link := range reservation.ReservationPaymentLinks {
+		if link.Payment.Status == constants.Completed {
+			bill.PaidAmount += link.Payment.Amount
+		}
+	}
+	bill.PaidAmount = round(bill.PaidAmount)
+	bill.Balance = round(bill.Total - bill.PaidAmount)
+	bill.FormattedTotal = currency.Format(bill.Total, bill.Currency)
+	bill.FormattedBalance = currency.Format(bill.Balance, bill.Currency)
+
+	return bill
+}
+
+// IsSettled reports whether the payments cover the bill
+func (b ReservationBillDto) IsSettled() bool {
+	return b.Balance <= 0
+}
@@ -9,21 +9,31 @@ import (
 )
 
 type ReservationDto struct {
-	ID                uint                                   `json:"id"`
-	AccountID         uint                                   `json:"accountId"`
-	ServiceID         uint                                   `json:"serviceId"`
-	DatePlaced        time.Time                              `json:"datePlaced"`
-	DateOfService     time.Time                              `json:"dateOfService"`
-	ReservationLength uint32                                 `json:"reservationLength"`
-	Status            constants.ReservationStatus            `json:"status"`
-	TipAmount         float64                                `json:"tipAmount"`
-	Customer          string                                 `json:"customer"`
-	CustomerEmail     string                                 `json:"customerEmail"`
-	CustomerPhone     string                                 `json:"customerPhone"`
-	Payments          []models.PaymentDto                    `json:"payments"`
-	PriceModifiers    []modelsas.PriceModifierDto `json:"priceModifiers"`
-	CreatedAt         time.Time                              `json:"createdAt"`
-	UpdatedAt         time.Time                              `json:"updatedAt"`
+	ID                   uint                        `json:"id"`
+	AccountID            uint                        `json:"accountId"`
+	ServiceID            uint                        `json:"serviceId"`
+	ResourceID           *uint                       `json:"resourceId,omitempty"`
+	DatePlaced           time.Time                   `json:"datePlaced"`
+	DateOfService        time.Time                   `json:"dateOfService"`
+	ReservationLength    uint32                      `json:"reservationLength"`
+	Status               constants.ReservationStatus `json:"status"`
+	TipAmount            float64                     `json:"tipAmount"`
+	Currency             string                      `json:"currency"`
+	Customer             string                      `json:"customer"`
+	CustomerEmail        string                      `json:"customerEmail"`
+	CustomerPhone        string                      `json:"customerPhone"`
+	BookedOnline         bool                        `json:"bookedOnline"`
+	DepositAmount        float64                     `json:"depositAmount"`
+	DepositPaymentID     *uint                       `json:"depositPaymentId,omitempty"`
+	DepositExpiresAt     *time.Time                  `json:"depositExpiresAt,omitempty"`
+	SavedPaymentMethodID *uint                       `json:"savedPaymentMethodId,omitempty"`
+	// DepositClientSecret is only returned when the reservation is created and a deposit is due
+	DepositClientSecret string                      `json:"depositClientSecret,omitempty"`
+	Payments            []models.PaymentDto         `json:"payments"`
+	PriceModifiers      []modelsas.PriceModifierDto `json:"priceModifiers"`
+	Bill                ReservationBillDto          `json:"bill"`
+	CreatedAt           time.Time                   `json:"createdAt"`
+	UpdatedAt           time.Time                   `json:"updatedAt"`
 }
 
 func NewReservationDtoFromEntity(reservation entities.Reservation) ReservationDto {
@@ -38,21 +48,28 @@ func NewReservationDtoFromEntity(reservation entities.Reservation) ReservationDt
 	}
 
 	return ReservationDto{
-		ID:                reservation.ID,
-		AccountID:         reservation.AccountID,
-		ServiceID:         reservation.ServiceID,
-		DatePlaced:        reservation.DatePlaced,
-		DateOfService:     reservation.DateOfService,
-		ReservationLength: reservation.ReservationLength,
-		Status:            reservation.Status,
-		TipAmount:         reservation.TipAmount,
-		Customer:          reservation.Customer,
-		CustomerEmail:     reservation.CustomerEmail,
-		CustomerPhone:     reservation.CustomerPhone,
-		Payments:          payments,
-		PriceModifiers:    priceModifiers,
-		CreatedAt:         reservation.CreatedAt,
-		UpdatedAt:         reservation.UpdatedAt,
+		ID:                   reservation.ID,
+		AccountID:            reservation.AccountID,
+		ServiceID:            reservation.ServiceID,
+		ResourceID:           reservation.ResourceID,
+		DatePlaced:           reservation.DatePlaced,
+		DateOfService:        reservation.DateOfService,
+		ReservationLength:    reservation.ReservationLength,
+		Status:               reservation.Status,
+		TipAmount:            reservation.TipAmount,
+		Currency:             reservation.Currency,
+		Customer:             reservation.Customer,
+		CustomerEmail:        reservation.CustomerEmail,
+		CustomerPhone:        reservation.CustomerPhone,
+		BookedOnline:         reservation.BookedOnline,
+		DepositAmount:        reservation.DepositAmount,
+		DepositPaymentID:     reservation.DepositPaymentID,
+		DepositExpiresAt:     reservation.DepositExpiresAt,
+		SavedPaymentMethodID: reservation.SavedPaymentMethodID,
+		Payments:             payments,
+		PriceModifiers:       priceModifiers,
+		Bill:                 NewReservationBillDtoFromEntity(reservation),
+		CreatedAt:            reservation.CreatedAt,
+		UpdatedAt:            reservation.UpdatedAt,
 	}
 }
-
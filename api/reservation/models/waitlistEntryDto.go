@@ -0,0 +1,44 @@
+package models
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"time"
+)
+
+type WaitlistEntryDto struct {
+	ID                   uint                     `json:"id"`
+	ServiceID            uint                     `json:"serviceId"`
+	WindowStart          time.Time                `json:"windowStart"`
+	WindowEnd            time.Time                `json:"windowEnd"`
+	ReservationLength    uint32                   `json:"reservationLength"`
+	Customer             string                   `json:"customer"`
+	CustomerEmail        string                   `json:"customerEmail"`
+	CustomerPhone        string                   `json:"customerPhone"`
+	Status               constants.WaitlistStatus `json:"status"`
+	OfferedAccountID     *uint                    `json:"offeredAccountId,omitempty"`
+	OfferedDateOfService *time.Time               `json:"offeredDateOfService,omitempty"`
+	HoldExpiresAt        *time.Time               `json:"holdExpiresAt,omitempty"`
+	ReservationID        *uint                    `json:"reservationId,omitempty"`
+	CreatedAt            time.Time                `json:"createdAt"`
+}
+
+// NewWaitlistEntryDtoFromEntity constructs a WaitlistEntryDto from the DB entity.
+func NewWaitlistEntryDtoFromEntity(e entities.WaitlistEntry) WaitlistEntryDto {
+	return WaitlistEntryDto{
+		ID:                   e.ID,
+		ServiceID:            e.ServiceID,
+		WindowStart:          e.WindowStart,
+		WindowEnd:            e.WindowEnd,
+		ReservationLength:    e.ReservationLength,
+		Customer:             e.Customer,
+		CustomerEmail:        e.CustomerEmail,
+		CustomerPhone:        e.CustomerPhone,
+		Status:               e.Status,
+		OfferedAccountID:     e.OfferedAccountID,
+		OfferedDateOfService: e.OfferedDateOfService,
+		HoldExpiresAt:        e.HoldExpiresAt,
+		ReservationID:        e.ReservationID,
+		CreatedAt:            e.CreatedAt,
+	}
+}
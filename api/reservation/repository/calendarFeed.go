@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CalendarFeedRepository struct{}
+
+func (r *CalendarFeedRepository) CreateFeed(feed *entities.CalendarFeed) error {
+	return database.DB.Create(feed).Error
+}
+
+func (r *CalendarFeedRepository) GetFeedByID(id uint) (*entities.CalendarFeed, error) {
+	var feed entities.CalendarFeed
+	if err := database.DB.First(&feed, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepository) GetFeedByTokenHash(tokenHash string) (*entities.CalendarFeed, error) {
+	var feed entities.CalendarFeed
+	if err := database.DB.Preload("Business").Preload("Account").Where("token_hash = ?", tokenHash).First(&feed).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepository) GetFeedsByBusinessID(businessID uint) ([]entities.CalendarFeed, error) {
+	var feeds []entities.CalendarFeed
+	if err := database.DB.Where("business_id = ?", businessID).Order("created_at").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+func (r *CalendarFeedRepository) DeleteFeed(id uint) error {
+	return database.DB.Delete(&entities.CalendarFeed{}, id).Error
+}
+
+// GetFeedReservations returns the reservations of the business, or only the employee's when accountID is set, starting after since
+func (r *CalendarFeedRepository) GetFeedReservations(businessID uint, accountID *uint, since time.Time) ([]entities.Reservation, error) {
+	var reservations []entities.Reservation
+	query := database.DB.Preload("Service").
+		Joins("JOIN services ON services.id = reservations.service_id").
+		Where("services.business_id = ? AND reservations.date_of_service >= ?", businessID, since)
+	if accountID != nil {
+		query = query.Where("reservations.account_id = ?", *accountID)
+	}
+	if err := query.Order("reservations.date_of_service").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
@@ -3,19 +3,24 @@ package repository
 import (
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct{}
 
-func (r *Repository) CreateReservation(reservation *entities.Reservation) error {
-	return database.DB.Create(reservation).Error
-}
-
 func (r *Repository) GetReservationByID(id uint) (*entities.Reservation, error) {
 	var reservation entities.Reservation
-	if err := database.DB.Preload("Account.MemberOf").Preload("ReservationPaymentLinks.Payment").Preload("PriceModifierLinks.PriceModifier").First(&reservation, id).Error; err != nil {
+	if err := database.DB.Preload("Account.MemberOf").
+		Preload("Service").
+		Preload("ReservationPaymentLinks.Payment").
+		Preload("PriceModifierLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		First(&reservation, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -26,14 +31,95 @@ func (r *Repository) GetReservationByID(id uint) (*entities.Reservation, error)
 
 func (r *Repository) GetReservations() ([]entities.Reservation, error) {
 	var reservations []entities.Reservation
-	if err := database.DB.Preload("Account.MemberOf").Preload("ReservationPaymentLinks.Payment").Preload("PriceModifierLinks.PriceModifier").Find(&reservations).Error; err != nil {
+	if err := database.DB.Preload("Account.MemberOf").
+		Preload("Service").
+		Preload("ReservationPaymentLinks.Payment").
+		Preload("PriceModifierLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Find(&reservations).Error; err != nil {
 		return nil, err
 	}
 	return reservations, nil
 }
 
 func (r *Repository) UpdateReservation(reservation *entities.Reservation) error {
-	return database.DB.Save(reservation).Error
+	// Omit associations so preloaded relations don't overwrite changed foreign keys
+	return database.DB.Omit(clause.Associations).Save(reservation).Error
+}
+
+// GetOverlappingReservations returns the account's active reservations overlapping [start, end)
+func (r *Repository) GetOverlappingReservations(accountID uint, start, end time.Time, excludeID uint) ([]entities.Reservation, error) {
+	var reservations []entities.Reservation
+	if err := database.DB.
+		Where("account_id = ? AND id <> ? AND status NOT IN ?", accountID, excludeID, []constants.ReservationStatus{constants.ReservationCancelled, constants.ReservationNoShow}).
+		Where("date_of_service < ? AND date_of_service + (reservation_length * INTERVAL '1 minute') > ?", end, start).
+		Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// GetExpiredPendingReservations returns online bookings whose confirmation code expired before they were confirmed,
+// and reservations whose deposit was not paid in time
+func (r *Repository) GetExpiredPendingReservations(now time.Time) ([]entities.Reservation, error) {
+	var reservations []entities.Reservation
+	if err := database.DB.
+		Where("status = ?", constants.ReservationPending).
+		Where("((booked_online = ? AND confirmation_code_expires_at <= ?) OR deposit_expires_at <= ?)", true, now, now).
+		Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// CountUnconfirmedBookings counts the online bookings still awaiting their confirmation code or deposit that were
+// placed from the client IP or for the customer email or phone
+func (r *Repository) CountUnconfirmedBookings(clientIP string, email string, phone string, now time.Time) (int64, error) {
+	var count int64
+	query := database.DB.Model(&entities.Reservation{}).
+		Where("booked_online = ? AND status = ?", true, constants.ReservationPending).
+		Where("((confirmation_code_hash <> '' AND confirmation_code_expires_at > ?) OR deposit_expires_at > ?)", now, now)
+
+	match := database.DB.Where("booked_from_ip = ?", clientIP)
+	if email != "" {
+		match = match.Or("LOWER(customer_email) = LOWER(?)", email)
+	}
+	if phone != "" {
+		match = match.Or("customer_phone = ?", phone)
+	}
+	if err := query.Where(match).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountOverlappingResourceReservations counts active reservations using the resource within [start, end)
+func (r *Repository) CountOverlappingResourceReservations(resourceID uint, start, end time.Time, excludeID uint) (int64, error) {
+	var count int64
+	if err := database.DB.Model(&entities.Reservation{}).
+		Where("resource_id = ? AND id <> ? AND status NOT IN ?", resourceID, excludeID, []constants.ReservationStatus{constants.ReservationCancelled, constants.ReservationNoShow}).
+		Where("date_of_service < ? AND date_of_service + (reservation_length * INTERVAL '1 minute') > ?", end, start).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LockSlotHolders locks the rows of the employees and resources a booking may take until tx ends, so concurrent
+// bookings check and take them one at a time. Rows are locked in ID order, accounts first, to avoid deadlocks.
+func (r *Repository) LockSlotHolders(tx *gorm.DB, accountIDs []uint, resourceIDs []uint) error {
+	if len(accountIDs) > 0 {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Order("id").Find(&[]entities.Account{}, accountIDs).Error; err != nil {
+			return err
+		}
+	}
+	if len(resourceIDs) > 0 {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Order("id").Find(&[]entities.Resource{}, resourceIDs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *Repository) CreatePriceModifierReservationLink(link *entities.PriceModifierReservationLink) (*entities.PriceModifierReservationLink, error) {
@@ -69,8 +155,11 @@ func (r *Repository) GetReservationsByPaymentID(paymentID uint) ([]entities.Rese
 	if result := database.DB.
 		Where("id IN ?", reservationIDs).
 		Preload("Account.MemberOf").
+		Preload("Service").
 		Preload("ReservationPaymentLinks.Payment").
-		Preload("PriceModifierLinks.PriceModifier").
+		Preload("PriceModifierLinks.PriceModifier", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
 		Find(&reservations); result.Error != nil {
 		return nil, result.Error
 	}
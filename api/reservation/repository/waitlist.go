@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistRepository struct{}
+
+func (r *WaitlistRepository) CreateEntry(entry *entities.WaitlistEntry) error {
+	return database.DB.Create(entry).Error
+}
+
+func (r *WaitlistRepository) GetEntryByID(id uint) (*entities.WaitlistEntry, error) {
+	var entry entities.WaitlistEntry
+	if err := database.DB.Preload("Service").First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *WaitlistRepository) GetEntriesByServiceID(serviceID uint) ([]entities.WaitlistEntry, error) {
+	var entries []entities.WaitlistEntry
+	if err := database.DB.Where("service_id = ?", serviceID).Order("created_at").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetWaitingEntries returns entries still waiting for a slot of the service starting at the given time, oldest first.
+// The slot has to fit in the entry's window for the entry's reservation length.
+func (r *WaitlistRepository) GetWaitingEntries(serviceID uint, slotStart time.Time) ([]entities.WaitlistEntry, error) {
+	var entries []entities.WaitlistEntry
+	if err := database.DB.
+		Where("service_id = ? AND status = ? AND window_start <= ?", serviceID, constants.WaitlistWaiting, slotStart).
+		Where("window_end - (reservation_length * INTERVAL '1 minute') >= ?", slotStart).
+		Order("created_at").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetExpiredOffers returns offered entries whose hold has run out
+func (r *WaitlistRepository) GetExpiredOffers(now time.Time) ([]entities.WaitlistEntry, error) {
+	var entries []entities.WaitlistEntry
+	if err := database.DB.Where("status = ? AND hold_expires_at <= ?", constants.WaitlistOffered, now).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetActiveHolds returns unexpired offers holding a slot of the account that overlaps [start, end)
+func (r *WaitlistRepository) GetActiveHolds(accountID uint, start, end time.Time, now time.Time) ([]entities.WaitlistEntry, error) {
+	var entries []entities.WaitlistEntry
+	if err := database.DB.
+		Where("status = ? AND hold_expires_at > ? AND offered_account_id = ?", constants.WaitlistOffered, now, accountID).
+		Where("offered_date_of_service < ? AND offered_date_of_service + (reservation_length * INTERVAL '1 minute') > ?", end, start).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *WaitlistRepository) UpdateEntry(entry *entities.WaitlistEntry) error {
+	return database.DB.Omit("Service").Save(entry).Error
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	accountService "VersatilePOS/account/service"
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bookSlot runs book in a transaction holding locks on the employees and the service's required resources, so the
+// availability book checks still holds when it saves the reservation through tx
+func (s *Service) bookSlot(service *entities.Service, accountIDs []uint, book func(tx *gorm.DB) error) error {
+	resourceIDs := make([]uint, 0, len(service.RequiredResources))
+	for _, resource := range service.RequiredResources {
+		resourceIDs = append(resourceIDs, resource.ID)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.LockSlotHolders(tx, accountIDs, resourceIDs); err != nil {
+			return errors.New("failed to lock slot")
+		}
+		return book(tx)
+	})
+}
+
+// candidateEmployees returns the employees a booking can be allocated to, the requested one or else those assigned to the service
+func candidateEmployees(service *entities.Service, requestedAccountID uint) []uint {
+	if requestedAccountID != 0 {
+		return []uint{requestedAccountID}
+	}
+	accountIDs := make([]uint, 0, len(service.Employees))
+	for _, employee := range service.Employees {
+		accountIDs = append(accountIDs, employee.ID)
+	}
+	return accountIDs
+}
+
+// checkEmployee fails unless the account is assigned to the service and works for the service's business
+func checkEmployee(service *entities.Service, accountID uint) error {
+	assigned := slices.ContainsFunc(service.Employees, func(employee entities.Account) bool {
+		return employee.ID == accountID
+	})
+	if !assigned {
+		return errors.New("employee is not assigned to the service")
+	}
+
+	businessIDs, err := accountService.GetBusinessIDsFromAccount(accountID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(businessIDs, service.BusinessID) {
+		return errors.New("employee does not belong to the service's business")
+	}
+	return nil
+}
+
+// ensureSlotAvailable fails if the account already has an active reservation or a held waitlist offer overlapping the slot.
+// holdEntryID is the waitlist entry allowed to use its own hold.
+func (s *Service) ensureSlotAvailable(accountID uint, start time.Time, length uint32, excludeReservationID uint, holdEntryID uint) error {
+	end := start.Add(time.Duration(length) * time.Minute)
+
+	overlapping, err := s.repo.GetOverlappingReservations(accountID, start, end, excludeReservationID)
+	if err != nil {
+		return errors.New("failed to check slot availability")
+	}
+	if len(overlapping) > 0 {
+		return errors.New("time slot is not available")
+	}
+
+	holds, err := s.waitlistRepo.GetActiveHolds(accountID, start, end, time.Now())
+	if err != nil {
+		return errors.New("failed to check slot availability")
+	}
+	for _, hold := range holds {
+		if hold.ID != holdEntryID {
+			return errors.New("time slot is not available")
+		}
+	}
+
+	return nil
+}
+
+// allocateEmployee returns the requested employee if they work on the service and are free, or else the first free
+// employee assigned to the service. Bookings allocate within bookSlot.
+func (s *Service) allocateEmployee(service *entities.Service, requestedAccountID uint, start time.Time, length uint32, holdEntryID uint) (uint, error) {
+	if requestedAccountID != 0 {
+		if err := checkEmployee(service, requestedAccountID); err != nil {
+			return 0, err
+		}
+		if err := s.ensureSlotAvailable(requestedAccountID, start, length, 0, holdEntryID); err != nil {
+			return 0, err
+		}
+		return requestedAccountID, nil
+	}
+
+	for _, employee := range service.Employees {
+		err := s.ensureSlotAvailable(employee.ID, start, length, 0, holdEntryID)
+		if err == nil {
+			return employee.ID, nil
+		}
+		if err.Error() != "time slot is not available" {
+			return 0, err
+		}
+	}
+
+	return 0, errors.New("no employee available")
+}
+
+// allocateResource picks a required resource of the service with free capacity for the slot, trying the preferred one first.
+// Services without required resources need none, so nil is returned. Bookings allocate within bookSlot.
+func (s *Service) allocateResource(service *entities.Service, start time.Time, length uint32, excludeReservationID uint, preferredResourceID *uint) (*uint, error) {
+	if len(service.RequiredResources) == 0 {
+		return nil, nil
+	}
+
+	end := start.Add(time.Duration(length) * time.Minute)
+
+	candidates := make([]entities.Resource, 0, len(service.RequiredResources))
+	for _, resource := range service.RequiredResources {
+		if preferredResourceID != nil && resource.ID == *preferredResourceID {
+			candidates = append([]entities.Resource{resource}, candidates...)
+		} else {
+			candidates = append(candidates, resource)
+		}
+	}
+
+	for _, resource := range candidates {
+		inUse, err := s.repo.CountOverlappingResourceReservations(resource.ID, start, end, excludeReservationID)
+		if err != nil {
+			return nil, errors.New("failed to check resource availability")
+		}
+		if inUse < int64(resource.Capacity) {
+			resourceID := resource.ID
+			return &resourceID, nil
+		}
+	}
+
+	return nil, errors.New("no resource available")
+}
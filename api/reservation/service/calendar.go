@@ -0,0 +1,203 @@
+package service
+
+import (
+	accountService "VersatilePOS/account/service"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/ical"
+	reservationModels "VersatilePOS/reservation/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// calendarFeedHistory is how far back calendar feeds include past reservations
+const calendarFeedHistory = 30 * 24 * time.Hour
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarFeed creates a feed of the business' or an employee's reservations and returns it together with its secret token
+func (s *Service) CreateCalendarFeed(req reservationModels.CreateCalendarFeedRequest, userID uint) (*reservationModels.CalendarFeedDto, string, error) {
+	hasAccess, err := s.hasReservationAccess(req.BusinessID, userID, constants.Read)
+	if err != nil {
+		return nil, "", err
+	}
+	if !hasAccess {
+		return nil, "", errors.New("unauthorized")
+	}
+
+	if req.AccountID != nil {
+		businessIDs, err := accountService.GetBusinessIDsFromAccount(*req.AccountID)
+		if err != nil {
+			return nil, "", err
+		}
+		belongs := false
+		for _, businessID := range businessIDs {
+			if businessID == req.BusinessID {
+				belongs = true
+				break
+			}
+		}
+		if !belongs {
+			return nil, "", errors.New("account does not belong to this business")
+		}
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", errors.New("failed to generate calendar feed token")
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	feed := &entities.CalendarFeed{
+		BusinessID:  req.BusinessID,
+		AccountID:   req.AccountID,
+		TokenHash:   hashCalendarToken(token),
+		CreatedByID: userID,
+	}
+	if err := s.calendarFeedRepo.CreateFeed(feed); err != nil {
+		return nil, "", errors.New("failed to create calendar feed")
+	}
+
+	dto := reservationModels.NewCalendarFeedDtoFromEntity(*feed)
+	return &dto, token, nil
+}
+
+func (s *Service) GetCalendarFeeds(businessID uint, userID uint) ([]reservationModels.CalendarFeedDto, error) {
+	hasAccess, err := s.hasReservationAccess(businessID, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	feeds, err := s.calendarFeedRepo.GetFeedsByBusinessID(businessID)
+	if err != nil {
+		return nil, errors.New("failed to get calendar feeds")
+	}
+
+	dtos := make([]reservationModels.CalendarFeedDto, 0, len(feeds))
+	for _, feed := range feeds {
+		dtos = append(dtos, reservationModels.NewCalendarFeedDtoFromEntity(feed))
+	}
+	return dtos, nil
+}
+
+// DeleteCalendarFeed revokes a feed, its URL stops working. Feeds can be revoked by their creator or with Reservations Write access.
+func (s *Service) DeleteCalendarFeed(id uint, userID uint) error {
+	feed, err := s.calendarFeedRepo.GetFeedByID(id)
+	if err != nil {
+		return errors.New("failed to get calendar feed")
+	}
+	if feed == nil {
+		return errors.New("calendar feed not found")
+	}
+
+	if feed.CreatedByID != userID {
+		hasAccess, err := s.hasReservationAccess(feed.BusinessID, userID, constants.Write)
+		if err != nil {
+			return err
+		}
+		if !hasAccess {
+			return errors.New("unauthorized")
+		}
+	}
+
+	if err := s.calendarFeedRepo.DeleteFeed(id); err != nil {
+		return errors.New("failed to delete calendar feed")
+	}
+	return nil
+}
+
+// GetCalendarFeed renders the feed identified by its secret token
+func (s *Service) GetCalendarFeed(token string) (string, error) {
+	feed, err := s.calendarFeedRepo.GetFeedByTokenHash(hashCalendarToken(token))
+	if err != nil {
+		return "", errors.New("failed to get calendar feed")
+	}
+	if feed == nil {
+		return "", errors.New("calendar feed not found")
+	}
+
+	// A feed is served with its creator's access, it stops working once they lose Reservations Read, are suspended
+	// or deleted
+	hasAccess, err := s.hasReservationAccess(feed.BusinessID, feed.CreatedByID, constants.Read)
+	if err != nil {
+		return "", err
+	}
+	if !hasAccess {
+		return "", errors.New("calendar feed not found")
+	}
+
+	reservations, err := s.calendarFeedRepo.GetFeedReservations(feed.BusinessID, feed.AccountID, time.Now().Add(-calendarFeedHistory))
+	if err != nil {
+		return "", errors.New("failed to get reservations")
+	}
+
+	name := feed.Business.Name + " reservations"
+	if feed.Account != nil {
+		name = fmt.Sprintf("%s - %s", feed.Business.Name, feed.Account.Name)
+	}
+
+	calendar := ical.Calendar{Name: name}
+	for _, reservation := range reservations {
+		calendar.Events = append(calendar.Events, reservationEvent(reservation))
+	}
+	return calendar.Render(), nil
+}
+
+// GetReservationICS renders a single reservation as an .ics attachment
+func (s *Service) GetReservationICS(id uint, userID uint) (string, error) {
+	if _, err := s.GetReservationByID(id, userID); err != nil {
+		return "", err
+	}
+
+	reservation, err := s.repo.GetReservationByID(id)
+	if err != nil || reservation == nil {
+		return "", errors.New("failed to get reservation")
+	}
+
+	calendar := ical.Calendar{
+		Method: "PUBLISH",
+		Events: []ical.Event{reservationEvent(*reservation)},
+	}
+	return calendar.Render(), nil
+}
+
+func reservationEvent(reservation entities.Reservation) ical.Event {
+	status := ical.StatusConfirmed
+	switch reservation.Status {
+	case constants.ReservationPending:
+		status = ical.StatusTentative
+	case constants.ReservationCancelled, constants.ReservationNoShow:
+		status = ical.StatusCancelled
+	}
+
+	details := []string{"Customer: " + reservation.Customer}
+	if reservation.CustomerEmail != "" {
+		details = append(details, "Email: "+reservation.CustomerEmail)
+	}
+	if reservation.CustomerPhone != "" {
+		details = append(details, "Phone: "+reservation.CustomerPhone)
+	}
+	details = append(details, "Status: "+string(reservation.Status))
+
+	return ical.Event{
+		UID:          fmt.Sprintf("reservation-%d@versatilepos", reservation.ID),
+		Start:        reservation.DateOfService,
+		End:          reservation.DateOfService.Add(time.Duration(reservation.ReservationLength) * time.Minute),
+		Summary:      fmt.Sprintf("%s - %s", reservation.Service.Name, reservation.Customer),
+		Description:  strings.Join(details, "\n"),
+		Status:       status,
+		Created:      reservation.CreatedAt,
+		LastModified: reservation.UpdatedAt,
+	}
+}
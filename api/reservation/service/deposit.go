@@ -0,0 +1,133 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"VersatilePOS/generic/env"
+	paymentService "VersatilePOS/payment/service"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const defaultDepositHoldMinutes = 30
+
+// depositHoldDuration is how long a reservation held Pending for its deposit waits for the deposit before it is released
+func depositHoldDuration() time.Duration {
+	return time.Duration(env.PositiveInt("RESERVATION_DEPOSIT_HOLD_MINUTES", defaultDepositHoldMinutes)) * time.Minute
+}
+
+// calculateDeposit returns the deposit due at booking for the given service and reservation length in minutes,
+// rounded to the reservation's currency
+func calculateDeposit(service *entities.Service, lengthMinutes uint32, code string) float64 {
+	if service.DepositValue <= 0 {
+		return 0
+	}
+	if !service.DepositIsPercentage {
+		return currency.Round(service.DepositValue, code)
+	}
+
+	base := service.HourlyPrice * float64(lengthMinutes) / 60
+	return currency.Round(base*service.DepositValue/100, code)
+}
+
+// requestDeposit creates a payment intent with the payment gateway for the reservation's deposit together with a
+// pending payment linked to the reservation. It returns the intent's client secret.
+func (s *Service) requestDeposit(reservation *entities.Reservation) (string, error) {
+	if s.gateway == nil {
+		return "", errors.New("deposit required but payment gateway is not configured")
+	}
+
+	businessID := reservation.Service.BusinessID
+	if reservation.Service.ID == 0 {
+		service, err := s.serviceRepo.GetServiceByID(reservation.ServiceID)
+		if err != nil || service == nil {
+			return "", errors.New("service not found")
+		}
+		businessID = service.BusinessID
+	}
+
+	metadata := map[string]string{
+		"business_id":    fmt.Sprintf("%d", businessID),
+		"reservation_id": fmt.Sprintf("%d", reservation.ID),
+		"purpose":        "deposit",
+	}
+
+	pi, err := s.gateway.CreateIntent(reservation.DepositAmount, reservation.Currency, metadata, paymentService.IntentOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	paymentIntentID := pi.ID
+	payment := &entities.Payment{
+		Amount:                reservation.DepositAmount,
+		Currency:              reservation.Currency,
+		Type:                  constants.CreditCard,
+		Status:                constants.Pending,
+		StripePaymentIntentID: &paymentIntentID,
+		BusinessID:            businessID,
+	}
+
+	createdPayment, err := s.paymentRepo.CreatePayment(payment)
+	if err != nil {
+		_, _ = s.gateway.CancelIntent(pi.ID)
+		return "", err
+	}
+
+	link := &entities.ReservationPaymentLink{
+		ReservationID: reservation.ID,
+		PaymentID:     createdPayment.ID,
+	}
+	if _, err := s.repo.CreateReservationPaymentLink(link); err != nil {
+		_, _ = s.gateway.CancelIntent(pi.ID)
+		return "", err
+	}
+
+	reservation.DepositPaymentID = &createdPayment.ID
+	if err := s.repo.UpdateReservation(reservation); err != nil {
+		return "", err
+	}
+
+	return pi.ClientSecret, nil
+}
+
+// depositOutstanding reports whether the reservation requires a deposit that has not been paid yet
+func (s *Service) depositOutstanding(reservation *entities.Reservation) (bool, error) {
+	if reservation.DepositAmount <= 0 {
+		return false, nil
+	}
+	if reservation.DepositPaymentID == nil {
+		return true, nil
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByID(*reservation.DepositPaymentID)
+	if err != nil {
+		return false, errors.New("failed to get deposit payment")
+	}
+	return payment == nil || payment.Status != constants.Completed, nil
+}
+
+// releaseDeposit cancels the deposit's payment intent when a reservation is cancelled before the deposit was paid
+func (s *Service) releaseDeposit(reservation *entities.Reservation) {
+	if reservation.DepositPaymentID == nil {
+		return
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByID(*reservation.DepositPaymentID)
+	if err != nil || payment == nil || payment.Status != constants.Pending {
+		return
+	}
+
+	if s.gateway != nil && payment.StripePaymentIntentID != nil {
+		if _, err := s.gateway.CancelIntent(*payment.StripePaymentIntentID); err != nil {
+			log.Printf("Warning: Failed to cancel deposit payment intent for reservation %d: %v", reservation.ID, err)
+		}
+	}
+
+	payment.Status = constants.Failed
+	if _, err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		log.Printf("Warning: Failed to mark deposit payment %d as failed: %v", payment.ID, err)
+	}
+}
@@ -2,31 +2,65 @@ package service
 
 import (
 	accountService "VersatilePOS/account/service"
+	businessRepository "VersatilePOS/business/repository"
 	"VersatilePOS/database"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
 	"VersatilePOS/generic/rbac"
 	paymentRepository "VersatilePOS/payment/repository"
+	paymentService "VersatilePOS/payment/service"
+	priceModifierRepository "VersatilePOS/priceModifier/repository"
 	reservationModels "VersatilePOS/reservation/models"
 	"VersatilePOS/reservation/repository"
+	serviceRepository "VersatilePOS/service/repository"
 	"errors"
+	"log"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service struct {
-	repo        repository.Repository
-	paymentRepo paymentRepository.Repository
+	repo              repository.Repository
+	waitlistRepo      repository.WaitlistRepository
+	calendarFeedRepo  repository.CalendarFeedRepository
+	paymentRepo       paymentRepository.Repository
+	serviceRepo       serviceRepository.Repository
+	priceModifierRepo priceModifierRepository.Repository
+	businessRepo      businessRepository.Repository
+	gateway           paymentService.PaymentGateway
+	paymentService    *paymentService.Service
 }
 
 func NewService() *Service {
+	gateway, _ := paymentService.Gateway()
+
 	return &Service{
-		repo:        repository.Repository{},
-		paymentRepo: paymentRepository.Repository{},
+		repo:              repository.Repository{},
+		waitlistRepo:      repository.WaitlistRepository{},
+		calendarFeedRepo:  repository.CalendarFeedRepository{},
+		paymentRepo:       paymentRepository.Repository{},
+		serviceRepo:       serviceRepository.Repository{},
+		priceModifierRepo: priceModifierRepository.Repository{},
+		businessRepo:      businessRepository.Repository{},
+		gateway:           gateway,
+		paymentService:    paymentService.NewService(),
 	}
 }
 
+// businessCurrency returns the currency new reservations of a business are billed in
+func (s *Service) businessCurrency(businessID uint) (string, error) {
+	code, err := s.businessRepo.GetBusinessCurrency(businessID)
+	if err != nil {
+		return "", err
+	}
+	if code == "" {
+		return "", errors.New("business not found")
+	}
+	return code, nil
+}
 
 // hasReservationAccess checks if user has access to reservations for a given business
 func (s *Service) hasReservationAccess(businessID uint, userID uint, level constants.AccessLevel) (bool, error) {
@@ -39,16 +73,21 @@ func (s *Service) hasReservationAccess(businessID uint, userID uint, level const
 
 
 func (s *Service) CreateReservation(req reservationModels.CreateReservationRequest, userID uint) (*reservationModels.ReservationDto, error) {
-	businessIDs, err := accountService.GetBusinessIDsFromAccount(req.AccountID)
+	s.releaseExpiredHolds()
+	return s.createReservation(req, userID, 0)
+}
+
+// createReservation books the slot, holdEntryID is the waitlist entry whose held offer is being accepted
+func (s *Service) createReservation(req reservationModels.CreateReservationRequest, userID uint, holdEntryID uint) (*reservationModels.ReservationDto, error) {
+	service, err := s.serviceRepo.GetServiceByID(req.ServiceID)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("failed to get service")
 	}
-	if len(businessIDs) == 0 {
-		return nil, errors.New("account does not belong to any business")
+	if service == nil {
+		return nil, errors.New("service not found")
 	}
-	businessID := businessIDs[0]
 
-	hasAccess, err := s.hasReservationAccess(businessID, userID, constants.Write)
+	hasAccess, err := s.hasReservationAccess(service.BusinessID, userID, constants.Write)
 	if err != nil {
 		return nil, err
 	}
@@ -56,30 +95,85 @@ func (s *Service) CreateReservation(req reservationModels.CreateReservationReque
 		return nil, errors.New("unauthorized")
 	}
 
+	billCurrency, err := s.businessCurrency(service.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	if req.SavedPaymentMethodID != nil {
+		if _, err := s.paymentService.GetReservationFeeCard(service.BusinessID, *req.SavedPaymentMethodID, req.CustomerEmail); err != nil {
+			return nil, err
+		}
+	}
+
 	reservation := &entities.Reservation{
-		AccountID:         req.AccountID,
-		ServiceID:         req.ServiceID,
-		DatePlaced:        req.DatePlaced,
-		DateOfService:     req.DateOfService,
-		ReservationLength: req.ReservationLength,
-		TipAmount:         req.TipAmount,
-		Customer:          req.Customer,
-		CustomerEmail:     req.CustomerEmail,
-		CustomerPhone:     req.CustomerPhone,
-	}
-
-	if req.Status != "" {
-		reservation.Status = req.Status
-	} else {
+		ServiceID:            req.ServiceID,
+		DatePlaced:           req.DatePlaced,
+		DateOfService:        req.DateOfService,
+		ReservationLength:    req.ReservationLength,
+		TipAmount:            req.TipAmount,
+		Currency:             billCurrency,
+		Customer:             req.Customer,
+		CustomerEmail:        req.CustomerEmail,
+		CustomerPhone:        req.CustomerPhone,
+		SavedPaymentMethodID: req.SavedPaymentMethodID,
+	}
+
+	// New reservations start either Pending or Confirmed, later statuses are reached via transitions
+	switch req.Status {
+	case "":
 		reservation.Status = constants.ReservationConfirmed
+	case constants.ReservationPending, constants.ReservationConfirmed:
+		reservation.Status = req.Status
+	default:
+		return nil, errors.New("invalid reservation status")
 	}
 
 	if req.DatePlaced.IsZero() {
 		reservation.DatePlaced = time.Now()
 	}
 
-	if err := s.repo.CreateReservation(reservation); err != nil {
-		return nil, errors.New("failed to create reservation")
+	// Reservations requiring a deposit are held as Pending until the deposit succeeds, or released at the deadline
+	reservation.DepositAmount = calculateDeposit(service, req.ReservationLength, billCurrency)
+	if reservation.DepositAmount > 0 {
+		if s.gateway == nil {
+			return nil, errors.New("deposit required but payment gateway is not configured")
+		}
+		reservation.Status = constants.ReservationPending
+		depositExpiresAt := time.Now().Add(depositHoldDuration())
+		reservation.DepositExpiresAt = &depositExpiresAt
+	}
+
+	// Both an employee and, if the service requires one, a resource must be free for the slot
+	err = s.bookSlot(service, candidateEmployees(service, req.AccountID), func(tx *gorm.DB) error {
+		accountID, err := s.allocateEmployee(service, req.AccountID, req.DateOfService, req.ReservationLength, holdEntryID)
+		if err != nil {
+			return err
+		}
+		resourceID, err := s.allocateResource(service, req.DateOfService, req.ReservationLength, 0, nil)
+		if err != nil {
+			return err
+		}
+		reservation.AccountID = accountID
+		reservation.ResourceID = resourceID
+
+		if err := tx.Create(reservation).Error; err != nil {
+			return errors.New("failed to create reservation")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var depositClientSecret string
+	if reservation.DepositAmount > 0 {
+		depositClientSecret, err = s.requestDeposit(reservation)
+		if err != nil {
+			log.Printf("Failed to request deposit for reservation %d: %v", reservation.ID, err)
+			reservation.Status = constants.ReservationCancelled
+			_ = s.repo.UpdateReservation(reservation)
+			return nil, errors.New("failed to request reservation deposit")
+		}
 	}
 
 	// Reload to get the full entity with relations
@@ -92,6 +186,7 @@ func (s *Service) CreateReservation(req reservationModels.CreateReservationReque
 	}
 
 	dto := reservationModels.NewReservationDtoFromEntity(*createdReservation)
+	dto.DepositClientSecret = depositClientSecret
 	return &dto, nil
 }
 
@@ -212,6 +307,12 @@ func (s *Service) UpdateReservation(id uint, req reservationModels.UpdateReserva
 		}
 	}
 
+	// Remember the current slot so it can be offered to the waitlist once freed
+	previousServiceID := reservation.ServiceID
+	previousAccountID := reservation.AccountID
+	previousDateOfService := reservation.DateOfService
+	previousLength := reservation.ReservationLength
+
 	// Update fields if provided
 	if req.AccountID != nil {
 		reservation.AccountID = *req.AccountID
@@ -228,8 +329,21 @@ func (s *Service) UpdateReservation(id uint, req reservationModels.UpdateReserva
 	if req.ReservationLength != nil {
 		reservation.ReservationLength = *req.ReservationLength
 	}
+	previousStatus := reservation.Status
 	if req.Status != nil {
-		reservation.Status = *req.Status
+		// A reservation held Pending for its deposit is confirmed by the deposit's payment, not by hand
+		if previousStatus == constants.ReservationPending && *req.Status == constants.ReservationConfirmed {
+			outstanding, err := s.depositOutstanding(reservation)
+			if err != nil {
+				return nil, err
+			}
+			if outstanding {
+				return nil, errors.New("reservation deposit is not paid")
+			}
+		}
+		if err := s.transitionStatus(reservation, *req.Status); err != nil {
+			return nil, err
+		}
 	}
 	if req.TipAmount != nil {
 		reservation.TipAmount = *req.TipAmount
@@ -244,8 +358,65 @@ func (s *Service) UpdateReservation(id uint, req reservationModels.UpdateReserva
 		reservation.CustomerPhone = *req.CustomerPhone
 	}
 
-	if err := s.repo.UpdateReservation(reservation); err != nil {
-		return nil, errors.New("failed to update reservation")
+	// A late cancellation or no-show fee is saved together with the status change
+	var fee *reservationFee
+	if reservation.Status != previousStatus {
+		fee, err = s.cancellationFee(reservation, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	moved := reservation.ServiceID != previousServiceID ||
+		reservation.AccountID != previousAccountID ||
+		!reservation.DateOfService.Equal(previousDateOfService) ||
+		reservation.ReservationLength != previousLength
+	if moved && !reservation.Status.IsFinal() {
+		s.releaseExpiredHolds()
+
+		service, err := s.serviceRepo.GetServiceByID(reservation.ServiceID)
+		if err != nil {
+			return nil, errors.New("failed to get service")
+		}
+		if service == nil {
+			return nil, errors.New("service not found")
+		}
+		if reservation.AccountID != previousAccountID || reservation.ServiceID != previousServiceID {
+			if err := checkEmployee(service, reservation.AccountID); err != nil {
+				return nil, err
+			}
+		}
+
+		err = s.bookSlot(service, []uint{reservation.AccountID}, func(tx *gorm.DB) error {
+			if err := s.ensureSlotAvailable(reservation.AccountID, reservation.DateOfService, reservation.ReservationLength, reservation.ID, 0); err != nil {
+				return err
+			}
+			resourceID, err := s.allocateResource(service, reservation.DateOfService, reservation.ReservationLength, reservation.ID, reservation.ResourceID)
+			if err != nil {
+				return err
+			}
+			reservation.ResourceID = resourceID
+
+			if err := tx.Omit(clause.Associations).Save(reservation).Error; err != nil {
+				return errors.New("failed to update reservation")
+			}
+			return recordReservationFee(tx, reservation, fee)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if err := s.saveWithFee(reservation, fee); err != nil {
+		return nil, err
+	}
+
+	if reservation.Status == constants.ReservationCancelled && previousStatus != constants.ReservationCancelled {
+		s.releaseDeposit(reservation)
+	}
+	s.collectReservationFee(reservation, fee)
+
+	freed := reservation.Status == constants.ReservationCancelled && previousStatus != constants.ReservationCancelled
+	if (freed || moved) && !previousStatus.IsFinal() {
+		s.offerFreedSlot(previousServiceID, previousAccountID, previousDateOfService, previousLength)
 	}
 
 	// Reload to get updated entity
@@ -287,6 +458,33 @@ func (s *Service) ApplyPriceModifierToReservation(reservationID uint, req reserv
 		return errors.New("unauthorized")
 	}
 
+	pm, err := s.priceModifierRepo.GetPriceModifierByID(req.PriceModifierID, reservation.Service.BusinessID)
+	if err != nil {
+		return errors.New("failed to get price modifier")
+	}
+	if pm == nil {
+		return errors.New("price modifier not found")
+	}
+	if !pm.IsPercentage && pm.Currency != reservation.Currency {
+		return errors.New("price modifier currency does not match the reservation currency")
+	}
+
+	// Discounts need the Apply Discounts permission, limited by their percentage or amount
+	if pm.ModifierType == constants.Discount {
+		value := pm.Value
+		scope := rbac.Scope{Amount: &value}
+		if pm.IsPercentage {
+			scope = rbac.Scope{Percentage: &value}
+		}
+		ok, err := rbac.HasScopedAccess(constants.ApplyDiscount, constants.Write, reservation.Service.BusinessID, userID, scope)
+		if err != nil {
+			return errors.New("failed to verify permissions")
+		}
+		if !ok {
+			return errors.New("unauthorized")
+		}
+	}
+
 	link := &entities.PriceModifierReservationLink{
 		PriceModifierID: req.PriceModifierID,
 		ReservationID:   reservationID,
@@ -338,9 +536,12 @@ func (s *Service) LinkPaymentToReservation(reservationID, paymentID uint, userID
 		}
 		return err
 	}
-	if payment == nil {
+	if payment == nil || payment.BusinessID != reservation.Service.BusinessID {
 		return errors.New("payment not found")
 	}
+	if payment.Currency != reservation.Currency {
+		return errors.New("payment currency does not match the reservation currency")
+	}
 
 	var existingLink entities.ReservationPaymentLink
 	if result := database.DB.Where("reservation_id = ? AND payment_id = ?", reservationID, paymentID).First(&existingLink); result.Error == nil {
@@ -355,5 +556,28 @@ func (s *Service) LinkPaymentToReservation(reservationID, paymentID uint, userID
 	}
 
 	_, err = s.repo.CreateReservationPaymentLink(link)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Only mark the reservation as paid once its bill is fully settled
+	if payment.Status == constants.Completed {
+		updatedReservation, err := s.repo.GetReservationByID(reservationID)
+		if err != nil || updatedReservation == nil {
+			log.Printf("Warning: Failed to reload reservation %d after linking payment: %v", reservationID, err)
+			return nil
+		}
+
+		bill := reservationModels.NewReservationBillDtoFromEntity(*updatedReservation)
+		if bill.IsSettled() && updatedReservation.Status.CanTransitionTo(constants.ReservationCompleted) {
+			updatedReservation.Status = constants.ReservationCompleted
+			if err := s.repo.UpdateReservation(updatedReservation); err != nil {
+				log.Printf("Warning: Failed to update reservation %d status after linking payment: %v", reservationID, err)
+			}
+		} else {
+			log.Printf("Reservation %d has an outstanding balance of %s, status remains %s", reservationID, currency.Format(bill.Balance, bill.Currency), updatedReservation.Status)
+		}
+	}
+
+	return nil
 }
\ No newline at end of file
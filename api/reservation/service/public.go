@@ -0,0 +1,442 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/notification"
+	reservationModels "VersatilePOS/reservation/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultBookingConfirmationMinutes = 30
+	defaultMaxUnconfirmedBookings     = 3
+	maxConfirmationAttempts           = 5
+)
+
+var (
+	linkSecretOnce sync.Once
+	linkSecret     []byte
+)
+
+// bookingConfirmationDuration is how long an online booking waits for its confirmation code before it is released
+func bookingConfirmationDuration() time.Duration {
+	return time.Duration(env.PositiveInt("PUBLIC_BOOKING_CONFIRMATION_MINUTES", defaultBookingConfirmationMinutes)) * time.Minute
+}
+
+// maxUnconfirmedBookings is how many online bookings a client IP, email or phone can hold awaiting confirmation at once
+func maxUnconfirmedBookings() int64 {
+	return int64(env.PositiveInt("PUBLIC_BOOKING_MAX_UNCONFIRMED", defaultMaxUnconfirmedBookings))
+}
+
+// bookingLinkSecret is the key cancel links are signed with, from PUBLIC_BOOKING_LINK_SECRET. When it is not set a
+// random key is used, so links sent before a restart stop working.
+func bookingLinkSecret() []byte {
+	linkSecretOnce.Do(func() {
+		if value := os.Getenv("PUBLIC_BOOKING_LINK_SECRET"); value != "" {
+			linkSecret = []byte(value)
+			return
+		}
+		log.Println("Warning: PUBLIC_BOOKING_LINK_SECRET is not set, cancel links will not survive a restart")
+		linkSecret = make([]byte, 32)
+		if _, err := rand.Read(linkSecret); err != nil {
+			log.Fatalf("Failed to generate the booking link secret: %v", err)
+		}
+	})
+	return linkSecret
+}
+
+// cancelSignature signs the reservation ID and the link's expiry so the cancel link sent to the customer cannot be
+// forged or used after it expires
+func cancelSignature(reservationID uint, expires int64) string {
+	mac := hmac.New(sha256.New, bookingLinkSecret())
+	fmt.Fprintf(mac, "reservation:%d:cancel:%d", reservationID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cancelLink is the link the customer cancels their booking with, it expires when the reservation starts
+func cancelLink(businessID uint, reservation *entities.Reservation) string {
+	baseURL := os.Getenv("PUBLIC_BOOKING_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	expires := reservation.DateOfService.Unix()
+	return fmt.Sprintf("%s/public/business/%d/reservation/%d/cancel?expires=%d&signature=%s", strings.TrimRight(baseURL, "/"), businessID, reservation.ID, expires, cancelSignature(reservation.ID, expires))
+}
+
+func generateConfirmationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// releaseExpiredHolds frees slots held by expired waitlist offers, unconfirmed online bookings and unpaid deposits
+func (s *Service) releaseExpiredHolds() {
+	s.expirePendingReservations()
+	s.expireWaitlistOffers()
+}
+
+// expirePendingReservations cancels Pending reservations whose confirmation code or deposit deadline passed, cancels
+// their deposit's payment intent and offers the freed slot to the waitlist
+func (s *Service) expirePendingReservations() {
+	expired, err := s.repo.GetExpiredPendingReservations(time.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to get expired pending reservations: %v", err)
+		return
+	}
+
+	for i := range expired {
+		reservation := &expired[i]
+		if reservation.ConfirmationCodeHash == "" {
+			// A deposit paid just before its deadline confirms the booking once the payment is processed
+			outstanding, err := s.depositOutstanding(reservation)
+			if err != nil {
+				log.Printf("Warning: Failed to check deposit of reservation %d: %v", reservation.ID, err)
+				continue
+			}
+			if !outstanding {
+				continue
+			}
+		}
+
+		reservation.Status = constants.ReservationCancelled
+		reservation.ConfirmationCodeHash = ""
+		if err := s.repo.UpdateReservation(reservation); err != nil {
+			log.Printf("Warning: Failed to release pending reservation %d: %v", reservation.ID, err)
+			continue
+		}
+		s.releaseDeposit(reservation)
+		s.offerFreedSlot(reservation.ServiceID, reservation.AccountID, reservation.DateOfService, reservation.ReservationLength)
+	}
+}
+
+func (s *Service) GetBookableServices(businessID uint) ([]reservationModels.PublicServiceDto, error) {
+	services, err := s.serviceRepo.GetServicesByBusinessID(businessID)
+	if err != nil {
+		return nil, errors.New("failed to get services")
+	}
+
+	// Only services with someone to provide them can be booked
+	dtos := make([]reservationModels.PublicServiceDto, 0, len(services))
+	for _, service := range services {
+		if len(service.Employees) > 0 {
+			dtos = append(dtos, reservationModels.NewPublicServiceDtoFromEntity(service))
+		}
+	}
+	return dtos, nil
+}
+
+// GetAvailability lists the slots of the service on the given day that an employee and, if required, a resource are free for
+func (s *Service) GetAvailability(businessID uint, serviceID uint, day time.Time, length uint32) ([]reservationModels.AvailableSlotDto, error) {
+	service, err := s.getBookableService(businessID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err = bookingLength(service, length)
+	if err != nil {
+		return nil, err
+	}
+
+	s.releaseExpiredHolds()
+
+	dayStart, dayEnd := provisioningHours(service, day)
+	step := time.Duration(service.ProvisioningInterval) * time.Minute
+	if step <= 0 {
+		step = time.Duration(length) * time.Minute
+	}
+	now := time.Now()
+
+	slots := make([]reservationModels.AvailableSlotDto, 0)
+	for start := dayStart; !start.Add(time.Duration(length) * time.Minute).After(dayEnd); start = start.Add(step) {
+		if start.Before(now) {
+			continue
+		}
+		if _, err := s.allocateEmployee(service, 0, start, length, 0); err != nil {
+			continue
+		}
+		if _, err := s.allocateResource(service, start, length, 0, nil); err != nil {
+			continue
+		}
+		slots = append(slots, reservationModels.AvailableSlotDto{
+			Start: start,
+			End:   start.Add(time.Duration(length) * time.Minute),
+		})
+	}
+
+	return slots, nil
+}
+
+// CreatePublicReservation books a slot for a customer. The booking stays Pending until confirmed with the code sent to the customer.
+// A client IP, email or phone can only hold PUBLIC_BOOKING_MAX_UNCONFIRMED unconfirmed bookings at once.
+func (s *Service) CreatePublicReservation(businessID uint, clientIP string, req reservationModels.CreatePublicReservationRequest) (*reservationModels.PublicReservationDto, error) {
+	if req.CustomerEmail == "" && req.CustomerPhone == "" {
+		return nil, errors.New("customer email or phone is required")
+	}
+
+	service, err := s.getBookableService(businessID, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := bookingLength(service, req.ReservationLength)
+	if err != nil {
+		return nil, err
+	}
+
+	start := req.DateOfService
+	if !start.After(time.Now()) {
+		return nil, errors.New("requested time is in the past")
+	}
+	dayStart, dayEnd := provisioningHours(service, start)
+	if start.Before(dayStart) || start.Add(time.Duration(length)*time.Minute).After(dayEnd) {
+		return nil, errors.New("requested time is outside the service's booking hours")
+	}
+
+	s.releaseExpiredHolds()
+
+	unconfirmed, err := s.repo.CountUnconfirmedBookings(clientIP, req.CustomerEmail, req.CustomerPhone, time.Now())
+	if err != nil {
+		return nil, errors.New("failed to check unconfirmed bookings")
+	}
+	if unconfirmed >= maxUnconfirmedBookings() {
+		return nil, errors.New("too many unconfirmed bookings")
+	}
+
+	billCurrency, err := s.businessCurrency(service.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateConfirmationCode()
+	if err != nil {
+		return nil, errors.New("failed to generate confirmation code")
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("failed to generate confirmation code")
+	}
+	expiresAt := time.Now().Add(bookingConfirmationDuration())
+
+	reservation := &entities.Reservation{
+		ServiceID:                 service.ID,
+		DatePlaced:                time.Now(),
+		DateOfService:             start,
+		ReservationLength:         length,
+		Status:                    constants.ReservationPending,
+		Customer:                  req.Customer,
+		CustomerEmail:             req.CustomerEmail,
+		CustomerPhone:             req.CustomerPhone,
+		Currency:                  billCurrency,
+		DepositAmount:             calculateDeposit(service, length, billCurrency),
+		BookedOnline:              true,
+		ConfirmationCodeHash:      string(codeHash),
+		ConfirmationCodeExpiresAt: &expiresAt,
+		BookedFromIP:              clientIP,
+	}
+
+	if reservation.DepositAmount > 0 && s.gateway == nil {
+		return nil, errors.New("deposit required but payment gateway is not configured")
+	}
+
+	err = s.bookSlot(service, candidateEmployees(service, 0), func(tx *gorm.DB) error {
+		accountID, err := s.allocateEmployee(service, 0, start, length, 0)
+		if err != nil {
+			return err
+		}
+		resourceID, err := s.allocateResource(service, start, length, 0, nil)
+		if err != nil {
+			return err
+		}
+		reservation.AccountID = accountID
+		reservation.ResourceID = resourceID
+
+		if err := tx.Create(reservation).Error; err != nil {
+			return errors.New("failed to create reservation")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	link := cancelLink(businessID, reservation)
+	if err := notification.Notify(notification.Event{
+		Type:    "reservation.confirmation_code",
+		Email:   reservation.CustomerEmail,
+		Phone:   reservation.CustomerPhone,
+		Subject: "Confirm your booking",
+		Message: fmt.Sprintf("Your confirmation code for %s on %s is %s. To cancel, visit %s", service.Name, start.Format(time.RFC1123), code, link),
+		Data: map[string]string{
+			"reservationId": strconv.FormatUint(uint64(reservation.ID), 10),
+			"code":          code,
+			"cancelUrl":     link,
+		},
+	}); err != nil {
+		log.Printf("Warning: Failed to send confirmation code for reservation %d: %v", reservation.ID, err)
+	}
+
+	reservation.Service = *service
+	dto := reservationModels.NewPublicReservationDtoFromEntity(*reservation)
+	return &dto, nil
+}
+
+// ConfirmPublicReservation confirms an online booking with the code sent to the customer.
+// Bookings requiring a deposit stay Pending until the deposit is paid.
+func (s *Service) ConfirmPublicReservation(businessID uint, reservationID uint, code string) (*reservationModels.PublicReservationDto, error) {
+	s.releaseExpiredHolds()
+
+	reservation, err := s.getPublicReservation(businessID, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status != constants.ReservationPending || reservation.ConfirmationCodeHash == "" {
+		return nil, errors.New("reservation is not awaiting confirmation")
+	}
+	if reservation.ConfirmationAttempts >= maxConfirmationAttempts {
+		return nil, errors.New("too many confirmation attempts")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(reservation.ConfirmationCodeHash), []byte(code)); err != nil {
+		reservation.ConfirmationAttempts++
+		if err := s.repo.UpdateReservation(reservation); err != nil {
+			log.Printf("Warning: Failed to record confirmation attempt for reservation %d: %v", reservation.ID, err)
+		}
+		return nil, errors.New("invalid confirmation code")
+	}
+
+	reservation.ConfirmationCodeHash = ""
+	reservation.ConfirmationCodeExpiresAt = nil
+	if reservation.DepositAmount <= 0 {
+		if err := s.transitionStatus(reservation, constants.ReservationConfirmed); err != nil {
+			return nil, err
+		}
+	} else {
+		// The slot stays held for the deposit only until its deadline
+		depositExpiresAt := time.Now().Add(depositHoldDuration())
+		reservation.DepositExpiresAt = &depositExpiresAt
+	}
+	if err := s.repo.UpdateReservation(reservation); err != nil {
+		return nil, errors.New("failed to update reservation")
+	}
+
+	var depositClientSecret string
+	if reservation.DepositAmount > 0 {
+		depositClientSecret, err = s.requestDeposit(reservation)
+		if err != nil {
+			log.Printf("Failed to request deposit for reservation %d: %v", reservation.ID, err)
+			reservation.Status = constants.ReservationCancelled
+			_ = s.repo.UpdateReservation(reservation)
+			return nil, errors.New("failed to request reservation deposit")
+		}
+	}
+
+	dto := reservationModels.NewPublicReservationDtoFromEntity(*reservation)
+	dto.DepositClientSecret = depositClientSecret
+	return &dto, nil
+}
+
+// CancelPublicReservation cancels an online booking through the signed link sent to the customer
+func (s *Service) CancelPublicReservation(businessID uint, reservationID uint, expires int64, signature string) (*reservationModels.PublicReservationDto, error) {
+	if !hmac.Equal([]byte(signature), []byte(cancelSignature(reservationID, expires))) {
+		return nil, errors.New("invalid signature")
+	}
+	if time.Now().Unix() >= expires {
+		return nil, errors.New("cancel link has expired")
+	}
+
+	reservation, err := s.getPublicReservation(businessID, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	previousStatus := reservation.Status
+	if err := s.transitionStatus(reservation, constants.ReservationCancelled); err != nil {
+		return nil, err
+	}
+	reservation.ConfirmationCodeHash = ""
+	reservation.ConfirmationCodeExpiresAt = nil
+
+	// Pending bookings were never confirmed, so only confirmed ones are subject to the cancellation policy
+	var fee *reservationFee
+	if previousStatus != constants.ReservationPending && previousStatus != constants.ReservationCancelled {
+		fee, err = s.cancellationFee(reservation, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.saveWithFee(reservation, fee); err != nil {
+		return nil, err
+	}
+
+	s.releaseDeposit(reservation)
+	s.collectReservationFee(reservation, fee)
+	s.offerFreedSlot(reservation.ServiceID, reservation.AccountID, reservation.DateOfService, reservation.ReservationLength)
+
+	dto := reservationModels.NewPublicReservationDtoFromEntity(*reservation)
+	return &dto, nil
+}
+
+func (s *Service) getBookableService(businessID uint, serviceID uint) (*entities.Service, error) {
+	service, err := s.serviceRepo.GetServiceByID(serviceID)
+	if err != nil {
+		return nil, errors.New("failed to get service")
+	}
+	if service == nil || service.BusinessID != businessID || len(service.Employees) == 0 {
+		return nil, errors.New("service not found")
+	}
+	return service, nil
+}
+
+func (s *Service) getPublicReservation(businessID uint, reservationID uint) (*entities.Reservation, error) {
+	reservation, err := s.repo.GetReservationByID(reservationID)
+	if err != nil {
+		return nil, errors.New("failed to get reservation")
+	}
+	if reservation == nil || !reservation.BookedOnline || reservation.Service.BusinessID != businessID {
+		return nil, errors.New("reservation not found")
+	}
+	return reservation, nil
+}
+
+// bookingLength defaults the reservation length to the service's provisioning interval
+func bookingLength(service *entities.Service, length uint32) (uint32, error) {
+	if length == 0 {
+		length = uint32(service.ProvisioningInterval)
+	}
+	if length == 0 {
+		return 0, errors.New("reservationLength is required")
+	}
+	return length, nil
+}
+
+// provisioningHours returns when the service can be provided on the day of the given time, in UTC
+func provisioningHours(service *entities.Service, day time.Time) (time.Time, time.Time) {
+	day = day.UTC()
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	startOfDay := service.ProvisioningStartTime.UTC()
+	endOfDay := service.ProvisioningEndTime.UTC()
+	start := date.Add(time.Duration(startOfDay.Hour())*time.Hour + time.Duration(startOfDay.Minute())*time.Minute)
+	end := date.Add(time.Duration(endOfDay.Hour())*time.Hour + time.Duration(endOfDay.Minute())*time.Minute)
+	return start, end
+}
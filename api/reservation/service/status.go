@@ -0,0 +1,140 @@
+package service
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/currency"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// transitionStatus moves the reservation to the next status if the state machine allows it
+func (s *Service) transitionStatus(reservation *entities.Reservation, next constants.ReservationStatus) error {
+	if !next.IsValid() {
+		return errors.New("invalid reservation status")
+	}
+	if reservation.Status == next {
+		return nil
+	}
+	if !reservation.Status.CanTransitionTo(next) {
+		return errors.New("invalid reservation status transition")
+	}
+
+	reservation.Status = next
+	return nil
+}
+
+// reservationFee is a late-cancellation or no-show fee incurred by a status change
+type reservationFee struct {
+	BusinessID uint
+	Name       string
+	Amount     float64
+}
+
+// cancellationFee returns the service's late-cancellation or no-show fee for a reservation that is being cancelled
+// or marked as a no-show at the given time, or nil when no fee is due.
+func (s *Service) cancellationFee(reservation *entities.Reservation, at time.Time) (*reservationFee, error) {
+	service, err := s.serviceRepo.GetServiceByID(reservation.ServiceID)
+	if err != nil {
+		return nil, errors.New("failed to get service")
+	}
+	if service == nil {
+		return nil, errors.New("service not found")
+	}
+
+	switch reservation.Status {
+	case constants.ReservationCancelled:
+		if service.LateCancellationFee <= 0 || service.CancellationWindow == 0 {
+			return nil, nil
+		}
+		deadline := reservation.DateOfService.Add(-time.Duration(service.CancellationWindow) * time.Minute)
+		if at.Before(deadline) {
+			return nil, nil
+		}
+		return &reservationFee{BusinessID: service.BusinessID, Name: "Late cancellation fee", Amount: service.LateCancellationFee}, nil
+	case constants.ReservationNoShow:
+		if service.NoShowFee <= 0 {
+			return nil, nil
+		}
+		return &reservationFee{BusinessID: service.BusinessID, Name: "No-show fee", Amount: service.NoShowFee}, nil
+	}
+
+	return nil, nil
+}
+
+// saveWithFee saves the reservation together with the fee its status change incurs, so neither is stored without
+// the other
+func (s *Service) saveWithFee(reservation *entities.Reservation, fee *reservationFee) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(reservation).Error; err != nil {
+			return errors.New("failed to update reservation")
+		}
+		return recordReservationFee(tx, reservation, fee)
+	})
+}
+
+// recordReservationFee adds a fee to the reservation as a one-off Surcharge within the transaction
+func recordReservationFee(tx *gorm.DB, reservation *entities.Reservation, fee *reservationFee) error {
+	if fee == nil {
+		return nil
+	}
+
+	now := time.Now()
+	modifier := &entities.PriceModifier{
+		BusinessID:   fee.BusinessID,
+		ModifierType: constants.Surcharge,
+		Name:         fee.Name,
+		Value:        currency.Round(fee.Amount, reservation.Currency),
+		IsPercentage: false,
+		Currency:     reservation.Currency,
+		// One-off modifier, expire it immediately so it isn't offered for reuse
+		EndDate: &now,
+	}
+	if err := tx.Create(modifier).Error; err != nil {
+		return errors.New("failed to record reservation fee")
+	}
+
+	link := &entities.PriceModifierReservationLink{
+		PriceModifierID: modifier.ID,
+		ReservationID:   reservation.ID,
+	}
+	if err := tx.Create(link).Error; err != nil {
+		return errors.New("failed to record reservation fee")
+	}
+	return nil
+}
+
+// collectReservationFee collects a recorded fee. Completed payments linked to the reservation, such as its deposit,
+// are retained and count towards the fee; the remainder is charged to the card linked to the reservation at booking,
+// whose consent covers reservation fees. Without a card, or when the charge fails, the remainder stays outstanding
+// on the reservation.
+func (s *Service) collectReservationFee(reservation *entities.Reservation, fee *reservationFee) {
+	if fee == nil {
+		return
+	}
+
+	amount := currency.Round(fee.Amount, reservation.Currency)
+	paid := 0.0
+	for _, paymentLink := range reservation.ReservationPaymentLinks {
+		if paymentLink.Payment.Status == constants.Completed {
+			paid += paymentLink.Payment.Amount
+		}
+	}
+	if paid >= amount {
+		log.Printf("%s of %s for reservation %d settled from its payments", fee.Name, currency.Format(amount, reservation.Currency), reservation.ID)
+		return
+	}
+
+	outstanding := currency.Round(amount-paid, reservation.Currency)
+	payment, err := s.paymentService.ChargeReservationFee(reservation.ID, fee.BusinessID, reservation.SavedPaymentMethodID, reservation.CustomerEmail, outstanding, reservation.Currency)
+	if err != nil {
+		log.Printf("%s of reservation %d not charged, %s outstanding: %v", fee.Name, reservation.ID, currency.Format(outstanding, reservation.Currency), err)
+		return
+	}
+	log.Printf("%s of reservation %d charged to its card, payment %d", fee.Name, reservation.ID, payment.ID)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/env"
+	"VersatilePOS/generic/notification"
+	reservationModels "VersatilePOS/reservation/models"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+const defaultWaitlistHoldMinutes = 15
+
+// waitlistHoldDuration is how long a freed slot is held for the offered waitlist entry
+func waitlistHoldDuration() time.Duration {
+	return time.Duration(env.PositiveInt("WAITLIST_HOLD_MINUTES", defaultWaitlistHoldMinutes)) * time.Minute
+}
+
+func (s *Service) CreateWaitlistEntry(req reservationModels.CreateWaitlistEntryRequest, userID uint) (*reservationModels.WaitlistEntryDto, error) {
+	service, err := s.serviceRepo.GetServiceByID(req.ServiceID)
+	if err != nil {
+		return nil, errors.New("failed to get service")
+	}
+	if service == nil {
+		return nil, errors.New("service not found")
+	}
+
+	hasAccess, err := s.hasReservationAccess(service.BusinessID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	if !req.WindowEnd.After(req.WindowStart) {
+		return nil, errors.New("waitlist window end must be after its start")
+	}
+
+	entry := &entities.WaitlistEntry{
+		ServiceID:         req.ServiceID,
+		WindowStart:       req.WindowStart,
+		WindowEnd:         req.WindowEnd,
+		ReservationLength: req.ReservationLength,
+		Customer:          req.Customer,
+		CustomerEmail:     req.CustomerEmail,
+		CustomerPhone:     req.CustomerPhone,
+		Status:            constants.WaitlistWaiting,
+	}
+
+	if err := s.waitlistRepo.CreateEntry(entry); err != nil {
+		return nil, errors.New("failed to create waitlist entry")
+	}
+
+	dto := reservationModels.NewWaitlistEntryDtoFromEntity(*entry)
+	return &dto, nil
+}
+
+func (s *Service) GetWaitlistEntries(serviceID uint, userID uint) ([]reservationModels.WaitlistEntryDto, error) {
+	service, err := s.serviceRepo.GetServiceByID(serviceID)
+	if err != nil {
+		return nil, errors.New("failed to get service")
+	}
+	if service == nil {
+		return nil, errors.New("service not found")
+	}
+
+	hasAccess, err := s.hasReservationAccess(service.BusinessID, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	s.expireWaitlistOffers()
+
+	entries, err := s.waitlistRepo.GetEntriesByServiceID(serviceID)
+	if err != nil {
+		return nil, errors.New("failed to get waitlist entries")
+	}
+
+	dtos := make([]reservationModels.WaitlistEntryDto, 0, len(entries))
+	for _, entry := range entries {
+		dtos = append(dtos, reservationModels.NewWaitlistEntryDtoFromEntity(entry))
+	}
+	return dtos, nil
+}
+
+func (s *Service) CancelWaitlistEntry(id uint, userID uint) error {
+	entry, err := s.getAuthorizedWaitlistEntry(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if entry.Status != constants.WaitlistWaiting && entry.Status != constants.WaitlistOffered {
+		return errors.New("waitlist entry is no longer active")
+	}
+
+	wasOffered := entry.Status == constants.WaitlistOffered
+	entry.Status = constants.WaitlistCancelled
+	if err := s.waitlistRepo.UpdateEntry(entry); err != nil {
+		return errors.New("failed to update waitlist entry")
+	}
+
+	// A declined offer passes the held slot on to the next customer
+	if wasOffered && entry.OfferedAccountID != nil && entry.OfferedDateOfService != nil {
+		s.offerFreedSlot(entry.ServiceID, *entry.OfferedAccountID, *entry.OfferedDateOfService, entry.ReservationLength)
+	}
+
+	return nil
+}
+
+// AcceptWaitlistOffer books the slot held for the entry while the hold has not expired
+func (s *Service) AcceptWaitlistOffer(id uint, userID uint) (*reservationModels.ReservationDto, error) {
+	s.expireWaitlistOffers()
+
+	entry, err := s.getAuthorizedWaitlistEntry(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Status != constants.WaitlistOffered || entry.OfferedAccountID == nil || entry.OfferedDateOfService == nil {
+		return nil, errors.New("waitlist entry has no active offer")
+	}
+
+	req := reservationModels.CreateReservationRequest{
+		AccountID:         *entry.OfferedAccountID,
+		ServiceID:         entry.ServiceID,
+		DateOfService:     *entry.OfferedDateOfService,
+		ReservationLength: entry.ReservationLength,
+		Customer:          entry.Customer,
+		CustomerEmail:     entry.CustomerEmail,
+		CustomerPhone:     entry.CustomerPhone,
+	}
+
+	reservation, err := s.createReservation(req, userID, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Status = constants.WaitlistAccepted
+	entry.ReservationID = &reservation.ID
+	if err := s.waitlistRepo.UpdateEntry(entry); err != nil {
+		log.Printf("Warning: Failed to mark waitlist entry %d as accepted: %v", entry.ID, err)
+	}
+
+	return reservation, nil
+}
+
+func (s *Service) getAuthorizedWaitlistEntry(id uint, userID uint) (*entities.WaitlistEntry, error) {
+	entry, err := s.waitlistRepo.GetEntryByID(id)
+	if err != nil {
+		return nil, errors.New("failed to get waitlist entry")
+	}
+	if entry == nil {
+		return nil, errors.New("waitlist entry not found")
+	}
+
+	hasAccess, err := s.hasReservationAccess(entry.Service.BusinessID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	return entry, nil
+}
+
+// expireWaitlistOffers expires offers whose hold ran out and offers their slots to the next entries
+func (s *Service) expireWaitlistOffers() {
+	expired, err := s.waitlistRepo.GetExpiredOffers(time.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to get expired waitlist offers: %v", err)
+		return
+	}
+
+	for i := range expired {
+		entry := &expired[i]
+		entry.Status = constants.WaitlistExpired
+		if err := s.waitlistRepo.UpdateEntry(entry); err != nil {
+			log.Printf("Warning: Failed to expire waitlist entry %d: %v", entry.ID, err)
+			continue
+		}
+		if entry.OfferedAccountID != nil && entry.OfferedDateOfService != nil {
+			s.offerFreedSlot(entry.ServiceID, *entry.OfferedAccountID, *entry.OfferedDateOfService, entry.ReservationLength)
+		}
+	}
+}
+
+// offerFreedSlot offers a freed slot to the oldest waiting entry whose window and length fit it
+func (s *Service) offerFreedSlot(serviceID uint, accountID uint, start time.Time, length uint32) {
+	entries, err := s.waitlistRepo.GetWaitingEntries(serviceID, start)
+	if err != nil {
+		log.Printf("Warning: Failed to get waitlist entries for service %d: %v", serviceID, err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ReservationLength > length {
+			continue
+		}
+		if err := s.ensureSlotAvailable(accountID, start, entry.ReservationLength, 0, 0); err != nil {
+			return
+		}
+
+		holdExpiresAt := time.Now().Add(waitlistHoldDuration())
+		offeredAccountID := accountID
+		offeredDateOfService := start
+		entry.Status = constants.WaitlistOffered
+		entry.OfferedAccountID = &offeredAccountID
+		entry.OfferedDateOfService = &offeredDateOfService
+		entry.HoldExpiresAt = &holdExpiresAt
+		if err := s.waitlistRepo.UpdateEntry(entry); err != nil {
+			log.Printf("Warning: Failed to offer slot to waitlist entry %d: %v", entry.ID, err)
+			return
+		}
+
+		if err := notification.Notify(notification.Event{
+			Type:    "waitlist.slot_offered",
+			Email:   entry.CustomerEmail,
+			Phone:   entry.CustomerPhone,
+			Subject: "A slot has opened up",
+			Message: fmt.Sprintf("A slot on %s is held for you until %s.", start.Format(time.RFC1123), holdExpiresAt.Format(time.RFC1123)),
+			Data: map[string]string{
+				"waitlistEntryId": strconv.FormatUint(uint64(entry.ID), 10),
+				"serviceId":       strconv.FormatUint(uint64(serviceID), 10),
+				"dateOfService":   start.Format(time.RFC3339),
+				"holdExpiresAt":   holdExpiresAt.Format(time.RFC3339),
+			},
+		}); err != nil {
+			log.Printf("Warning: Failed to notify waitlist entry %d: %v", entry.ID, err)
+		}
+		return
+	}
+}
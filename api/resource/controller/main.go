@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"VersatilePOS/generic/models"
+	"VersatilePOS/middleware"
+	resourceModels "VersatilePOS/resource/models"
+	"VersatilePOS/resource/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller struct {
+	service *service.Service
+}
+
+func NewController() *Controller {
+	return &Controller{
+		service: service.NewService(),
+	}
+}
+
+// @Summary Create resource
+// @Description Create a bookable resource (chair, room, machine) of a business. Capacity defaults to 1.
+// @Tags resource
+// @Accept  json
+// @Produce  json
+// @Param   resource  body  models.CreateResourceRequest  true  "Resource to create"
+// @Success 201 {object} models.ResourceDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /resource [post]
+// @Id createResource
+func (ctrl *Controller) CreateResource(c *gin.Context) {
+	var req resourceModels.CreateResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	resource, err := ctrl.service.CreateResource(req, userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, resource)
+}
+
+// @Summary Get resources
+// @Description Get all resources of a business
+// @Tags resource
+// @Produce  json
+// @Param   businessId query int true "Business ID"
+// @Success 200 {array} models.ResourceDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /resource [get]
+// @Id getResources
+func (ctrl *Controller) GetResources(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	businessIDStr := c.Query("businessId")
+	if businessIDStr == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "businessId query parameter is required"})
+		return
+	}
+
+	businessID, err := strconv.ParseUint(businessIDStr, 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "invalid businessId"})
+		return
+	}
+
+	resources, err := ctrl.service.GetResources(uint(businessID), userID)
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, resources)
+}
+
+// @Summary Get resource by id
+// @Description Get a resource by its ID
+// @Tags resource
+// @Produce  json
+// @Param   id   path      int  true  "Resource ID"
+// @Success 200 {object} models.ResourceDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /resource/{id} [get]
+// @Id getResourceById
+func (ctrl *Controller) GetResourceById(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid resource ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	resource, err := ctrl.service.GetResourceByID(uint(id), userID)
+	if err != nil {
+		if err.Error() == "resource not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, resource)
+}
+
+// @Summary Update resource
+// @Description Update resource details
+// @Tags resource
+// @Accept  json
+// @Produce  json
+// @Param   id   path      int  true  "Resource ID"
+// @Param   resource  body  models.UpdateResourceRequest  true  "Resource updates"
+// @Success 200 {object} models.ResourceDto
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /resource/{id} [put]
+// @Id updateResource
+func (ctrl *Controller) UpdateResource(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid resource ID"})
+		return
+	}
+
+	var req resourceModels.UpdateResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	resource, err := ctrl.service.UpdateResource(uint(id), req, userID)
+	if err != nil {
+		if err.Error() == "resource not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "capacity must be at least 1" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, resource)
+}
+
+// @Summary Delete resource
+// @Description Delete a resource and unlink it from all services
+// @Tags resource
+// @Param   id   path      int  true  "Resource ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /resource/{id} [delete]
+// @Id deleteResource
+func (ctrl *Controller) DeleteResource(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid resource ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	if err := ctrl.service.DeleteResource(uint(id), userID); err != nil {
+		if err.Error() == "resource not found" {
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		} else if err.Error() == "unauthorized" {
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else {
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
+	resourceGroup := r.Group("/resource")
+	resourceGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
+	{
+		resourceGroup.POST("", ctrl.CreateResource)
+		resourceGroup.GET("", ctrl.GetResources)
+		resourceGroup.GET("/:id", ctrl.GetResourceById)
+		resourceGroup.PUT("/:id", ctrl.UpdateResource)
+		resourceGroup.DELETE("/:id", ctrl.DeleteResource)
+	}
+}
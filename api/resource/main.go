@@ -0,0 +1,12 @@
+package resource
+
+import (
+	"VersatilePOS/resource/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterHandlers(r *gin.Engine) {
+	resourceController := controller.NewController()
+	resourceController.RegisterRoutes(r)
+}
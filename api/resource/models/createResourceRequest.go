@@ -0,0 +1,8 @@
+package models
+
+type CreateResourceRequest struct {
+	BusinessID uint   `json:"businessId" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	Type       string `json:"type"`
+	Capacity   uint   `json:"capacity"`
+}
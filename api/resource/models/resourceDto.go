@@ -0,0 +1,21 @@
+package models
+
+import "VersatilePOS/database/entities"
+
+type ResourceDto struct {
+	ID         uint   `json:"id"`
+	BusinessID uint   `json:"businessId"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Capacity   uint   `json:"capacity"`
+}
+
+func NewResourceDtoFromEntity(r entities.Resource) ResourceDto {
+	return ResourceDto{
+		ID:         r.ID,
+		BusinessID: r.BusinessID,
+		Name:       r.Name,
+		Type:       r.Type,
+		Capacity:   r.Capacity,
+	}
+}
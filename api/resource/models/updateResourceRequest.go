@@ -0,0 +1,7 @@
+package models
+
+type UpdateResourceRequest struct {
+	Name     *string `json:"name"`
+	Type     *string `json:"type"`
+	Capacity *uint   `json:"capacity"`
+}
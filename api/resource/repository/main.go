@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"VersatilePOS/database"
+	"VersatilePOS/database/entities"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct{}
+
+func (r *Repository) CreateResource(resource *entities.Resource) error {
+	return database.DB.Create(resource).Error
+}
+
+func (r *Repository) GetResourceByID(id uint) (*entities.Resource, error) {
+	var resource entities.Resource
+	if err := database.DB.First(&resource, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &resource, nil
+}
+
+func (r *Repository) GetResourcesByBusinessID(businessID uint) ([]entities.Resource, error) {
+	var resources []entities.Resource
+	if err := database.DB.Where("business_id = ?", businessID).Order("name").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func (r *Repository) UpdateResource(resource *entities.Resource) error {
+	return database.DB.Save(resource).Error
+}
+
+func (r *Repository) DeleteResource(resource *entities.Resource) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(resource).Association("Services").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(resource).Error
+	})
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"VersatilePOS/database/entities"
+	"VersatilePOS/generic/constants"
+	"VersatilePOS/generic/rbac"
+	resourceModels "VersatilePOS/resource/models"
+	"VersatilePOS/resource/repository"
+	"errors"
+)
+
+type Service struct {
+	repo repository.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: repository.Repository{},
+	}
+}
+
+// hasResourceAccess checks if user has access to resources for a given business.
+// Resources are part of the service setup, so they share the Services permission.
+func (s *Service) hasResourceAccess(businessID uint, userID uint, level constants.AccessLevel) (bool, error) {
+	ok, err := rbac.HasAccess(constants.Services, level, businessID, userID)
+	if err != nil {
+		return false, errors.New("failed to verify permissions")
+	}
+	return ok, nil
+}
+
+func (s *Service) CreateResource(req resourceModels.CreateResourceRequest, userID uint) (*resourceModels.ResourceDto, error) {
+	hasAccess, err := s.hasResourceAccess(req.BusinessID, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	resource := &entities.Resource{
+		BusinessID: req.BusinessID,
+		Name:       req.Name,
+		Type:       req.Type,
+		Capacity:   req.Capacity,
+	}
+	if resource.Capacity == 0 {
+		resource.Capacity = 1
+	}
+
+	if err := s.repo.CreateResource(resource); err != nil {
+		return nil, errors.New("failed to create resource")
+	}
+
+	dto := resourceModels.NewResourceDtoFromEntity(*resource)
+	return &dto, nil
+}
+
+func (s *Service) GetResources(businessID uint, userID uint) ([]resourceModels.ResourceDto, error) {
+	hasAccess, err := s.hasResourceAccess(businessID, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	resources, err := s.repo.GetResourcesByBusinessID(businessID)
+	if err != nil {
+		return nil, errors.New("failed to get resources")
+	}
+
+	dtos := make([]resourceModels.ResourceDto, 0, len(resources))
+	for _, resource := range resources {
+		dtos = append(dtos, resourceModels.NewResourceDtoFromEntity(resource))
+	}
+	return dtos, nil
+}
+
+func (s *Service) GetResourceByID(id uint, userID uint) (*resourceModels.ResourceDto, error) {
+	resource, err := s.getAuthorizedResource(id, userID, constants.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := resourceModels.NewResourceDtoFromEntity(*resource)
+	return &dto, nil
+}
+
+func (s *Service) UpdateResource(id uint, req resourceModels.UpdateResourceRequest, userID uint) (*resourceModels.ResourceDto, error) {
+	resource, err := s.getAuthorizedResource(id, userID, constants.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		resource.Name = *req.Name
+	}
+	if req.Type != nil {
+		resource.Type = *req.Type
+	}
+	if req.Capacity != nil {
+		if *req.Capacity == 0 {
+			return nil, errors.New("capacity must be at least 1")
+		}
+		resource.Capacity = *req.Capacity
+	}
+
+	if err := s.repo.UpdateResource(resource); err != nil {
+		return nil, errors.New("failed to update resource")
+	}
+
+	dto := resourceModels.NewResourceDtoFromEntity(*resource)
+	return &dto, nil
+}
+
+func (s *Service) DeleteResource(id uint, userID uint) error {
+	resource, err := s.getAuthorizedResource(id, userID, constants.Write)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteResource(resource); err != nil {
+		return errors.New("failed to delete resource")
+	}
+
+	return nil
+}
+
+func (s *Service) getAuthorizedResource(id uint, userID uint, level constants.AccessLevel) (*entities.Resource, error) {
+	resource, err := s.repo.GetResourceByID(id)
+	if err != nil {
+		return nil, errors.New("failed to get resource")
+	}
+	if resource == nil {
+		return nil, errors.New("resource not found")
+	}
+
+	hasAccess, err := s.hasResourceAccess(resource.BusinessID, userID, level)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("unauthorized")
+	}
+
+	return resource, nil
+}
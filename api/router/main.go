@@ -6,10 +6,12 @@ import (
 	_ "VersatilePOS/docs"
 	"VersatilePOS/giftCard"
 	"VersatilePOS/item"
+	"VersatilePOS/location"
 	"VersatilePOS/order"
 	"VersatilePOS/payment"
 	"VersatilePOS/priceModifier"
 	"VersatilePOS/reservation"
+	"VersatilePOS/resource"
 	"VersatilePOS/service"
 	"VersatilePOS/tag"
 
@@ -27,8 +29,10 @@ func RegisterRoutes(r *gin.Engine) {
 	payment.RegisterHandlers(r)
 	priceModifier.RegisterHandlers(r)
 	service.RegisterHandlers(r)
+	resource.RegisterHandlers(r)
 	tag.RegisterHandlers(r)
 	giftCard.RegisterHandlers(r)
+	location.RegisterHandlers(r)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
@@ -2,9 +2,11 @@ package main
 
 import (
 	"VersatilePOS/database"
+	paymentService "VersatilePOS/payment/service"
 	"VersatilePOS/router"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -34,13 +36,23 @@ func main() {
 
 	database.Connect()
 
+	payments := paymentService.NewService()
+	payments.StartWebhookWorker()
+	payments.StartAuthorizationSweeper()
+	payments.StartReconciler()
+
 	r := gin.Default()
 
+	// Client IPs key the rate limits, so X-Forwarded-For is only believed from the proxies in TRUSTED_PROXIES
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Approval-Token", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
 		AllowCredentials: false,
 	}))
 
@@ -57,3 +69,14 @@ func main() {
 
 	r.Run(host + ":" + port)
 }
+
+// trustedProxies reads the comma-separated proxy IPs or CIDRs from TRUSTED_PROXIES. None are trusted by default.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -7,6 +7,7 @@ import (
 	"VersatilePOS/service/service"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -52,6 +53,8 @@ func (ctrl *Controller) CreateService(c *gin.Context) {
 	if err != nil {
 		if err.Error() == "unauthorized" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if strings.HasSuffix(err.Error(), "must not be negative") || err.Error() == "percentage deposit must not exceed 100" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		} else {
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
@@ -192,6 +195,8 @@ func (ctrl *Controller) UpdateService(c *gin.Context) {
 			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
 		} else if err.Error() == "unauthorized" || err.Error() == "unauthorized to assign service to this business" {
 			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		} else if strings.HasSuffix(err.Error(), "must not be negative") || err.Error() == "percentage deposit must not exceed 100" {
+			c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
 		} else {
 			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
 		}
@@ -346,9 +351,109 @@ func (ctrl *Controller) RemoveServiceFromEmployee(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Assign a required resource to a service
+// @Description Link a resource to a service. Reservations of the service are allocated one free resource out of its required resources. The resource must belong to the same business as the service.
+// @Tags service
+// @Accept  json
+// @Produce  json
+// @Param   id   path      int  true  "Service ID"
+// @Param   request  body  models.AssignResourceRequest  true  "Resource assignment request"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /service/{id}/resource [post]
+// @Id assignResourceToService
+func (ctrl *Controller) AssignResourceToService(c *gin.Context) {
+	serviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid service ID"})
+		return
+	}
+
+	var req serviceModels.AssignResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	err = ctrl.service.AssignResourceToService(uint(serviceID), req, userID)
+	if err != nil {
+		switch err.Error() {
+		case "service not found", "resource not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized", "resource does not belong to the service's business":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Remove a required resource from a service
+// @Description Unlink a resource from a service.
+// @Tags service
+// @Param   id   path      int  true  "Service ID"
+// @Param   resourceId   path      int  true  "Resource ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.HTTPError
+// @Failure 401 {object} models.HTTPError
+// @Failure 403 {object} models.HTTPError
+// @Failure 404 {object} models.HTTPError
+// @Failure 500 {object} models.HTTPError
+// @Security BearerAuth
+// @Router /service/{id}/resource/{resourceId} [delete]
+// @Id removeResourceFromService
+func (ctrl *Controller) RemoveResourceFromService(c *gin.Context) {
+	serviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid service ID"})
+		return
+	}
+
+	resourceID, err := strconv.ParseUint(c.Param("resourceId"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.HTTPError{Error: "Invalid resource ID"})
+		return
+	}
+
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusUnauthorized, models.HTTPError{Error: err.Error()})
+		return
+	}
+
+	err = ctrl.service.RemoveResourceFromService(uint(serviceID), uint(resourceID), userID)
+	if err != nil {
+		switch err.Error() {
+		case "service not found", "resource not found":
+			c.IndentedJSON(http.StatusNotFound, models.HTTPError{Error: err.Error()})
+		case "unauthorized":
+			c.IndentedJSON(http.StatusForbidden, models.HTTPError{Error: err.Error()})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.HTTPError{Error: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 	serviceGroup := r.Group("/service")
-	serviceGroup.Use(middleware.AuthMiddleware())
+	serviceGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		serviceGroup.POST("", ctrl.CreateService)
 		serviceGroup.GET("", ctrl.GetServices)
@@ -357,6 +462,8 @@ func (ctrl *Controller) RegisterRoutes(r *gin.Engine) {
 		serviceGroup.PUT("/:id", ctrl.UpdateService)
 		serviceGroup.DELETE("/:id", ctrl.DeleteService)
 		serviceGroup.DELETE("/:id/employee/:employeeId", ctrl.RemoveServiceFromEmployee)
+		serviceGroup.POST("/:id/resource", ctrl.AssignResourceToService)
+		serviceGroup.DELETE("/:id/resource/:resourceId", ctrl.RemoveResourceFromService)
 	}
 }
 
@@ -0,0 +1,5 @@
+package models
+
+type AssignResourceRequest struct {
+	ResourceID uint `json:"resourceId" binding:"required"`
+}
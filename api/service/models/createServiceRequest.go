@@ -8,5 +8,10 @@ type CreateServiceRequest struct {
 	ProvisioningStartTime string `json:"provisioningStartTime" validate:"required"`
 	ProvisioningEndTime   string `json:"provisioningEndTime" validate:"required"`
 	ProvisioningInterval  uint   `json:"provisioningInterval" validate:"required,gt=0"`
+	CancellationWindow    uint    `json:"cancellationWindow"`
+	LateCancellationFee   float64 `json:"lateCancellationFee" validate:"gte=0"`
+	NoShowFee             float64 `json:"noShowFee" validate:"gte=0"`
+	DepositValue          float64 `json:"depositValue" validate:"gte=0"`
+	DepositIsPercentage   bool    `json:"depositIsPercentage"`
 }
 
@@ -14,7 +14,13 @@ type ServiceDto struct {
 	ProvisioningStartTime string `json:"provisioningStartTime"`
 	ProvisioningEndTime   string `json:"provisioningEndTime"`
 	ProvisioningInterval  uint   `json:"provisioningInterval"`
+	CancellationWindow    uint    `json:"cancellationWindow"`
+	LateCancellationFee   float64 `json:"lateCancellationFee"`
+	NoShowFee             float64 `json:"noShowFee"`
+	DepositValue          float64 `json:"depositValue"`
+	DepositIsPercentage   bool    `json:"depositIsPercentage"`
 	Employees    []models.AccountDto `json:"employees,omitempty"`
+	RequiredResources []ServiceResourceDto `json:"requiredResources"`
 	CreatedAt    string  `json:"createdAt"`
 	UpdatedAt    string  `json:"updatedAt"`
 }
@@ -25,6 +31,11 @@ func NewServiceDtoFromEntity(s entities.Service) ServiceDto {
 		employees = append(employees, models.NewAccountDtoFromEntity(employee, nil))
 	}
 
+	requiredResources := make([]ServiceResourceDto, 0)
+	for _, resource := range s.RequiredResources {
+		requiredResources = append(requiredResources, NewServiceResourceDtoFromEntity(resource))
+	}
+
 	return ServiceDto{
 		ID:                    s.ID,
 		BusinessID:            s.BusinessID,
@@ -34,7 +45,13 @@ func NewServiceDtoFromEntity(s entities.Service) ServiceDto {
 		ProvisioningStartTime: s.ProvisioningStartTime.UTC().Format("15:04"),
 		ProvisioningEndTime:   s.ProvisioningEndTime.UTC().Format("15:04"),
 		ProvisioningInterval:  s.ProvisioningInterval,
+		CancellationWindow:    s.CancellationWindow,
+		LateCancellationFee:   s.LateCancellationFee,
+		NoShowFee:             s.NoShowFee,
+		DepositValue:          s.DepositValue,
+		DepositIsPercentage:   s.DepositIsPercentage,
 		Employees:             employees,
+		RequiredResources:     requiredResources,
 		CreatedAt:             s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:             s.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
@@ -0,0 +1,20 @@
+package models
+
+import "VersatilePOS/database/entities"
+
+// ServiceResourceDto is a resource required by a service
+type ServiceResourceDto struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Capacity uint   `json:"capacity"`
+}
+
+func NewServiceResourceDtoFromEntity(r entities.Resource) ServiceResourceDto {
+	return ServiceResourceDto{
+		ID:       r.ID,
+		Name:     r.Name,
+		Type:     r.Type,
+		Capacity: r.Capacity,
+	}
+}
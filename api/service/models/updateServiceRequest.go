@@ -8,5 +8,10 @@ type UpdateServiceRequest struct {
 	ProvisioningStartTime *string `json:"provisioningStartTime"`
 	ProvisioningEndTime   *string `json:"provisioningEndTime"`
 	ProvisioningInterval  *uint   `json:"provisioningInterval"`
+	CancellationWindow    *uint    `json:"cancellationWindow"`
+	LateCancellationFee   *float64 `json:"lateCancellationFee"`
+	NoShowFee             *float64 `json:"noShowFee"`
+	DepositValue          *float64 `json:"depositValue"`
+	DepositIsPercentage   *bool    `json:"depositIsPercentage"`
 }
 
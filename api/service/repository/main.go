@@ -15,7 +15,7 @@ func (r *Repository) CreateService(service *entities.Service) error {
 
 func (r *Repository) GetServiceByID(id uint) (*entities.Service, error) {
 	var service entities.Service
-	if err := database.DB.Preload("Employees").First(&service, id).Error; err != nil {
+	if err := database.DB.Preload("Employees").Preload("RequiredResources").First(&service, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -34,14 +34,14 @@ func (r *Repository) GetServices() ([]entities.Service, error) {
 
 func (r *Repository) GetServicesByBusinessID(businessID uint) ([]entities.Service, error) {
 	var services []entities.Service
-	if err := database.DB.Preload("Employees").Where("business_id = ?", businessID).Find(&services).Error; err != nil {
+	if err := database.DB.Preload("Employees").Preload("RequiredResources").Where("business_id = ?", businessID).Find(&services).Error; err != nil {
 		return nil, err
 	}
 	return services, nil
 }
 
 func (r *Repository) UpdateService(service *entities.Service) error {
-	return database.DB.Save(service).Error
+	return database.DB.Omit("RequiredResources").Save(service).Error
 }
 
 func (r *Repository) DeleteService(id uint) error {
@@ -64,3 +64,10 @@ func (r *Repository) RemoveServiceFromEmployee(service *entities.Service, employ
 	return database.DB.Model(service).Association("Employees").Delete(employee)
 }
 
+func (r *Repository) AssignResourceToService(service *entities.Service, resource *entities.Resource) error {
+	return database.DB.Model(service).Association("RequiredResources").Append(resource)
+}
+
+func (r *Repository) RemoveResourceFromService(service *entities.Service, resource *entities.Resource) error {
+	return database.DB.Model(service).Association("RequiredResources").Delete(resource)
+}
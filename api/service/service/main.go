@@ -2,6 +2,7 @@ package service
 
 import (
 	accountRepository "VersatilePOS/account/repository"
+	resourceRepository "VersatilePOS/resource/repository"
 	"VersatilePOS/database/entities"
 	"VersatilePOS/generic/constants"
 	"VersatilePOS/generic/rbac"
@@ -14,14 +15,16 @@ import (
 )
 
 type Service struct {
-	repo        repository.Repository
-	accountRepo accountRepository.Repository
+	repo         repository.Repository
+	accountRepo  accountRepository.Repository
+	resourceRepo resourceRepository.Repository
 }
 
 func NewService() *Service {
 	return &Service{
-		repo:        repository.Repository{},
-		accountRepo: accountRepository.Repository{},
+		repo:         repository.Repository{},
+		accountRepo:  accountRepository.Repository{},
+		resourceRepo: resourceRepository.Repository{},
 	}
 }
 
@@ -63,6 +66,24 @@ func (s *Service) CreateService(req serviceModels.CreateServiceRequest, userID u
 		ProvisioningStartTime: startTime,
 		ProvisioningEndTime:   endTime,
 		ProvisioningInterval:  req.ProvisioningInterval,
+		CancellationWindow:    req.CancellationWindow,
+		LateCancellationFee:   req.LateCancellationFee,
+		NoShowFee:             req.NoShowFee,
+		DepositValue:          req.DepositValue,
+		DepositIsPercentage:   req.DepositIsPercentage,
+	}
+
+	if service.LateCancellationFee < 0 {
+		return nil, errors.New("lateCancellationFee must not be negative")
+	}
+	if service.NoShowFee < 0 {
+		return nil, errors.New("noShowFee must not be negative")
+	}
+	if service.DepositValue < 0 {
+		return nil, errors.New("depositValue must not be negative")
+	}
+	if service.DepositIsPercentage && service.DepositValue > 100 {
+		return nil, errors.New("percentage deposit must not exceed 100")
 	}
 
 	if err := s.repo.CreateService(service); err != nil {
@@ -184,6 +205,33 @@ func (s *Service) UpdateService(id uint, req serviceModels.UpdateServiceRequest,
 	if req.ProvisioningInterval != nil {
 		service.ProvisioningInterval = *req.ProvisioningInterval
 	}
+	if req.CancellationWindow != nil {
+		service.CancellationWindow = *req.CancellationWindow
+	}
+	if req.LateCancellationFee != nil {
+		if *req.LateCancellationFee < 0 {
+			return nil, errors.New("lateCancellationFee must not be negative")
+		}
+		service.LateCancellationFee = *req.LateCancellationFee
+	}
+	if req.NoShowFee != nil {
+		if *req.NoShowFee < 0 {
+			return nil, errors.New("noShowFee must not be negative")
+		}
+		service.NoShowFee = *req.NoShowFee
+	}
+	if req.DepositValue != nil {
+		if *req.DepositValue < 0 {
+			return nil, errors.New("depositValue must not be negative")
+		}
+		service.DepositValue = *req.DepositValue
+	}
+	if req.DepositIsPercentage != nil {
+		service.DepositIsPercentage = *req.DepositIsPercentage
+	}
+	if service.DepositIsPercentage && service.DepositValue > 100 {
+		return nil, errors.New("percentage deposit must not exceed 100")
+	}
 
 	if err := s.repo.UpdateService(service); err != nil {
 		return nil, errors.New("failed to update service")
@@ -321,4 +369,61 @@ func (s *Service) RemoveServiceFromEmployee(employeeID uint, serviceID uint, use
 	return nil
 }
 
+func (s *Service) AssignResourceToService(serviceID uint, req serviceModels.AssignResourceRequest, userID uint) error {
+	service, resource, err := s.getServiceAndResource(serviceID, req.ResourceID, userID)
+	if err != nil {
+		return err
+	}
 
+	if resource.BusinessID != service.BusinessID {
+		return errors.New("resource does not belong to the service's business")
+	}
+
+	if err := s.repo.AssignResourceToService(service, resource); err != nil {
+		return errors.New("failed to assign resource to service")
+	}
+
+	return nil
+}
+
+func (s *Service) RemoveResourceFromService(serviceID uint, resourceID uint, userID uint) error {
+	service, resource, err := s.getServiceAndResource(serviceID, resourceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveResourceFromService(service, resource); err != nil {
+		return errors.New("failed to remove resource from service")
+	}
+
+	return nil
+}
+
+func (s *Service) getServiceAndResource(serviceID uint, resourceID uint, userID uint) (*entities.Service, *entities.Resource, error) {
+	service, err := s.repo.GetServiceByID(serviceID)
+	if err != nil {
+		return nil, nil, errors.New("failed to get service")
+	}
+	if service == nil {
+		return nil, nil, errors.New("service not found")
+	}
+
+	// Check if user has write access to services for this business
+	hasAccess, err := s.hasServiceAccess(service.BusinessID, userID, constants.Write)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hasAccess {
+		return nil, nil, errors.New("unauthorized")
+	}
+
+	resource, err := s.resourceRepo.GetResourceByID(resourceID)
+	if err != nil {
+		return nil, nil, errors.New("failed to get resource")
+	}
+	if resource == nil {
+		return nil, nil, errors.New("resource not found")
+	}
+
+	return service, resource, nil
+}
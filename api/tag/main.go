@@ -11,7 +11,7 @@ func RegisterHandlers(r *gin.Engine) {
 	ctrl := controller.NewController()
 
 	tagGroup := r.Group("/tag")
-	tagGroup.Use(middleware.AuthMiddleware())
+	tagGroup.Use(middleware.AuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		tagGroup.POST("", ctrl.CreateTag)
 		tagGroup.GET("", ctrl.GetTags)
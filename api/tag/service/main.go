@@ -429,6 +429,7 @@ func (s *Service) GetItemsByTag(tagID uint, userID uint) ([]itemModels.ItemDto,
 			BusinessID: item.BusinessID,
 			Name:       item.Name,
 			Price:      item.Price,
+			IsGiftCard: item.IsGiftCard,
 		}
 		if inv, exists := inventoryMap[item.ID]; exists {
 			dtos[i].QuantityInStock = &inv.QuantityInStock
@@ -706,6 +707,7 @@ func (s *Service) GetAllEntitiesByTag(tagID uint, userID uint) (*tagModels.TagEn
 			BusinessID: item.BusinessID,
 			Name:       item.Name,
 			Price:      item.Price,
+			IsGiftCard: item.IsGiftCard,
 		}
 		if inv, exists := itemInventoryMap[item.ID]; exists {
 			dto.QuantityInStock = &inv.QuantityInStock